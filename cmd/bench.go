@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+
+	"forgor/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// benchCmd represents the bench command
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Measure cold vs warm system context build time",
+	Long: `Measure how long forgor takes to build its system context.
+
+This times a cold build (full tool detection), a load from the persistent
+cache and a warm in-memory lookup, and breaks tool detection down by category.
+No LLM calls are made, so the numbers are reproducible for performance reports.
+
+Note: the cold build refreshes the persistent system context cache.
+
+Examples:
+  forgor bench                           # Show the timing breakdown`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fmt.Printf("%s Building system context from scratch...\n\n", utils.Styled("[BENCH]", utils.StyleInfo))
+
+		result, err := utils.RunContextBenchmark()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s\n", result.Report())
+
+		cold := result.StepDuration("Cold Build (RefreshSystemContext)")
+		warm := result.StepDuration("Warm Lookup (GetSystemContext)")
+		if warm > 0 {
+			fmt.Printf("\n%s Warm lookup is %.0fx faster than a cold build\n",
+				utils.Styled("[RESULT]", utils.StyleSuccess), float64(cold)/float64(warm))
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+}
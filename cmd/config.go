@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
@@ -9,6 +10,7 @@ import (
 
 	"forgor/internal/config"
 	"forgor/internal/llm"
+	"forgor/internal/utils"
 
 	"github.com/spf13/cobra"
 )
@@ -23,36 +25,73 @@ Examples:
   forgor config init                  # Create default config file
   forgor config show                  # Show current configuration
   forgor config set-default openai    # Set default provider
-  forgor config list-providers        # List available providers`,
+  forgor config list-providers        # List available providers
+  forgor config profile add work      # Add a provider profile
+  forgor config path                  # Show config and cache locations`,
 }
 
 // configInitCmd represents the config init command
 var configInitCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize default configuration",
-	Long:  `Create a default configuration file in ~/.config/forgor/config.yaml`,
+	Long:  `Create a default configuration file in ~/.config/forgor/config.yaml, or in $XDG_CONFIG_HOME/forgor when XDG_CONFIG_HOME is set`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := config.CreateDefaultConfig(); err != nil {
 			fmt.Printf("Error creating config: %v\n", err)
 			return
 		}
 		fmt.Println("✅ Default configuration created successfully!")
-		fmt.Println("📝 Edit ~/.config/forgor/config.yaml to customize your settings")
+		if path, err := config.GetConfigFilePath(); err == nil {
+			fmt.Printf("📝 Edit %s to customize your settings\n", path)
+		}
 		fmt.Println("🔑 Set your API keys in environment variables (e.g., OPENAI_API_KEY)")
 	},
 }
 
+// configShowFormat and configShowJSON select the config show output format
+var (
+	configShowFormat string
+	configShowJSON   bool
+)
+
 // configShowCmd represents the config show command
 var configShowCmd = &cobra.Command{
 	Use:   "show",
 	Short: "Show current configuration",
-	Long:  `Display the current configuration including all profiles`,
-	Run: func(cmd *cobra.Command, args []string) {
+	Long: `Display the current configuration including all profiles.
+
+API keys are masked; environment references such as ${OPENAI_API_KEY} are
+shown as written.
+
+Examples:
+  forgor config show              # Show the configuration
+  forgor config show --json       # Machine-readable output (same as -f json)`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if configShowJSON {
+			configShowFormat = "json"
+		}
+		if configShowFormat != "plain" && configShowFormat != "json" {
+			return fmt.Errorf("unsupported format: %s. Supported formats: plain, json", configShowFormat)
+		}
+
 		cfg, err := config.Load()
 		if err != nil {
+			if configShowFormat == "json" {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
 			fmt.Printf("Error loading config: %v\n", err)
 			fmt.Println("💡 Run 'forgor config init' to create a default configuration")
-			return
+			return nil
+		}
+
+		if configShowFormat == "json" {
+			data, err := cfg.MaskedJSON()
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
 		}
 
 		fmt.Printf("📋 Current Configuration\n")
@@ -67,24 +106,27 @@ var configShowCmd = &cobra.Command{
 			fmt.Printf("  %s%s:\n", name, marker)
 			fmt.Printf("    Provider: %s\n", profile.Provider)
 			fmt.Printf("    Model: %s\n", profile.Model)
-			if profile.APIKey != "" {
-				if profile.APIKey == "${OPENAI_API_KEY}" || profile.APIKey == "${ANTHROPIC_API_KEY}" || profile.APIKey == "${GOOGLE_AI_API_KEY}" {
-					fmt.Printf("    API Key: %s\n", profile.APIKey)
-				} else {
-					fmt.Printf("    API Key: %s***\n", profile.APIKey[:min(4, len(profile.APIKey))])
-				}
+			if apiKey := config.MaskAPIKey(profile.APIKey); apiKey != "" {
+				fmt.Printf("    API Key: %s\n", apiKey)
+			}
+			if profile.APIKeyFile != "" {
+				fmt.Printf("    API Key File: %s\n", profile.APIKeyFile)
 			}
 			if profile.Endpoint != "" {
 				fmt.Printf("    Endpoint: %s\n", profile.Endpoint)
 			}
+			if profile.Proxy != "" {
+				fmt.Printf("    Proxy: %s\n", config.MaskProxy(profile.Proxy))
+			}
 			fmt.Printf("    Max Tokens: %d\n", profile.MaxTokens)
 			fmt.Printf("    Temperature: %.1f\n\n", profile.Temperature)
 		}
 
-		fmt.Printf("📚 History: Max %d commands from %v shells\n",
-			cfg.History.MaxCommands, cfg.History.Shells)
+		fmt.Printf("📚 History: Max %d commands (%d characters, %d per command) from %v shells\n",
+			cfg.History.MaxCommands, cfg.History.MaxChars, cfg.History.MaxCommandChars, cfg.History.Shells)
 		fmt.Printf("🔒 Security: Redact sensitive data = %v\n", cfg.Security.RedactSensitive)
 		fmt.Printf("📤 Output: Format = %s\n", cfg.Output.Format)
+		return nil
 	},
 }
 
@@ -191,6 +233,49 @@ var configListProvidersCmd = &cobra.Command{
 	},
 }
 
+// configPathFormat is the output format for the config path command
+var configPathFormat string
+
+// configPathCmd represents the config path command
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Show config and cache file locations",
+	Long: `Display where forgor reads its configuration from and where it keeps its caches.
+
+Examples:
+  forgor config path              # Show locations
+  forgor config path -f json      # Machine-readable output`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		paths, err := config.GetPaths()
+		if err != nil {
+			return err
+		}
+
+		switch configPathFormat {
+		case "json":
+			data, err := json.MarshalIndent(paths, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal paths: %w", err)
+			}
+			fmt.Println(string(data))
+		case "plain":
+			existsMarker := "✅ exists"
+			if !paths.ConfigExists {
+				existsMarker = "❌ not found (run 'forgor config init')"
+			}
+			fmt.Printf("📁 Config File: %s (%s)\n", paths.ConfigFile, existsMarker)
+			fmt.Printf("🗄️  System Context Cache: %s\n", paths.SystemContextCache)
+			fmt.Printf("📝 Last Command Cache: %s\n", paths.LastCommandCache)
+			fmt.Printf("🕘 Recent Commands: %s\n", paths.RecentCommands)
+		default:
+			return fmt.Errorf("unsupported format: %s. Supported formats: plain, json", configPathFormat)
+		}
+
+		return nil
+	},
+}
+
 // configCompletionCmd represents the config completion command
 var configCompletionCmd = &cobra.Command{
 	Use:   "completion [shell]",
@@ -282,10 +367,10 @@ func setupBashCompletion(homeDir string) error {
 	}
 
 	// Add sourcing line to shell config
-	completionLine := fmt.Sprintf(`# forgor shell completion
+	completionLine := fmt.Sprintf(`%s
 if [ -f "%s" ]; then
     source "%s"
-fi`, completionFile, completionFile)
+fi`, completionMarker, completionFile, completionFile)
 
 	return addCompletionToFile(configFile, completionLine, "bash")
 }
@@ -312,10 +397,10 @@ func setupZshCompletion(homeDir string) error {
 	}
 
 	// Add sourcing line to shell config
-	completionLine := fmt.Sprintf(`# forgor shell completion
+	completionLine := fmt.Sprintf(`%s
 if [ -f "%s" ]; then
     source "%s"
-fi`, completionFile, completionFile)
+fi`, completionMarker, completionFile, completionFile)
 
 	return addCompletionToFile(configFile, completionLine, "zsh")
 }
@@ -349,6 +434,9 @@ func setupFishCompletion(homeDir string) error {
 	return nil
 }
 
+// completionMarker identifies the block forgor adds to shell rc files
+const completionMarker = "# forgor shell completion"
+
 func addCompletionToFile(configFile, completionLines, shell string) error {
 	// Check if completion is already set up
 	if isCompletionAlreadySetup(configFile) {
@@ -362,17 +450,15 @@ func addCompletionToFile(configFile, completionLines, shell string) error {
 		fmt.Printf("📋 Created backup: %s\n", backupFile)
 	}
 
-	// Add completion lines
-	file, err := os.OpenFile(configFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to open %s: %w", configFile, err)
-	}
-	defer file.Close()
-
-	_, err = file.WriteString("\n" + completionLines + "\n")
+	// Add completion lines (written atomically so a crash can't corrupt the rc file)
+	added, err := utils.AppendBlockToFile(configFile, completionLines, completionMarker)
 	if err != nil {
 		return fmt.Errorf("failed to write to %s: %w", configFile, err)
 	}
+	if !added {
+		fmt.Printf("✅ forgor completion is already set up in %s\n", configFile)
+		return nil
+	}
 
 	fmt.Printf("✅ Added forgor completion to %s\n", configFile)
 	fmt.Printf("🔄 Run 'source %s' or restart your %s shell to enable completion\n", configFile, shell)
@@ -401,7 +487,7 @@ func isCompletionAlreadySetup(configFile string) bool {
 		return false
 	}
 
-	return strings.Contains(string(content), "forgor completion")
+	return strings.Contains(string(content), completionMarker)
 }
 
 func copyFile(src, dst string) error {
@@ -424,6 +510,11 @@ func init() {
 	configCmd.AddCommand(configSetDefaultCmd)
 	configCmd.AddCommand(configListProvidersCmd)
 	configCmd.AddCommand(configCompletionCmd)
+	configCmd.AddCommand(configPathCmd)
+
+	configPathCmd.Flags().StringVarP(&configPathFormat, "format", "f", "plain", "output format: plain, json")
+	configShowCmd.Flags().StringVarP(&configShowFormat, "format", "f", "plain", "output format: plain, json")
+	configShowCmd.Flags().BoolVar(&configShowJSON, "json", false, "print the configuration as JSON (same as -f json)")
 }
 
 // min helper function
@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"forgor/internal/config"
 	"forgor/internal/utils"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -63,7 +65,7 @@ var configToolsAddCmd = &cobra.Command{
 
 		// Trigger background cache refresh to include new tools
 		if verbose {
-			fmt.Println("🔄 Triggering background cache refresh...")
+			utils.Debugf("🔄 Triggering background cache refresh...\n")
 		}
 		utils.RefreshSystemContextBackground()
 
@@ -93,7 +95,7 @@ var configToolsRemoveCmd = &cobra.Command{
 
 		// Trigger background cache refresh to update tools list
 		if verbose {
-			fmt.Println("🔄 Triggering background cache refresh...")
+			utils.Debugf("🔄 Triggering background cache refresh...\n")
 		}
 		utils.RefreshSystemContextBackground()
 
@@ -116,7 +118,7 @@ var configToolsClearCmd = &cobra.Command{
 
 		// Trigger background cache refresh to update tools list
 		if verbose {
-			fmt.Println("🔄 Triggering background cache refresh...")
+			utils.Debugf("🔄 Triggering background cache refresh...\n")
 		}
 		utils.RefreshSystemContextBackground()
 
@@ -148,7 +150,19 @@ var configToolsCategoriesCmd = &cobra.Command{
 var configCacheStatusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show system context cache status",
+	Long: `Show the age and freshness of the system context cache and where it is
+stored. With --json the status is printed as a JSON object for monitoring
+scripts; age_seconds and last_modified are null when there is no cache.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			data, err := json.MarshalIndent(utils.GetCacheStatus(), "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
 		age := utils.GetCacheAge()
 		refreshing := utils.IsRefreshInProgress()
 		cacheInfo := utils.GetCacheInfo()
@@ -161,8 +175,8 @@ var configCacheStatusCmd = &cobra.Command{
 			fmt.Printf("%s Cache available\n", utils.Styled("[STATUS]", utils.StyleSuccess))
 			fmt.Printf("%s %v\n", utils.Styled("Age:", utils.StyleInfo), age)
 
-			expiry := 5 * time.Minute
-			grace := 1 * time.Minute
+			expiry := utils.CacheExpiration()
+			grace := utils.CacheGracePeriod()
 
 			if age < expiry {
 				remaining := expiry - age
@@ -183,10 +197,13 @@ var configCacheStatusCmd = &cobra.Command{
 			fmt.Printf("%s Idle\n", utils.Styled("Background Refresh:", utils.StyleSubtle))
 		}
 
-		fmt.Printf("%s %v\n", utils.Styled("Cache Expiry:", utils.StyleSubtle), 5*time.Minute)
-		fmt.Printf("%s %v\n", utils.Styled("Grace Period:", utils.StyleSubtle), 1*time.Minute)
+		fmt.Printf("%s %v\n", utils.Styled("Cache Expiry:", utils.StyleSubtle), utils.CacheExpiration())
+		fmt.Printf("%s %v\n", utils.Styled("Grace Period:", utils.StyleSubtle), utils.CacheGracePeriod())
 
-		if cacheInfo.FilePath != "" {
+		if cacheInfo.InMemoryOnly {
+			fmt.Printf("\n%s %s is not writable; caching in memory only\n",
+				utils.Styled("[WARNING]", utils.StyleWarning), cacheInfo.CacheDir)
+		} else if cacheInfo.FilePath != "" {
 			fmt.Printf("\n%s\n", utils.Divider("PERSISTENT CACHE", utils.StyleInfo))
 			fmt.Printf("%s %s\n", utils.Styled("Location:", utils.StyleInfo), cacheInfo.FilePath)
 			if cacheInfo.FileSize > 0 {
@@ -209,10 +226,20 @@ var configCacheStatusCmd = &cobra.Command{
 var configCacheRefreshCmd = &cobra.Command{
 	Use:   "refresh",
 	Short: "Force refresh of system context cache",
+	Long: `Force a refresh of the system context cache. With --category, only that
+category of tools is re-detected and the others are kept as cached.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		background, _ := cmd.Flags().GetBool("background")
+		category, _ := cmd.Flags().GetString("category")
 
-		if background {
+		if category != "" {
+			fmt.Printf("%s Refreshing %s...\n", utils.Styled("[INFO]", utils.StyleInfo), category)
+			start := time.Now()
+			if _, err := utils.RefreshToolCategory(category); err != nil {
+				return err
+			}
+			fmt.Printf("%s %s refreshed in %v\n", utils.Styled("[SUCCESS]", utils.StyleSuccess), category, time.Since(start))
+		} else if background {
 			fmt.Printf("%s Starting background cache refresh...\n", utils.Styled("[INFO]", utils.StyleInfo))
 			utils.RefreshSystemContextBackground()
 			fmt.Printf("%s Background refresh initiated\n", utils.Styled("[SUCCESS]", utils.StyleSuccess))
@@ -244,6 +271,51 @@ var configCacheClearCmd = &cobra.Command{
 	},
 }
 
+// configCachePruneCmd removes stale cache files
+var configCachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove stale cache files",
+	Long: `Remove an expired system context cache, leftover temporary files from
+interrupted writes, and recent commands beyond the ring's limit.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		now := time.Now()
+		cacheInfo := utils.GetCacheInfo()
+
+		// An unwritable cache directory holds nothing to prune
+		var result utils.PruneResult
+		if !cacheInfo.InMemoryOnly {
+			var err error
+			if result, err = utils.PruneCacheDir(cacheInfo.CacheDir, now); err != nil {
+				return fmt.Errorf("failed to prune cache: %w", err)
+			}
+		}
+
+		// The recent-command ring and last command live in the config directory
+		if ringPath, err := config.GetRecentCommandsPath(); err == nil {
+			configResult, err := utils.PruneTempFiles(filepath.Dir(ringPath), now)
+			if err != nil {
+				return fmt.Errorf("failed to prune temporary files: %w", err)
+			}
+			result.Add(configResult)
+		}
+
+		trimmed, err := config.TruncateRecentCommands(config.MaxRecentCommands)
+		if err != nil {
+			return fmt.Errorf("failed to truncate recent commands: %w", err)
+		}
+		result.BytesReclaimed += trimmed
+
+		for _, path := range result.Removed {
+			fmt.Printf("%s %s\n", utils.Styled("Removed:", utils.StyleSubtle), path)
+		}
+		if trimmed > 0 {
+			fmt.Printf("%s recent commands to the last %d\n", utils.Styled("Trimmed:", utils.StyleSubtle), config.MaxRecentCommands)
+		}
+		fmt.Printf("%s Reclaimed %.1f KB\n", utils.Styled("[SUCCESS]", utils.StyleSuccess), float64(result.BytesReclaimed)/1024)
+		return nil
+	},
+}
+
 // configCacheLocationCmd shows cache file location
 var configCacheLocationCmd = &cobra.Command{
 	Use:   "location",
@@ -256,7 +328,9 @@ var configCacheLocationCmd = &cobra.Command{
 		fmt.Printf("%s %s\n", utils.Styled("Cache File:", utils.StyleInfo), cacheInfo.FilePath)
 		fmt.Printf("%s %s\n", utils.Styled("Lock File:", utils.StyleInfo), cacheInfo.LockFile)
 
-		if cacheInfo.FileExists {
+		if cacheInfo.InMemoryOnly {
+			fmt.Printf("\n%s Cache directory is not writable; caching in memory only\n", utils.Styled("[STATUS]", utils.StyleWarning))
+		} else if cacheInfo.FileExists {
 			fmt.Printf("\n%s Cache file exists\n", utils.Styled("[STATUS]", utils.StyleSuccess))
 			if cacheInfo.FileSize > 0 {
 				fmt.Printf("%s %.1f KB\n", utils.Styled("Size:", utils.StyleInfo), float64(cacheInfo.FileSize)/1024)
@@ -285,8 +359,14 @@ func init() {
 	configCacheCmd.AddCommand(configCacheStatusCmd)
 	configCacheCmd.AddCommand(configCacheRefreshCmd)
 	configCacheCmd.AddCommand(configCacheClearCmd)
+	configCacheCmd.AddCommand(configCachePruneCmd)
 	configCacheCmd.AddCommand(configCacheLocationCmd)
 
 	// Flags
+	configCacheStatusCmd.Flags().Bool("json", false, "Print the status as JSON")
 	configCacheRefreshCmd.Flags().BoolP("background", "b", false, "Refresh in background")
+	configCacheRefreshCmd.Flags().String("category", "", "Refresh only one tool category (e.g. container_tools)")
+	configCacheRefreshCmd.RegisterFlagCompletionFunc("category", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return utils.ToolCategories(), cobra.ShellCompDirectiveNoFileComp
+	})
 }
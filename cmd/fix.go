@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"forgor/internal/config"
+	"forgor/internal/llm"
+	"forgor/internal/utils"
+)
+
+// maxFixAttempts bounds how many times a failing -R command is sent back to
+// the provider for a fix
+const maxFixAttempts = 3
+
+// offerFix offers to send a failed command and its output back to the
+// provider for a corrected command. If the user runs the fix and it fails
+// too, the offer repeats up to maxFixAttempts times.
+func offerFix(ctx context.Context, provider llm.Provider, request *llm.Request, failure *utils.CommandError) error {
+	// Without a terminal there is nobody to ask
+	if utils.StdinIsPiped() {
+		return failure
+	}
+
+	for attempt := 1; attempt <= maxFixAttempts; attempt++ {
+		fmt.Printf("\n%s Ask %s to diagnose the failure and suggest a fix? [y/N]: ",
+			utils.Styled("[FIX]", utils.StyleInfo), provider.GetProviderInfo().Name)
+		if !confirmYesNo() {
+			return failure
+		}
+
+		// The command's output can hold secrets it printed, such as a failed
+		// 'cat .env', so it gets the same on_secret handling as queries
+		result := failure.Result
+		texts, err := protectOutgoing(loadSecurityConfig().OnSecret, "the failed command and its output", result.Command, result.Output)
+		if err != nil {
+			return err
+		}
+		fix, err := llm.SuggestFix(ctx, provider, request, texts[0], result.ExitCode, texts[1])
+		if err != nil {
+			return fmt.Errorf("failed to get a fix: %w", err)
+		}
+		if fix.Command == "" {
+			fmt.Printf("%s The provider did not suggest a fix\n", utils.Styled("[WARNING]", utils.StyleWarning))
+			return failure
+		}
+
+		// Make the fix available to 'forgor run' even if it isn't run now
+		if err := config.SaveLastCommand(fix.Command); err != nil {
+			utils.Warnf("%s Failed to cache command: %v\n", utils.Styled("[WARNING]", utils.StyleWarning), err)
+		}
+
+		fmt.Printf("\n%s\n", utils.Divider("SUGGESTED FIX", utils.StyleCommand))
+		fmt.Printf("%s\n", utils.SimpleBox(fix.Command, utils.StyleCommand))
+		if fix.Explanation != "" {
+			fmt.Printf("%s %s\n", utils.Styled("Explanation:", utils.StyleInfo), formatExplanation(fix.Explanation))
+		}
+		if len(fix.Warnings) > 0 {
+			fmt.Printf("%s\n", utils.List(fix.Warnings, utils.StyleWarning))
+		}
+
+		fmt.Printf("\n%s [y/N]: ", utils.Styled("Run the suggested fix?", utils.StyleInfo))
+		if !confirmYesNo() {
+			return failure
+		}
+
+		err = executeCommand(fix.Command, fix.Warnings)
+		var nextFailure *utils.CommandError
+		if !errors.As(err, &nextFailure) {
+			return err
+		}
+		failure = nextFailure
+	}
+
+	fmt.Printf("%s Giving up after %d fix attempts\n", utils.Styled("[STOPPED]", utils.StyleWarning), maxFixAttempts)
+	return failure
+}
+
+// confirmYesNo reads a y/N answer from stdin, defaulting to no
+func confirmYesNo() bool {
+	reader := bufio.NewReader(os.Stdin)
+	response, err := reader.ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	response = strings.TrimSpace(strings.ToLower(response))
+	return response == "y" || response == "yes"
+}
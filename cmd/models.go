@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"forgor/internal/config"
+	"forgor/internal/llm"
+	"forgor/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// modelsLive fetches models from the provider API instead of the built-in list
+var modelsLive bool
+
+// modelsCmd represents the models command
+var modelsCmd = &cobra.Command{
+	Use:   "models [provider]",
+	Short: "List available models per provider",
+	Long: `List the models forgor knows to be valid for each provider.
+
+The model configured for the active profile is marked. Use --live to fetch
+the models available to your API key from providers that support it (OpenAI, Groq, Mistral, Ollama).
+
+Examples:
+  forgor models                  # List known models for all providers
+  forgor models gemini           # List known Gemini models
+  forgor models openai --live    # Fetch models from the OpenAI API`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"openai", "anthropic", "gemini", "groq", "mistral", "ollama"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		providers := []string{"openai", "anthropic", "gemini", "groq", "mistral", "ollama"}
+		if len(args) > 0 {
+			providerType := strings.ToLower(args[0])
+			if llm.SupportedModels(providerType) == nil {
+				return fmt.Errorf("unsupported provider: %s. Supported providers: %s",
+					args[0], strings.Join(providers, ", "))
+			}
+			providers = []string{providerType}
+		}
+
+		// The config is optional here; without it nothing is marked as current
+		cfg, cfgErr := config.Load()
+		var current config.Profile
+		if cfgErr == nil {
+			current, _ = cfg.GetProfile(resolveProfileName(cfg))
+		}
+
+		for i, providerType := range providers {
+			if i > 0 {
+				fmt.Println()
+			}
+
+			models := llm.SupportedModels(providerType)
+			source := "known models"
+
+			if modelsLive {
+				liveModels, err := fetchLiveModels(cfg, providerType)
+				if err != nil {
+					utils.Warnf("%s Could not fetch live models for %s: %v\n", utils.Styled("[WARNING]", utils.StyleWarning), providerType, err)
+				} else if liveModels != nil {
+					models = liveModels
+					source = "live"
+				}
+			}
+
+			currentModel := ""
+			if llm.SupportedModels(current.Provider) != nil && sameProviderType(current.Provider, providerType) {
+				currentModel = current.Model
+			}
+
+			fmt.Printf("%s %s\n", utils.Styled(strings.ToUpper(providerType), utils.StyleHighlight), utils.Styled("("+source+")", utils.StyleSubtle))
+			fmt.Print(llm.FormatModelList(models, currentModel))
+			if currentModel != "" {
+				limits, known := llm.GetModelLimits(providerType, currentModel)
+				note := ""
+				if !known {
+					note = " (assumed, model limits unknown)"
+				}
+				fmt.Printf("  %s %s%s\n", utils.Styled("Limits for "+currentModel+":", utils.StyleSubtle), limits, note)
+			}
+		}
+
+		return nil
+	},
+}
+
+// sameProviderType compares provider types, treating "google" as "gemini"
+func sameProviderType(a, b string) bool {
+	normalize := func(s string) string {
+		s = strings.ToLower(s)
+		if s == "google" {
+			return "gemini"
+		}
+		return s
+	}
+	return normalize(a) == normalize(b)
+}
+
+// fetchLiveModels lists models from the API of the first configured profile
+// for providerType. It returns nil if the provider has no list endpoint.
+func fetchLiveModels(cfg *config.Config, providerType string) ([]string, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("no configuration found, run 'forgor config init'")
+	}
+
+	profileName := ""
+	if active, err := cfg.GetProfile(resolveProfileName(cfg)); err == nil && sameProviderType(active.Provider, providerType) {
+		profileName = resolveProfileName(cfg)
+	} else {
+		for name, p := range cfg.Profiles {
+			if sameProviderType(p.Provider, providerType) {
+				profileName = name
+				break
+			}
+		}
+	}
+	if profileName == "" {
+		return nil, fmt.Errorf("no profile configured for %s", providerType)
+	}
+
+	provider, err := llm.NewFactory(cfg).GetProvider(profileName)
+	if err != nil {
+		return nil, err
+	}
+
+	lister, ok := provider.(llm.ModelLister)
+	if !ok {
+		utils.Debugf("%s does not support listing models, showing known models\n", providerType)
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	return lister.ListModels(ctx)
+}
+
+func init() {
+	rootCmd.AddCommand(modelsCmd)
+
+	modelsCmd.Flags().BoolVar(&modelsLive, "live", false, "fetch available models from the provider API (OpenAI)")
+}
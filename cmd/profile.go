@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"forgor/internal/config"
+	"forgor/internal/llm"
+	"forgor/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// Note: configCmd is defined in config.go
+
+// configProfileCmd represents the config profile command
+var configProfileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage provider profiles",
+	Long:  `Add and manage provider profiles without editing the config file by hand.`,
+}
+
+// configProfileAddCmd represents the config profile add command
+var configProfileAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add a provider profile",
+	Long: `Add a provider profile, prompting for the provider, model, API key
+environment variable, max tokens and temperature. Flags provide the defaults
+for each prompt, or all values with --non-interactive.
+
+Examples:
+  forgor config profile add work
+  forgor config profile add fast --non-interactive --provider openai --model gpt-4o-mini`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		name := args[0]
+		if _, exists := cfg.Profiles[name]; exists {
+			return fmt.Errorf("profile '%s' already exists", name)
+		}
+
+		values := profileAddOptions
+		if !profileNonInteractive {
+			if values, err = promptProfileOptions(bufio.NewReader(os.Stdin), os.Stdout, values); err != nil {
+				return err
+			}
+		}
+
+		profile, err := values.profile()
+		if err != nil {
+			return err
+		}
+		if err := config.AddProfile(cfg, name, profile); err != nil {
+			return err
+		}
+
+		fmt.Printf("✅ Profile '%s' added (%s, %s)\n", name, profile.Provider, profile.Model)
+		if missing := config.MissingEnvVars(profile.APIKey); len(missing) > 0 {
+			fmt.Printf("Export %s before using it\n", strings.Join(missing, ", "))
+		}
+		fmt.Printf("Use it with '%s' or make it the default with '%s'\n",
+			utils.Styled(fmt.Sprintf("forgor -p %s", name), utils.StyleCommand),
+			utils.Styled(fmt.Sprintf("forgor config set-default %s", name), utils.StyleCommand))
+		return nil
+	},
+}
+
+// configProfileRemoveCmd represents the config profile remove command
+var configProfileRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a provider profile",
+	Long: `Remove a provider profile from the config file.
+
+The default profile is only removed with --force, which also needs --default
+to choose the profile that replaces it.
+
+Examples:
+  forgor config profile remove work
+  forgor config profile remove openai --force --default anthropic`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		name := args[0]
+		if name == cfg.DefaultProfile {
+			if !profileRemoveForce {
+				return fmt.Errorf("profile '%s' is the default profile; use --force --default <profile> to remove it", name)
+			}
+			if profileRemoveNewDefault == "" {
+				return fmt.Errorf("removing the default profile needs --default <profile> to choose a new default")
+			}
+		}
+
+		if err := config.RemoveProfile(cfg, name, profileRemoveNewDefault); err != nil {
+			return err
+		}
+
+		fmt.Printf("✅ Profile '%s' removed\n", name)
+		if profileRemoveNewDefault != "" {
+			fmt.Printf("Default profile is now '%s'\n", cfg.DefaultProfile)
+		}
+		return nil
+	},
+}
+
+// profileOptions holds the values used to build a new profile
+type profileOptions struct {
+	provider    string
+	model       string
+	apiKeyEnv   string
+	maxTokens   int
+	temperature float64
+}
+
+var (
+	// profileAddOptions are set by the config profile add flags
+	profileAddOptions profileOptions
+
+	// profileNonInteractive builds the profile from flags without prompting
+	profileNonInteractive bool
+
+	// profileRemoveForce allows removing the default profile
+	profileRemoveForce bool
+
+	// profileRemoveNewDefault is the profile that becomes the default
+	profileRemoveNewDefault string
+)
+
+// profile builds a config profile, filling in the provider's default model
+// and API key environment variable when they weren't given
+func (o profileOptions) profile() (config.Profile, error) {
+	if o.provider == "" {
+		return config.Profile{}, fmt.Errorf("a provider is required (%s)", strings.Join(llm.GetSupportedProviders(), ", "))
+	}
+
+	model := o.model
+	if model == "" {
+		model = llm.GetDefaultModels()[o.provider]
+	}
+
+	apiKeyEnv := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(o.apiKeyEnv, "$"), "{"), "}")
+	if apiKeyEnv == "" {
+		apiKeyEnv = llm.DefaultAPIKeyEnv(o.provider)
+	}
+
+	profile := config.Profile{
+		Provider:    o.provider,
+		Model:       model,
+		MaxTokens:   o.maxTokens,
+		Temperature: o.temperature,
+	}
+	if apiKeyEnv != "" {
+		profile.APIKey = fmt.Sprintf("${%s}", apiKeyEnv)
+	}
+	return profile, nil
+}
+
+// promptProfileOptions asks for each profile value, offering the current
+// values as defaults
+func promptProfileOptions(reader *bufio.Reader, w io.Writer, defaults profileOptions) (profileOptions, error) {
+	values := defaults
+
+	if values.provider == "" {
+		values.provider = "openai"
+	}
+	provider, err := promptValue(reader, w, fmt.Sprintf("Provider (%s)", strings.Join(llm.GetSupportedProviders(), ", ")), values.provider)
+	if err != nil {
+		return values, err
+	}
+	if llm.SupportedModels(provider) == nil {
+		return values, fmt.Errorf("unsupported provider: %s", provider)
+	}
+	if provider != values.provider {
+		values.model = ""
+		values.apiKeyEnv = ""
+	}
+	values.provider = provider
+
+	models := llm.SupportedModels(provider)
+	if values.model == "" {
+		values.model = models[0]
+	}
+	fmt.Fprintf(w, "%s\n", utils.NumberedList(models, utils.StyleSubtle))
+	model, err := promptValue(reader, w, "Model (number or name)", values.model)
+	if err != nil {
+		return values, err
+	}
+	if n, err := strconv.Atoi(model); err == nil && n >= 1 && n <= len(models) {
+		model = models[n-1]
+	}
+	values.model = model
+
+	if values.apiKeyEnv == "" {
+		values.apiKeyEnv = llm.DefaultAPIKeyEnv(provider)
+	}
+	if values.apiKeyEnv, err = promptValue(reader, w, "API key environment variable", values.apiKeyEnv); err != nil {
+		return values, err
+	}
+
+	maxTokens, err := promptValue(reader, w, "Max tokens", strconv.Itoa(values.maxTokens))
+	if err != nil {
+		return values, err
+	}
+	if values.maxTokens, err = strconv.Atoi(maxTokens); err != nil || values.maxTokens <= 0 {
+		return values, fmt.Errorf("invalid max tokens: %s", maxTokens)
+	}
+
+	temperature, err := promptValue(reader, w, "Temperature", strconv.FormatFloat(values.temperature, 'f', -1, 64))
+	if err != nil {
+		return values, err
+	}
+	if values.temperature, err = strconv.ParseFloat(temperature, 64); err != nil || values.temperature < 0 || values.temperature > 2 {
+		return values, fmt.Errorf("invalid temperature: %s", temperature)
+	}
+
+	return values, nil
+}
+
+// promptValue asks for a value, returning def when the answer is empty
+func promptValue(reader *bufio.Reader, w io.Writer, label, def string) (string, error) {
+	fmt.Fprintf(w, "%s [%s]: ", label, def)
+
+	answer, err := reader.ReadString('\n')
+	if err != nil && (err != io.EOF || answer == "") {
+		return "", fmt.Errorf("failed to read %s: %w", strings.ToLower(label), err)
+	}
+
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+func init() {
+	configCmd.AddCommand(configProfileCmd)
+	configProfileCmd.AddCommand(configProfileAddCmd)
+	configProfileCmd.AddCommand(configProfileRemoveCmd)
+
+	flags := configProfileAddCmd.Flags()
+	flags.BoolVar(&profileNonInteractive, "non-interactive", false, "build the profile from flags without prompting")
+	flags.StringVar(&profileAddOptions.provider, "provider", "", "provider type (openai, anthropic, gemini, groq, mistral, ollama)")
+	flags.StringVar(&profileAddOptions.model, "model", "", "model name (defaults to the provider's default model)")
+	flags.StringVar(&profileAddOptions.apiKeyEnv, "api-key-env", "", "environment variable holding the API key")
+	flags.IntVar(&profileAddOptions.maxTokens, "max-tokens", 150, "maximum tokens per response")
+	flags.Float64Var(&profileAddOptions.temperature, "temperature", 0.1, "sampling temperature")
+
+	configProfileAddCmd.RegisterFlagCompletionFunc("provider", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return llm.GetSupportedProviders(), cobra.ShellCompDirectiveNoFileComp
+	})
+
+	configProfileRemoveCmd.Flags().BoolVar(&profileRemoveForce, "force", false, "allow removing the default profile")
+	configProfileRemoveCmd.Flags().StringVar(&profileRemoveNewDefault, "default", "", "profile to make the default")
+	configProfileRemoveCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeProfileNames(cmd, args, toComplete)
+	}
+	configProfileRemoveCmd.RegisterFlagCompletionFunc("default", completeProfileNames)
+}
+
+// completeProfileNames completes the names of configured profiles
+func completeProfileNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var profiles []string
+	for name := range cfg.Profiles {
+		profiles = append(profiles, name)
+	}
+	return profiles, cobra.ShellCompDirectiveNoFileComp
+}
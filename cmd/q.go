@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"forgor/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+// qCmd runs a saved query from the config
+var qCmd = &cobra.Command{
+	Use:   "q <name> [placeholder=value...]",
+	Short: "Run a saved query, filling in its placeholders",
+	Long: `Run a query saved under queries in the config. {name} placeholders in the
+query are filled in from name=value arguments before the command is
+generated, so the query works like any other. Run without arguments to list
+the saved queries.
+
+The query flags (--profile, --explain, -R, ...) work as they do for forgor.
+
+Example config:
+  queries:
+    deploy: "deploy {app} to {env}"
+
+Examples:
+  forgor q                               # List saved queries
+  forgor q deploy app=web env=prod       # Asks for "deploy web to prod"
+  forgor q deploy app=web env=prod -e    # ...and explains the command`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+
+		if len(args) == 0 {
+			listQueries(cfg)
+			return nil
+		}
+
+		name := args[0]
+		template, exists := cfg.Queries[name]
+		if !exists {
+			return fmt.Errorf("no saved query named '%s'. Saved queries: %s", name, formatQueryNames(cfg))
+		}
+
+		values, err := config.ParseQueryArgs(args[1:])
+		if err != nil {
+			return err
+		}
+
+		query, err := config.ExpandQuery(template, values)
+		if err != nil {
+			return fmt.Errorf("query '%s' (%s): %w", name, template, err)
+		}
+
+		return runQuery(cmd, query)
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		cfg, err := config.Load()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		if len(args) == 0 {
+			return cfg.QueryNames(), cobra.ShellCompDirectiveNoFileComp
+		}
+
+		// Offer the placeholders that haven't been given yet
+		var completions []string
+		for _, placeholder := range config.QueryPlaceholders(cfg.Queries[args[0]]) {
+			given := false
+			for _, arg := range args[1:] {
+				if strings.HasPrefix(arg, placeholder+"=") {
+					given = true
+					break
+				}
+			}
+			if !given {
+				completions = append(completions, placeholder+"=")
+			}
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+	},
+}
+
+// listQueries prints the saved queries with their text
+func listQueries(cfg *config.Config) {
+	if len(cfg.Queries) == 0 {
+		fmt.Println("No saved queries. Add some under 'queries' in the config, e.g.:")
+		fmt.Println("  queries:")
+		fmt.Println("    deploy: \"deploy {app} to {env}\"")
+		return
+	}
+
+	for _, name := range cfg.QueryNames() {
+		fmt.Printf("%s: %s\n", name, cfg.Queries[name])
+	}
+}
+
+// formatQueryNames lists the saved query names for error messages
+func formatQueryNames(cfg *config.Config) string {
+	names := cfg.QueryNames()
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+func init() {
+	rootCmd.AddCommand(qCmd)
+}
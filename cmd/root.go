@@ -3,14 +3,20 @@ package cmd
 import (
 	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
+	"slices"
 	"strings"
+	"time"
 
 	"forgor/internal/config"
 	"forgor/internal/history"
 	"forgor/internal/llm"
+	"forgor/internal/prompt"
+	"forgor/internal/security"
 	"forgor/internal/utils"
 
 	"github.com/spf13/cobra"
@@ -20,14 +26,38 @@ import (
 var (
 	cfgFile      string
 	verbose      bool
+	logFormat    string
+	timingFile   string
+	debugLog     string
+	dumpContext  string
+	jsonErrors   bool
 	profile      string
 	historyCount int
+	historyAge   time.Duration
 	interactive  bool
 	explain      bool
+	explainAfter bool
 	format       string
 	confirm      bool
 	localOnly    bool
+	offline      bool
 	forceRun     bool
+	quiet        bool
+	safetyLevel  string
+	rawMarkdown  bool
+	workingDir   string
+	userContext  string
+	contextFile  string
+	includeEnv   bool
+	safeMode     bool
+	appendToLog  bool
+	retryOnEmpty bool
+
+	continueOnError   bool
+	noHistoryFallback bool
+
+	temperatureOverride float64
+	maxTokensOverride   int
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -42,18 +72,22 @@ Examples:
   ff show me how to make a new tmux session called dev
   ff --history 2 fix the above command
   ff -R list all files in current directory  # Force run the generated command
-  forgor -p gemini -e how much space is left on my disk?`,
+  forgor -p gemini -e how much space is left on my disk?
+  echo "find large files" | ff -            # Read the query from stdin
+  cmd=$(ff -q list files by size)           # Capture only the bare command`,
 	Args: cobra.ArbitraryArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if len(args) == 0 {
-			return fmt.Errorf("no query provided")
+		query, err := utils.ResolveQuery(args, os.Stdin, utils.StdinIsPiped())
+		if err != nil {
+			return err
 		}
-		query := strings.Join(args, " ")
 		return runQuery(cmd, query)
 	},
 	CompletionOptions: cobra.CompletionOptions{
 		DisableDefaultCmd: true,
 	},
+	// Errors are printed once, by PrintError, so they can be JSON
+	SilenceErrors: true,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -61,6 +95,30 @@ func Execute() error {
 	return rootCmd.Execute()
 }
 
+// jsonErrorsEnabled reports whether errors are printed as JSON, with
+// --json-errors or -f json
+func jsonErrorsEnabled() bool {
+	return jsonErrors || format == "json"
+}
+
+// PrintError reports an error returned by Execute on w. With --json-errors
+// or -f json it is a single JSON object whose type (auth, network, ...) comes
+// from llm.Error; otherwise it is plain text.
+func PrintError(w io.Writer, err error) {
+	if jsonErrorsEnabled() {
+		report := llm.NewErrorReport(err)
+		var blocked *security.BlockedError
+		if errors.As(err, &blocked) {
+			report.Error.Type = "blocked"
+		}
+		if data, marshalErr := json.Marshal(report); marshalErr == nil {
+			fmt.Fprintln(w, string(data))
+			return
+		}
+	}
+	fmt.Fprintf(w, "Error: %v\n", err)
+}
+
 // setupCompletions configures custom completion functions for flags
 func setupCompletions() {
 	// Profile completion - complete with available profiles from config
@@ -68,7 +126,7 @@ func setupCompletions() {
 		cfg, err := config.Load()
 		if err != nil {
 			// Return common defaults if config loading fails
-			return []string{"default", "openai", "anthropic", "gemini"}, cobra.ShellCompDirectiveNoFileComp
+			return []string{"default", "openai", "anthropic", "gemini", "groq", "mistral", "ollama"}, cobra.ShellCompDirectiveNoFileComp
 		}
 
 		var profiles []string
@@ -85,9 +143,20 @@ func setupCompletions() {
 
 	// Format completion - complete with valid output formats
 	rootCmd.RegisterFlagCompletionFunc("format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return []string{"plain", "json"}, cobra.ShellCompDirectiveNoFileComp
+		return []string{"plain", "json", "shell"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	rootCmd.RegisterFlagCompletionFunc("log-format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{utils.LogFormatText, utils.LogFormatJSON}, cobra.ShellCompDirectiveNoFileComp
 	})
 
+	rootCmd.RegisterFlagCompletionFunc("safety", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{prompt.SafetyLevelStrict, prompt.SafetyLevelModerate, prompt.SafetyLevelPermissive}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	// Working directory completion - complete with directories only
+	rootCmd.MarkFlagDirname("cwd")
+
 	// History completion - suggest reasonable values
 	rootCmd.RegisterFlagCompletionFunc("history", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return []string{"0", "1", "2", "3", "5", "10"}, cobra.ShellCompDirectiveNoFileComp
@@ -98,26 +167,56 @@ func init() {
 	cobra.OnInitialize(initConfig)
 
 	// Global flags
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.config/forgor/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $XDG_CONFIG_HOME/forgor/config.yaml or $HOME/.config/forgor/config.yaml)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", utils.LogFormatText, "diagnostic log format on stderr: text, json")
+	rootCmd.PersistentFlags().StringVar(&timingFile, "timing-file", "", "append timing metrics as JSON lines to this file (or set FORGOR_TIMING_FILE)")
+	rootCmd.PersistentFlags().BoolVar(&safeMode, "safe", false, "refuse to run commands above security.safe_max_level (default medium) and mark them as blocked")
+	rootCmd.PersistentFlags().BoolVar(&appendToLog, "append-to-history", false, "record executed commands and their exit codes in ~/.command_log so --history sees them")
+	rootCmd.PersistentFlags().BoolVar(&jsonErrors, "json-errors", false, "print errors as JSON on stderr: {\"error\": {\"type\", \"message\", \"code\"}} (implied by -f json)")
+	rootCmd.PersistentFlags().StringVar(&debugLog, "debug-log", "", "record API requests and responses to this file for bug reports, with keys and secrets redacted")
 
 	// Query flags
 	rootCmd.Flags().StringVarP(&profile, "profile", "p", "default", "config profile to use")
 	rootCmd.Flags().IntVarP(&historyCount, "history", "n", 0, "number of commands from history to include")
+	rootCmd.Flags().BoolVar(&noHistoryFallback, "no-history-fallback", false, "read history only from ~/.command_log, never from the shell's history file")
+	rootCmd.Flags().DurationVar(&historyAge, "max-history-age", 0, "skip logged history commands older than this, e.g. 2h (default from history.max_age_hours)")
 	rootCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "interactive mode with follow-ups")
 	rootCmd.Flags().BoolVarP(&explain, "explain", "e", false, "explain the command instead of just returning it")
-	rootCmd.Flags().StringVarP(&format, "format", "f", "plain", "output format: plain, json")
+	rootCmd.Flags().BoolVar(&explainAfter, "explain-after", false, "generate the command, then explain exactly that command in a second request")
+	rootCmd.Flags().StringVarP(&format, "format", "f", "plain", "output format: plain, json, shell (FORGOR_COMMAND='...' for eval)")
 	rootCmd.Flags().BoolVarP(&confirm, "confirm", "c", false, "ask for confirmation before showing command")
+	rootCmd.Flags().StringVar(&workingDir, "cwd", "", "generate commands as if run from this directory (the current directory is not changed)")
+	rootCmd.Flags().StringVar(&userContext, "context", "", "extra background for the model, e.g. an error message")
+	rootCmd.Flags().StringVar(&dumpContext, "dump-context", "", "write the context sent with the query (tools, history, env, cwd) to this file as JSON, with secrets redacted")
+	rootCmd.Flags().StringVar(&contextFile, "context-file", "", "read extra background for the model from a file (first 16KB)")
+	rootCmd.Flags().BoolVar(&includeEnv, "include-env", false, "send selected environment variables (PATH, EDITOR, VIRTUAL_ENV, ...) to the model")
 	rootCmd.Flags().BoolVar(&localOnly, "local-only", false, "don't send data to external APIs")
+	rootCmd.Flags().BoolVar(&offline, "offline", false, "answer common requests with built-in rules instead of a provider (low confidence)")
+	rootCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "print only the generated command (for use in $(...))")
+	rootCmd.Flags().BoolVar(&rawMarkdown, "raw-markdown", false, "show explanations as returned by the model, without rendering markdown")
+	rootCmd.Flags().BoolVar(&retryOnEmpty, "retries-on-empty", true, "ask again once, for only the command, when the answer has no command (default from retry_on_empty)")
+	rootCmd.Flags().Float64Var(&temperatureOverride, "temperature", 0, "sampling temperature for this query, 0-2 (default from the profile)")
+	rootCmd.Flags().IntVar(&maxTokensOverride, "max-tokens", 0, "maximum tokens for this query's answer (default from the profile)")
+	rootCmd.Flags().StringVar(&safetyLevel, "safety", "", "generation safety level: strict, moderate, permissive (default from config, else moderate)")
 
 	// Execution flags (uppercase for potentially unsafe operations)
 	rootCmd.Flags().BoolVarP(&forceRun, "force-run", "R", false, "immediately run the generated command (DANGEROUS)")
+	rootCmd.Flags().BoolVar(&continueOnError, "continue-on-error", false, "keep running remaining steps of a multi-step command after a failure")
+
+	rootCmd.MarkFlagsMutuallyExclusive("quiet", "force-run")
+	rootCmd.MarkFlagsMutuallyExclusive("quiet", "verbose")
+	rootCmd.MarkFlagsMutuallyExclusive("explain", "explain-after")
+
+	// Saved queries run through runQuery too, so they share the query flags
+	qCmd.Flags().AddFlagSet(rootCmd.Flags())
 
 	// Set up custom completions
 	setupCompletions()
 
 	// Bind flags to viper
 	viper.BindPFlag("profile", rootCmd.Flags().Lookup("profile"))
+	viper.BindPFlag("format", rootCmd.Flags().Lookup("format"))
 	viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
 }
 
@@ -131,6 +230,7 @@ func runQuery(cmd *cobra.Command, query string) error {
 	// Initialize timing for the entire operation
 	timer := utils.NewTimer("Command Execution", verbose)
 	defer timer.PrintSummary()
+	defer saveTimingSummary(timer)
 
 	// Load configuration
 	configStep := timer.StartStep("Config Loading")
@@ -138,8 +238,8 @@ func runQuery(cmd *cobra.Command, query string) error {
 	if err != nil {
 		configStep.EndWithResult("error - using defaults")
 		if verbose {
-			fmt.Printf("%s Error loading config: %v\n", utils.Styled("[ERROR]", utils.StyleError), err)
-			fmt.Printf("%s Run 'forgor config init' to create a default configuration\n", utils.Styled("[TIP]", utils.StyleInfo))
+			utils.Debugf("%s Error loading config: %v\n", utils.Styled("[ERROR]", utils.StyleError), err)
+			utils.Debugf("%s Run 'forgor config init' to create a default configuration\n", utils.Styled("[TIP]", utils.StyleInfo))
 		}
 		// Create a minimal default config for basic functionality
 		cfg = &config.Config{
@@ -158,30 +258,83 @@ func runQuery(cmd *cobra.Command, query string) error {
 		configStep.EndWithResult("success")
 	}
 
+	// A custom prompt template replaces the built-in system prompt. It is
+	// checked here rather than in config validation, so a bad template fails
+	// the query instead of the whole config.
+	if cfg.Prompt.TemplateFile != "" {
+		tmpl, err := prompt.LoadSystemTemplate(config.ExpandEnv(cfg.Prompt.TemplateFile))
+		if err != nil {
+			return fmt.Errorf("invalid prompt.template_file: %w", err)
+		}
+		prompt.SetSystemTemplate(tmpl)
+	}
+	if cfg.Prompt.ExplainInstruction != "" {
+		prompt.SetExplainInstruction(cfg.Prompt.ExplainInstruction)
+	}
+	prompt.SetHistoryCommandLimit(cfg.History.MaxCommandChars)
+
+	if cfg.Updates.Check {
+		checkForUpdatesInBackground(cfg.Updates)
+	}
+
+	// Without --profile, directory_profiles can pick one for this directory
+	profileName := resolveProfileName(cfg)
+
+	// --temperature and --max-tokens override the profile for this query
+	var overrides llm.RequestOverrides
+	if cmd.Flags().Changed("temperature") {
+		overrides.Temperature = &temperatureOverride
+	}
+	if cmd.Flags().Changed("max-tokens") {
+		overrides.MaxTokens = &maxTokensOverride
+	}
+	if err := overrides.Validate(); err != nil {
+		return err
+	}
+
 	if verbose {
-		fmt.Printf("\n%s\n", utils.Divider("QUERY PROCESSING", utils.StyleInfo))
-		fmt.Printf("%s %s\n", utils.Styled("Query:", utils.StyleInfo), query)
-		fmt.Printf("%s %s\n", utils.Styled("Profile:", utils.StyleInfo), profile)
+		utils.Debugf("\n%s\n", utils.Divider("QUERY PROCESSING", utils.StyleInfo))
+		utils.Debugf("%s %s\n", utils.Styled("Query:", utils.StyleInfo), query)
+		utils.Debugf("%s %s\n", utils.Styled("Profile:", utils.StyleInfo), profileName)
 	}
 
 	// Create LLM factory
 	providerStep := timer.StartStep("Provider Setup")
 	factory := llm.NewFactory(cfg)
+	if path := debugLogPath(cfg); path != "" {
+		factory.SetDebugLog(path, func(text string) string {
+			redacted, _ := security.RedactSecrets(text)
+			return redacted
+		})
+	}
 
-	// Get the provider
-	provider, err := factory.GetProvider(profile)
-	if err != nil {
+	// Get the provider; --offline needs none
+	var provider llm.Provider
+	if offline {
+		provider = llm.NewOfflineProvider()
+	} else if provider, err = factory.GetProvider(profileName); err != nil {
 		providerStep.EndWithResult("error")
 		return fmt.Errorf("failed to get provider: %w", err)
 	}
 	providerStep.EndWithResult("success")
 
+	// An answer without a command is asked again once (--retries-on-empty)
+	if !cmd.Flags().Changed("retries-on-empty") {
+		retryOnEmpty = cfg.RetryOnEmpty
+	}
+	if retryOnEmpty && !offline {
+		provider = llm.RetryOnEmpty(provider)
+	}
+
 	if verbose {
 		info := provider.GetProviderInfo()
-		fmt.Printf("%s %s with model %s\n",
+		utils.Debugf("%s %s with model %s\n",
 			utils.Styled("Provider:", utils.StyleInfo),
 			utils.Styled(info.Name, utils.StyleHighlight),
 			utils.Styled(info.Metadata["model"], utils.StyleHighlight))
+		utils.Debugf("%s %d output tokens\n",
+			utils.Styled("Model Limit:", utils.StyleInfo),
+			info.Limits["max_tokens"])
 	}
 
 	// Build request context
@@ -190,8 +343,38 @@ func runQuery(cmd *cobra.Command, query string) error {
 	// Build enhanced context with tool detection
 	contextStep := timer.StartStep("System Context Building")
 	requestContext := llm.BuildContextFromSystem()
+	requestContext = llm.EnhanceContextWithCustomTools(requestContext, cfg.CustomTools)
+	if includeEnv {
+		requestContext = llm.EnhanceContextWithEnvironment(requestContext, utils.GetRelevantEnvironment())
+	}
 	contextStep.End()
 
+	// Generate as if run from another directory, without changing ours
+	if workingDir != "" {
+		requestContext, err = llm.EnhanceContextWithWorkingDirectory(requestContext, workingDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Extra background from --context and --context-file
+	if userContext != "" || contextFile != "" {
+		var fileContent string
+		if contextFile != "" {
+			if fileContent, err = utils.ReadContextFile(contextFile, utils.MaxContextFileSize); err != nil {
+				return err
+			}
+		}
+		requestContext = llm.EnhanceContextWithUserInput(requestContext, utils.BuildUserContext(userContext, fileContent))
+	}
+
+	// Add git branch and status when inside a repository
+	if cfg.Prompt.GitStatus {
+		gitStep := timer.StartStep("Git Status")
+		requestContext = llm.EnhanceContextWithGitStatus(requestContext)
+		gitStep.End()
+	}
+
 	// Add command history
 	historyStep := timer.StartStep("History Processing")
 
@@ -206,7 +389,7 @@ func runQuery(cmd *cobra.Command, query string) error {
 	var historyCommands []history.HistoryEntry
 	if numHistory > 0 {
 		// Check if history is enabled for the current shell
-		currentShell := utils.GetCurrentShell()
+		currentShell := utils.NormalizeShellName(utils.GetCurrentShell())
 		isShellAllowed := false
 		if len(cfg.History.Shells) == 0 {
 			isShellAllowed = true // If list is empty, all shells are allowed
@@ -221,14 +404,19 @@ func runQuery(cmd *cobra.Command, query string) error {
 
 		if isShellAllowed {
 			var err error
-			historyCommands, err = utils.GetHistory(numHistory)
+			historyCommands, err = utils.GetHistoryWithOptions(utils.HistoryOptions{
+				MaxCommands:      numHistory,
+				MaxAge:           maxHistoryAge(cmd, cfg),
+				CurrentSession:   cfg.History.CurrentSession,
+				NoNativeFallback: noHistoryFallback || cfg.History.NoNativeFallback,
+			})
 			if err != nil {
 				if verbose {
-					fmt.Printf("%s Could not read history: %v\n", utils.Styled("[WARN]", utils.StyleWarning), err)
+					utils.Debugf("%s Could not read history: %v\n", utils.Styled("[WARN]", utils.StyleWarning), err)
 				}
 			}
 			if verbose && len(historyCommands) > 0 {
-				fmt.Printf("%s Loaded %d commands from history for '%s' shell\n", utils.Styled("[INFO]", utils.StyleInfo), len(historyCommands), currentShell)
+				utils.Debugf("%s Loaded %d commands from history for '%s' shell\n", utils.Styled("[INFO]", utils.StyleInfo), len(historyCommands), currentShell)
 				historyStrings := make([]string, len(historyCommands))
 				for i, h := range historyCommands {
 					status := ""
@@ -239,26 +427,28 @@ func runQuery(cmd *cobra.Command, query string) error {
 					}
 					historyStrings[i] = h.Command + status
 				}
-				fmt.Printf("%s\n", utils.List(historyStrings, utils.StyleInfo))
+				utils.Debugf("%s\n", utils.List(historyStrings, utils.StyleInfo))
 			}
 		} else if verbose {
-			fmt.Printf("%s History skipped: current shell '%s' is not in the configured list %v.\n", utils.Styled("[INFO]", utils.StyleInfo), currentShell, cfg.History.Shells)
+			utils.Debugf("%s History skipped: current shell '%s' is not in the configured list %v.\n", utils.Styled("[INFO]", utils.StyleInfo), currentShell, cfg.History.Shells)
 		}
 
+		// Long or numerous commands are trimmed to keep the prompt small
+		historyCommands = prompt.LimitHistory(historyCommands, cfg.History.MaxChars)
 		requestContext = llm.EnhanceContextWithHistory(requestContext, historyCommands)
 	} else if verbose {
 		reason := "configuration"
 		if cmd.Flags().Changed("history") {
 			reason = "command-line flag"
 		}
-		fmt.Printf("%s History context is disabled by %s.\n", utils.Styled("[INFO]", utils.StyleInfo), reason)
+		utils.Debugf("%s History context is disabled by %s.\n", utils.Styled("[INFO]", utils.StyleInfo), reason)
 	}
 
 	historyStep.End()
 
 	if verbose {
-		fmt.Printf("\n%s\n", utils.Divider("SYSTEM CONTEXT", utils.StyleSubtle))
-		fmt.Printf("%s %s on %s (%s) in %s\n",
+		utils.Debugf("\n%s\n", utils.Divider("SYSTEM CONTEXT", utils.StyleSubtle))
+		utils.Debugf("%s %s on %s (%s) in %s\n",
 			utils.Styled("Environment:", utils.StyleSubtle),
 			requestContext.Shell,
 			requestContext.OS,
@@ -266,31 +456,114 @@ func runQuery(cmd *cobra.Command, query string) error {
 			requestContext.WorkingDirectory)
 
 		toolSummary := utils.GetToolContextSummary()
-		fmt.Printf("%s %s\n", utils.Styled("Tools:", utils.StyleSubtle), toolSummary)
+		utils.Debugf("%s %s\n", utils.Styled("Tools:", utils.StyleSubtle), toolSummary)
+	}
+
+	// Precedence: command-line flag > config file > moderate
+	requestedSafety := cfg.Security.SafetyLevel
+	if cmd.Flags().Changed("safety") {
+		requestedSafety = safetyLevel
+	}
+	safety, err := prompt.ParseSafetyLevel(requestedSafety)
+	if err != nil {
+		return err
+	}
+
+	// History is filtered when read, but the query and extra context are not
+	query, requestContext.UserContext, err = protectSecrets(cfg.Security.OnSecret, query, requestContext.UserContext)
+	if err != nil {
+		return err
 	}
 
 	// Generate response
 	llmStep := timer.StartStep("LLM API Request")
-	response, err := provider.GenerateCommand(ctx, &llm.Request{
+	// Token and temperature settings come from the profile the provider
+	// was created for, unless overridden by flags
+	request := &llm.Request{
 		Query:   query,
 		Context: requestContext,
 		Options: llm.RequestOptions{
 			IncludeExplanation: explain,
-			MaxTokens:          150,
+			SafetyLevel:        safety,
 		},
-	})
+	}
+	activeProfile, _ := cfg.GetProfile(profileName)
+	overrides.Apply(&request.Options, activeProfile)
+
+	// --dump-context pairs a bug report with the exact context sent
+	if dumpContext != "" {
+		err := llm.WriteContextDump(dumpContext, request.Context, func(text string) string {
+			redacted, _ := security.RedactSecrets(text)
+			return redacted
+		})
+		if err != nil {
+			utils.Warnf("%s %v\n", utils.Styled("[WARNING]", utils.StyleWarning), err)
+		}
+	}
+
+	var response *llm.Response
+	spinner := startQuerySpinner()
+	if explainAfter {
+		response, err = llm.GenerateAndExplain(ctx, provider, request)
+	} else {
+		response, err = provider.GenerateCommand(ctx, request)
+	}
+	spinner.Stop()
+
+	// offline_fallback answers with the built-in rules when the provider
+	// can't be reached
+	if err != nil && cfg.OfflineFallback && !offline && llm.IsUnreachable(err) {
+		if fallback, fallbackErr := llm.NewOfflineProvider().GenerateCommand(ctx, request); fallbackErr == nil {
+			utils.Warnf("%s Provider unreachable (%v), using the offline rules\n", utils.Styled("[WARNING]", utils.StyleWarning), err)
+			response, err = fallback, nil
+		}
+	}
 
 	if err != nil {
 		llmStep.EndWithResult("error")
+
+		var llmErr *llm.Error
+		if errors.As(err, &llmErr) && llmErr.Type == llm.ErrorTypeSafety {
+			return fmt.Errorf("your query was content-filtered by the provider. Try rephrasing it: %w", err)
+		}
 		return fmt.Errorf("failed to generate command: %w", err)
 	}
 	llmStep.EndWithResult("success")
 
+	// Catch hallucinated or missing tools before the command is offered
+	if warning := utils.MissingCommandWarning(response.Command, requestContext.PackageManagers); warning != "" {
+		response.Warnings = append(response.Warnings, warning)
+	}
+
+	// Explanations also say how risky the explained command is to run
+	if explain || explainAfter {
+		security.AnnotateDanger(response, &requestContext)
+	}
+	if format == "json" {
+		security.AssessSafety(response, &requestContext)
+	}
+	security.AnnotateElevation(response)
+
+	// Safe mode marks commands above its danger limit as blocked
+	if securityConfig := loadSecurityConfig(); safeModeEnabled(securityConfig) && response.Command != "" {
+		assessment, err := security.CheckSafeMode(response.Command, &requestContext, securityConfig.SafeMaxLevel)
+		if err != nil {
+			response.Blocked = true
+			response.Safety = &assessment
+		}
+	}
+
 	// Display response
 	displayStep := timer.StartStep("Response Display")
 	err = displayResponse(response, explain)
 	if err != nil {
 		displayStep.EndWithResult("error")
+
+		// A failed -R command can be sent back to the provider for a fix
+		var cmdErr *utils.CommandError
+		if forceRun && errors.As(err, &cmdErr) {
+			return offerFix(ctx, provider, request, cmdErr)
+		}
 		return err
 	}
 	displayStep.EndWithResult("success")
@@ -298,48 +571,118 @@ func runQuery(cmd *cobra.Command, query string) error {
 	return nil
 }
 
-// TODO: remove this function
-// isLikelyCommand checks if the input looks like a shell command
-func isLikelyCommand(input string) bool {
-	// Simple heuristic: if it starts with common command patterns
-	commandPrefixes := []string{
-		"ls", "cd", "mkdir", "rm", "cp", "mv", "find", "grep", "cat", "echo",
-		"ps", "kill", "top", "df", "du", "tar", "git", "docker", "npm", "pip",
-		"sudo", "chmod", "chown", "ssh", "scp", "curl", "wget",
+// startQuerySpinner shows a spinner on stderr while waiting for the
+// provider. Scripts read -f json, -f shell and --quiet output, so those
+// never get one.
+func startQuerySpinner() *utils.Spinner {
+	if quiet || format == "json" || format == "shell" {
+		return utils.StartSpinner(io.Discard, "")
 	}
+	return utils.StartSpinner(os.Stderr, "Generating command...")
+}
 
-	for _, prefix := range commandPrefixes {
-		if len(input) >= len(prefix) && input[:len(prefix)] == prefix {
-			return true
-		}
+// printDangerLevel shows the assessed danger level of an explained command
+func printDangerLevel(level llm.DangerLevel) {
+	if level == "" {
+		return
 	}
-	return false
+	style := utils.StyleWarning
+	if level == llm.DangerLevelSafe {
+		style = utils.StyleSuccess
+	}
+	fmt.Printf("\n%s %s\n", utils.DangerIcon(string(level)),
+		utils.Styled(strings.ToUpper(string(level))+" DANGER LEVEL", style))
+}
+
+// saveTimingSummary appends the run's timing summary to the --timing-file
+// (or FORGOR_TIMING_FILE) if one is configured
+func saveTimingSummary(timer *utils.Timer) {
+	path := timingFile
+	if path == "" {
+		path = os.Getenv("FORGOR_TIMING_FILE")
+	}
+	if path == "" {
+		return
+	}
+
+	if err := utils.AppendTimingSummary(path, timer.GetSummary()); err != nil {
+		utils.Warnf("%s Failed to save timing metrics: %v", utils.Styled("[WARNING]", utils.StyleWarning), err)
+	}
+}
+
+// maxHistoryAge returns the --max-history-age flag, falling back to
+// history.max_age_hours. Zero includes history of any age.
+func maxHistoryAge(cmd *cobra.Command, cfg *config.Config) time.Duration {
+	if cmd.Flags().Changed("max-history-age") {
+		return historyAge
+	}
+	return time.Duration(cfg.History.MaxAgeHours) * time.Hour
+}
+
+// debugLogPath returns the --debug-log path, falling back to the debug_log
+// config option. Debug logging is off when both are empty.
+func debugLogPath(cfg *config.Config) string {
+	if debugLog != "" {
+		return debugLog
+	}
+	return config.ExpandEnv(cfg.DebugLog)
 }
 
 // displayResponse formats and displays the LLM response
 func displayResponse(response *llm.Response, isExplanation bool) error {
+	// Blocked commands are never cached for 'forgor run' or offered to run
+	if response.Blocked {
+		return displayBlocked(response)
+	}
+
 	// Save the command to cache for later use with 'forgor run' (do this first to ensure it's always saved)
 	if response.Command != "" {
 		if err := config.SaveLastCommand(response.Command); err != nil && verbose {
-			fmt.Printf("%s Failed to cache command: %v\n", utils.Styled("[WARNING]", utils.StyleWarning), err)
+			utils.Warnf("%s Failed to cache command: %v\n", utils.Styled("[WARNING]", utils.StyleWarning), err)
+		}
+	}
+
+	// The shell format prints an assignment for eval "$(ff -f shell ...)"
+	if format == "shell" {
+		return utils.WriteShellAssignment(os.Stdout, os.Stderr, response.Command, response.Warnings)
+	}
+
+	// The json format prints the whole response, with its safety assessment
+	if format == "json" {
+		return utils.WriteJSON(os.Stdout, response)
+	}
+
+	// Quiet mode prints only the bare command so it can be used in $(...)
+	if quiet {
+		if (isExplanation || explainAfter) && response.Explanation != "" {
+			explanation := response.Explanation
+			if !rawMarkdown {
+				explanation = utils.StripMarkdown(explanation)
+			}
+			fmt.Fprintln(os.Stderr, explanation)
 		}
+		return utils.WriteCommandOnly(os.Stdout, os.Stderr, response.Command, response.Warnings)
 	}
 
 	// Handle explanation display
 	if isExplanation {
 		fmt.Printf("\n%s\n", utils.Box("COMMAND EXPLANATION", "", utils.StyleInfo))
 		fmt.Printf("%s %s\n\n", utils.Styled("Command:", utils.StyleCommand), response.Command)
-		fmt.Printf("%s\n", response.Explanation)
+		fmt.Printf("%s\n", formatExplanation(response.Explanation))
+		printDangerLevel(response.DangerLevel)
 
 		// If force-run is also enabled, continue to execution
 		if !forceRun {
+			if len(response.Warnings) > 0 {
+				fmt.Printf("\n%s\n", utils.List(response.Warnings, utils.StyleWarning))
+			}
 			return nil
 		}
 		fmt.Println() // Add spacing before execution
 	} else {
 		// Display generated command with optional explanation
 		if explain && response.Explanation != "" {
-			fmt.Printf("\n%s %s\n", utils.Styled("Explanation:", utils.StyleInfo), response.Explanation)
+			fmt.Printf("\n%s %s\n", utils.Styled("Explanation:", utils.StyleInfo), formatExplanation(response.Explanation))
 		}
 	}
 
@@ -351,14 +694,33 @@ func displayResponse(response *llm.Response, isExplanation bool) error {
 	}
 
 	// Show the command (unless we already showed it in explanation mode)
+	steps := prompt.SplitCommandSteps(response.Command)
 	if !isExplanation {
-		fmt.Printf("\n%s\n", utils.Divider("GENERATED COMMAND", utils.StyleCommand))
-		fmt.Printf("%s\n", utils.SimpleBox(response.Command, utils.StyleCommand))
+		if len(steps) > 1 {
+			fmt.Printf("\n%s\n", utils.Divider(fmt.Sprintf("GENERATED COMMANDS (%d STEPS)", len(steps)), utils.StyleCommand))
+			fmt.Printf("%s\n", utils.NumberedList(steps, utils.StyleCommand))
+		} else {
+			fmt.Printf("\n%s\n", utils.Divider("GENERATED COMMAND", utils.StyleCommand))
+			fmt.Printf("%s\n", utils.SimpleBox(response.Command, utils.StyleCommand))
+		}
+
+		// Interactive sessions list alternatives in the picker below instead
+		if len(response.Alternatives) > 0 && (forceRun || utils.StdinIsPiped()) {
+			fmt.Printf("\n%s\n", utils.Divider("ALTERNATIVES", utils.StyleSubtle))
+			fmt.Printf("%s\n", utils.NumberedList(response.Alternatives, utils.StyleSubtle))
+		}
+
+		// With --explain-after the explanation describes the command shown above
+		if explainAfter && response.Explanation != "" {
+			fmt.Printf("\n%s\n", utils.Divider("EXPLANATION", utils.StyleInfo))
+			fmt.Printf("%s\n", formatExplanation(response.Explanation))
+			printDangerLevel(response.DangerLevel)
+		}
 	}
 
 	// Show confidence and usage info in verbose mode
 	if verbose {
-		fmt.Printf("\n%s\n", utils.Divider("RESPONSE DETAILS", utils.StyleSubtle))
+		utils.Debugf("\n%s\n", utils.Divider("RESPONSE DETAILS", utils.StyleSubtle))
 
 		// Confidence
 		confidencePercent := response.Confidence * 100
@@ -368,13 +730,13 @@ func displayResponse(response *llm.Response, isExplanation bool) error {
 		} else if confidencePercent < 50 {
 			confidenceStyle = utils.StyleError
 		}
-		fmt.Printf("%s %s\n",
+		utils.Debugf("%s %s\n",
 			utils.Styled("Confidence:", utils.StyleSubtle),
 			utils.Styled(fmt.Sprintf("%.1f%%", confidencePercent), confidenceStyle))
 
 		// Token usage
 		if response.Usage != nil {
-			fmt.Printf("%s %d prompt + %d completion = %d total\n",
+			utils.Debugf("%s %d prompt + %d completion = %d total\n",
 				utils.Styled("Tokens:", utils.StyleSubtle),
 				response.Usage.PromptTokens,
 				response.Usage.CompletionTokens,
@@ -391,6 +753,15 @@ func displayResponse(response *llm.Response, isExplanation bool) error {
 	// Offer to run the command (don't show if we're in explanation mode and not force-running)
 	if !isExplanation && response.Command != "" {
 		fmt.Printf("\n%s\n", utils.Divider("NEXT STEPS", utils.StyleInfo))
+		if len(response.Alternatives) > 0 && !utils.StdinIsPiped() {
+			return pickCommand(response)
+		}
+		if len(steps) > 1 {
+			fmt.Printf("%s Use '%s' to run each step in order\n",
+				utils.Styled("Run these commands?", utils.StyleInfo),
+				utils.Styled("forgor run", utils.StyleCommand))
+			return nil
+		}
 		fmt.Printf("%s Use '%s' or '%s'\n",
 			utils.Styled("Run this command?", utils.StyleInfo),
 			utils.Styled("forgor run", utils.StyleCommand),
@@ -400,6 +771,143 @@ func displayResponse(response *llm.Response, isExplanation bool) error {
 	return nil
 }
 
+// displayBlocked shows a command that safe mode refused, with the reason and
+// how to make it safer. Nothing is printed to stdout in quiet and shell
+// formats, so $(...) and eval get no command.
+func displayBlocked(response *llm.Response) error {
+	blocked := &security.BlockedError{
+		Assessment: *response.Safety,
+		MaxLevel:   security.SafeMaxLevel(loadSecurityConfig().SafeMaxLevel),
+	}
+
+	switch {
+	case format == "json":
+		if err := utils.WriteJSON(os.Stdout, response); err != nil {
+			return err
+		}
+	case quiet || format == "shell":
+		return blocked
+	default:
+		if len(response.Warnings) > 0 {
+			fmt.Printf("\n%s\n", utils.Divider("WARNINGS", utils.StyleWarning))
+			fmt.Printf("%s\n", utils.List(response.Warnings, utils.StyleWarning))
+		}
+		fmt.Printf("\n%s\n", utils.Divider("GENERATED COMMAND", utils.StyleCommand))
+		fmt.Printf("%s\n", utils.SimpleBox(response.Command, utils.StyleCommand))
+		printBlocked(blocked)
+	}
+
+	if forceRun {
+		return blocked
+	}
+	return nil
+}
+
+// pickCommand lets the user run or copy the generated command or one of its
+// alternatives. The chosen command goes through the usual safety checks.
+func pickCommand(response *llm.Response) error {
+	commands := append([]string{response.Command}, response.Alternatives...)
+	selection, err := utils.SelectCommand(os.Stdin, os.Stdout, commands)
+	if err != nil {
+		return err
+	}
+	if selection.Action == utils.SelectionNone {
+		fmt.Printf("Use '%s' to run the first command later\n", utils.Styled("forgor run", utils.StyleCommand))
+		return nil
+	}
+
+	chosen := commands[selection.Index]
+	warnings := response.Warnings
+	if selection.Index > 0 {
+		warnings = prompt.CheckCommandSafety(chosen)
+		// Make 'forgor run' and 'forgor last' refer to the chosen command
+		if err := config.SaveLastCommand(chosen); err != nil && verbose {
+			utils.Warnf("%s Failed to cache command: %v\n", utils.Styled("[WARNING]", utils.StyleWarning), err)
+		}
+	}
+
+	if selection.Action == utils.SelectionCopy {
+		fmt.Printf("\n%s\n\n", chosen)
+		fmt.Printf("Saved for '%s' and '%s'\n",
+			utils.Styled("forgor run", utils.StyleCommand),
+			utils.Styled("forgor last", utils.StyleCommand))
+		return nil
+	}
+
+	return executeCommand(chosen, warnings)
+}
+
+// protectSecrets checks the query and extra context for secrets before they
+// are sent, redacting them or refusing to send depending on action
+func protectSecrets(action, query, userContext string) (string, string, error) {
+	texts, err := protectOutgoing(action, "the query", query, userContext)
+	if err != nil {
+		return "", "", err
+	}
+	return texts[0], texts[1], nil
+}
+
+// protectOutgoing checks texts about to be sent to the provider for secrets.
+// Depending on action they are redacted, or nothing is sent and the error
+// names what was withheld.
+func protectOutgoing(action, what string, texts ...string) ([]string, error) {
+	action, err := security.ParseSecretAction(action)
+	if err != nil {
+		return nil, err
+	}
+
+	if action == security.SecretActionAbort {
+		if kinds := security.FindSecrets(strings.Join(texts, "\n")); len(kinds) > 0 {
+			return nil, fmt.Errorf("not sending %s: it looks like it contains a %s. Remove it, or set security.on_secret to redact", what, strings.Join(kinds, ", "))
+		}
+		return texts, nil
+	}
+
+	var kinds []string
+	redacted := make([]string, len(texts))
+	for i, text := range texts {
+		var found []string
+		redacted[i], found = security.RedactSecrets(text)
+		kinds = append(kinds, found...)
+	}
+	if len(kinds) > 0 {
+		utils.Warnf("%s Redacted what looks like a %s before sending\n",
+			utils.Styled("[WARNING]", utils.StyleWarning), strings.Join(slices.Compact(slices.Sorted(slices.Values(kinds))), ", "))
+	}
+	return redacted, nil
+}
+
+// formatExplanation renders markdown in an explanation unless --raw-markdown is set
+func formatExplanation(explanation string) string {
+	if rawMarkdown {
+		return explanation
+	}
+	return utils.RenderMarkdown(explanation)
+}
+
+// resolveProfileName returns the profile selected by --profile, then by the
+// directory_profiles entry matching the working directory, falling back to
+// the configured default
+func resolveProfileName(cfg *config.Config) string {
+	if profile != "" && profile != "default" {
+		return profile
+	}
+	if mapped := cfg.ProfileForDirectory(commandWorkingDirectory()); mapped != "" {
+		return mapped
+	}
+	return cfg.DefaultProfile
+}
+
+// commandWorkingDirectory returns the --cwd override, or the current directory
+func commandWorkingDirectory() string {
+	if workingDir != "" {
+		if dir, err := utils.ResolveWorkingDirectory(workingDir); err == nil {
+			return dir
+		}
+	}
+	return utils.GetWorkingDirectory()
+}
+
 // executeCommand runs a shell command with safety checks
 func executeCommand(command string, warnings []string) error {
 	if command == "" {
@@ -407,11 +915,37 @@ func executeCommand(command string, warnings []string) error {
 	}
 
 	// Safety checks
-	if isDangerousCommand(command) {
+	ctx := &llm.Context{
+		OS:               utils.GetOperatingSystem(),
+		Shell:            utils.GetCurrentShell(),
+		WorkingDirectory: commandWorkingDirectory(),
+	}
+	if err := enforceSafeMode(command, ctx); err != nil {
+		return err
+	}
+
+	detector := security.NewDangerDetector()
+	assessment := detector.AssessCommand(command, ctx)
+	securityConfig := loadSecurityConfig()
+	autoRun := security.ShouldAutoRun(assessment.Level, securityConfig.AutoRunMaxLevel)
+
+	if assessment.Level.IsAtLeastLevel(llm.DangerLevelMedium) {
 		fmt.Printf("⚠️  DANGEROUS COMMAND DETECTED!\n")
 		fmt.Printf("Command: %s\n", command)
+		fmt.Printf("Reason: %s\n", assessment.Reason)
 
-		if !forceRun {
+		required := security.RequiredConfirmation(securityConfig.CriticalConfirmation, command)
+		if !forceRun && !autoRun && assessment.Level == llm.DangerLevelCritical && required != "" {
+			// security.critical_confirmation asks for the command or a phrase
+			confirmed, err := security.ConfirmCritical(os.Stdin, os.Stdout, required)
+			if err != nil {
+				return err
+			}
+			if !confirmed {
+				fmt.Printf("❌ Command execution cancelled\n")
+				return nil
+			}
+		} else if !forceRun && !autoRun {
 			fmt.Printf("This command may be destructive. Continue? (type 'yes' to confirm): ")
 
 			reader := bufio.NewReader(os.Stdin)
@@ -424,11 +958,11 @@ func executeCommand(command string, warnings []string) error {
 				fmt.Printf("❌ Command execution cancelled\n")
 				return nil
 			}
-		} else {
+		} else if forceRun {
 			fmt.Printf("⚠️  Force execution enabled - proceeding with dangerous command\n")
 		}
-	} else if !forceRun {
-		// For non-dangerous commands, still ask for confirmation unless forced
+	} else if !forceRun && !autoRun {
+		// For non-dangerous commands, still ask for confirmation unless forced or auto-run
 		fmt.Printf("Execute: %s\n", command)
 		fmt.Printf("Continue? [Y/n]: ")
 
@@ -445,6 +979,19 @@ func executeCommand(command string, warnings []string) error {
 		}
 	}
 
+	// Skipping the usual confirmation could leave a sudo password prompt
+	// waiting unnoticed, so commands that may ask for one confirm separately
+	if (forceRun || autoRun) && os.Geteuid() != 0 && security.DetectElevation(command).MayPrompt() {
+		confirmed, err := security.ConfirmElevation(os.Stdin, os.Stdout, command)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Printf("❌ Command execution cancelled\n")
+			return nil
+		}
+	}
+
 	// Show warnings again before execution
 	if len(warnings) > 0 {
 		fmt.Printf("⚠️  Final warnings:\n")
@@ -454,18 +1001,35 @@ func executeCommand(command string, warnings []string) error {
 		fmt.Println()
 	}
 
+	// Multi-line output runs as one script, stopping at the first failing
+	// step unless --continue-on-error is set. A failure is returned as a
+	// *utils.CommandError for the failed step, so it can be offered a fix.
+	if steps := prompt.SplitCommandSteps(command); len(steps) > 1 {
+		err := executeSteps(steps, quiet)
+		exitCode := utils.ExitCode(err)
+		var cmdErr *utils.CommandError
+		if errors.As(err, &cmdErr) {
+			exitCode = cmdErr.Result.ExitCode
+		}
+		if recordErr := config.RecordRecentCommand(command, exitCode); recordErr != nil {
+			utils.Debugf("%s Failed to record command: %v\n", utils.Styled("[WARNING]", utils.StyleWarning), recordErr)
+		}
+		return err
+	}
+
 	// Execute the command
 	fmt.Printf("⚡ Executing: %s\n", command)
 	fmt.Println("─────────────────────────────────────")
 
-	cmd := exec.Command(utils.GetCurrentShell(), "-c", command)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-
-	err := cmd.Run()
+	// Output is captured as well as shown so a failure can be diagnosed
+	result, err := utils.RunCapturedCommand(utils.GetCurrentShell(), command, os.Stdin, os.Stdout, os.Stderr)
 	fmt.Println("─────────────────────────────────────")
 
+	if recordErr := config.RecordRecentCommand(command, result.ExitCode); recordErr != nil {
+		utils.Debugf("%s Failed to record command: %v\n", utils.Styled("[WARNING]", utils.StyleWarning), recordErr)
+	}
+	appendToHistory(command, result.ExitCode)
+
 	if err != nil {
 		fmt.Printf("❌ Command failed: %v\n", err)
 		return err
@@ -475,34 +1039,20 @@ func executeCommand(command string, warnings []string) error {
 	return nil
 }
 
-// isDangerousCommand checks if a command is potentially destructive
-func isDangerousCommand(command string) bool {
-	dangerousPatterns := []string{
-		"rm -rf", "rm -fr", "rm -r", "rm -f",
-		"sudo rm", "sudo delete", "sudo dd",
-		"mkfs", "fdisk", "parted",
-		"shutdown", "reboot", "halt",
-		"chmod 777", "chmod -R 777",
-		"chown -R", "find . -delete", "find / -delete",
-		"> /dev/", "dd if=", "dd of=",
-		"curl.*|.*sh", "wget.*|.*sh", "curl.*|.*bash", "wget.*|.*bash",
-		":(){ :|:& };:", // fork bomb
-		"mv /*", "cp /* ",
-		"truncate", "shred",
-	}
+// initConfig reads in config file and ENV variables if set.
+func initConfig() {
+	// Diagnostics go to stderr; debug output only in verbose mode
+	cobra.CheckErr(utils.InitLogger(verbose, logFormat))
 
-	command = strings.ToLower(command)
-	for _, pattern := range dangerousPatterns {
-		if strings.Contains(command, strings.ToLower(pattern)) {
-			return true
-		}
-	}
+	// Provider warnings come from the same detector as 'forgor run', for
+	// every command that generates or shows a command
+	prompt.SetSafetyChecker(security.CommandWarnings)
 
-	return false
-}
+	// Usage text after an error would break JSON on stderr
+	if jsonErrorsEnabled() {
+		rootCmd.SilenceUsage = true
+	}
 
-// initConfig reads in config file and ENV variables if set.
-func initConfig() {
 	if cfgFile != "" {
 		// Use config file from the flag.
 		viper.SetConfigFile(cfgFile)
@@ -511,7 +1061,10 @@ func initConfig() {
 		home, err := os.UserHomeDir()
 		cobra.CheckErr(err)
 
-		// Search config in home directory with name ".forgor" (without extension).
+		// Search the XDG config directory first, then ~/.config/forgor
+		if configDir, err := config.GetConfigDir(); err == nil {
+			viper.AddConfigPath(configDir)
+		}
 		viper.AddConfigPath(home + "/.config/forgor")
 		viper.AddConfigPath(home)
 		viper.AddConfigPath(".")
@@ -519,10 +1072,15 @@ func initConfig() {
 		viper.SetConfigName("config")
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
+	// Read FORGOR_* environment variables (e.g. FORGOR_PROFILE, FORGOR_FORMAT)
+	config.ConfigureEnv(viper.GetViper())
+
+	// Explicit flags win over environment variables, which win over flag defaults
+	profile = viper.GetString("profile")
+	format = viper.GetString("format")
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil && verbose {
-		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+		utils.Debugf("Using config file: %s\n", viper.ConfigFileUsed())
 	}
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"forgor/internal/config"
 	"forgor/internal/llm"
+	"forgor/internal/prompt"
 	"forgor/internal/security"
 	"forgor/internal/utils"
 	"os"
@@ -38,7 +39,8 @@ Examples:
   forgor run                             # Execute the last generated command
   forgor run "ls -la"                    # Execute specific command with confirmation  
   forgor run -F "rm temp.txt"            # Force execute without confirmation
-  forgor run -q "echo hello"             # Quiet mode (less output)`,
+  forgor run -q "echo hello"             # Quiet mode (less output)
+  forgor run --continue-on-error         # Run every step of a multi-step command`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		var command string
@@ -76,7 +78,7 @@ Examples:
 		}
 
 		if verbose && !runQuiet {
-			fmt.Printf("🔍 Executing command: %s\n", command)
+			utils.Debugf("🔍 Executing command: %s\n", command)
 		}
 
 		// Use enhanced danger assessment
@@ -116,7 +118,7 @@ Examples:
 		defer func() { runForce = oldForceRun }()
 
 		if verbose && !runQuiet {
-			fmt.Printf("🔍 Executing command: %s\n", command)
+			utils.Debugf("🔍 Executing command: %s\n", command)
 		}
 
 		// Use enhanced danger assessment
@@ -137,6 +139,9 @@ func executeCommandEnhanced(command string) error {
 		Shell:            utils.GetCurrentShell(),
 		WorkingDirectory: utils.GetWorkingDirectory(),
 	}
+	if err := enforceSafeMode(command, ctx); err != nil {
+		return err
+	}
 	assessment := detector.AssessCommand(command, ctx)
 
 	// Show danger assessment
@@ -161,16 +166,24 @@ func executeCommandEnhanced(command string) error {
 		fmt.Println()
 	}
 
+	// Commands at or below security.auto_run_max_level skip the prompt
+	autoRunMaxLevel := loadAutoRunMaxLevel()
+	autoRun := !runForce && security.ShouldAutoRun(assessment.Level, autoRunMaxLevel)
+	if autoRun && !runQuiet {
+		fmt.Printf("%s %s is within security.auto_run_max_level (%s), running without confirmation\n",
+			utils.Styled("[AUTO-RUN]", utils.StyleInfo), strings.ToUpper(string(assessment.Level)), autoRunMaxLevel)
+	}
+
 	// Enhanced safety checks based on danger level
-	if assessment.Level.IsAtLeastLevel(llm.DangerLevelMedium) && !runForce {
+	if assessment.Level.IsAtLeastLevel(llm.DangerLevelMedium) && !runForce && !autoRun {
 		if err := handleDangerousExecution(command, assessment); err != nil {
 			if errors.Is(err, ErrCommandCancelled) {
 				return nil // User cancelled, not an error
 			}
 			return err
 		}
-	} else if !runForce {
-		// For low/safe commands, still ask for confirmation unless forced
+	} else if !runForce && !autoRun {
+		// For low/safe commands, still ask for confirmation unless forced or auto-run
 		if !runQuiet {
 			fmt.Printf("\n%s\n", utils.Divider("CONFIRMATION", utils.StyleInfo))
 			fmt.Printf("%s %s\n", utils.Styled("Execute:", utils.StyleCommand), command)
@@ -197,6 +210,12 @@ func executeCommandEnhanced(command string) error {
 		fmt.Printf("%s\n", factorList)
 	}
 
+	// Multi-line output runs as one script, stopping at the first failing
+	// step unless --continue-on-error is set
+	if steps := prompt.SplitCommandSteps(command); len(steps) > 1 {
+		return executeSteps(steps, runQuiet)
+	}
+
 	// Execute the command directly
 	if !runQuiet {
 		fmt.Printf("\n%s\n", utils.Divider("EXECUTION", utils.StyleCommand))
@@ -204,12 +223,8 @@ func executeCommandEnhanced(command string) error {
 		fmt.Printf("%s\n", utils.Divider("", utils.StyleSubtle))
 	}
 
-	cmd := exec.Command(utils.GetCurrentShell(), "-c", command)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-
-	err := cmd.Run()
+	err := runShellCommand(command)
+	appendToHistory(command, utils.ExitCode(err))
 
 	if !runQuiet {
 		fmt.Printf("%s\n", utils.Divider("", utils.StyleSubtle))
@@ -227,6 +242,140 @@ func executeCommandEnhanced(command string) error {
 	return nil
 }
 
+// loadAutoRunMaxLevel returns the configured security.auto_run_max_level.
+// If the config can't be loaded, auto-run stays disabled so every command prompts.
+func loadAutoRunMaxLevel() string {
+	return loadSecurityConfig().AutoRunMaxLevel
+}
+
+// loadSecurityConfig returns the configured security settings, or the zero
+// settings if the config can't be loaded
+func loadSecurityConfig() config.SecurityConfig {
+	cfg, err := config.Load()
+	if err != nil {
+		return config.SecurityConfig{}
+	}
+	return cfg.Security
+}
+
+// appendToHistory records an executed command in the enhanced command log
+// when --append-to-history or history.append_executed is set
+func appendToHistory(command string, exitCode int) {
+	if !appendToLog {
+		cfg, err := config.Load()
+		if err != nil || !cfg.History.AppendExecuted {
+			return
+		}
+	}
+
+	if err := utils.AppendToCommandLog(command, exitCode, utils.GetWorkingDirectory()); err != nil {
+		utils.Debugf("%s Failed to append command to history: %v\n", utils.Styled("[WARNING]", utils.StyleWarning), err)
+	}
+}
+
+// safeModeEnabled reports whether --safe or security.safe_mode is on
+func safeModeEnabled(securityConfig config.SecurityConfig) bool {
+	return safeMode || securityConfig.SafeMode
+}
+
+// enforceSafeMode refuses to run command when safe mode is on and the
+// command is above security.safe_max_level
+func enforceSafeMode(command string, ctx *llm.Context) error {
+	securityConfig := loadSecurityConfig()
+	if !safeModeEnabled(securityConfig) {
+		return nil
+	}
+
+	_, err := security.CheckSafeMode(command, ctx, securityConfig.SafeMaxLevel)
+	var blocked *security.BlockedError
+	if errors.As(err, &blocked) {
+		fmt.Printf("\n%s %s\n", utils.Styled("Command:", utils.StyleCommand), command)
+		printBlocked(blocked)
+	}
+	return err
+}
+
+// printBlocked explains why safe mode blocked a command and lists the
+// assessment's mitigations
+func printBlocked(blocked *security.BlockedError) {
+	assessment := blocked.Assessment
+	fmt.Printf("\n%s\n", utils.Divider("BLOCKED BY SAFE MODE", utils.StyleError))
+	fmt.Printf("%s %s\n", utils.DangerIcon(string(assessment.Level)),
+		utils.Styled(fmt.Sprintf("%s DANGER LEVEL (limit: %s)", strings.ToUpper(string(assessment.Level)), blocked.MaxLevel), utils.StyleError))
+	fmt.Printf("%s %s\n", utils.Styled("Reason:", utils.StyleSubtle), assessment.Reason)
+
+	if len(assessment.Factors) > 0 {
+		fmt.Printf("\n%s\n", utils.Styled("Risk Factors:", utils.StyleWarning))
+		fmt.Printf("%s\n", utils.List(assessment.Factors, utils.StyleWarning))
+	}
+	if len(assessment.Mitigations) > 0 {
+		fmt.Printf("\n%s\n", utils.Styled("Safety Recommendations:", utils.StyleInfo))
+		fmt.Printf("%s\n", utils.List(assessment.Mitigations, utils.StyleInfo))
+	}
+	fmt.Printf("\n%s\n", utils.Styled("The command was not run. Review it, or raise security.safe_max_level to allow it.", utils.StyleSubtle))
+}
+
+// executeSteps runs the steps of a multi-step command in order as one script,
+// so cd, export and source carry over between steps. Execution stops at the
+// first non-zero exit unless --continue-on-error is set.
+func executeSteps(steps []string, hideProgress bool) error {
+	if !hideProgress {
+		fmt.Printf("\n%s\n", utils.Divider(fmt.Sprintf("EXECUTING %d STEPS", len(steps)), utils.StyleCommand))
+	}
+
+	run := utils.StepRun{
+		Shell:           utils.GetCurrentShell(),
+		ContinueOnError: continueOnError,
+		Stdin:           os.Stdin,
+		Stdout:          os.Stdout,
+		Stderr:          os.Stderr,
+	}
+	if !hideProgress {
+		run.Header = func(index int, step string) string {
+			return fmt.Sprintf("\n%s %s\n%s",
+				utils.Styled(fmt.Sprintf("[%d/%d]", index+1, len(steps)), utils.StyleInfo),
+				utils.Styled(step, utils.StyleCommand),
+				utils.Divider("", utils.StyleSubtle))
+		}
+	}
+
+	results, err := utils.RunSteps(steps, run)
+	for _, result := range results {
+		appendToHistory(result.Command, result.ExitCode)
+		if result.ExitCode != 0 {
+			fmt.Printf("%s Step %d failed with exit code %d\n", utils.Styled("[ERROR]", utils.StyleError), result.Index+1, result.ExitCode)
+		}
+	}
+
+	if err != nil {
+		skipped := len(steps) - len(results)
+		switch {
+		case skipped > 0 && !continueOnError:
+			fmt.Printf("%s Skipped %d remaining step(s). Use --continue-on-error to run them anyway\n",
+				utils.Styled("[STOPPED]", utils.StyleWarning), skipped)
+		case skipped > 0:
+			// With --continue-on-error, only a step that exits the shell skips the rest
+			fmt.Printf("%s Skipped %d remaining step(s) after a step exited the shell\n",
+				utils.Styled("[STOPPED]", utils.StyleWarning), skipped)
+		}
+		return err
+	}
+
+	if !hideProgress {
+		fmt.Printf("%s All %d steps completed successfully\n", utils.Styled("[SUCCESS]", utils.StyleSuccess), len(steps))
+	}
+	return nil
+}
+
+// runShellCommand runs a single command in the user's shell, attached to the terminal
+func runShellCommand(command string) error {
+	cmd := exec.Command(utils.GetCurrentShell(), "-c", command)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
+
 // handleDangerousExecution handles execution confirmation for dangerous commands
 func handleDangerousExecution(command string, assessment llm.DangerAssessment) error {
 	dangerIcon := utils.DangerIcon(string(assessment.Level))
@@ -237,7 +386,18 @@ func handleDangerousExecution(command string, assessment llm.DangerAssessment) e
 	fmt.Printf("%s %s\n", utils.Styled("Command:", utils.StyleCommand), command)
 	fmt.Printf("%s %s\n\n", utils.Styled("Reason:", utils.StyleSubtle), assessment.Reason)
 
-	if assessment.Level.IsAtLeastLevel(llm.DangerLevelHigh) {
+	// security.critical_confirmation replaces the phrase for critical commands
+	required := security.RequiredConfirmation(loadSecurityConfig().CriticalConfirmation, command)
+	if assessment.Level == llm.DangerLevelCritical && required != "" {
+		confirmed, err := security.ConfirmCritical(os.Stdin, os.Stdout, required)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Printf("%s Command execution cancelled\n", utils.Styled("[CANCELLED]", utils.StyleError))
+			return ErrCommandCancelled
+		}
+	} else if assessment.Level.IsAtLeastLevel(llm.DangerLevelHigh) {
 		fmt.Printf("%s ", utils.Styled("This command may be destructive. Type 'YES I UNDERSTAND THE RISKS' to confirm:", utils.StyleDanger))
 
 		reader := bufio.NewReader(os.Stdin)
@@ -293,4 +453,5 @@ func init() {
 	// Add flags for the run command
 	runCmd.Flags().BoolVarP(&runForce, "force", "F", false, "force execute without confirmation (DANGEROUS)")
 	runCmd.Flags().BoolVarP(&runQuiet, "quiet", "q", false, "quiet mode - less output")
+	runCmd.Flags().BoolVar(&continueOnError, "continue-on-error", false, "keep running remaining steps of a multi-step command after a failure")
 }
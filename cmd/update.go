@@ -2,12 +2,16 @@ package cmd
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
+	"forgor/internal/config"
 	"forgor/internal/utils"
 
 	"github.com/spf13/cobra"
@@ -17,24 +21,46 @@ import (
 var updateCmd = &cobra.Command{
 	Use:   "update",
 	Short: "Update forgor to the latest version",
-	Long:  `Checks for the latest release of forgor on GitHub and, if a newer version is found, downloads and installs it.`,
+	Long: `Checks for the latest release of forgor on GitHub and, if a newer version is found, downloads and installs it.
+
+The replaced binary is kept so 'forgor update --rollback' can restore it.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return runUpdate()
+		if rollbackUpdate {
+			return runRollback()
+		}
+		return runUpdate(cmd.Context(), updateChannel)
 	},
 }
 
-func runUpdate() error {
+var (
+	// updateChannel selects stable releases or also prereleases
+	updateChannel string
+
+	// rollbackUpdate restores the binary replaced by the last update
+	rollbackUpdate bool
+)
+
+// withInterrupt returns a context that Ctrl-C cancels, so a slow network call
+// can be aborted. Call stop when the call returns to restore the default
+// Ctrl-C handling for prompts that follow.
+func withInterrupt(ctx context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(ctx, os.Interrupt)
+}
+
+func runUpdate(ctx context.Context, channel string) error {
 	fmt.Printf("Checking for new releases of forgor...\n")
 
 	// Get the latest release information from GitHub
-	latestRelease, err := utils.GetLatestVersion()
+	checkCtx, stop := withInterrupt(ctx)
+	latestRelease, err := utils.GetLatestRelease(checkCtx, channel)
+	stop()
 	if err != nil {
 		return fmt.Errorf("failed to get latest version: %w", err)
 	}
 
 	// Compare versions
 	currentVersion := Version
-	if latestRelease.TagName == currentVersion {
+	if utils.CompareVersions(latestRelease.TagName, currentVersion) <= 0 {
 		fmt.Printf("✅ You are already using the latest version of forgor: %s\n", utils.Styled(currentVersion, utils.StyleSuccess))
 		return nil
 	}
@@ -78,7 +104,9 @@ func runUpdate() error {
 
 	// 2. Download the binary from assetURL into a temp directory.
 	fmt.Printf("⬇️  Downloading %s...\n", utils.Styled(assetName, utils.StyleHighlight))
-	downloadedArchivePath, err := utils.DownloadUpdate(assetURL)
+	downloadCtx, stop := withInterrupt(ctx)
+	downloadedArchivePath, err := utils.DownloadUpdate(downloadCtx, assetURL, os.Stdout)
+	stop()
 	if err != nil {
 		return fmt.Errorf("failed to download update: %w", err)
 	}
@@ -98,18 +126,102 @@ func runUpdate() error {
 	if err != nil {
 		return fmt.Errorf("could not find current executable path: %w", err)
 	}
+	// Replace the real binary rather than a symlink pointing at it
+	if resolved, err := filepath.EvalSymlinks(currentExec); err == nil {
+		currentExec = resolved
+	}
+
+	// Archives may nest the binary, so find it and make sure it runs here
+	newExecPath, err := utils.FindExtractedBinary(tempDir)
+	if err != nil {
+		return fmt.Errorf("failed to find the new binary: %w", err)
+	}
+	if err := utils.VerifyBinary(newExecPath); err != nil {
+		return fmt.Errorf("refusing to install the update: %w", err)
+	}
+
+	// Keep the current binary so 'forgor update --rollback' can undo this
+	backupDir, err := utils.GetUpdateBackupDir()
+	if err != nil {
+		return err
+	}
+	if err := utils.BackupExecutable(currentExec, currentVersion, backupDir); err != nil {
+		return fmt.Errorf("failed to back up the current version: %w", err)
+	}
 
-	newExecPath := filepath.Join(tempDir, "forgor")
 	fmt.Printf("🚀 Replacing current version at %s...\n", utils.Styled(currentExec, utils.StyleSubtle))
-	err = os.Rename(newExecPath, currentExec)
+	err = utils.ReplaceExecutable(newExecPath, currentExec)
 	if err != nil {
 		return fmt.Errorf("failed to replace executable (you may need to run with sudo or as an administrator): %w", err)
 	}
 
 	fmt.Printf("✅ Forgor has been successfully updated to version %s!\n", utils.Styled(latestRelease.TagName, utils.StyleSuccess))
+	fmt.Printf("   To undo, run: %s\n", utils.Styled("forgor update --rollback", utils.StyleCommand))
 	return nil
 }
 
+// runRollback restores the binary backed up by the last update
+func runRollback() error {
+	backupDir, err := utils.GetUpdateBackupDir()
+	if err != nil {
+		return err
+	}
+
+	info, err := utils.RestoreExecutable(backupDir)
+	if err != nil {
+		return fmt.Errorf("failed to roll back (you may need to run with sudo or as an administrator): %w", err)
+	}
+
+	fmt.Printf("✅ Rolled back to version %s at %s\n",
+		utils.Styled(info.Version, utils.StyleSuccess),
+		utils.Styled(info.Executable, utils.StyleSubtle))
+	return nil
+}
+
+// checkForUpdatesInBackground announces a newer release found by an earlier
+// check, and starts a new check when the configured interval has passed. The
+// check runs alongside the query; if forgor exits first it is retried on the
+// next run.
+func checkForUpdatesInBackground(updates config.UpdatesConfig) {
+	if Version == "dev" || Version == "unknown" {
+		return
+	}
+
+	statePath, err := utils.GetUpdateCheckStatePath()
+	if err != nil {
+		utils.Debugf("Skipping update check: %v\n", err)
+		return
+	}
+
+	state := utils.LoadUpdateCheckState(statePath)
+	if notice := utils.UpdateNotice(state, Version); notice != "" && !quiet {
+		fmt.Fprintln(os.Stderr, notice)
+		state.NotifiedVersion = state.LatestVersion
+		if err := utils.SaveUpdateCheckState(statePath, state); err != nil {
+			utils.Debugf("Failed to save update check state: %v\n", err)
+		}
+	}
+
+	interval := time.Duration(updates.CheckIntervalHours) * time.Hour
+	if utils.UpdateCheckDue(state, time.Now(), interval) {
+		go func() {
+			latest := func() (*utils.ReleaseInfo, error) {
+				return utils.GetLatestVersion(context.Background())
+			}
+			if err := utils.RefreshUpdateCheck(statePath, time.Now(), latest); err != nil {
+				utils.Debugf("Background update check failed: %v\n", err)
+			}
+		}()
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(updateCmd)
+
+	updateCmd.Flags().BoolVar(&rollbackUpdate, "rollback", false, "restore the version replaced by the last update")
+	updateCmd.Flags().StringVar(&updateChannel, "channel", utils.UpdateChannelStable, "release channel to update from (stable, prerelease)")
+	updateCmd.RegisterFlagCompletionFunc("channel", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{utils.UpdateChannelStable, utils.UpdateChannelPrerelease}, cobra.ShellCompDirectiveNoFileComp
+	})
+	updateCmd.MarkFlagsMutuallyExclusive("rollback", "channel")
 }
@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"runtime"
 	"time"
 
 	"forgor/internal/utils"
@@ -17,17 +19,20 @@ var (
 	BuildDate = "unknown"
 )
 
+// checkUpdates forces an update check, even for development builds
+var checkUpdates bool
+
 // versionCmd represents the version command
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Show version information",
 	Long:  `Display the version, git commit, and build date of forgor`,
 	Run: func(cmd *cobra.Command, args []string) {
-		showVersion()
+		showVersion(cmd.Context())
 	},
 }
 
-func showVersion() {
+func showVersion(ctx context.Context) {
 	fmt.Printf("\n%s\n", utils.Divider("FORGOR VERSION INFO", utils.StyleInfo))
 
 	// Parse build date for better formatting
@@ -48,8 +53,8 @@ func showVersion() {
 		{utils.Styled("Version", utils.StyleHighlight), utils.Styled(Version, utils.StyleSuccess)},
 		{utils.Styled("Git Commit", utils.StyleHighlight), GitCommit},
 		{utils.Styled("Build Date", utils.StyleHighlight), buildTime},
-		{utils.Styled("Go Version", utils.StyleHighlight), getGoVersion()},
-		{utils.Styled("Platform", utils.StyleHighlight), getPlatform()},
+		{utils.Styled("Go Version", utils.StyleHighlight), runtime.Version()},
+		{utils.Styled("Platform", utils.StyleHighlight), utils.GetPlatform()},
 	}
 
 	fmt.Printf("%s\n", utils.Table(headers, rows, utils.StyleInfo))
@@ -61,61 +66,17 @@ func showVersion() {
 			utils.Styled(execPath, utils.StyleSubtle))
 	}
 
-	// Show latest version info if available
-	utils.CheckForUpdates(Version)
+	// Release builds always check; --check forces it for development builds.
+	// Ctrl-C aborts a slow check.
+	ctx, stop := withInterrupt(ctx)
+	defer stop()
+	utils.CheckForUpdates(ctx, Version, checkUpdates)
 
 	fmt.Println()
 }
 
-func getForgorVersion() string {
-	return Version
-}
-
-func getGoVersion() string {
-	return "1.23"
-}
-
-func getPlatform() string {
-	return fmt.Sprintf("%s/%s", getOS(), getArch())
-}
-
-func getOS() string {
-	switch {
-	case isWindows():
-		return "windows"
-	case isDarwin():
-		return "darwin"
-	case isLinux():
-		return "linux"
-	default:
-		return "unknown"
-	}
-}
-
-func getArch() string {
-	// This is a simplified approach - in a real implementation,
-	// you might want to use runtime.GOARCH
-	return "amd64"
-}
-
-func isWindows() bool {
-	return os.PathSeparator == '\\'
-}
-
-func isDarwin() bool {
-	if stat, err := os.Stat("/System/Library/CoreServices/SystemVersion.plist"); err == nil && !stat.IsDir() {
-		return true
-	}
-	return false
-}
-
-func isLinux() bool {
-	if stat, err := os.Stat("/proc/version"); err == nil && !stat.IsDir() {
-		return true
-	}
-	return false
-}
-
 func init() {
 	rootCmd.AddCommand(versionCmd)
+
+	versionCmd.Flags().BoolVar(&checkUpdates, "check", false, "check GitHub for a newer release, even in development builds")
 }
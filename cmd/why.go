@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"os"
+
+	"forgor/internal/config"
+	"forgor/internal/llm"
+	"forgor/internal/security"
+	"forgor/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// whyCmd represents the why command
+var whyCmd = &cobra.Command{
+	Use:   "why",
+	Short: "Explain the danger assessment of the last generated command",
+	Long: `Assess the last command generated by forgor and show why it was flagged:
+its danger level, the reasons, risk factors and safety recommendations.
+
+The assessment runs locally; nothing is sent to a provider.
+
+Examples:
+  forgor why                             # Why was the last command flagged?`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		command, err := config.LoadLastCommand()
+		if err != nil {
+			return err
+		}
+
+		assessment := security.NewDangerDetector().AssessCommand(command, &llm.Context{
+			OS:               utils.GetOperatingSystem(),
+			Shell:            utils.GetCurrentShell(),
+			WorkingDirectory: utils.GetWorkingDirectory(),
+		})
+		security.WriteDangerReport(os.Stdout, command, assessment)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(whyCmd)
+}
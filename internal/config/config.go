@@ -1,15 +1,30 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"forgor/internal/utils"
+
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
 )
 
+// DefaultHistoryMaxChars is the default character budget for command history
+// sent with a query
+const DefaultHistoryMaxChars = 2000
+
+// DefaultHistoryMaxCommandChars is the default character limit for a single
+// history command sent with a query
+const DefaultHistoryMaxCommandChars = 300
+
+// DefaultMaxTokens is used for profiles that don't set max_tokens
+const DefaultMaxTokens = 150
+
 // Config represents the overall configuration structure
 type Config struct {
 	DefaultProfile string             `yaml:"default_profile" mapstructure:"default_profile"`
@@ -18,28 +33,87 @@ type Config struct {
 	Security       SecurityConfig     `yaml:"security" mapstructure:"security"`
 	Output         OutputConfig       `yaml:"output" mapstructure:"output"`
 	CustomTools    CustomToolsConfig  `yaml:"custom_tools" mapstructure:"custom_tools"`
+	Prompt         PromptConfig       `yaml:"prompt,omitempty" mapstructure:"prompt"`
+	Updates        UpdatesConfig      `yaml:"updates" mapstructure:"updates"`
+	// DirectoryProfiles pick a profile by working directory when --profile
+	// isn't given. The longest matching path wins.
+	DirectoryProfiles []DirectoryProfile `yaml:"directory_profiles,omitempty" mapstructure:"directory_profiles"`
+	// DebugLog records API requests and responses to this file for bug
+	// reports, with API keys and secrets redacted. Empty disables it.
+	DebugLog string `yaml:"debug_log,omitempty" mapstructure:"debug_log"`
+	// OfflineFallback answers with the built-in offline rules when the
+	// provider can't be reached, instead of failing
+	OfflineFallback bool `yaml:"offline_fallback,omitempty" mapstructure:"offline_fallback"`
+	// RetryOnEmpty asks the provider once more, for only the command, when
+	// its answer contains no command
+	RetryOnEmpty bool `yaml:"retry_on_empty" mapstructure:"retry_on_empty"`
+	// Queries are saved queries run with 'forgor q <name>'. {name}
+	// placeholders are filled in from name=value arguments.
+	Queries map[string]string `yaml:"queries,omitempty" mapstructure:"queries"`
 }
 
 // Profile represents an LLM provider profile
 type Profile struct {
 	Provider    string  `yaml:"provider" mapstructure:"provider"`
 	APIKey      string  `yaml:"api_key" mapstructure:"api_key"`
+	APIKeyFile  string  `yaml:"api_key_file,omitempty" mapstructure:"api_key_file"`
 	Model       string  `yaml:"model" mapstructure:"model"`
 	MaxTokens   int     `yaml:"max_tokens" mapstructure:"max_tokens"`
 	Temperature float64 `yaml:"temperature" mapstructure:"temperature"`
-	Endpoint    string  `yaml:"endpoint,omitempty" mapstructure:"endpoint"`
+	// Endpoint replaces the provider's API base URL, e.g. to use a gateway
+	Endpoint string `yaml:"endpoint,omitempty" mapstructure:"endpoint"`
+	// Proxy overrides HTTPS_PROXY/HTTP_PROXY for this profile's API requests
+	Proxy string `yaml:"proxy,omitempty" mapstructure:"proxy"`
+	// SystemPromptPrefix and SystemPromptSuffix add text before and after the
+	// system prompt for this profile only
+	SystemPromptPrefix string `yaml:"system_prompt_prefix,omitempty" mapstructure:"system_prompt_prefix"`
+	SystemPromptSuffix string `yaml:"system_prompt_suffix,omitempty" mapstructure:"system_prompt_suffix"`
 }
 
 // HistoryConfig represents shell history configuration
 type HistoryConfig struct {
 	MaxCommands int      `yaml:"max_commands" mapstructure:"max_commands"`
 	Shells      []string `yaml:"shells" mapstructure:"shells"`
+	// MaxChars caps the total characters of history sent with a query. The
+	// oldest commands are dropped first; 0 disables the cap.
+	MaxChars int `yaml:"max_chars" mapstructure:"max_chars"`
+	// MaxCommandChars shortens longer history commands, such as a curl with
+	// a large payload, with an ellipsis; 0 sends commands in full.
+	MaxCommandChars int `yaml:"max_command_chars" mapstructure:"max_command_chars"`
+	// MaxAgeHours skips commands from the enhanced command log that are
+	// older than this; 0 includes commands of any age.
+	MaxAgeHours int `yaml:"max_age_hours,omitempty" mapstructure:"max_age_hours"`
+	// CurrentSession only includes logged commands from the terminal
+	// session forgor runs in, when the history logger identifies it
+	CurrentSession bool `yaml:"current_session,omitempty" mapstructure:"current_session"`
+	// AppendExecuted records commands forgor runs in the enhanced command
+	// log, since the shell doesn't add them to its own history
+	AppendExecuted bool `yaml:"append_executed,omitempty" mapstructure:"append_executed"`
+	// NoNativeFallback reads only the enhanced command log, never the
+	// shell's own history file, as if --no-history-fallback were given
+	NoNativeFallback bool `yaml:"no_native_fallback,omitempty" mapstructure:"no_native_fallback"`
 }
 
 // SecurityConfig represents security and privacy settings
 type SecurityConfig struct {
 	RedactSensitive bool     `yaml:"redact_sensitive" mapstructure:"redact_sensitive"`
 	Filters         []string `yaml:"filters" mapstructure:"filters"`
+	// AutoRunMaxLevel lets commands assessed at or below this danger level run
+	// without a confirmation prompt. Critical commands always prompt.
+	AutoRunMaxLevel string `yaml:"auto_run_max_level,omitempty" mapstructure:"auto_run_max_level"`
+	// SafetyLevel controls how cautious generated commands are:
+	// strict, moderate (default) or permissive
+	SafetyLevel string `yaml:"safety_level,omitempty" mapstructure:"safety_level"`
+	// OnSecret decides what happens when a query or its extra context looks
+	// like it contains a secret: redact (default) or abort
+	OnSecret string `yaml:"on_secret,omitempty" mapstructure:"on_secret"`
+	// CriticalConfirmation is what must be typed to run a critical command:
+	// "command" to retype the command itself, or a phrase. Empty keeps "yes".
+	CriticalConfirmation string `yaml:"critical_confirmation,omitempty" mapstructure:"critical_confirmation"`
+	// SafeMode refuses to run, and marks as blocked, commands assessed above
+	// SafeMaxLevel (default medium), as with --safe
+	SafeMode     bool   `yaml:"safe_mode,omitempty" mapstructure:"safe_mode"`
+	SafeMaxLevel string `yaml:"safe_max_level,omitempty" mapstructure:"safe_max_level"`
 }
 
 // CustomToolsConfig represents user-defined custom tools
@@ -55,6 +129,27 @@ type CustomToolsConfig struct {
 	Other            []string `yaml:"other" mapstructure:"other"`
 }
 
+// PromptConfig represents prompt customization settings
+type PromptConfig struct {
+	// TemplateFile is a text/template file that replaces the built-in system prompt
+	TemplateFile string `yaml:"template_file,omitempty" mapstructure:"template_file"`
+	// ExplainInstruction replaces the built-in instruction used when explaining
+	// commands. The user's OS and shell are still added.
+	ExplainInstruction string `yaml:"explain_instruction,omitempty" mapstructure:"explain_instruction"`
+	// GitStatus adds the current git branch and dirty/clean state to the
+	// prompt when the working directory is inside a repository
+	GitStatus bool `yaml:"git_status" mapstructure:"git_status"`
+}
+
+// UpdatesConfig controls background checks for new releases
+type UpdatesConfig struct {
+	// Check enables a periodic check for new releases. It is off by default
+	// so forgor doesn't contact GitHub unless asked to.
+	Check bool `yaml:"check" mapstructure:"check"`
+	// CheckIntervalHours is the minimum time between checks
+	CheckIntervalHours int `yaml:"check_interval_hours" mapstructure:"check_interval_hours"`
+}
+
 // OutputConfig represents output formatting configuration
 type OutputConfig struct {
 	Format           string `yaml:"format" mapstructure:"format"`
@@ -97,9 +192,65 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if err := c.validateDirectoryProfiles(); err != nil {
+		return err
+	}
+
+	if err := c.validateQueries(); err != nil {
+		return err
+	}
+
+	if c.History.MaxChars < 0 {
+		return fmt.Errorf("history.max_chars must not be negative")
+	}
+	if c.History.MaxCommandChars < 0 {
+		return fmt.Errorf("history.max_command_chars must not be negative")
+	}
+	if c.History.MaxAgeHours < 0 {
+		return fmt.Errorf("history.max_age_hours must not be negative")
+	}
+
+	if c.Updates.CheckIntervalHours < 0 {
+		return fmt.Errorf("updates.check_interval_hours must not be negative")
+	}
+
+	if err := c.Security.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// Validate checks if the security settings are valid
+func (s *SecurityConfig) Validate() error {
+	switch strings.ToLower(s.SafetyLevel) {
+	case "", "strict", "moderate", "permissive":
+	default:
+		return fmt.Errorf("invalid security.safety_level: %s. Valid levels: strict, moderate, permissive", s.SafetyLevel)
+	}
+
+	switch strings.ToLower(s.OnSecret) {
+	case "", "redact", "abort":
+	default:
+		return fmt.Errorf("invalid security.on_secret: %s. Valid actions: redact, abort", s.OnSecret)
+	}
+
+	switch strings.ToLower(s.SafeMaxLevel) {
+	case "", "safe", "low", "medium", "high":
+	default:
+		return fmt.Errorf("invalid security.safe_max_level: %s. Valid levels: safe, low, medium, high", s.SafeMaxLevel)
+	}
+
+	switch strings.ToLower(s.AutoRunMaxLevel) {
+	case "", "safe", "low", "medium", "high":
+		return nil
+	case "critical":
+		return fmt.Errorf("security.auto_run_max_level cannot be critical: critical commands always require confirmation")
+	default:
+		return fmt.Errorf("invalid security.auto_run_max_level: %s. Valid levels: safe, low, medium, high", s.AutoRunMaxLevel)
+	}
+}
+
 // Validate checks if a profile configuration is valid
 func (p *Profile) Validate() error {
 	if p.Provider == "" {
@@ -110,23 +261,57 @@ func (p *Profile) Validate() error {
 		return fmt.Errorf("model must be specified")
 	}
 
+	// max_tokens may be left unset (0) to use DefaultMaxTokens
+	if p.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens must be positive, got %d", p.MaxTokens)
+	}
+
+	if p.Temperature < 0 || p.Temperature > 2 {
+		return fmt.Errorf("temperature must be between 0 and 2, got %g", p.Temperature)
+	}
+
+	if p.Proxy != "" {
+		if proxyURL, err := url.Parse(ExpandEnv(p.Proxy)); err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return fmt.Errorf("invalid proxy URL: %s", p.Proxy)
+		}
+	}
+
 	// Provider-specific validation
 	switch p.Provider {
-	case "openai", "anthropic", "gemini", "google":
-		if p.APIKey == "" {
-			return fmt.Errorf("api_key is required for %s provider", p.Provider)
+	case "openai", "anthropic", "gemini", "google", "groq", "mistral":
+		if p.APIKey == "" && p.APIKeyFile == "" {
+			return fmt.Errorf("api_key or api_key_file is required for %s provider", p.Provider)
 		}
 	case "local":
 		if p.Endpoint == "" {
 			return fmt.Errorf("endpoint is required for local provider")
 		}
+	case "ollama":
+		// endpoint defaults to Ollama's local address
 	default:
-		return fmt.Errorf("unsupported provider: %s", p.Provider)
+		requiresAPIKey, ok := registeredProviderType(p.Provider)
+		if !ok {
+			return fmt.Errorf("unsupported provider: %s", p.Provider)
+		}
+		if requiresAPIKey && p.APIKey == "" && p.APIKeyFile == "" {
+			return fmt.Errorf("api_key or api_key_file is required for %s provider", p.Provider)
+		}
 	}
 
 	return nil
 }
 
+// RequiresAPIKey reports whether the profile's provider authenticates with an API key
+func (p *Profile) RequiresAPIKey() bool {
+	switch p.Provider {
+	case "openai", "anthropic", "gemini", "google", "groq", "mistral":
+		return true
+	default:
+		requiresAPIKey, _ := registeredProviderType(p.Provider)
+		return requiresAPIKey
+	}
+}
+
 // GetProfile returns the specified profile or the default profile
 func (c *Config) GetProfile(name string) (Profile, error) {
 	if name == "" || name == "default" {
@@ -143,7 +328,7 @@ func (c *Config) GetProfile(name string) (Profile, error) {
 
 // CreateDefaultConfig creates a default configuration file
 func CreateDefaultConfig() error {
-	configDir, err := getConfigDir()
+	configDir, err := GetConfigDir()
 	if err != nil {
 		return err
 	}
@@ -175,16 +360,27 @@ func CreateDefaultConfig() error {
 // setDefaults sets default values for viper
 func setDefaults() {
 	viper.SetDefault("default_profile", "openai")
+	viper.SetDefault("retry_on_empty", true)
 	viper.SetDefault("history.max_commands", 10)
-	viper.SetDefault("history.shells", []string{"bash", "zsh", "fish"})
+	viper.SetDefault("history.shells", []string{"bash", "zsh", "fish", "sh", "dash", "ksh"})
+	viper.SetDefault("history.max_chars", DefaultHistoryMaxChars)
+	viper.SetDefault("history.max_command_chars", DefaultHistoryMaxCommandChars)
 	viper.SetDefault("security.redact_sensitive", true)
 	viper.SetDefault("security.filters", []string{"password", "token", "secret", "key"})
 	viper.SetDefault("output.format", "plain")
 	viper.SetDefault("output.confirm_before_run", false)
+	viper.SetDefault("prompt.git_status", true)
+	viper.SetDefault("updates.check", false)
+	viper.SetDefault("updates.check_interval_hours", 24)
 }
 
-// getConfigDir returns the configuration directory path
-func getConfigDir() (string, error) {
+// GetConfigDir returns the configuration directory: $XDG_CONFIG_HOME/forgor
+// when XDG_CONFIG_HOME is set, otherwise ~/.config/forgor
+func GetConfigDir() (string, error) {
+	if xdgConfig := os.Getenv("XDG_CONFIG_HOME"); xdgConfig != "" {
+		return filepath.Join(xdgConfig, "forgor"), nil
+	}
+
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
@@ -192,10 +388,34 @@ func getConfigDir() (string, error) {
 	return filepath.Join(home, ".config", "forgor"), nil
 }
 
+// GetConfigFilePath returns the path of the config file in use, falling back to
+// the default location when no config file has been loaded
+func GetConfigFilePath() (string, error) {
+	if used := viper.ConfigFileUsed(); used != "" {
+		return used, nil
+	}
+
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "config.yaml"), nil
+}
+
+// GetLastCommandPath returns the path of the last generated command cache
+func GetLastCommandPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "last_command"), nil
+}
+
 // getDefaultConfig returns a default configuration
 func getDefaultConfig() *Config {
 	return &Config{
 		DefaultProfile: "openai",
+		RetryOnEmpty:   true,
 		Profiles: map[string]Profile{
 			"openai": {
 				Provider:    "openai",
@@ -218,6 +438,26 @@ func getDefaultConfig() *Config {
 				MaxTokens:   150,
 				Temperature: 0.1,
 			},
+			"groq": {
+				Provider:    "groq",
+				APIKey:      "${GROQ_API_KEY}",
+				Model:       "llama-3.3-70b-versatile",
+				MaxTokens:   150,
+				Temperature: 0.1,
+			},
+			"mistral": {
+				Provider:    "mistral",
+				APIKey:      "${MISTRAL_API_KEY}",
+				Model:       "codestral-latest",
+				MaxTokens:   150,
+				Temperature: 0.1,
+			},
+			"ollama": {
+				Provider:  "ollama",
+				Endpoint:  "http://localhost:11434",
+				Model:     "qwen2.5-coder",
+				MaxTokens: 150,
+			},
 			"local": {
 				Provider:  "local",
 				Endpoint:  "http://localhost:11434",
@@ -226,8 +466,10 @@ func getDefaultConfig() *Config {
 			},
 		},
 		History: HistoryConfig{
-			MaxCommands: 10,
-			Shells:      []string{"bash", "zsh", "fish"},
+			MaxCommands:     10,
+			Shells:          []string{"bash", "zsh", "fish", "sh", "dash", "ksh"},
+			MaxChars:        DefaultHistoryMaxChars,
+			MaxCommandChars: DefaultHistoryMaxCommandChars,
 		},
 		Security: SecurityConfig{
 			RedactSensitive: true,
@@ -237,12 +479,16 @@ func getDefaultConfig() *Config {
 			Format:           "plain",
 			ConfirmBeforeRun: false,
 		},
+		Updates: UpdatesConfig{
+			Check:              false,
+			CheckIntervalHours: 24,
+		},
 	}
 }
 
 // SaveConfig saves the configuration to the config file
 func SaveConfig(config *Config) error {
-	configDir, err := getConfigDir()
+	configDir, err := GetConfigDir()
 	if err != nil {
 		return fmt.Errorf("failed to get config directory: %w", err)
 	}
@@ -272,18 +518,16 @@ func SaveLastCommand(command string) error {
 		return nil // Don't save empty commands
 	}
 
-	configDir, err := getConfigDir()
+	cachePath, err := GetLastCommandPath()
 	if err != nil {
 		return fmt.Errorf("failed to get config directory: %w", err)
 	}
 
 	// Ensure config directory exists
-	if err := os.MkdirAll(configDir, 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	cachePath := filepath.Join(configDir, "last_command")
-
 	if err := os.WriteFile(cachePath, []byte(command), 0644); err != nil {
 		return fmt.Errorf("failed to write last command cache: %w", err)
 	}
@@ -293,13 +537,11 @@ func SaveLastCommand(command string) error {
 
 // LoadLastCommand loads the last generated command from cache
 func LoadLastCommand() (string, error) {
-	configDir, err := getConfigDir()
+	cachePath, err := GetLastCommandPath()
 	if err != nil {
 		return "", fmt.Errorf("failed to get config directory: %w", err)
 	}
 
-	cachePath := filepath.Join(configDir, "last_command")
-
 	data, err := os.ReadFile(cachePath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -315,3 +557,79 @@ func LoadLastCommand() (string, error) {
 
 	return command, nil
 }
+
+// Paths describes where forgor keeps its configuration and cache files
+type Paths struct {
+	ConfigFile         string `json:"config_file"`
+	ConfigExists       bool   `json:"config_exists"`
+	SystemContextCache string `json:"system_context_cache"`
+	LastCommandCache   string `json:"last_command_cache"`
+	RecentCommands     string `json:"recent_commands"`
+}
+
+// GetPaths resolves the config file and cache locations used by forgor
+func GetPaths() (Paths, error) {
+	configFile, err := GetConfigFilePath()
+	if err != nil {
+		return Paths{}, fmt.Errorf("failed to resolve config path: %w", err)
+	}
+
+	lastCommandPath, err := GetLastCommandPath()
+	if err != nil {
+		return Paths{}, fmt.Errorf("failed to resolve last command path: %w", err)
+	}
+
+	recentCommandsPath, err := GetRecentCommandsPath()
+	if err != nil {
+		return Paths{}, fmt.Errorf("failed to resolve recent commands path: %w", err)
+	}
+
+	_, statErr := os.Stat(configFile)
+
+	return Paths{
+		ConfigFile:         configFile,
+		ConfigExists:       statErr == nil,
+		SystemContextCache: utils.GetCacheInfo().FilePath,
+		LastCommandCache:   lastCommandPath,
+		RecentCommands:     recentCommandsPath,
+	}, nil
+}
+
+// MaskProxy returns a proxy URL for display with any credentials hidden
+func MaskProxy(proxy string) string {
+	if proxyURL, err := url.Parse(proxy); err == nil {
+		return proxyURL.Redacted()
+	}
+	return proxy
+}
+
+// Masked returns a copy of the config that is safe to display: API keys are
+// masked with MaskAPIKey and proxy credentials are hidden
+func (c *Config) Masked() *Config {
+	masked := *c
+	masked.Profiles = make(map[string]Profile, len(c.Profiles))
+	for name, profile := range c.Profiles {
+		profile.APIKey = MaskAPIKey(profile.APIKey)
+		if profile.Proxy != "" {
+			profile.Proxy = MaskProxy(profile.Proxy)
+		}
+		masked.Profiles[name] = profile
+	}
+	return &masked
+}
+
+// MaskedJSON returns the masked config as indented JSON, using the same keys
+// as the config file
+func (c *Config) MaskedJSON() ([]byte, error) {
+	// The config structs only carry yaml tags, so go through YAML to get
+	// the config file's key names
+	data, err := yaml.Marshal(c.Masked())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	var fields map[string]interface{}
+	if err := yaml.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return json.MarshalIndent(fields, "", "  ")
+}
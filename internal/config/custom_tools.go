@@ -5,6 +5,8 @@ import (
 	"os/exec"
 	"slices"
 	"strings"
+
+	"forgor/internal/utils"
 )
 
 // ValidToolCategories defines the available tool categories
@@ -41,7 +43,7 @@ func AddCustomTools(category string, tools []string) error {
 		}
 
 		if _, err := exec.LookPath(tool); err != nil {
-			fmt.Printf("Warning: Tool '%s' not found in PATH, adding anyway\n", tool)
+			utils.Warnf("Warning: Tool '%s' not found in PATH, adding anyway", tool)
 		}
 		validTools = append(validTools, tool)
 	}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DirectoryProfile selects a profile for queries run inside a directory
+type DirectoryProfile struct {
+	// Path is a directory prefix; ~ and environment references are expanded
+	Path    string `yaml:"path" mapstructure:"path"`
+	Profile string `yaml:"profile" mapstructure:"profile"`
+}
+
+// ProfileForDirectory returns the profile mapped to the longest configured
+// directory prefix containing dir, or "" if no mapping matches
+func (c *Config) ProfileForDirectory(dir string) string {
+	dir = filepath.Clean(dir)
+
+	bestProfile := ""
+	bestLength := -1
+	for _, mapping := range c.DirectoryProfiles {
+		prefix := expandDirectory(mapping.Path)
+		if prefix == "" || !isWithinDirectory(dir, prefix) {
+			continue
+		}
+		if len(prefix) > bestLength {
+			bestProfile = mapping.Profile
+			bestLength = len(prefix)
+		}
+	}
+
+	return bestProfile
+}
+
+// validateDirectoryProfiles checks that every mapping has a path and names a
+// configured profile
+func (c *Config) validateDirectoryProfiles() error {
+	for i, mapping := range c.DirectoryProfiles {
+		if strings.TrimSpace(mapping.Path) == "" {
+			return fmt.Errorf("directory_profiles[%d]: path must be specified", i)
+		}
+		if _, exists := c.Profiles[mapping.Profile]; !exists {
+			return fmt.Errorf("directory_profiles[%d]: profile '%s' not found in profiles", i, mapping.Profile)
+		}
+	}
+	return nil
+}
+
+// expandDirectory resolves ~, environment references and relative paths in
+// a configured directory
+func expandDirectory(path string) string {
+	path = ExpandEnv(strings.TrimSpace(path))
+	if path == "" {
+		return ""
+	}
+
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	return absPath
+}
+
+// isWithinDirectory reports whether dir is prefix or one of its
+// subdirectories, so /work does not match /workspace
+func isWithinDirectory(dir, prefix string) bool {
+	if dir == prefix {
+		return true
+	}
+	if !strings.HasSuffix(prefix, string(filepath.Separator)) {
+		prefix += string(filepath.Separator)
+	}
+	return strings.HasPrefix(dir, prefix)
+}
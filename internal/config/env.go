@@ -0,0 +1,131 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix is the prefix for environment variables read by forgor, e.g.
+// FORGOR_PROFILE or FORGOR_HISTORY_MAX_COMMANDS
+const EnvPrefix = "FORGOR"
+
+// ConfigureEnv makes v read FORGOR_-prefixed environment variables. Nested
+// keys map with underscores, so history.max_commands is read from
+// FORGOR_HISTORY_MAX_COMMANDS. Flags bound to v still take precedence when set.
+func ConfigureEnv(v *viper.Viper) {
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
+	v.AutomaticEnv()
+}
+
+// ExpandEnv replaces ${VAR} and $VAR references in a config value with the
+// corresponding environment variable. It also supports shell-style defaults,
+// ${VAR:-default}, which yield the default when VAR is unset or empty.
+func ExpandEnv(value string) string {
+	return os.Expand(value, func(name string) string {
+		if key, fallback, ok := strings.Cut(name, ":-"); ok {
+			if envValue := os.Getenv(key); envValue != "" {
+				return envValue
+			}
+			return fallback
+		}
+		return os.Getenv(name)
+	})
+}
+
+// Expanded returns a copy of the profile with environment references in the
+// API key, endpoint, model and proxy resolved
+func (p Profile) Expanded() Profile {
+	p.APIKey = ExpandEnv(p.APIKey)
+	p.Endpoint = ExpandEnv(p.Endpoint)
+	p.Model = ExpandEnv(p.Model)
+	p.Proxy = ExpandEnv(p.Proxy)
+	return p
+}
+
+// MissingEnvVars returns the environment variables referenced by value that
+// are unset and have no ${VAR:-default} fallback
+func MissingEnvVars(value string) []string {
+	var missing []string
+	os.Expand(value, func(name string) string {
+		if _, _, hasDefault := strings.Cut(name, ":-"); hasDefault {
+			return ""
+		}
+		if os.Getenv(name) == "" {
+			missing = append(missing, name)
+		}
+		return ""
+	})
+	return missing
+}
+
+// ResolveAPIKey returns the profile's API key. When api_key_file is set the key
+// is read from that file (with surrounding whitespace trimmed) and takes
+// precedence over api_key. The key itself is never included in errors.
+func (p Profile) ResolveAPIKey() (string, error) {
+	if p.APIKeyFile != "" {
+		keyPath := ExpandEnv(p.APIKeyFile)
+		data, err := os.ReadFile(keyPath) // #nosec G304 - path comes from the user's own config
+		if err != nil {
+			return "", fmt.Errorf("failed to read api_key_file %s: %w", keyPath, err)
+		}
+
+		key := strings.TrimSpace(string(data))
+		if key == "" {
+			return "", fmt.Errorf("api_key_file %s is empty", keyPath)
+		}
+		return key, nil
+	}
+
+	return ExpandEnv(p.APIKey), nil
+}
+
+// ValidateAPIKey checks that the profile's API key resolves to a non-empty
+// value, naming any unset environment variables it references
+func (p Profile) ValidateAPIKey() error {
+	key, err := p.ResolveAPIKey()
+	if err != nil {
+		return err
+	}
+	if key != "" {
+		return nil
+	}
+
+	if missing := MissingEnvVars(p.APIKey); len(missing) > 0 {
+		return fmt.Errorf("api_key for %s provider references unset environment variable %s. Export it or set api_key in the config",
+			p.Provider, strings.Join(missing, ", "))
+	}
+
+	return fmt.Errorf("api_key is required for %s provider", p.Provider)
+}
+
+// envPlaceholderPattern matches a value that is only an environment
+// reference, such as ${OPENAI_API_KEY} or $OPENAI_API_KEY
+var envPlaceholderPattern = regexp.MustCompile(`^\$(\{[^}]+\}|[A-Za-z_][A-Za-z0-9_]*)$`)
+
+// minKeyLengthForSuffix is the shortest literal key whose last 4 characters
+// are shown when masked; shorter keys are hidden entirely
+const minKeyLengthForSuffix = 12
+
+// MaskAPIKey returns an API key for display. Environment placeholders are
+// shown unchanged since they hold no secret. Literal keys reveal at most
+// their last 4 characters, and nothing for short keys. An empty key yields "".
+func MaskAPIKey(key string) string {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return ""
+	}
+	if envPlaceholderPattern.MatchString(key) {
+		return key
+	}
+
+	runes := []rune(key)
+	if len(runes) < minKeyLengthForSuffix {
+		return "****"
+	}
+	return "****" + string(runes[len(runes)-4:])
+}
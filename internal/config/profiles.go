@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// AddProfile validates a new profile, adds it to cfg under name and saves the
+// config. The API key may reference an environment variable that isn't set
+// yet; it is checked when the profile is used. Nothing is saved, and cfg is
+// left unchanged, if validation fails.
+func AddProfile(cfg *Config, name string, profile Profile) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("profile name must not be empty")
+	}
+	if name == "default" {
+		return fmt.Errorf("'default' is reserved and cannot be used as a profile name")
+	}
+	if _, exists := cfg.Profiles[name]; exists {
+		return fmt.Errorf("profile '%s' already exists", name)
+	}
+
+	if err := profile.Validate(); err != nil {
+		return fmt.Errorf("invalid profile '%s': %w", name, err)
+	}
+
+	updated := *cfg
+	updated.Profiles = maps.Clone(cfg.Profiles)
+	if updated.Profiles == nil {
+		updated.Profiles = make(map[string]Profile)
+	}
+	updated.Profiles[name] = profile
+
+	if err := SaveConfig(&updated); err != nil {
+		return err
+	}
+
+	*cfg = updated
+	return nil
+}
+
+// RemoveProfile deletes a profile and saves the config. The default profile
+// can only be removed when newDefault names another profile to take its
+// place. The config is validated before saving, and left unchanged if
+// anything fails.
+func RemoveProfile(cfg *Config, name, newDefault string) error {
+	if _, exists := cfg.Profiles[name]; !exists {
+		return fmt.Errorf("profile '%s' not found", name)
+	}
+
+	if newDefault == name {
+		return fmt.Errorf("the new default profile cannot be the profile being removed")
+	}
+	if name == cfg.DefaultProfile && newDefault == "" {
+		return fmt.Errorf("profile '%s' is the default profile; choose a new default to remove it", name)
+	}
+
+	updated := *cfg
+	updated.Profiles = maps.Clone(cfg.Profiles)
+	delete(updated.Profiles, name)
+	if newDefault != "" {
+		updated.DefaultProfile = newDefault
+	}
+
+	// Make sure the config still loads without the profile
+	if err := updated.Validate(); err != nil {
+		return fmt.Errorf("removing profile '%s' would leave an invalid config: %w", name, err)
+	}
+
+	if err := SaveConfig(&updated); err != nil {
+		return err
+	}
+
+	*cfg = updated
+	return nil
+}
@@ -0,0 +1,35 @@
+package config
+
+import "sync"
+
+var (
+	providerTypesMutex sync.RWMutex
+	// providerTypes holds provider types registered in addition to the
+	// built-in ones, and whether each authenticates with an API key
+	providerTypes = make(map[string]bool)
+)
+
+// RegisterProviderType lets profiles use a provider type beyond the built-in
+// ones. With requiresAPIKey, profiles of that type need api_key or
+// api_key_file like the built-in hosted providers.
+func RegisterProviderType(name string, requiresAPIKey bool) {
+	providerTypesMutex.Lock()
+	defer providerTypesMutex.Unlock()
+	providerTypes[name] = requiresAPIKey
+}
+
+// UnregisterProviderType removes a provider type added with RegisterProviderType
+func UnregisterProviderType(name string) {
+	providerTypesMutex.Lock()
+	defer providerTypesMutex.Unlock()
+	delete(providerTypes, name)
+}
+
+// registeredProviderType reports whether name was registered and whether it
+// requires an API key
+func registeredProviderType(name string) (requiresAPIKey, ok bool) {
+	providerTypesMutex.RLock()
+	defer providerTypesMutex.RUnlock()
+	requiresAPIKey, ok = providerTypes[name]
+	return requiresAPIKey, ok
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// queryPlaceholder matches a {name} placeholder in a saved query
+var queryPlaceholder = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_-]*)\}`)
+
+// QueryPlaceholders returns the names of the placeholders in a saved query,
+// in order of first use
+func QueryPlaceholders(template string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range queryPlaceholder.FindAllStringSubmatch(template, -1) {
+		if name := match[1]; !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ParseQueryArgs parses name=value arguments for a saved query. A name given
+// more than once keeps its last value.
+func ParseQueryArgs(args []string) (map[string]string, error) {
+	values := make(map[string]string, len(args))
+	for _, arg := range args {
+		name, value, found := strings.Cut(arg, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid argument %q: expected name=value", arg)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// ExpandQuery substitutes values for the placeholders in a saved query. Every
+// placeholder needs a value, and every value must match a placeholder, so a
+// mistyped name is reported rather than silently ignored.
+func ExpandQuery(template string, values map[string]string) (string, error) {
+	placeholders := QueryPlaceholders(template)
+
+	var missing []string
+	for _, name := range placeholders {
+		if _, ok := values[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing values for placeholders: %s", strings.Join(missing, ", "))
+	}
+
+	var unknown []string
+	for name := range values {
+		if !slices.Contains(placeholders, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return "", fmt.Errorf("unknown placeholders: %s", strings.Join(unknown, ", "))
+	}
+
+	return queryPlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		return values[placeholder[1:len(placeholder)-1]]
+	}), nil
+}
+
+// QueryNames returns the names of the saved queries, sorted
+func (c *Config) QueryNames() []string {
+	names := make([]string, 0, len(c.Queries))
+	for name := range c.Queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateQueries checks that every saved query has a name and text
+func (c *Config) validateQueries() error {
+	for _, name := range c.QueryNames() {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("queries: name must not be empty")
+		}
+		if strings.TrimSpace(c.Queries[name]) == "" {
+			return fmt.Errorf("queries.%s: query must not be empty", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// MaxRecentCommands is how many executed commands the recent-command ring keeps
+const MaxRecentCommands = 20
+
+// RecentCommand is an executed command and how it exited
+type RecentCommand struct {
+	Command   string    `json:"command"`
+	ExitCode  int       `json:"exit_code"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Failed reports whether the command exited unsuccessfully
+func (r RecentCommand) Failed() bool {
+	return r.ExitCode != 0
+}
+
+// GetRecentCommandsPath returns the path of the recent-command ring
+func GetRecentCommandsPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "recent_commands.json"), nil
+}
+
+// RecordRecentCommand appends an executed command and its exit code to the
+// ring, dropping the oldest entries beyond MaxRecentCommands
+func RecordRecentCommand(command string, exitCode int) error {
+	if command == "" {
+		return nil
+	}
+
+	ringPath, err := GetRecentCommandsPath()
+	if err != nil {
+		return fmt.Errorf("failed to get config directory: %w", err)
+	}
+
+	recent, err := LoadRecentCommands()
+	if err != nil {
+		// A corrupt ring is not worth failing over; start a new one
+		recent = nil
+	}
+
+	recent = append(recent, RecentCommand{
+		Command:   command,
+		ExitCode:  exitCode,
+		Timestamp: time.Now(),
+	})
+	if len(recent) > MaxRecentCommands {
+		recent = recent[len(recent)-MaxRecentCommands:]
+	}
+
+	return writeRecentCommands(ringPath, recent)
+}
+
+// TruncateRecentCommands trims the ring to its newest keep commands and
+// returns how many bytes were reclaimed
+func TruncateRecentCommands(keep int) (int64, error) {
+	ringPath, err := GetRecentCommandsPath()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get config directory: %w", err)
+	}
+
+	before, err := os.Stat(ringPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read recent commands: %w", err)
+	}
+
+	recent, err := LoadRecentCommands()
+	if err != nil {
+		return 0, err
+	}
+	if len(recent) <= keep {
+		return 0, nil
+	}
+
+	if err := writeRecentCommands(ringPath, recent[len(recent)-keep:]); err != nil {
+		return 0, err
+	}
+
+	after, err := os.Stat(ringPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read recent commands: %w", err)
+	}
+	return before.Size() - after.Size(), nil
+}
+
+// writeRecentCommands replaces the ring at ringPath with recent
+func writeRecentCommands(ringPath string, recent []RecentCommand) error {
+	data, err := json.MarshalIndent(recent, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal recent commands: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(ringPath), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	// Write atomically so a concurrent reader never sees a partial ring
+	tmpPath := ringPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write recent commands: %w", err)
+	}
+	if err := os.Rename(tmpPath, ringPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write recent commands: %w", err)
+	}
+
+	return nil
+}
+
+// LoadRecentCommands returns the executed commands in the ring, oldest first
+func LoadRecentCommands() ([]RecentCommand, error) {
+	ringPath, err := GetRecentCommandsPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config directory: %w", err)
+	}
+
+	data, err := os.ReadFile(ringPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read recent commands: %w", err)
+	}
+
+	var recent []RecentCommand
+	if err := json.Unmarshal(data, &recent); err != nil {
+		return nil, fmt.Errorf("failed to parse recent commands: %w", err)
+	}
+
+	return recent, nil
+}
@@ -1,7 +1,45 @@
 package history
 
+import (
+	"strings"
+	"time"
+)
+
 // HistoryEntry represents a single command from the shell's history.
 type HistoryEntry struct {
 	Command  string `json:"command"`
 	ExitCode int    `json:"exit_code"`
+	// Count is how many consecutive runs were collapsed into this entry.
+	// Zero or one means the command ran once.
+	Count int `json:"count,omitempty"`
+
+	// WorkingDirectory and Timestamp are where and when the command ran.
+	// Only the enhanced command log records them; they are empty otherwise.
+	WorkingDirectory string    `json:"working_directory,omitempty"`
+	Timestamp        time.Time `json:"timestamp,omitempty"`
+
+	// Session and TTY identify the terminal session that ran the command,
+	// also only known from the enhanced command log
+	Session string `json:"session,omitempty"`
+	TTY     string `json:"tty,omitempty"`
+}
+
+// CollapseDuplicates merges runs of consecutive identical commands, such as
+// retries, into a single entry. The merged entry keeps the exit code of the
+// most recent run, including where and when it ran, and counts how many
+// times the command ran.
+func CollapseDuplicates(entries []HistoryEntry) []HistoryEntry {
+	collapsed := make([]HistoryEntry, 0, len(entries))
+	for _, entry := range entries {
+		if n := len(collapsed); n > 0 && strings.TrimSpace(collapsed[n-1].Command) == strings.TrimSpace(entry.Command) {
+			previous := &collapsed[n-1]
+			previous.Count = max(previous.Count, 1) + max(entry.Count, 1)
+			previous.ExitCode = entry.ExitCode
+			previous.WorkingDirectory = entry.WorkingDirectory
+			previous.Timestamp = entry.Timestamp
+			continue
+		}
+		collapsed = append(collapsed, entry)
+	}
+	return collapsed
 }
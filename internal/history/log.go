@@ -0,0 +1,59 @@
+package history
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// commandLogFields is the number of fields in an enhanced command log line:
+// timestamp|shell|pid|session_id|tty|pwd|exit_code|full_command_line
+const commandLogFields = 8
+
+// ParseCommandLogLine parses a line of the enhanced command log written by
+// the history logger script. The command is the last field and may itself
+// contain "|". Lines with fewer fields, from older loggers, keep only the
+// command and a leading timestamp, with an unknown exit code. It returns
+// false for lines without a command.
+func ParseCommandLogLine(line string) (HistoryEntry, bool) {
+	parts := strings.SplitN(line, "|", commandLogFields)
+
+	entry := HistoryEntry{
+		Command:  strings.TrimSpace(parts[len(parts)-1]),
+		ExitCode: -1,
+	}
+	if len(parts) > 1 {
+		entry.Timestamp = parseUnixTime(parts[0])
+	}
+
+	if len(parts) == commandLogFields {
+		entry.Session = strings.TrimSpace(parts[3])
+		entry.TTY = strings.TrimSpace(parts[4])
+		entry.WorkingDirectory = strings.TrimSpace(parts[5])
+		if exitCode, err := strconv.Atoi(strings.TrimSpace(parts[6])); err == nil {
+			entry.ExitCode = exitCode
+		}
+	}
+
+	return entry, entry.Command != ""
+}
+
+// FormatCommandLogLine formats entry as a line of the enhanced command log,
+// as the history logger script writes it. Line breaks in the command are
+// replaced with spaces so it stays on one line.
+func FormatCommandLogLine(entry HistoryEntry, shell string, pid int) string {
+	command := strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(entry.Command)
+	return fmt.Sprintf("%d|%s|%d|%s|%s|%s|%d|%s",
+		entry.Timestamp.Unix(), shell, pid, entry.Session, entry.TTY, entry.WorkingDirectory, entry.ExitCode, command)
+}
+
+// parseUnixTime parses a timestamp in seconds since the epoch, returning
+// the zero time if it isn't one
+func parseUnixTime(field string) time.Time {
+	seconds, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+	if err != nil || seconds <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"strings"
-	"time"
 
 	"forgor/internal/prompt"
 
@@ -17,6 +16,7 @@ type AnthropicProvider struct {
 	apiKey  string
 	model   string
 	baseURL string
+	options providerOptions
 }
 
 // Anthropic API request/response structures
@@ -25,7 +25,7 @@ type anthropicRequest struct {
 	MaxTokens   int                `json:"max_tokens"`
 	Messages    []anthropicMessage `json:"messages"`
 	System      string             `json:"system,omitempty"`
-	Temperature float64            `json:"temperature,omitempty"`
+	Temperature *float64           `json:"temperature,omitempty"`
 }
 
 type anthropicMessage struct {
@@ -61,9 +61,10 @@ type anthropicError struct {
 }
 
 // NewAnthropicProvider creates a new Anthropic provider
-func NewAnthropicProvider(apiKey, model string) *AnthropicProvider {
-	client := resty.New()
-	client.SetTimeout(30 * time.Second)
+func NewAnthropicProvider(apiKey, model string, opts ...ProviderOption) *AnthropicProvider {
+	options := applyProviderOptions(opts)
+	client := newHTTPClient(options)
+	options.debugLog.attach(client, "anthropic", model, apiKey)
 	client.SetHeader("x-api-key", apiKey)
 	client.SetHeader("content-type", "application/json")
 	client.SetHeader("anthropic-version", "2023-06-01")
@@ -73,11 +74,20 @@ func NewAnthropicProvider(apiKey, model string) *AnthropicProvider {
 		apiKey:  apiKey,
 		model:   model,
 		baseURL: "https://api.anthropic.com/v1",
+		options: options,
 	}
 }
 
+// SetBaseURL overrides the API base URL, e.g. to point at a proxy or test server
+func (p *AnthropicProvider) SetBaseURL(baseURL string) {
+	p.baseURL = strings.TrimSuffix(baseURL, "/")
+}
+
 // GenerateCommand generates a shell command from a natural language query
 func (p *AnthropicProvider) GenerateCommand(ctx context.Context, request *Request) (*Response, error) {
+	// Settings the request leaves unset fall back to the profile's
+	options := p.options.requestOptions(request.Options)
+
 	// Convert to prompt package request format
 	promptReq := &prompt.Request{
 		Query: request.Query,
@@ -88,8 +98,9 @@ func (p *AnthropicProvider) GenerateCommand(ctx context.Context, request *Reques
 		},
 		Options: prompt.RequestOptions{
 			IncludeExplanation: request.Options.IncludeExplanation,
-			MaxTokens:          request.Options.MaxTokens,
-			Temperature:        request.Options.Temperature,
+			MaxTokens:          options.MaxTokens,
+			Temperature:        options.Temperature,
+			SafetyLevel:        request.Options.SafetyLevel,
 		},
 	}
 
@@ -102,18 +113,24 @@ func (p *AnthropicProvider) GenerateCommand(ctx context.Context, request *Reques
 		Architecture:     request.Context.Architecture,
 		User:             request.Context.User,
 		WorkingDirectory: request.Context.WorkingDirectory,
+		ProjectType:      request.Context.ProjectType,
+		GitStatus:        request.Context.GitStatus,
 		ToolsSummary:     request.Context.ToolsSummary,
 		PackageManagers:  request.Context.PackageManagers,
 		Languages:        request.Context.Languages,
 		ContainerTools:   request.Context.ContainerTools,
 		CloudTools:       request.Context.CloudTools,
+		Environment:      request.Context.Environment,
+
+		SystemPromptPrefix: p.options.systemPromptPrefix,
+		SystemPromptSuffix: p.options.systemPromptSuffix,
 	}
 
 	systemPrompt := prompt.GetSystemPrompt(promptContext)
 
 	anthropicReq := anthropicRequest{
 		Model:     p.model,
-		MaxTokens: request.Options.MaxTokens,
+		MaxTokens: options.MaxTokens,
 		System:    systemPrompt,
 		Messages: []anthropicMessage{
 			{
@@ -121,7 +138,7 @@ func (p *AnthropicProvider) GenerateCommand(ctx context.Context, request *Reques
 				Content: userPrompt,
 			},
 		},
-		Temperature: request.Options.Temperature,
+		Temperature: options.Temperature,
 	}
 
 	var resp anthropicResponse
@@ -129,6 +146,7 @@ func (p *AnthropicProvider) GenerateCommand(ctx context.Context, request *Reques
 		SetContext(ctx).
 		SetBody(anthropicReq).
 		SetResult(&resp).
+		SetError(&resp).
 		Post(p.baseURL + "/messages")
 
 	if err != nil {
@@ -151,40 +169,44 @@ func (p *AnthropicProvider) GenerateCommand(ctx context.Context, request *Reques
 	}
 
 	content := resp.Content[0].Text
-	command, explanation := p.parseResponse(content, request.Options.IncludeExplanation)
+	command, explanation, llmDangerLevel, llmDangerReason, alternatives := p.parseResponse(content, request.Options.IncludeExplanation)
 
 	return &Response{
-		Command:     command,
-		Explanation: explanation,
-		Confidence:  p.calculateConfidence(resp.StopReason),
-		Warnings:    prompt.CheckCommandSafety(command),
+		Command:      command,
+		Explanation:  explanation,
+		Confidence:   p.calculateConfidence(resp.StopReason),
+		DangerLevel:  llmDangerLevel,
+		DangerReason: llmDangerReason,
+		Alternatives: alternatives,
+		Warnings:     prompt.CheckCommandSafety(command),
 		Usage: &Usage{
 			PromptTokens:     resp.Usage.InputTokens,
 			CompletionTokens: resp.Usage.OutputTokens,
 			TotalTokens:      resp.Usage.InputTokens + resp.Usage.OutputTokens,
 		},
 		Metadata: map[string]interface{}{
-			"model":       resp.Model,
-			"stop_reason": resp.StopReason,
+			"model":            resp.Model,
+			"stop_reason":      resp.StopReason,
+			"llm_danger_level": string(llmDangerLevel),
 		},
 	}, nil
 }
 
 // ExplainCommand explains what a command does
 func (p *AnthropicProvider) ExplainCommand(ctx context.Context, command string) (*Response, error) {
-	prompt := fmt.Sprintf("Explain what this shell command does:\n\n%s\n\nProvide a clear, concise explanation of what this command accomplishes.", command)
+	userPrompt := prompt.BuildExplainPrompt(command)
 
 	anthropicReq := anthropicRequest{
 		Model:     p.model,
 		MaxTokens: 300,
-		System:    "You are a helpful assistant that explains shell commands clearly and concisely.",
+		System:    explainSystemPrompt(),
 		Messages: []anthropicMessage{
 			{
 				Role:    "user",
-				Content: prompt,
+				Content: userPrompt,
 			},
 		},
-		Temperature: 0.1,
+		Temperature: float64Ptr(0.1),
 	}
 
 	var resp anthropicResponse
@@ -192,6 +214,7 @@ func (p *AnthropicProvider) ExplainCommand(ctx context.Context, command string)
 		SetContext(ctx).
 		SetBody(anthropicReq).
 		SetResult(&resp).
+		SetError(&resp).
 		Post(p.baseURL + "/messages")
 
 	if err != nil {
@@ -230,18 +253,14 @@ func (p *AnthropicProvider) GetProviderInfo() ProviderInfo {
 	return ProviderInfo{
 		Name:    "Anthropic",
 		Version: "1.0.0",
-		Models:  []string{"claude-3-opus-20240229", "claude-3-sonnet-20240229", "claude-3-haiku-20240307"},
+		Models:  SupportedModels("anthropic"),
 		Capabilities: []string{
 			"command_generation",
 			"command_explanation",
 			"context_awareness",
 			"safety_filtering",
 		},
-		Limits: map[string]int{
-			"max_tokens":      4096,
-			"max_history":     10,
-			"timeout_seconds": 30,
-		},
+		Limits: providerLimits("anthropic", p.model),
 		Metadata: map[string]string{
 			"provider": "anthropic",
 			"model":    p.model,
@@ -249,22 +268,10 @@ func (p *AnthropicProvider) GetProviderInfo() ProviderInfo {
 	}
 }
 
-// parseResponse extracts command and explanation from the response
-func (p *AnthropicProvider) parseResponse(content string, includeExplanation bool) (command, explanation string) {
-	content = strings.TrimSpace(content)
-
-	if includeExplanation && strings.Contains(content, "||") {
-		parts := strings.SplitN(content, "||", 2)
-		command = strings.TrimSpace(parts[0])
-		explanation = strings.TrimSpace(parts[1])
-	} else {
-		command = content
-	}
-
-	// Clean up command using centralized function
-	command = prompt.CleanCommand(command)
-
-	return command, explanation
+// parseResponse extracts command, explanation, danger assessment and alternatives from the response
+func (p *AnthropicProvider) parseResponse(content string, includeExplanation bool) (command, explanation string, dangerLevel DangerLevel, dangerReason string, alternatives []string) {
+	parsed := prompt.ParseStructuredResponse(content, includeExplanation)
+	return parsed.Command, parsed.Explanation, ParseDangerLevel(parsed.DangerLevel), parsed.DangerReason, parsed.Alternatives
 }
 
 // calculateConfidence estimates confidence based on stop reason
@@ -300,14 +307,14 @@ func (p *AnthropicProvider) handleAPIError(resp *resty.Response, apiResp *anthro
 			errorType = ErrorTypeUnknown
 		}
 
-		return &Error{
+		return withRetryHint(&Error{
 			Type:    errorType,
 			Message: apiResp.Error.Message,
-		}
+		}, resp)
 	}
 
-	return &Error{
+	return withRetryHint(&Error{
 		Type:    ErrorTypeNetwork,
 		Message: fmt.Sprintf("HTTP %d: %s", resp.StatusCode(), resp.String()),
-	}
+	}, resp)
 }
@@ -0,0 +1,92 @@
+package llm
+
+import (
+	"net/http"
+	"time"
+
+	"forgor/internal/config"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// ProviderOption customizes a provider at construction time
+type ProviderOption func(*providerOptions)
+
+// providerOptions holds the settings applied by ProviderOptions
+type providerOptions struct {
+	proxy              string
+	systemPromptPrefix string
+	systemPromptSuffix string
+	requestDefaults    RequestOptions
+	debugLog           *debugLog
+}
+
+// WithProxy routes provider requests through the given proxy URL, overriding
+// the HTTPS_PROXY/HTTP_PROXY/NO_PROXY environment variables
+func WithProxy(proxy string) ProviderOption {
+	return func(o *providerOptions) {
+		o.proxy = proxy
+	}
+}
+
+// WithSystemPrompt adds extra text before and after the system prompt, to
+// nudge a profile (e.g. "prefer fd over find") without replacing the prompt
+func WithSystemPrompt(prefix, suffix string) ProviderOption {
+	return func(o *providerOptions) {
+		o.systemPromptPrefix = prefix
+		o.systemPromptSuffix = suffix
+	}
+}
+
+// WithRequestDefaults sets the max_tokens and temperature used when a
+// request doesn't set them, normally taken from the provider's profile
+func WithRequestDefaults(defaults RequestOptions) ProviderOption {
+	return func(o *providerOptions) {
+		o.requestDefaults = defaults
+	}
+}
+
+// requestOptions fills the token and temperature settings request leaves
+// unset from the provider's defaults. Without either, max_tokens falls back
+// to config.DefaultMaxTokens so every provider gets a usable limit.
+func (o providerOptions) requestOptions(request RequestOptions) RequestOptions {
+	if request.MaxTokens <= 0 {
+		request.MaxTokens = o.requestDefaults.MaxTokens
+	}
+	if request.MaxTokens <= 0 {
+		request.MaxTokens = config.DefaultMaxTokens
+	}
+	if request.Temperature == nil {
+		request.Temperature = o.requestDefaults.Temperature
+	}
+	return request
+}
+
+// float64Ptr returns a pointer to value, for optional request fields
+func float64Ptr(value float64) *float64 {
+	return &value
+}
+
+// applyProviderOptions collects opts into providerOptions
+func applyProviderOptions(opts []ProviderOption) providerOptions {
+	options := providerOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// newHTTPClient creates the resty client shared by all providers. Without an
+// explicit proxy, the standard proxy environment variables are honored.
+func newHTTPClient(options providerOptions) *resty.Client {
+	client := resty.New()
+	client.SetTimeout(30 * time.Second)
+
+	if options.proxy != "" {
+		client.SetProxy(options.proxy)
+	} else if transport, ok := client.GetClient().Transport.(*http.Transport); ok {
+		transport.Proxy = http.ProxyFromEnvironment
+	}
+
+	return client
+}
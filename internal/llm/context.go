@@ -1,6 +1,11 @@
 package llm
 
 import (
+	"maps"
+	"slices"
+	"strings"
+
+	"forgor/internal/config"
 	"forgor/internal/history"
 	"forgor/internal/utils"
 )
@@ -40,6 +45,7 @@ func BuildContextFromSystemWithTiming(verbose bool) Context {
 		OS:               systemCtx.OS,
 		Architecture:     systemCtx.Architecture,
 		WorkingDirectory: systemCtx.WorkingDirectory,
+		ProjectType:      utils.DetectProjectContext(systemCtx.WorkingDirectory).Summary(),
 		ToolsSummary:     utils.GetToolContextSummary(),
 	}
 
@@ -77,12 +83,104 @@ func EnhanceContextWithHistory(context Context, historyEntries []history.History
 	return context
 }
 
+// EnhanceContextWithWorkingDirectory generates commands as if run from dir
+// instead of the current directory. The process directory is not changed.
+func EnhanceContextWithWorkingDirectory(context Context, dir string) (Context, error) {
+	resolved, err := utils.ResolveWorkingDirectory(dir)
+	if err != nil {
+		return context, err
+	}
+	context.WorkingDirectory = resolved
+	context.ProjectType = utils.DetectProjectContext(resolved).Summary()
+	return context, nil
+}
+
+// EnhanceContextWithGitStatus adds the branch and dirty/clean state of the
+// git repository containing the context's working directory. Outside a
+// repository, or if git is slow or unavailable, the context is unchanged.
+func EnhanceContextWithGitStatus(context Context) Context {
+	status, err := utils.GetGitStatus(context.WorkingDirectory)
+	if err != nil {
+		utils.Debugf("%s Git status skipped: %v\n", utils.Styled("[INFO]", utils.StyleInfo), err)
+		return context
+	}
+	context.GitStatus = status.Summary()
+	return context
+}
+
 // EnhanceContextWithUserInput adds user-provided context
 func EnhanceContextWithUserInput(context Context, userContext string) Context {
 	context.UserContext = userContext
 	return context
 }
 
+// EnhanceContextWithEnvironment adds environment variables to the context.
+// Nothing from the environment is sent unless this is called, which only
+// happens when the user passes --include-env.
+func EnhanceContextWithEnvironment(context Context, env map[string]string) Context {
+	context.Environment = maps.Clone(env)
+	return context
+}
+
+// EnhanceContextWithCustomTools merges the tools added with 'config tools add'
+// into the detected tool lists, skipping ones already detected. Categories
+// the prompt has no line for, such as system commands, are listed in the
+// tools summary so the model still sees them.
+func EnhanceContextWithCustomTools(context Context, custom config.CustomToolsConfig) Context {
+	context.PackageManagers = mergeTools(context.PackageManagers, custom.PackageManagers)
+	context.Languages = mergeTools(context.Languages, custom.Languages)
+	context.DevelopmentTools = mergeTools(context.DevelopmentTools, custom.DevelopmentTools)
+	context.ContainerTools = mergeTools(context.ContainerTools, custom.ContainerTools)
+	context.CloudTools = mergeTools(context.CloudTools, custom.CloudTools)
+	context.DatabaseTools = mergeTools(context.DatabaseTools, custom.DatabaseTools)
+	context.NetworkTools = mergeTools(context.NetworkTools, custom.NetworkTools)
+
+	var unlisted []string
+	for _, tools := range [][]string{
+		custom.DevelopmentTools, custom.SystemCommands, custom.DatabaseTools,
+		custom.NetworkTools, custom.Other,
+	} {
+		unlisted = mergeTools(unlisted, tools)
+	}
+	if len(unlisted) > 0 {
+		summary := "Custom tools: " + strings.Join(unlisted, ", ")
+		if context.ToolsSummary != "" {
+			summary = context.ToolsSummary + "; " + summary
+		}
+		context.ToolsSummary = summary
+	}
+
+	available := make(map[string]bool, len(context.ToolsAvailable))
+	for tool, ok := range context.ToolsAvailable {
+		available[tool] = ok
+	}
+	for _, tools := range [][]string{
+		custom.PackageManagers, custom.Languages, custom.DevelopmentTools,
+		custom.SystemCommands, custom.ContainerTools, custom.CloudTools,
+		custom.DatabaseTools, custom.NetworkTools, custom.Other,
+	} {
+		for _, tool := range tools {
+			available[tool] = true
+		}
+	}
+	context.ToolsAvailable = available
+
+	return context
+}
+
+// mergeTools returns detected followed by the custom tools it doesn't
+// already contain. detected is never modified, as it may be shared with the
+// system context cache.
+func mergeTools(detected, custom []string) []string {
+	merged := slices.Clone(detected)
+	for _, tool := range custom {
+		if tool = strings.TrimSpace(tool); tool != "" && !slices.Contains(merged, tool) {
+			merged = append(merged, tool)
+		}
+	}
+	return merged
+}
+
 // GetToolCapabilitiesText returns a formatted text description of available tools
 func GetToolCapabilitiesText(context Context) string {
 	if context.ToolsSummary != "" {
@@ -0,0 +1,30 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// WriteContextDump writes context, as sent with a request, to path as
+// indented JSON so a bug report can include the exact context. Environment
+// values are passed through redact, if not nil, to remove secrets.
+func WriteContextDump(path string, context Context, redact func(string) string) error {
+	if redact != nil && len(context.Environment) > 0 {
+		env := make(map[string]string, len(context.Environment))
+		for name, value := range context.Environment {
+			env[name] = redact(value)
+		}
+		context.Environment = env
+	}
+
+	data, err := json.MarshalIndent(context, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal context: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write context dump: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"forgor/internal/utils"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// redactedAPIKey replaces the provider's API key in the debug log
+const redactedAPIKey = "[REDACTED API key]"
+
+// debugLog records provider requests and responses for bug reports
+type debugLog struct {
+	path   string
+	redact func(string) string
+}
+
+// debugLogEntry is one request/response pair in the debug log
+type debugLogEntry struct {
+	Time       time.Time `json:"time"`
+	Provider   string    `json:"provider"`
+	Model      string    `json:"model"`
+	URL        string    `json:"url"`
+	Status     int       `json:"status,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+	Request    string    `json:"request"`
+	Response   string    `json:"response,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// WithDebugLog appends every API request and response, with timing, to a
+// JSON lines file at path. The API key is always redacted; redact, if not
+// nil, is applied to everything written as well, e.g. to remove secrets.
+func WithDebugLog(path string, redact func(string) string) ProviderOption {
+	return func(o *providerOptions) {
+		o.debugLog = &debugLog{path: path, redact: redact}
+	}
+}
+
+// attach adds hooks to client that log its requests. It does nothing when no
+// debug log is configured.
+func (d *debugLog) attach(client *resty.Client, provider, model, apiKey string) {
+	if d == nil || d.path == "" {
+		return
+	}
+
+	client.OnAfterResponse(func(c *resty.Client, resp *resty.Response) error {
+		entry := d.entry(resp.Request, provider, model)
+		entry.Status = resp.StatusCode()
+		entry.DurationMS = resp.Time().Milliseconds()
+		entry.Response = string(resp.Body())
+		d.write(entry, apiKey)
+		return nil
+	})
+
+	client.OnError(func(req *resty.Request, err error) {
+		entry := d.entry(req, provider, model)
+		entry.DurationMS = time.Since(req.Time).Milliseconds()
+		entry.Error = err.Error()
+		d.write(entry, apiKey)
+	})
+}
+
+// entry starts a log entry for req
+func (d *debugLog) entry(req *resty.Request, provider, model string) debugLogEntry {
+	entry := debugLogEntry{
+		Time:     req.Time,
+		Provider: provider,
+		Model:    model,
+		URL:      req.URL,
+	}
+
+	if req.Body != nil {
+		if body, err := json.Marshal(req.Body); err == nil {
+			entry.Request = string(body)
+		} else {
+			entry.Request = fmt.Sprintf("%v", req.Body)
+		}
+	}
+	return entry
+}
+
+// write redacts entry and appends it to the log. Failures only warn, so a
+// bad log path never breaks the query itself.
+func (d *debugLog) write(entry debugLogEntry, apiKey string) {
+	redact := func(text string) string {
+		if apiKey != "" {
+			text = strings.ReplaceAll(text, apiKey, redactedAPIKey)
+		}
+		if d.redact != nil {
+			text = d.redact(text)
+		}
+		return text
+	}
+
+	entry.URL = redact(entry.URL)
+	entry.Request = redact(entry.Request)
+	entry.Response = redact(entry.Response)
+	entry.Error = redact(entry.Error)
+
+	if err := utils.AppendJSONLine(d.path, entry); err != nil {
+		utils.Warnf("%s Failed to write debug log: %v\n", utils.Styled("[WARNING]", utils.StyleWarning), err)
+	}
+}
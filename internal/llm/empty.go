@@ -0,0 +1,64 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"forgor/internal/prompt"
+)
+
+// commandOnlyInstruction is added to the query when asking again after an
+// answer without a command
+const commandOnlyInstruction = "Your previous answer did not contain a command. Put only the shell command itself on the COMMAND: line."
+
+// emptyRetryProvider asks its provider a second time when the generated
+// command is empty after cleaning
+type emptyRetryProvider struct {
+	Provider
+}
+
+// RetryOnEmpty wraps provider so that an answer without a command is retried
+// once with a stricter instruction to return only the command. If the retry
+// is empty too, GenerateCommand fails with ErrorTypeEmptyCommand.
+func RetryOnEmpty(provider Provider) Provider {
+	return &emptyRetryProvider{Provider: provider}
+}
+
+// GenerateCommand generates a command, asking once more if it is empty
+func (p *emptyRetryProvider) GenerateCommand(ctx context.Context, request *Request) (*Response, error) {
+	response, err := p.Provider.GenerateCommand(ctx, request)
+	if !isEmptyCommand(response, err) {
+		return response, err
+	}
+
+	retryRequest := *request
+	retryRequest.Query = strings.TrimSpace(request.Query) + "\n\n" + commandOnlyInstruction
+
+	retried, retryErr := p.Provider.GenerateCommand(ctx, &retryRequest)
+	if retryErr != nil && !isEmptyCommand(nil, retryErr) {
+		return nil, retryErr
+	}
+	if isEmptyCommand(retried, retryErr) {
+		return nil, &Error{
+			Type:    ErrorTypeEmptyCommand,
+			Message: fmt.Sprintf("%s returned no command, even when asked again for only the command", p.GetProviderInfo().Name),
+		}
+	}
+
+	if response != nil {
+		retried.Usage = addUsage(response.Usage, retried.Usage)
+	}
+	return retried, nil
+}
+
+// isEmptyCommand reports whether a GenerateCommand result is an answer
+// without a usable command
+func isEmptyCommand(response *Response, err error) bool {
+	if err != nil {
+		var llmErr *Error
+		return errors.As(err, &llmErr) && llmErr.Type == ErrorTypeEmptyCommand
+	}
+	return response == nil || prompt.CleanCommand(response.Command) == ""
+}
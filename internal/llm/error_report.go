@@ -0,0 +1,35 @@
+package llm
+
+import "errors"
+
+// ErrorReport is an error in machine-readable form, as written on stderr by
+// --json-errors: {"error": {"type": "auth", "message": "...", "code": "..."}}
+type ErrorReport struct {
+	Error ErrorReportDetail `json:"error"`
+}
+
+// ErrorReportDetail describes the error in an ErrorReport
+type ErrorReportDetail struct {
+	Type    ErrorType `json:"type"`
+	Message string    `json:"message"`
+	Code    string    `json:"code"`
+}
+
+// NewErrorReport describes err for automation. The type and code come from
+// the first *Error in err's chain, so wrappers can tell an auth failure from
+// a network one; other errors are reported as unknown. The message is the
+// full error text.
+func NewErrorReport(err error) ErrorReport {
+	report := ErrorReport{Error: ErrorReportDetail{Type: ErrorTypeUnknown}}
+	if err == nil {
+		return report
+	}
+
+	report.Error.Message = err.Error()
+	var llmErr *Error
+	if errors.As(err, &llmErr) {
+		report.Error.Type = llmErr.Type
+		report.Error.Code = llmErr.Code
+	}
+	return report
+}
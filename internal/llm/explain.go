@@ -0,0 +1,59 @@
+package llm
+
+import (
+	"context"
+	"runtime"
+
+	"forgor/internal/prompt"
+	"forgor/internal/utils"
+)
+
+// GenerateAndExplain generates a command and then makes a separate
+// ExplainCommand call on exactly the generated command, so the explanation
+// describes the actual output rather than the model's intent. A failed
+// explanation is not fatal: the generated command is still returned.
+func GenerateAndExplain(ctx context.Context, provider Provider, request *Request) (*Response, error) {
+	response, err := provider.GenerateCommand(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Command == "" {
+		return response, nil
+	}
+
+	explained, err := provider.ExplainCommand(ctx, response.Command)
+	if err != nil {
+		utils.Warnf("%s Could not explain the generated command: %v\n", utils.Styled("[WARNING]", utils.StyleWarning), err)
+		return response, nil
+	}
+
+	response.Explanation = explained.Explanation
+	response.Usage = addUsage(response.Usage, explained.Usage)
+
+	return response, nil
+}
+
+// addUsage sums token usage across two requests
+func addUsage(a, b *Usage) *Usage {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	return &Usage{
+		PromptTokens:     a.PromptTokens + b.PromptTokens,
+		CompletionTokens: a.CompletionTokens + b.CompletionTokens,
+		TotalTokens:      a.TotalTokens + b.TotalTokens,
+	}
+}
+
+// explainSystemPrompt returns the explain system prompt for this platform
+func explainSystemPrompt() string {
+	return prompt.GetExplainSystemPrompt(prompt.Context{
+		OS:           utils.GetOperatingSystem(),
+		Shell:        utils.GetCurrentShell(),
+		Architecture: runtime.GOARCH,
+	})
+}
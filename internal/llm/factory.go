@@ -2,16 +2,21 @@ package llm
 
 import (
 	"fmt"
-	"os"
 	"strings"
+	"sync"
 
 	"forgor/internal/config"
+	"forgor/internal/utils"
 )
 
 // Factory manages the creation and selection of LLM providers
 type Factory struct {
-	providers map[string]Provider
-	config    *config.Config
+	// providers caches the provider created for each profile; providersMu
+	// guards it so GetProvider can be called concurrently
+	providers   map[string]Provider
+	providersMu sync.RWMutex
+	config      *config.Config
+	debugLog    *debugLog
 }
 
 // NewFactory creates a new LLM provider factory
@@ -22,6 +27,12 @@ func NewFactory(cfg *config.Config) *Factory {
 	}
 }
 
+// SetDebugLog makes providers created from now on log their API requests and
+// responses to path. See WithDebugLog.
+func (f *Factory) SetDebugLog(path string, redact func(string) string) {
+	f.debugLog = &debugLog{path: path, redact: redact}
+}
+
 // GetProvider returns a provider for the specified profile name
 func (f *Factory) GetProvider(profileName string) (Provider, error) {
 	if profileName == "" || profileName == "default" {
@@ -29,7 +40,10 @@ func (f *Factory) GetProvider(profileName string) (Provider, error) {
 	}
 
 	// Check if provider already exists in cache
-	if provider, exists := f.providers[profileName]; exists {
+	f.providersMu.RLock()
+	provider, exists := f.providers[profileName]
+	f.providersMu.RUnlock()
+	if exists {
 		return provider, nil
 	}
 
@@ -40,12 +54,18 @@ func (f *Factory) GetProvider(profileName string) (Provider, error) {
 	}
 
 	// Create provider based on configuration
-	provider, err := f.createProvider(profile)
+	provider, err = f.createProvider(profile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create provider for profile '%s': %w", profileName, err)
 	}
 
-	// Cache the provider
+	// Cache the provider. If another call created one for this profile in
+	// the meantime, keep that one so every caller shares the same provider.
+	f.providersMu.Lock()
+	defer f.providersMu.Unlock()
+	if cached, exists := f.providers[profileName]; exists {
+		return cached, nil
+	}
 	f.providers[profileName] = provider
 
 	return provider, nil
@@ -92,6 +112,16 @@ func (f *Factory) ValidateProvider(profileName string) error {
 		return err
 	}
 
+	// Make sure the API key actually resolves before checking anything else
+	if profile.RequiresAPIKey() {
+		if err := profile.ValidateAPIKey(); err != nil {
+			return err
+		}
+	}
+
+	// Resolve environment references before provider-specific checks
+	profile = profile.Expanded()
+
 	// Provider-specific validation
 	switch profile.Provider {
 	case "openai":
@@ -100,93 +130,115 @@ func (f *Factory) ValidateProvider(profileName string) error {
 		return f.validateAnthropic(profile)
 	case "gemini", "google":
 		return f.validateGemini(profile)
+	case "groq":
+		return f.validateGroq(profile)
+	case "mistral":
+		return f.validateMistral(profile)
+	case "ollama":
+		return f.validateOllama(profile)
 	default:
+		// Registered providers have no model allowlist to check
+		if _, ok := lookupProvider(profile.Provider); ok {
+			return nil
+		}
 		return fmt.Errorf("unsupported provider: %s", profile.Provider)
 	}
 }
 
 // createProvider creates a new provider instance based on the profile
 func (f *Factory) createProvider(profile config.Profile) (Provider, error) {
-	// Expand environment variables in API key
-	apiKey := os.ExpandEnv(profile.APIKey)
-
-	switch profile.Provider {
-	case "openai":
-		return NewOpenAIProvider(apiKey, profile.Model), nil
+	// Fail early with a precise message instead of an opaque auth error later
+	if profile.RequiresAPIKey() {
+		if err := profile.ValidateAPIKey(); err != nil {
+			return nil, err
+		}
+	}
 
-	case "anthropic":
-		return NewAnthropicProvider(apiKey, profile.Model), nil
+	// Resolve the API key (from api_key_file if set) before expanding the rest
+	apiKey, err := profile.ResolveAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	profile = profile.Expanded()
 
-	case "gemini", "google":
-		return NewGeminiProvider(apiKey, profile.Model), nil
+	opts := []ProviderOption{WithRequestDefaults(ProfileRequestOptions(profile))}
+	if f.debugLog != nil {
+		opts = append(opts, WithDebugLog(f.debugLog.path, f.debugLog.redact))
+	}
+	if profile.Proxy != "" {
+		opts = append(opts, WithProxy(profile.Proxy))
+	}
+	if profile.SystemPromptPrefix != "" || profile.SystemPromptSuffix != "" {
+		opts = append(opts, WithSystemPrompt(profile.SystemPromptPrefix, profile.SystemPromptSuffix))
+	}
 
-	default:
+	newProvider, ok := lookupProvider(profile.Provider)
+	if !ok {
 		return nil, fmt.Errorf("unsupported provider: %s", profile.Provider)
 	}
-}
-
-// validateOpenAI validates OpenAI provider configuration
-func (f *Factory) validateOpenAI(profile config.Profile) error {
-	apiKey := os.ExpandEnv(profile.APIKey)
-	if apiKey == "" {
-		return fmt.Errorf("openAI API key not found. Set OPENAI_API_KEY environment variable or add api_key to config")
+	profile.APIKey = apiKey
+	provider, err := newProvider(profile, opts...)
+	if err != nil {
+		return nil, err
 	}
 
-	validModels := []string{
-		"gpt-4", "gpt-4-turbo", "gpt-4-turbo-preview",
-		"gpt-3.5-turbo", "gpt-3.5-turbo-16k",
+	// endpoint points any provider at another address, such as a gateway or
+	// a test server
+	if setter, ok := provider.(baseURLSetter); ok && profile.Endpoint != "" {
+		setter.SetBaseURL(profile.Endpoint)
 	}
 
-	if !contains(validModels, profile.Model) {
-		return fmt.Errorf("invalid OpenAI model: %s. Valid models: %s",
-			profile.Model, strings.Join(validModels, ", "))
-	}
+	return provider, nil
+}
 
-	return nil
+// baseURLSetter is implemented by providers whose API base URL can be changed
+type baseURLSetter interface {
+	SetBaseURL(baseURL string)
+}
+
+// validateOpenAI validates OpenAI provider configuration
+func (f *Factory) validateOpenAI(profile config.Profile) error {
+	return validateModel("OpenAI", "openai", profile.Model)
 }
 
 // validateAnthropic validates Anthropic provider configuration
 func (f *Factory) validateAnthropic(profile config.Profile) error {
-	apiKey := os.ExpandEnv(profile.APIKey)
-	if apiKey == "" {
-		return fmt.Errorf("anthropic API key not found. Set ANTHROPIC_API_KEY environment variable or add api_key to config")
-	}
+	return validateModel("Anthropic", "anthropic", profile.Model)
+}
 
-	validModels := []string{
-		"claude-3-opus-20240229",
-		"claude-3-sonnet-20240229",
-		"claude-3-haiku-20240307",
-	}
+// validateGemini validates Google AI/Gemini provider configuration
+func (f *Factory) validateGemini(profile config.Profile) error {
+	return validateModel("Gemini", "gemini", profile.Model)
+}
 
-	if !contains(validModels, profile.Model) {
-		return fmt.Errorf("invalid Anthropic model: %s. Valid models: %s",
-			profile.Model, strings.Join(validModels, ", "))
-	}
+// validateGroq validates Groq provider configuration
+func (f *Factory) validateGroq(profile config.Profile) error {
+	return validateModel("Groq", "groq", profile.Model)
+}
 
-	return nil
+// validateMistral validates Mistral provider configuration
+func (f *Factory) validateMistral(profile config.Profile) error {
+	return validateModel("Mistral", "mistral", profile.Model)
 }
 
-// validateGemini validates Google AI/Gemini provider configuration
-func (f *Factory) validateGemini(profile config.Profile) error {
-	apiKey := os.ExpandEnv(profile.APIKey)
-	if apiKey == "" {
-		return fmt.Errorf("google AI API key not found. Set GOOGLE_AI_API_KEY environment variable or add api_key to config")
-	}
+// validateOllama validates Ollama provider configuration
+func (f *Factory) validateOllama(profile config.Profile) error {
+	return validateModel("Ollama", "ollama", profile.Model)
+}
 
-	validModels := []string{
-		"gemini-1.5-pro",
-		"gemini-1.5-flash",
-		"gemini-1.0-pro",
-		"gemini-2.0-flash-exp",
-		"gemini-2.5-flash-lite-preview-06-17",
-		"gemini-exp-1114",
+// validateModel checks a model against the known models for a provider.
+// Unknown models only produce a warning so newly released models keep
+// working before the allowlist catches up.
+func validateModel(displayName, providerType, model string) error {
+	if model == "" {
+		return fmt.Errorf("%s model must be specified", displayName)
 	}
 
-	if !contains(validModels, profile.Model) {
-		return fmt.Errorf("invalid Gemini model: %s. Valid models: %s",
-			profile.Model, strings.Join(validModels, ", "))
+	validModels := SupportedModels(providerType)
+	if !contains(validModels, model) {
+		utils.Warnf("%s Unknown %s model: %s. It will be used as configured. Known models: %s\n",
+			utils.Styled("[WARNING]", utils.StyleWarning), displayName, model, strings.Join(validModels, ", "))
 	}
-
 	return nil
 }
 
@@ -200,19 +252,10 @@ func contains(slice []string, item string) bool {
 	return false
 }
 
-// GetSupportedProviders returns a list of all supported provider types
+// GetSupportedProviders returns a list of all supported provider types: the
+// built-in ones followed by any added with RegisterProvider
 func GetSupportedProviders() []string {
-	return []string{"openai", "anthropic", "gemini", "google"}
-}
-
-// GetDefaultModels returns default models for each provider type
-func GetDefaultModels() map[string]string {
-	return map[string]string{
-		"openai":    "gpt-4.1",
-		"anthropic": "claude-3.5-sonnet",
-		"gemini":    "gemini-2.5-flash-lite-preview-06-17",
-		"google":    "gemini-2.5-flash",
-	}
+	return registeredProviders()
 }
 
 // GetProviderCapabilities returns capabilities for each provider type
@@ -245,5 +288,25 @@ func GetProviderCapabilities() map[string][]string {
 			"safety_filtering",
 			"multimodal",
 		},
+		"groq": {
+			"command_generation",
+			"command_explanation",
+			"context_awareness",
+			"safety_filtering",
+			"fast_inference",
+		},
+		"mistral": {
+			"command_generation",
+			"command_explanation",
+			"context_awareness",
+			"safety_filtering",
+		},
+		"ollama": {
+			"command_generation",
+			"command_explanation",
+			"context_awareness",
+			"safety_filtering",
+			"local_inference",
+		},
 	}
 }
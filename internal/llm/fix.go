@@ -0,0 +1,41 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SuggestFix asks the provider for a corrected command after command failed
+// with exitCode and output. The original request's context and options are
+// reused so the fix targets the same environment.
+func SuggestFix(ctx context.Context, provider Provider, original *Request, command string, exitCode int, output string) (*Response, error) {
+	fixRequest := &Request{
+		Query: BuildFixQuery("", command, exitCode, output),
+	}
+	if original != nil {
+		fixRequest.Query = BuildFixQuery(original.Query, command, exitCode, output)
+		fixRequest.Context = original.Context
+		fixRequest.Options = original.Options
+	}
+
+	return provider.GenerateCommand(ctx, fixRequest)
+}
+
+// BuildFixQuery describes a failed command so the model can diagnose it
+func BuildFixQuery(originalQuery, command string, exitCode int, output string) string {
+	var b strings.Builder
+
+	if originalQuery != "" {
+		fmt.Fprintf(&b, "I asked for: %s\n", originalQuery)
+	}
+	fmt.Fprintf(&b, "I ran this command:\n%s\n", command)
+	fmt.Fprintf(&b, "It failed with exit code %d.\n", exitCode)
+
+	if output = strings.TrimSpace(output); output != "" {
+		fmt.Fprintf(&b, "Its output was:\n%s\n", output)
+	}
+
+	b.WriteString("Diagnose the failure and provide a corrected command that accomplishes the same goal.")
+	return b.String()
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"strings"
-	"time"
 
 	"forgor/internal/prompt"
 
@@ -17,6 +16,7 @@ type GeminiProvider struct {
 	apiKey  string
 	model   string
 	baseURL string
+	options providerOptions
 }
 
 // Gemini API request/response structures
@@ -41,10 +41,10 @@ type geminiSystemInstruction struct {
 }
 
 type geminiGenerationConfig struct {
-	Temperature     float64 `json:"temperature,omitempty"`
-	MaxOutputTokens int     `json:"maxOutputTokens,omitempty"`
-	TopP            float64 `json:"topP,omitempty"`
-	TopK            int     `json:"topK,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
+	TopP            float64  `json:"topP,omitempty"`
+	TopK            int      `json:"topK,omitempty"`
 }
 
 type geminiSafetySetting struct {
@@ -69,6 +69,7 @@ type geminiCandidate struct {
 type geminiSafetyRating struct {
 	Category    string `json:"category"`
 	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked,omitempty"`
 }
 
 type geminiPromptFeedback struct {
@@ -89,9 +90,10 @@ type geminiError struct {
 }
 
 // NewGeminiProvider creates a new Google AI Gemini provider
-func NewGeminiProvider(apiKey, model string) *GeminiProvider {
-	client := resty.New()
-	client.SetTimeout(30 * time.Second)
+func NewGeminiProvider(apiKey, model string, opts ...ProviderOption) *GeminiProvider {
+	options := applyProviderOptions(opts)
+	client := newHTTPClient(options)
+	options.debugLog.attach(client, "gemini", model, apiKey)
 	client.SetHeader("Content-Type", "application/json")
 
 	return &GeminiProvider{
@@ -99,11 +101,20 @@ func NewGeminiProvider(apiKey, model string) *GeminiProvider {
 		apiKey:  apiKey,
 		model:   model,
 		baseURL: "https://generativelanguage.googleapis.com/v1beta",
+		options: options,
 	}
 }
 
+// SetBaseURL overrides the API base URL, e.g. to point at a proxy or test server
+func (p *GeminiProvider) SetBaseURL(baseURL string) {
+	p.baseURL = strings.TrimSuffix(baseURL, "/")
+}
+
 // GenerateCommand generates a shell command from a natural language query
 func (p *GeminiProvider) GenerateCommand(ctx context.Context, request *Request) (*Response, error) {
+	// Settings the request leaves unset fall back to the profile's
+	options := p.options.requestOptions(request.Options)
+
 	// Convert to prompt package request format
 	promptReq := &prompt.Request{
 		Query: request.Query,
@@ -114,8 +125,9 @@ func (p *GeminiProvider) GenerateCommand(ctx context.Context, request *Request)
 		},
 		Options: prompt.RequestOptions{
 			IncludeExplanation: request.Options.IncludeExplanation,
-			MaxTokens:          request.Options.MaxTokens,
-			Temperature:        request.Options.Temperature,
+			MaxTokens:          options.MaxTokens,
+			Temperature:        options.Temperature,
+			SafetyLevel:        request.Options.SafetyLevel,
 		},
 	}
 
@@ -128,11 +140,17 @@ func (p *GeminiProvider) GenerateCommand(ctx context.Context, request *Request)
 		Architecture:     request.Context.Architecture,
 		User:             request.Context.User,
 		WorkingDirectory: request.Context.WorkingDirectory,
+		ProjectType:      request.Context.ProjectType,
+		GitStatus:        request.Context.GitStatus,
 		ToolsSummary:     request.Context.ToolsSummary,
 		PackageManagers:  request.Context.PackageManagers,
 		Languages:        request.Context.Languages,
 		ContainerTools:   request.Context.ContainerTools,
 		CloudTools:       request.Context.CloudTools,
+		Environment:      request.Context.Environment,
+
+		SystemPromptPrefix: p.options.systemPromptPrefix,
+		SystemPromptSuffix: p.options.systemPromptSuffix,
 	}
 
 	systemPrompt := prompt.GetSystemPrompt(promptContext)
@@ -152,17 +170,12 @@ func (p *GeminiProvider) GenerateCommand(ctx context.Context, request *Request)
 			},
 		},
 		GenerationConfig: &geminiGenerationConfig{
-			Temperature:     request.Options.Temperature,
-			MaxOutputTokens: request.Options.MaxTokens,
+			Temperature:     options.Temperature,
+			MaxOutputTokens: options.MaxTokens,
 			TopP:            0.8,
 			TopK:            40,
 		},
-		SafetySettings: []geminiSafetySetting{
-			{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_MEDIUM_AND_ABOVE"},
-			{Category: "HARM_CATEGORY_HATE_SPEECH", Threshold: "BLOCK_MEDIUM_AND_ABOVE"},
-			{Category: "HARM_CATEGORY_SEXUALLY_EXPLICIT", Threshold: "BLOCK_MEDIUM_AND_ABOVE"},
-			{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Threshold: "BLOCK_MEDIUM_AND_ABOVE"},
-		},
+		SafetySettings: geminiSafetySettings(request.Options.SafetyLevel),
 	}
 
 	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", p.baseURL, p.model, p.apiKey)
@@ -172,6 +185,7 @@ func (p *GeminiProvider) GenerateCommand(ctx context.Context, request *Request)
 		SetContext(ctx).
 		SetBody(geminiReq).
 		SetResult(&resp).
+		SetError(&resp).
 		Post(url)
 
 	if err != nil {
@@ -186,6 +200,10 @@ func (p *GeminiProvider) GenerateCommand(ctx context.Context, request *Request)
 		return nil, p.handleAPIError(restResp, &resp)
 	}
 
+	if err := p.checkBlocked(&resp); err != nil {
+		return nil, err
+	}
+
 	if len(resp.Candidates) == 0 {
 		return nil, &Error{
 			Type:    ErrorTypeModel,
@@ -202,7 +220,7 @@ func (p *GeminiProvider) GenerateCommand(ctx context.Context, request *Request)
 	}
 
 	content := candidate.Content.Parts[0].Text
-	command, explanation := p.parseResponse(content, request.Options.IncludeExplanation)
+	command, explanation, llmDangerLevel, llmDangerReason, alternatives := p.parseResponse(content, request.Options.IncludeExplanation)
 
 	var usage *Usage
 	if resp.UsageMetadata != nil {
@@ -214,38 +232,42 @@ func (p *GeminiProvider) GenerateCommand(ctx context.Context, request *Request)
 	}
 
 	return &Response{
-		Command:     command,
-		Explanation: explanation,
-		Confidence:  p.calculateConfidence(candidate.FinishReason),
-		Warnings:    prompt.CheckCommandSafety(command),
-		Usage:       usage,
+		Command:      command,
+		Explanation:  explanation,
+		Confidence:   p.calculateConfidence(candidate.FinishReason),
+		DangerLevel:  llmDangerLevel,
+		DangerReason: llmDangerReason,
+		Alternatives: alternatives,
+		Warnings:     prompt.CheckCommandSafety(command),
+		Usage:        usage,
 		Metadata: map[string]interface{}{
-			"model":         p.model,
-			"finish_reason": candidate.FinishReason,
+			"model":            p.model,
+			"finish_reason":    candidate.FinishReason,
+			"llm_danger_level": string(llmDangerLevel),
 		},
 	}, nil
 }
 
 // ExplainCommand explains what a command does
 func (p *GeminiProvider) ExplainCommand(ctx context.Context, command string) (*Response, error) {
-	prompt := fmt.Sprintf("Explain what this shell command does:\n\n%s\n\nProvide a clear, concise explanation of what this command accomplishes.", command)
+	userPrompt := prompt.BuildExplainPrompt(command)
 
 	geminiReq := geminiRequest{
 		Contents: []geminiContent{
 			{
 				Parts: []geminiPart{
-					{Text: prompt},
+					{Text: userPrompt},
 				},
 				Role: "user",
 			},
 		},
 		SystemInstruction: &geminiSystemInstruction{
 			Parts: []geminiPart{
-				{Text: "You are a helpful assistant that explains shell commands clearly and concisely."},
+				{Text: explainSystemPrompt()},
 			},
 		},
 		GenerationConfig: &geminiGenerationConfig{
-			Temperature:     0.1,
+			Temperature:     float64Ptr(0.1),
 			MaxOutputTokens: 300,
 		},
 	}
@@ -257,6 +279,7 @@ func (p *GeminiProvider) ExplainCommand(ctx context.Context, command string) (*R
 		SetContext(ctx).
 		SetBody(geminiReq).
 		SetResult(&resp).
+		SetError(&resp).
 		Post(url)
 
 	if err != nil {
@@ -271,6 +294,10 @@ func (p *GeminiProvider) ExplainCommand(ctx context.Context, command string) (*R
 		return nil, p.handleAPIError(restResp, &resp)
 	}
 
+	if err := p.checkBlocked(&resp); err != nil {
+		return nil, err
+	}
+
 	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
 		return nil, &Error{
 			Type:    ErrorTypeModel,
@@ -300,18 +327,14 @@ func (p *GeminiProvider) GetProviderInfo() ProviderInfo {
 	return ProviderInfo{
 		Name:    "Google AI",
 		Version: "1.0.0",
-		Models:  []string{"gemini-1.5-pro", "gemini-1.5-flash", "gemini-1.0-pro"},
+		Models:  SupportedModels("gemini"),
 		Capabilities: []string{
 			"command_generation",
 			"command_explanation",
 			"context_awareness",
 			"safety_filtering",
 		},
-		Limits: map[string]int{
-			"max_tokens":      8192,
-			"max_history":     10,
-			"timeout_seconds": 30,
-		},
+		Limits: providerLimits("gemini", p.model),
 		Metadata: map[string]string{
 			"provider": "google",
 			"model":    p.model,
@@ -319,22 +342,10 @@ func (p *GeminiProvider) GetProviderInfo() ProviderInfo {
 	}
 }
 
-// parseResponse extracts command and explanation from the response
-func (p *GeminiProvider) parseResponse(content string, includeExplanation bool) (command, explanation string) {
-	content = strings.TrimSpace(content)
-
-	if includeExplanation && strings.Contains(content, "||") {
-		parts := strings.SplitN(content, "||", 2)
-		command = strings.TrimSpace(parts[0])
-		explanation = strings.TrimSpace(parts[1])
-	} else {
-		command = content
-	}
-
-	// Clean up command using centralized function
-	command = prompt.CleanCommand(command)
-
-	return command, explanation
+// parseResponse extracts command, explanation, danger assessment and alternatives from the response
+func (p *GeminiProvider) parseResponse(content string, includeExplanation bool) (command, explanation string, dangerLevel DangerLevel, dangerReason string, alternatives []string) {
+	parsed := prompt.ParseStructuredResponse(content, includeExplanation)
+	return parsed.Command, parsed.Explanation, ParseDangerLevel(parsed.DangerLevel), parsed.DangerReason, parsed.Alternatives
 }
 
 // calculateConfidence estimates confidence based on finish reason
@@ -353,6 +364,67 @@ func (p *GeminiProvider) calculateConfidence(finishReason string) float64 {
 	}
 }
 
+// geminiSafetySettings maps the request safety level onto Gemini's content
+// filter thresholds
+func geminiSafetySettings(safetyLevel string) []geminiSafetySetting {
+	threshold := "BLOCK_MEDIUM_AND_ABOVE"
+	switch safetyLevel {
+	case prompt.SafetyLevelStrict:
+		threshold = "BLOCK_LOW_AND_ABOVE"
+	case prompt.SafetyLevelPermissive:
+		threshold = "BLOCK_ONLY_HIGH"
+	}
+
+	return []geminiSafetySetting{
+		{Category: "HARM_CATEGORY_HARASSMENT", Threshold: threshold},
+		{Category: "HARM_CATEGORY_HATE_SPEECH", Threshold: threshold},
+		{Category: "HARM_CATEGORY_SEXUALLY_EXPLICIT", Threshold: threshold},
+		{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Threshold: threshold},
+	}
+}
+
+// checkBlocked returns a safety error if Gemini refused the prompt or stopped
+// generating because of its content filters
+func (p *GeminiProvider) checkBlocked(resp *geminiResponse) error {
+	if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+		return newGeminiSafetyError("Query was blocked by Gemini's content filter",
+			resp.PromptFeedback.BlockReason, resp.PromptFeedback.SafetyRatings)
+	}
+
+	if len(resp.Candidates) > 0 {
+		candidate := resp.Candidates[0]
+		switch candidate.FinishReason {
+		case "SAFETY", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII":
+			return newGeminiSafetyError("Response was blocked by Gemini's content filter",
+				candidate.FinishReason, candidate.SafetyRatings)
+		}
+	}
+
+	return nil
+}
+
+// newGeminiSafetyError builds a safety error naming the block reason and the
+// categories that triggered it
+func newGeminiSafetyError(message, reason string, ratings []geminiSafetyRating) error {
+	var categories []string
+	for _, rating := range ratings {
+		if rating.Blocked || rating.Probability == "HIGH" || rating.Probability == "MEDIUM" {
+			categories = append(categories, strings.TrimPrefix(rating.Category, "HARM_CATEGORY_"))
+		}
+	}
+
+	message = fmt.Sprintf("%s (reason: %s)", message, reason)
+	if len(categories) > 0 {
+		message = fmt.Sprintf("%s, category: %s", message, strings.Join(categories, ", "))
+	}
+
+	return &Error{
+		Type:    ErrorTypeSafety,
+		Message: message,
+		Code:    reason,
+	}
+}
+
 // handleAPIError converts Gemini API errors to our error format
 func (p *GeminiProvider) handleAPIError(resp *resty.Response, apiResp *geminiResponse) error {
 	if apiResp.Error != nil {
@@ -370,15 +442,15 @@ func (p *GeminiProvider) handleAPIError(resp *resty.Response, apiResp *geminiRes
 			errorType = ErrorTypeUnknown
 		}
 
-		return &Error{
+		return withRetryHint(&Error{
 			Type:    errorType,
 			Message: apiResp.Error.Message,
 			Code:    fmt.Sprintf("%d", apiResp.Error.Code),
-		}
+		}, resp)
 	}
 
-	return &Error{
+	return withRetryHint(&Error{
 		Type:    ErrorTypeNetwork,
 		Message: fmt.Sprintf("HTTP %d: %s", resp.StatusCode(), resp.String()),
-	}
+	}, resp)
 }
@@ -0,0 +1,17 @@
+package llm
+
+// groqBaseURL is Groq's OpenAI-compatible API endpoint
+const groqBaseURL = "https://api.groq.com/openai/v1"
+
+// GroqProvider implements the Provider interface for Groq. Groq's API is
+// OpenAI-compatible, so requests go through the OpenAI provider.
+type GroqProvider struct {
+	*OpenAIProvider
+}
+
+// NewGroqProvider creates a new Groq provider
+func NewGroqProvider(apiKey, model string, opts ...ProviderOption) *GroqProvider {
+	return &GroqProvider{
+		OpenAIProvider: newOpenAICompatibleProvider("groq", "Groq", groqBaseURL, apiKey, model, opts),
+	}
+}
@@ -0,0 +1,83 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// mistralBaseURL is Mistral's API endpoint, which accepts OpenAI's chat
+// completion requests
+const mistralBaseURL = "https://api.mistral.ai/v1"
+
+// MistralProvider implements the Provider interface for Mistral AI. Mistral's
+// API is OpenAI-compatible apart from its error responses, so requests go
+// through the OpenAI provider.
+type MistralProvider struct {
+	*OpenAIProvider
+}
+
+// mistralError is the body of an error response. Message is usually a
+// string, but validation errors report an object with the details.
+type mistralError struct {
+	Object  string          `json:"object"`
+	Message json.RawMessage `json:"message"`
+	Type    string          `json:"type"`
+	Code    json.RawMessage `json:"code,omitempty"`
+}
+
+// NewMistralProvider creates a new Mistral provider
+func NewMistralProvider(apiKey, model string, opts ...ProviderOption) *MistralProvider {
+	provider := newOpenAICompatibleProvider("mistral", "Mistral", mistralBaseURL, apiKey, model, opts)
+	provider.apiError = mistralAPIError
+	return &MistralProvider{OpenAIProvider: provider}
+}
+
+// mistralAPIError converts Mistral API errors to our error format. Mistral
+// doesn't always send an error type, so the HTTP status decides otherwise.
+func mistralAPIError(resp *resty.Response) error {
+	var apiErr mistralError
+	_ = json.Unmarshal(resp.Body(), &apiErr) // a body that isn't JSON leaves the fields empty
+
+	message := mistralErrorField(apiErr.Message)
+	if message == "" {
+		message = fmt.Sprintf("HTTP %d: %s", resp.StatusCode(), resp.String())
+	}
+
+	var errorType ErrorType
+	switch {
+	case apiErr.Type == "invalid_request_error", apiErr.Type == "invalid_request_message_error":
+		errorType = ErrorTypeInvalidInput
+	case resp.StatusCode() == http.StatusUnauthorized, resp.StatusCode() == http.StatusForbidden:
+		errorType = ErrorTypeAuth
+	case resp.StatusCode() == http.StatusTooManyRequests:
+		errorType = ErrorTypeRateLimit
+	case resp.StatusCode() == http.StatusBadRequest, resp.StatusCode() == http.StatusUnprocessableEntity:
+		errorType = ErrorTypeInvalidInput
+	case resp.StatusCode() >= http.StatusInternalServerError:
+		errorType = ErrorTypeModel
+	default:
+		errorType = ErrorTypeNetwork
+	}
+
+	return withRetryHint(&Error{
+		Type:    errorType,
+		Message: message,
+		Code:    mistralErrorField(apiErr.Code),
+	}, resp)
+}
+
+// mistralErrorField returns an error field that may be a JSON string, a
+// number or, for validation errors, an object, which is returned as JSON
+func mistralErrorField(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var message string
+	if err := json.Unmarshal(raw, &message); err == nil {
+		return message
+	}
+	return string(raw)
+}
@@ -0,0 +1,236 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// supportedModels is the single source of truth for the known-valid models
+// of each provider type. The first model of each list is the default.
+var supportedModels = map[string][]string{
+	"openai": {
+		"gpt-4.1",
+		"gpt-4.1-2025-04-14",
+		"gpt-4.1-mini",
+		"gpt-4.1-nano",
+		"gpt-4o",
+		"gpt-4o-2024-08-06",
+		"gpt-4o-mini",
+		"o1",
+		"o1-mini",
+		"o3",
+		"o3-mini",
+		"o4-mini",
+		"o4-mini-2025-04-16",
+		"gpt-4",
+		"gpt-4-turbo",
+		"gpt-4-turbo-preview",
+		"gpt-3.5-turbo",
+		"gpt-3.5-turbo-16k",
+	},
+	"anthropic": {
+		"claude-3-5-sonnet-20241022",
+		"claude-3-opus-20240229",
+		"claude-3-sonnet-20240229",
+		"claude-3-haiku-20240307",
+	},
+	"gemini": {
+		"gemini-2.5-flash-lite-preview-06-17",
+		"gemini-2.5-flash",
+		"gemini-2.5-pro",
+		"gemini-2.0-flash-exp",
+		"gemini-1.5-pro",
+		"gemini-1.5-flash",
+		"gemini-1.0-pro",
+		"gemini-exp-1114",
+	},
+	"groq": {
+		"llama-3.3-70b-versatile",
+		"llama-3.1-70b-versatile",
+		"llama-3.1-8b-instant",
+		"mixtral-8x7b-32768",
+		"gemma2-9b-it",
+	},
+	"mistral": {
+		"codestral-latest",
+		"mistral-large-latest",
+		"mistral-medium-latest",
+		"mistral-small-latest",
+		"open-mistral-nemo",
+	},
+	// Ollama runs whatever has been pulled; these are common picks for
+	// shell commands, and `forgor models ollama --live` lists the rest
+	"ollama": {
+		"qwen2.5-coder",
+		"llama3.2",
+		"llama3.1",
+		"codellama",
+		"deepseek-coder-v2",
+	},
+}
+
+// normalizeProviderType maps provider aliases to their canonical type
+func normalizeProviderType(providerType string) string {
+	providerType = strings.ToLower(providerType)
+	if providerType == "google" {
+		return "gemini"
+	}
+	return providerType
+}
+
+// GetSupportedModels returns the known-valid models for each provider type
+func GetSupportedModels() map[string][]string {
+	models := make(map[string][]string, len(supportedModels))
+	for providerType := range supportedModels {
+		models[providerType] = SupportedModels(providerType)
+	}
+	return models
+}
+
+// SupportedModels returns the known-valid models for a provider type, or nil
+// if the provider is not supported. "google" is an alias for "gemini".
+func SupportedModels(providerType string) []string {
+	models, ok := supportedModels[normalizeProviderType(providerType)]
+	if !ok {
+		return nil
+	}
+	return append([]string(nil), models...)
+}
+
+// GetDefaultModels returns default models for each provider type
+func GetDefaultModels() map[string]string {
+	defaults := make(map[string]string)
+	for _, providerType := range GetSupportedProviders() {
+		if models := supportedModels[normalizeProviderType(providerType)]; len(models) > 0 {
+			defaults[providerType] = models[0]
+		}
+	}
+	return defaults
+}
+
+// FormatModelList renders models one per line, marking currentModel. If the
+// current model is not in the list, a note is appended instead.
+func FormatModelList(models []string, currentModel string) string {
+	var b strings.Builder
+	for _, model := range models {
+		marker := ""
+		if model == currentModel {
+			marker = " (current)"
+		}
+		fmt.Fprintf(&b, "  • %s%s\n", model, marker)
+	}
+
+	if currentModel != "" && !contains(models, currentModel) {
+		fmt.Fprintf(&b, "  Configured model %s is not in this list\n", currentModel)
+	}
+
+	return b.String()
+}
+
+// ModelLimits are a model's token limits. ContextWindow is 0 when unknown.
+type ModelLimits struct {
+	MaxOutputTokens int `json:"max_output_tokens"`
+	ContextWindow   int `json:"context_window,omitempty"`
+}
+
+// modelLimits lists the published limits of known models
+var modelLimits = map[string]ModelLimits{
+	"gpt-4.1":             {MaxOutputTokens: 32768, ContextWindow: 1047576},
+	"gpt-4.1-2025-04-14":  {MaxOutputTokens: 32768, ContextWindow: 1047576},
+	"gpt-4.1-mini":        {MaxOutputTokens: 32768, ContextWindow: 1047576},
+	"gpt-4.1-nano":        {MaxOutputTokens: 32768, ContextWindow: 1047576},
+	"gpt-4o":              {MaxOutputTokens: 16384, ContextWindow: 128000},
+	"gpt-4o-2024-08-06":   {MaxOutputTokens: 16384, ContextWindow: 128000},
+	"gpt-4o-mini":         {MaxOutputTokens: 16384, ContextWindow: 128000},
+	"o1":                  {MaxOutputTokens: 100000, ContextWindow: 200000},
+	"o1-mini":             {MaxOutputTokens: 65536, ContextWindow: 128000},
+	"o3":                  {MaxOutputTokens: 100000, ContextWindow: 200000},
+	"o3-mini":             {MaxOutputTokens: 100000, ContextWindow: 200000},
+	"o4-mini":             {MaxOutputTokens: 100000, ContextWindow: 200000},
+	"o4-mini-2025-04-16":  {MaxOutputTokens: 100000, ContextWindow: 200000},
+	"gpt-4":               {MaxOutputTokens: 8192, ContextWindow: 8192},
+	"gpt-4-turbo":         {MaxOutputTokens: 4096, ContextWindow: 128000},
+	"gpt-4-turbo-preview": {MaxOutputTokens: 4096, ContextWindow: 128000},
+	"gpt-3.5-turbo":       {MaxOutputTokens: 4096, ContextWindow: 16385},
+	"gpt-3.5-turbo-16k":   {MaxOutputTokens: 4096, ContextWindow: 16385},
+
+	"claude-3-5-sonnet-20241022": {MaxOutputTokens: 8192, ContextWindow: 200000},
+	"claude-3-opus-20240229":     {MaxOutputTokens: 4096, ContextWindow: 200000},
+	"claude-3-sonnet-20240229":   {MaxOutputTokens: 4096, ContextWindow: 200000},
+	"claude-3-haiku-20240307":    {MaxOutputTokens: 4096, ContextWindow: 200000},
+
+	"gemini-2.5-flash-lite-preview-06-17": {MaxOutputTokens: 65536, ContextWindow: 1000000},
+	"gemini-2.5-flash":                    {MaxOutputTokens: 65536, ContextWindow: 1048576},
+	"gemini-2.5-pro":                      {MaxOutputTokens: 65536, ContextWindow: 1048576},
+	"gemini-2.0-flash-exp":                {MaxOutputTokens: 8192, ContextWindow: 1048576},
+	"gemini-1.5-pro":                      {MaxOutputTokens: 8192, ContextWindow: 2097152},
+	"gemini-1.5-flash":                    {MaxOutputTokens: 8192, ContextWindow: 1048576},
+	"gemini-1.0-pro":                      {MaxOutputTokens: 2048, ContextWindow: 32760},
+	"gemini-exp-1114":                     {MaxOutputTokens: 8192, ContextWindow: 32768},
+	"llama-3.3-70b-versatile":             {MaxOutputTokens: 32768, ContextWindow: 131072},
+	"llama-3.1-70b-versatile":             {MaxOutputTokens: 8000, ContextWindow: 131072},
+	"llama-3.1-8b-instant":                {MaxOutputTokens: 8192, ContextWindow: 131072},
+	"mixtral-8x7b-32768":                  {MaxOutputTokens: 32768, ContextWindow: 32768},
+	"gemma2-9b-it":                        {MaxOutputTokens: 8192, ContextWindow: 8192},
+	"codestral-latest":                    {MaxOutputTokens: 8192, ContextWindow: 256000},
+	"mistral-large-latest":                {MaxOutputTokens: 8192, ContextWindow: 131072},
+	"mistral-medium-latest":               {MaxOutputTokens: 8192, ContextWindow: 131072},
+	"mistral-small-latest":                {MaxOutputTokens: 8192, ContextWindow: 131072},
+	"open-mistral-nemo":                   {MaxOutputTokens: 8192, ContextWindow: 131072},
+	"qwen2.5-coder":                       {MaxOutputTokens: 8192, ContextWindow: 32768},
+	"llama3.2":                            {MaxOutputTokens: 4096, ContextWindow: 131072},
+	"llama3.1":                            {MaxOutputTokens: 4096, ContextWindow: 131072},
+	"codellama":                           {MaxOutputTokens: 4096, ContextWindow: 16384},
+	"deepseek-coder-v2":                   {MaxOutputTokens: 8192, ContextWindow: 163840},
+}
+
+// defaultModelLimits are assumed for models missing from modelLimits
+var defaultModelLimits = map[string]ModelLimits{
+	"openai":    {MaxOutputTokens: 4096},
+	"anthropic": {MaxOutputTokens: 4096},
+	"gemini":    {MaxOutputTokens: 8192},
+	"groq":      {MaxOutputTokens: 8192},
+	"mistral":   {MaxOutputTokens: 8192},
+	"ollama":    {MaxOutputTokens: 4096},
+}
+
+// GetModelLimits returns the limits of a model. Unknown models get a
+// conservative default for their provider, and known is false.
+func GetModelLimits(providerType, model string) (limits ModelLimits, known bool) {
+	if limits, ok := modelLimits[model]; ok && contains(supportedModels[normalizeProviderType(providerType)], model) {
+		return limits, true
+	}
+	return defaultModelLimits[normalizeProviderType(providerType)], false
+}
+
+// ClampMaxTokens caps maxTokens at the model's output limit. It reports
+// whether the value was lowered.
+func ClampMaxTokens(providerType, model string, maxTokens int) (int, bool) {
+	limits, _ := GetModelLimits(providerType, model)
+	if limits.MaxOutputTokens > 0 && maxTokens > limits.MaxOutputTokens {
+		return limits.MaxOutputTokens, true
+	}
+	return maxTokens, false
+}
+
+// String describes the limits, e.g. "16384 output tokens, 128000 token context"
+func (l ModelLimits) String() string {
+	if l.ContextWindow == 0 {
+		return fmt.Sprintf("%d output tokens", l.MaxOutputTokens)
+	}
+	return fmt.Sprintf("%d output tokens, %d token context", l.MaxOutputTokens, l.ContextWindow)
+}
+
+// providerLimits returns the Limits reported by GetProviderInfo for a model
+func providerLimits(providerType, model string) map[string]int {
+	limits, _ := GetModelLimits(providerType, model)
+	info := map[string]int{
+		"max_tokens":      limits.MaxOutputTokens,
+		"max_history":     10,
+		"timeout_seconds": 30,
+	}
+	if limits.ContextWindow > 0 {
+		info["context_window"] = limits.ContextWindow
+	}
+	return info
+}
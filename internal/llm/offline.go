@@ -0,0 +1,155 @@
+package llm
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"forgor/internal/prompt"
+)
+
+// offlineConfidence marks commands from the offline rules, which only
+// recognise wording and can't weigh the rest of the request
+const offlineConfidence = 0.2
+
+// offlineWarning is attached to every offline command
+const offlineWarning = "Generated offline by a built-in rule, not a model. Check it before running."
+
+// offlineRule maps queries matching pattern to a command. command may
+// refer to the pattern's groups as $1, $2, ...
+type offlineRule struct {
+	pattern     *regexp.Regexp
+	command     string
+	explanation string
+}
+
+// offlineRules are tried in order and the first match wins, so more specific
+// wording comes before general wording. They cover the common requests from
+// the system prompt's examples.
+var offlineRules = []offlineRule{
+	{
+		pattern:     regexp.MustCompile(`\bfind\s+(?:all\s+)?(?:the\s+)?\.?([a-z0-9]{1,5})\s+files\b`),
+		command:     `find . -name "*.$1"`,
+		explanation: "Searches the current directory and its subdirectories for files with this extension.",
+	},
+	{
+		pattern:     regexp.MustCompile(`\b(?:options|flags|help)\s+(?:for|of)\s+([a-z0-9][a-z0-9_.-]*)\b`),
+		command:     "$1 --help",
+		explanation: "Shows the command's built-in help with its options.",
+	},
+	{
+		pattern:     regexp.MustCompile(`\bport\s+(\d{1,5})\b`),
+		command:     "lsof -i :$1",
+		explanation: "Lists the processes with connections on this port.",
+	},
+	{
+		pattern:     regexp.MustCompile(`\b(?:largest|biggest)\b.*\b(?:files|folders|directories)\b`),
+		command:     "du -sh * | sort -hr | head -10",
+		explanation: "Shows the ten largest entries in the current directory.",
+	},
+	{
+		pattern:     regexp.MustCompile(`\bdisk\s+(?:usage|space)\b|\bfree\s+space\b`),
+		command:     "df -h",
+		explanation: "Shows disk usage per filesystem in human-readable sizes.",
+	},
+	{
+		pattern:     regexp.MustCompile(`\b(?:list|show)\b.*\bprocesses\b`),
+		command:     "ps aux",
+		explanation: "Lists all running processes for all users.",
+	},
+	{
+		pattern:     regexp.MustCompile(`\bcompress\b.*\b(?:folder|directory|dir)\b`),
+		command:     "tar -czf archive.tar.gz .",
+		explanation: "Creates a gzip-compressed tar archive of the current directory.",
+	},
+	{
+		pattern:     regexp.MustCompile(`\b(?:environment|env)\s+variables\b`),
+		command:     "printenv",
+		explanation: "Prints all environment variables.",
+	},
+	{
+		pattern:     regexp.MustCompile(`\b(?:show|print|echo)\b.*\bpath\b`),
+		command:     "echo $$PATH",
+		explanation: "Prints the directories searched for commands.",
+	},
+	{
+		pattern:     regexp.MustCompile(`\bcurrent\s+(?:directory|dir|folder)\b|\bwhere\s+am\s+i\b`),
+		command:     "pwd",
+		explanation: "Prints the current working directory.",
+	},
+	{
+		pattern:     regexp.MustCompile(`\bgit\s+status\b|\bwhat\s+changed\b`),
+		command:     "git status",
+		explanation: "Shows changed, staged and untracked files in the repository.",
+	},
+	{
+		pattern:     regexp.MustCompile(`\b(?:list|show)\b.*\bfiles\b`),
+		command:     "ls -la",
+		explanation: "Lists all files, including hidden ones, with details.",
+	},
+}
+
+// OfflineProvider translates a few common requests into commands with
+// built-in rules, for use without network access
+type OfflineProvider struct{}
+
+// NewOfflineProvider creates a new offline provider
+func NewOfflineProvider() *OfflineProvider {
+	return &OfflineProvider{}
+}
+
+// GenerateCommand returns the command of the first rule matching the query,
+// with low confidence, or an error if no rule matches
+func (p *OfflineProvider) GenerateCommand(ctx context.Context, request *Request) (*Response, error) {
+	query := strings.ToLower(strings.TrimSpace(request.Query))
+
+	for _, rule := range offlineRules {
+		match := rule.pattern.FindStringSubmatchIndex(query)
+		if match == nil {
+			continue
+		}
+
+		command := string(rule.pattern.ExpandString(nil, rule.command, query, match))
+		response := &Response{
+			Command:    command,
+			Confidence: offlineConfidence,
+			Warnings:   append([]string{offlineWarning}, prompt.CheckCommandSafety(command)...),
+			Metadata: map[string]interface{}{
+				"model":   "offline",
+				"offline": true,
+			},
+		}
+		if request.Options.IncludeExplanation {
+			response.Explanation = rule.explanation
+		}
+		return response, nil
+	}
+
+	return nil, &Error{
+		Type:    ErrorTypeModel,
+		Message: "No offline rule matches this query. Offline mode only knows a few common requests, such as \"show disk usage\"",
+	}
+}
+
+// ExplainCommand is not available offline
+func (p *OfflineProvider) ExplainCommand(ctx context.Context, command string) (*Response, error) {
+	return nil, &Error{
+		Type:    ErrorTypeModel,
+		Message: "Explaining commands needs a provider and is not available offline",
+	}
+}
+
+// GetProviderInfo returns information about the offline provider
+func (p *OfflineProvider) GetProviderInfo() ProviderInfo {
+	return ProviderInfo{
+		Name:         "Offline",
+		Version:      "1.0.0",
+		Models:       []string{},
+		Capabilities: []string{"command_generation"},
+		Limits:       map[string]int{},
+		Metadata: map[string]string{
+			"provider": "offline",
+			"model":    "offline",
+		},
+	}
+}
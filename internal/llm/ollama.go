@@ -0,0 +1,371 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"forgor/internal/prompt"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// DefaultOllamaEndpoint is where Ollama listens unless a profile sets endpoint
+const DefaultOllamaEndpoint = "http://localhost:11434"
+
+// ollamaTimeout allows for Ollama loading a model into memory on its first request
+const ollamaTimeout = 2 * time.Minute
+
+// OllamaProvider implements the Provider interface for a local Ollama server
+// using its native API
+type OllamaProvider struct {
+	client  *resty.Client
+	model   string
+	baseURL string
+	options providerOptions
+}
+
+// Ollama API request/response structures
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Options  ollamaOptions   `json:"options,omitempty"`
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"`
+}
+
+type ollamaChatResponse struct {
+	Model           string        `json:"model"`
+	CreatedAt       string        `json:"created_at"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+}
+
+type ollamaError struct {
+	Error string `json:"error"`
+}
+
+// ollamaTagsResponse is the response from /api/tags, the locally pulled models
+type ollamaTagsResponse struct {
+	Models []struct {
+		Name string `json:"name"`
+	} `json:"models"`
+}
+
+// NewOllamaProvider creates a new Ollama provider for the server at
+// endpoint, or DefaultOllamaEndpoint if it is empty
+func NewOllamaProvider(endpoint, model string, opts ...ProviderOption) *OllamaProvider {
+	options := applyProviderOptions(opts)
+	client := newHTTPClient(options)
+	client.SetTimeout(ollamaTimeout)
+	options.debugLog.attach(client, "ollama", model, "")
+	client.SetHeader("Content-Type", "application/json")
+
+	if endpoint == "" {
+		endpoint = DefaultOllamaEndpoint
+	}
+
+	return &OllamaProvider{
+		client:  client,
+		model:   model,
+		baseURL: strings.TrimSuffix(endpoint, "/"),
+		options: options,
+	}
+}
+
+// SetBaseURL overrides the server URL, e.g. to point at a test server
+func (p *OllamaProvider) SetBaseURL(baseURL string) {
+	p.baseURL = strings.TrimSuffix(baseURL, "/")
+}
+
+// GenerateCommand generates a shell command from a natural language query
+func (p *OllamaProvider) GenerateCommand(ctx context.Context, request *Request) (*Response, error) {
+	// Settings the request leaves unset fall back to the profile's
+	options := p.options.requestOptions(request.Options)
+
+	// Convert to prompt package request format
+	promptReq := &prompt.Request{
+		Query: request.Query,
+		Context: prompt.RequestContext{
+			WorkingDirectory: request.Context.WorkingDirectory,
+			History:          request.Context.History,
+			UserContext:      request.Context.UserContext,
+		},
+		Options: prompt.RequestOptions{
+			IncludeExplanation: request.Options.IncludeExplanation,
+			MaxTokens:          options.MaxTokens,
+			Temperature:        options.Temperature,
+			SafetyLevel:        request.Options.SafetyLevel,
+		},
+	}
+
+	userPrompt := prompt.BuildOllamaCommandPrompt(promptReq)
+
+	// Convert to prompt package context format
+	promptContext := prompt.Context{
+		OS:               request.Context.OS,
+		Shell:            request.Context.Shell,
+		Architecture:     request.Context.Architecture,
+		User:             request.Context.User,
+		WorkingDirectory: request.Context.WorkingDirectory,
+		ProjectType:      request.Context.ProjectType,
+		GitStatus:        request.Context.GitStatus,
+		ToolsSummary:     request.Context.ToolsSummary,
+		PackageManagers:  request.Context.PackageManagers,
+		Languages:        request.Context.Languages,
+		ContainerTools:   request.Context.ContainerTools,
+		CloudTools:       request.Context.CloudTools,
+		Environment:      request.Context.Environment,
+
+		SystemPromptPrefix: p.options.systemPromptPrefix,
+		SystemPromptSuffix: p.options.systemPromptSuffix,
+	}
+
+	systemPrompt := prompt.GetSystemPrompt(promptContext)
+
+	resp, err := p.chat(ctx, ollamaChatRequest{
+		Model: p.model,
+		Messages: []ollamaMessage{
+			{
+				Role:    "system",
+				Content: systemPrompt,
+			},
+			{
+				Role:    "user",
+				Content: userPrompt,
+			},
+		},
+		Stream: false,
+		Options: ollamaOptions{
+			Temperature: options.Temperature,
+			NumPredict:  options.MaxTokens,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	command, explanation, llmDangerLevel, llmDangerReason, alternatives := p.parseResponse(resp.Message.Content, request.Options.IncludeExplanation)
+	if command == "" {
+		return nil, &Error{
+			Type:    ErrorTypeEmptyCommand,
+			Message: "Ollama response did not contain a command",
+		}
+	}
+
+	return &Response{
+		Command:      command,
+		Explanation:  explanation,
+		Confidence:   p.calculateConfidence(resp.DoneReason),
+		DangerLevel:  llmDangerLevel,
+		DangerReason: llmDangerReason,
+		Alternatives: alternatives,
+		Warnings:     prompt.CheckCommandSafety(command),
+		Usage: &Usage{
+			PromptTokens:     resp.PromptEvalCount,
+			CompletionTokens: resp.EvalCount,
+			TotalTokens:      resp.PromptEvalCount + resp.EvalCount,
+		},
+		Metadata: map[string]interface{}{
+			"model":            resp.Model,
+			"finish_reason":    resp.DoneReason,
+			"llm_danger_level": string(llmDangerLevel),
+		},
+	}, nil
+}
+
+// ExplainCommand explains what a command does
+func (p *OllamaProvider) ExplainCommand(ctx context.Context, command string) (*Response, error) {
+	userPrompt := prompt.BuildExplainPrompt(command)
+
+	resp, err := p.chat(ctx, ollamaChatRequest{
+		Model: p.model,
+		Messages: []ollamaMessage{
+			{
+				Role:    "system",
+				Content: explainSystemPrompt(),
+			},
+			{
+				Role:    "user",
+				Content: userPrompt,
+			},
+		},
+		Stream: false,
+		Options: ollamaOptions{
+			Temperature: float64Ptr(0.1),
+			NumPredict:  300,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{
+		Command:     command,
+		Explanation: strings.TrimSpace(resp.Message.Content),
+		Confidence:  1.0, // High confidence for explanations
+		Usage: &Usage{
+			PromptTokens:     resp.PromptEvalCount,
+			CompletionTokens: resp.EvalCount,
+			TotalTokens:      resp.PromptEvalCount + resp.EvalCount,
+		},
+	}, nil
+}
+
+// chat sends a request to /api/chat, returning an error unless the reply
+// has content
+func (p *OllamaProvider) chat(ctx context.Context, chatReq ollamaChatRequest) (*ollamaChatResponse, error) {
+	var resp ollamaChatResponse
+	var apiErr ollamaError
+	restResp, err := p.client.R().
+		SetContext(ctx).
+		SetBody(chatReq).
+		SetResult(&resp).
+		SetError(&apiErr).
+		Post(p.baseURL + "/api/chat")
+
+	if err != nil {
+		return nil, p.connectionError(err)
+	}
+
+	if restResp.IsError() {
+		return nil, p.handleAPIError(restResp, &apiErr)
+	}
+
+	if strings.TrimSpace(resp.Message.Content) == "" {
+		return nil, &Error{
+			Type:    ErrorTypeModel,
+			Message: fmt.Sprintf("Ollama returned an empty response (done reason: %s)", resp.DoneReason),
+		}
+	}
+
+	return &resp, nil
+}
+
+// ListModels returns the models pulled on the Ollama server, from /api/tags
+func (p *OllamaProvider) ListModels(ctx context.Context) ([]string, error) {
+	var resp ollamaTagsResponse
+	var apiErr ollamaError
+	restResp, err := p.client.R().
+		SetContext(ctx).
+		SetResult(&resp).
+		SetError(&apiErr).
+		Get(p.baseURL + "/api/tags")
+
+	if err != nil {
+		return nil, p.connectionError(err)
+	}
+
+	if restResp.IsError() {
+		return nil, p.handleAPIError(restResp, &apiErr)
+	}
+
+	models := make([]string, 0, len(resp.Models))
+	for _, model := range resp.Models {
+		models = append(models, model.Name)
+	}
+	sort.Strings(models)
+
+	return models, nil
+}
+
+// GetProviderInfo returns information about the Ollama provider
+func (p *OllamaProvider) GetProviderInfo() ProviderInfo {
+	return ProviderInfo{
+		Name:    "Ollama",
+		Version: "1.0.0",
+		Models:  SupportedModels("ollama"),
+		Capabilities: []string{
+			"command_generation",
+			"command_explanation",
+			"context_awareness",
+			"safety_filtering",
+			"local_inference",
+		},
+		Limits: providerLimits("ollama", p.model),
+		Metadata: map[string]string{
+			"provider": "ollama",
+			"model":    p.model,
+			"endpoint": p.baseURL,
+		},
+	}
+}
+
+// parseResponse extracts command, explanation, danger assessment and alternatives from the response
+func (p *OllamaProvider) parseResponse(content string, includeExplanation bool) (command, explanation string, dangerLevel DangerLevel, dangerReason string, alternatives []string) {
+	parsed := prompt.ParseStructuredResponse(content, includeExplanation)
+	return parsed.Command, parsed.Explanation, ParseDangerLevel(parsed.DangerLevel), parsed.DangerReason, parsed.Alternatives
+}
+
+// calculateConfidence estimates confidence based on the done reason
+func (p *OllamaProvider) calculateConfidence(doneReason string) float64 {
+	switch doneReason {
+	case "stop":
+		return 0.9
+	case "length":
+		return 0.7
+	default:
+		return 0.5
+	}
+}
+
+// connectionError explains a failed request, suggesting starting Ollama
+// when nothing is listening on its endpoint
+func (p *OllamaProvider) connectionError(err error) error {
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return &Error{
+			Type:    ErrorTypeNetwork,
+			Message: fmt.Sprintf("Could not connect to Ollama at %s. Is ollama running? Start it with 'ollama serve'", p.baseURL),
+			Cause:   err,
+		}
+	}
+	return &Error{
+		Type:    ErrorTypeNetwork,
+		Message: fmt.Sprintf("Failed to call Ollama API at %s", p.baseURL),
+		Cause:   err,
+	}
+}
+
+// handleAPIError converts Ollama API errors to our error format. A model
+// that hasn't been pulled gets the command to pull it.
+func (p *OllamaProvider) handleAPIError(resp *resty.Response, apiErr *ollamaError) error {
+	message := apiErr.Error
+	if message == "" {
+		message = fmt.Sprintf("HTTP %d: %s", resp.StatusCode(), resp.String())
+	}
+
+	var errorType ErrorType
+	switch {
+	case resp.StatusCode() == http.StatusNotFound && strings.Contains(message, "not found"):
+		errorType = ErrorTypeModel
+		message = fmt.Sprintf("%s. Pull it with 'ollama pull %s'", message, p.model)
+	case resp.StatusCode() == http.StatusBadRequest:
+		errorType = ErrorTypeInvalidInput
+	case resp.StatusCode() >= http.StatusInternalServerError:
+		errorType = ErrorTypeModel
+	default:
+		errorType = ErrorTypeUnknown
+	}
+
+	return withRetryHint(&Error{
+		Type:    errorType,
+		Message: message,
+	}, resp)
+}
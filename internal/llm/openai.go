@@ -3,20 +3,32 @@ package llm
 import (
 	"context"
 	"fmt"
+	"math"
+	"sort"
 	"strings"
-	"time"
 
 	"forgor/internal/prompt"
 
 	"github.com/go-resty/resty/v2"
 )
 
-// OpenAIProvider implements the Provider interface for OpenAI
+// OpenAIProvider implements the Provider interface for OpenAI and, through
+// newOpenAICompatibleProvider, for providers with OpenAI-compatible APIs
 type OpenAIProvider struct {
 	client  *resty.Client
 	apiKey  string
 	model   string
 	baseURL string
+	options providerOptions
+
+	// name is the provider's display name and providerType its type in
+	// profiles, model allowlists and limits
+	name         string
+	providerType string
+
+	// apiError, if set, decodes error responses of an OpenAI-compatible API
+	// whose errors aren't in OpenAI's format
+	apiError func(resp *resty.Response) error
 }
 
 // OpenAI API request/response structures
@@ -24,13 +36,16 @@ type openAIRequest struct {
 	Model       string          `json:"model"`
 	Messages    []openAIMessage `json:"messages"`
 	MaxTokens   int             `json:"max_tokens,omitempty"`
-	Temperature float64         `json:"temperature,omitempty"`
+	Temperature *float64        `json:"temperature,omitempty"`
 	Stream      bool            `json:"stream"`
+	Logprobs    bool            `json:"logprobs,omitempty"`
 }
 
 type openAIMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+	// Refusal is set instead of Content when the model declines the request
+	Refusal string `json:"refusal,omitempty"`
 }
 
 type openAIResponse struct {
@@ -47,6 +62,17 @@ type openAIChoice struct {
 	Index        int           `json:"index"`
 	Message      openAIMessage `json:"message"`
 	FinishReason string        `json:"finish_reason"`
+	// Logprobs is set when the request asked for token log probabilities
+	Logprobs *openAILogprobs `json:"logprobs,omitempty"`
+}
+
+type openAILogprobs struct {
+	Content []openAITokenLogprob `json:"content"`
+}
+
+type openAITokenLogprob struct {
+	Token   string  `json:"token"`
+	Logprob float64 `json:"logprob"`
 }
 
 type openAIUsage struct {
@@ -62,22 +88,40 @@ type openAIError struct {
 }
 
 // NewOpenAIProvider creates a new OpenAI provider
-func NewOpenAIProvider(apiKey, model string) *OpenAIProvider {
-	client := resty.New()
-	client.SetTimeout(30 * time.Second)
+func NewOpenAIProvider(apiKey, model string, opts ...ProviderOption) *OpenAIProvider {
+	return newOpenAICompatibleProvider("openai", "OpenAI", "https://api.openai.com/v1", apiKey, model, opts)
+}
+
+// newOpenAICompatibleProvider creates a provider for an API that accepts
+// OpenAI's chat completion requests at baseURL
+func newOpenAICompatibleProvider(providerType, name, baseURL, apiKey, model string, opts []ProviderOption) *OpenAIProvider {
+	options := applyProviderOptions(opts)
+	client := newHTTPClient(options)
+	options.debugLog.attach(client, providerType, model, apiKey)
 	client.SetHeader("Authorization", "Bearer "+apiKey)
 	client.SetHeader("Content-Type", "application/json")
 
 	return &OpenAIProvider{
-		client:  client,
-		apiKey:  apiKey,
-		model:   model,
-		baseURL: "https://api.openai.com/v1",
+		client:       client,
+		apiKey:       apiKey,
+		model:        model,
+		baseURL:      baseURL,
+		options:      options,
+		name:         name,
+		providerType: providerType,
 	}
 }
 
+// SetBaseURL overrides the API base URL, e.g. to point at a proxy or test server
+func (p *OpenAIProvider) SetBaseURL(baseURL string) {
+	p.baseURL = strings.TrimSuffix(baseURL, "/")
+}
+
 // GenerateCommand generates a shell command from a natural language query
 func (p *OpenAIProvider) GenerateCommand(ctx context.Context, request *Request) (*Response, error) {
+	// Settings the request leaves unset fall back to the profile's
+	options := p.options.requestOptions(request.Options)
+
 	// Convert to prompt package request format
 	promptReq := &prompt.Request{
 		Query: request.Query,
@@ -88,8 +132,9 @@ func (p *OpenAIProvider) GenerateCommand(ctx context.Context, request *Request)
 		},
 		Options: prompt.RequestOptions{
 			IncludeExplanation: request.Options.IncludeExplanation,
-			MaxTokens:          request.Options.MaxTokens,
-			Temperature:        request.Options.Temperature,
+			MaxTokens:          options.MaxTokens,
+			Temperature:        options.Temperature,
+			SafetyLevel:        request.Options.SafetyLevel,
 		},
 	}
 
@@ -102,11 +147,17 @@ func (p *OpenAIProvider) GenerateCommand(ctx context.Context, request *Request)
 		Architecture:     request.Context.Architecture,
 		User:             request.Context.User,
 		WorkingDirectory: request.Context.WorkingDirectory,
+		ProjectType:      request.Context.ProjectType,
+		GitStatus:        request.Context.GitStatus,
 		ToolsSummary:     request.Context.ToolsSummary,
 		PackageManagers:  request.Context.PackageManagers,
 		Languages:        request.Context.Languages,
 		ContainerTools:   request.Context.ContainerTools,
 		CloudTools:       request.Context.CloudTools,
+		Environment:      request.Context.Environment,
+
+		SystemPromptPrefix: p.options.systemPromptPrefix,
+		SystemPromptSuffix: p.options.systemPromptSuffix,
 	}
 
 	systemPrompt := prompt.GetSystemPrompt(promptContext)
@@ -123,9 +174,10 @@ func (p *OpenAIProvider) GenerateCommand(ctx context.Context, request *Request)
 				Content: userPrompt,
 			},
 		},
-		MaxTokens:   request.Options.MaxTokens,
-		Temperature: request.Options.Temperature,
+		MaxTokens:   options.MaxTokens,
+		Temperature: options.Temperature,
 		Stream:      false,
+		Logprobs:    p.supportsLogprobs(),
 	}
 
 	var resp openAIResponse
@@ -133,12 +185,13 @@ func (p *OpenAIProvider) GenerateCommand(ctx context.Context, request *Request)
 		SetContext(ctx).
 		SetBody(openAIReq).
 		SetResult(&resp).
+		SetError(&resp).
 		Post(p.baseURL + "/chat/completions")
 
 	if err != nil {
 		return nil, &Error{
 			Type:    ErrorTypeNetwork,
-			Message: "Failed to call OpenAI API",
+			Message: fmt.Sprintf("Failed to call %s API", p.name),
 			Cause:   err,
 		}
 	}
@@ -150,19 +203,29 @@ func (p *OpenAIProvider) GenerateCommand(ctx context.Context, request *Request)
 	if len(resp.Choices) == 0 {
 		return nil, &Error{
 			Type:    ErrorTypeModel,
-			Message: "No response from OpenAI",
+			Message: fmt.Sprintf("No response from %s", p.name),
 		}
 	}
 
 	choice := resp.Choices[0]
-	command, explanation, llmDangerLevel, llmDangerReason := p.parseResponse(choice.Message.Content, request.Options.IncludeExplanation)
+	if err := checkOpenAIChoice(p.name, choice); err != nil {
+		return nil, err
+	}
+	command, explanation, llmDangerLevel, llmDangerReason, alternatives := p.parseResponse(choice.Message.Content, request.Options.IncludeExplanation)
+	if command == "" {
+		return nil, &Error{
+			Type:    ErrorTypeEmptyCommand,
+			Message: fmt.Sprintf("%s response did not contain a command", p.name),
+		}
+	}
 
 	return &Response{
 		Command:      command,
 		Explanation:  explanation,
-		Confidence:   p.calculateConfidence(choice.FinishReason),
+		Confidence:   p.confidence(choice),
 		DangerLevel:  llmDangerLevel,
 		DangerReason: llmDangerReason,
+		Alternatives: alternatives,
 		Warnings:     prompt.CheckCommandSafety(command),
 		Usage: &Usage{
 			PromptTokens:     resp.Usage.PromptTokens,
@@ -179,22 +242,22 @@ func (p *OpenAIProvider) GenerateCommand(ctx context.Context, request *Request)
 
 // ExplainCommand explains what a command does
 func (p *OpenAIProvider) ExplainCommand(ctx context.Context, command string) (*Response, error) {
-	prompt := fmt.Sprintf("Explain what this shell command does:\n\n%s\n\nProvide a clear, concise explanation of what this command accomplishes.", command)
+	userPrompt := prompt.BuildExplainPrompt(command)
 
 	openAIReq := openAIRequest{
 		Model: p.model,
 		Messages: []openAIMessage{
 			{
 				Role:    "system",
-				Content: "You are a helpful assistant that explains shell commands clearly and concisely.",
+				Content: explainSystemPrompt(),
 			},
 			{
 				Role:    "user",
-				Content: prompt,
+				Content: userPrompt,
 			},
 		},
 		MaxTokens:   300,
-		Temperature: 0.1,
+		Temperature: float64Ptr(0.1),
 		Stream:      false,
 	}
 
@@ -203,12 +266,13 @@ func (p *OpenAIProvider) ExplainCommand(ctx context.Context, command string) (*R
 		SetContext(ctx).
 		SetBody(openAIReq).
 		SetResult(&resp).
+		SetError(&resp).
 		Post(p.baseURL + "/chat/completions")
 
 	if err != nil {
 		return nil, &Error{
 			Type:    ErrorTypeNetwork,
-			Message: "Failed to call OpenAI API",
+			Message: fmt.Sprintf("Failed to call %s API", p.name),
 			Cause:   err,
 		}
 	}
@@ -220,10 +284,14 @@ func (p *OpenAIProvider) ExplainCommand(ctx context.Context, command string) (*R
 	if len(resp.Choices) == 0 {
 		return nil, &Error{
 			Type:    ErrorTypeModel,
-			Message: "No response from OpenAI",
+			Message: fmt.Sprintf("No response from %s", p.name),
 		}
 	}
 
+	if err := checkOpenAIChoice(p.name, resp.Choices[0]); err != nil {
+		return nil, err
+	}
+
 	return &Response{
 		Command:     command,
 		Explanation: strings.TrimSpace(resp.Choices[0].Message.Content),
@@ -236,74 +304,167 @@ func (p *OpenAIProvider) ExplainCommand(ctx context.Context, command string) (*R
 	}, nil
 }
 
+// openAIModelsResponse is the response from the /models endpoint
+type openAIModelsResponse struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+	Error *openAIError `json:"error,omitempty"`
+}
+
+// ListModels fetches the models available to the API key from /models
+func (p *OpenAIProvider) ListModels(ctx context.Context) ([]string, error) {
+	var resp openAIModelsResponse
+	restResp, err := p.client.R().
+		SetContext(ctx).
+		SetResult(&resp).
+		SetError(&resp).
+		Get(p.baseURL + "/models")
+
+	if err != nil {
+		return nil, &Error{
+			Type:    ErrorTypeNetwork,
+			Message: fmt.Sprintf("Failed to list %s models", p.name),
+			Cause:   err,
+		}
+	}
+
+	if restResp.IsError() {
+		return nil, p.handleAPIError(restResp, &openAIResponse{Error: resp.Error})
+	}
+
+	models := make([]string, 0, len(resp.Data))
+	for _, model := range resp.Data {
+		models = append(models, model.ID)
+	}
+	sort.Strings(models)
+
+	return models, nil
+}
+
 // GetProviderInfo returns information about the OpenAI provider
 func (p *OpenAIProvider) GetProviderInfo() ProviderInfo {
 	return ProviderInfo{
-		Name:    "OpenAI",
+		Name:    p.name,
 		Version: "1.0.0",
-		Models:  []string{"gpt-4", "gpt-4-turbo", "gpt-3.5-turbo"},
+		Models:  SupportedModels(p.providerType),
 		Capabilities: []string{
 			"command_generation",
 			"command_explanation",
 			"context_awareness",
 			"safety_filtering",
 		},
-		Limits: map[string]int{
-			"max_tokens":      4096,
-			"max_history":     10,
-			"timeout_seconds": 30,
-		},
+		Limits: providerLimits(p.providerType, p.model),
 		Metadata: map[string]string{
-			"provider": "openai",
+			"provider": p.providerType,
 			"model":    p.model,
 		},
 	}
 }
 
-// parseResponse extracts command, explanation, and danger assessment from the response
-func (p *OpenAIProvider) parseResponse(content string, includeExplanation bool) (command, explanation string, dangerLevel DangerLevel, dangerReason string) {
-	content = strings.TrimSpace(content)
-	lines := strings.Split(content, "\n")
-
-	// Default values
-	dangerLevel = DangerLevelSafe
-	dangerReason = "No specific assessment provided"
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		if strings.HasPrefix(line, "COMMAND:") {
-			command = strings.TrimSpace(strings.TrimPrefix(line, "COMMAND:"))
-		} else if strings.HasPrefix(line, "EXPLANATION:") && includeExplanation {
-			explanation = strings.TrimSpace(strings.TrimPrefix(line, "EXPLANATION:"))
-		} else if strings.HasPrefix(line, "DANGER_LEVEL:") {
-			levelStr := strings.TrimSpace(strings.TrimPrefix(line, "DANGER_LEVEL:"))
-			switch strings.ToLower(levelStr) {
-			case "safe":
-				dangerLevel = DangerLevelSafe
-			case "low":
-				dangerLevel = DangerLevelLow
-			case "medium":
-				dangerLevel = DangerLevelMedium
-			case "high":
-				dangerLevel = DangerLevelHigh
-			case "critical":
-				dangerLevel = DangerLevelCritical
-			}
-		} else if strings.HasPrefix(line, "DANGER_REASON:") {
-			dangerReason = strings.TrimSpace(strings.TrimPrefix(line, "DANGER_REASON:"))
+// checkOpenAIChoice turns a refusal, a content-filtered answer or an empty
+// answer from the named provider into an error, so an empty command never
+// looks like a success
+func checkOpenAIChoice(name string, choice openAIChoice) error {
+	if refusal := strings.TrimSpace(choice.Message.Refusal); refusal != "" {
+		return &Error{
+			Type:    ErrorTypeSafety,
+			Message: fmt.Sprintf("%s declined the request: %s", name, refusal),
+			Code:    "refusal",
 		}
 	}
 
-	// Fallback: if no structured response, treat whole content as command
-	if command == "" {
-		command = content
+	if choice.FinishReason == "content_filter" {
+		return &Error{
+			Type:    ErrorTypeSafety,
+			Message: fmt.Sprintf("Response was blocked by %s's content filter", name),
+			Code:    choice.FinishReason,
+		}
+	}
+
+	if strings.TrimSpace(choice.Message.Content) == "" {
+		return &Error{
+			Type:    ErrorTypeModel,
+			Message: fmt.Sprintf("%s returned an empty response (finish reason: %s)", name, choice.FinishReason),
+		}
+	}
+
+	return nil
+}
+
+// parseResponse extracts command, explanation, danger assessment and alternatives from the response
+func (p *OpenAIProvider) parseResponse(content string, includeExplanation bool) (command, explanation string, dangerLevel DangerLevel, dangerReason string, alternatives []string) {
+	parsed := prompt.ParseStructuredResponse(content, includeExplanation)
+	return parsed.Command, parsed.Explanation, ParseDangerLevel(parsed.DangerLevel), parsed.DangerReason, parsed.Alternatives
+}
+
+// supportsLogprobs reports whether requests should ask for token log
+// probabilities. OpenAI's reasoning models (o1, o3, ...) and the
+// OpenAI-compatible providers don't all support them.
+func (p *OpenAIProvider) supportsLogprobs() bool {
+	return p.providerType == "openai" && !strings.HasPrefix(p.model, "o")
+}
+
+// confidence estimates confidence from the command's token probabilities,
+// falling back to the finish reason when logprobs are unavailable
+func (p *OpenAIProvider) confidence(choice openAIChoice) float64 {
+	if choice.Logprobs != nil {
+		if confidence, ok := commandLogprobConfidence(choice.Logprobs.Content); ok {
+			return confidence
+		}
+	}
+	return p.calculateConfidence(choice.FinishReason)
+}
+
+// commandLogprobConfidence returns the average probability of the tokens on
+// the response's COMMAND: line. ok is false if the tokens have no command.
+func commandLogprobConfidence(tokens []openAITokenLogprob) (confidence float64, ok bool) {
+	// Rebuild the content from the tokens so their offsets line up
+	var content strings.Builder
+	offsets := make([]int, len(tokens))
+	for i, token := range tokens {
+		offsets[i] = content.Len()
+		content.WriteString(token.Token)
 	}
 
-	// Clean up command using centralized function
-	command = prompt.CleanCommand(command)
+	start, end := commandLineSpan(content.String())
+	if start >= end {
+		return 0, false
+	}
+
+	var sum float64
+	var count int
+	for i, token := range tokens {
+		tokenStart, tokenEnd := offsets[i], offsets[i]+len(token.Token)
+		if tokenEnd <= start || tokenStart >= end {
+			continue
+		}
+		sum += math.Exp(token.Logprob)
+		count++
+	}
+	if count == 0 {
+		return 0, false
+	}
 
-	return command, explanation, dangerLevel, dangerReason
+	return sum / float64(count), true
+}
+
+// commandLineSpan returns the byte range of the command after "COMMAND:" in
+// a structured response, or an empty range if there is none
+func commandLineSpan(content string) (start, end int) {
+	offset := 0
+	for _, line := range strings.SplitAfter(content, "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		if strings.HasPrefix(trimmed, "COMMAND:") {
+			valueStart := offset + len(line) - len(trimmed) + len("COMMAND:")
+			value := strings.TrimRight(content[valueStart:offset+len(line)], " \t\r\n")
+			trimmedValue := strings.TrimLeft(value, " \t")
+			start = valueStart + len(value) - len(trimmedValue)
+			return start, start + len(trimmedValue)
+		}
+		offset += len(line)
+	}
+	return 0, 0
 }
 
 // calculateConfidence estimates confidence based on finish reason
@@ -311,7 +472,7 @@ func (p *OpenAIProvider) calculateConfidence(finishReason string) float64 {
 	switch finishReason {
 	case "stop":
 		return 0.9
-	case "length":
+	case "length", "model_length":
 		return 0.7
 	case "content_filter":
 		return 0.3
@@ -322,6 +483,10 @@ func (p *OpenAIProvider) calculateConfidence(finishReason string) float64 {
 
 // handleAPIError converts OpenAI API errors to our error format
 func (p *OpenAIProvider) handleAPIError(resp *resty.Response, apiResp *openAIResponse) error {
+	if p.apiError != nil {
+		return p.apiError(resp)
+	}
+
 	if apiResp.Error != nil {
 		var errorType ErrorType
 		switch apiResp.Error.Type {
@@ -341,15 +506,15 @@ func (p *OpenAIProvider) handleAPIError(resp *resty.Response, apiResp *openAIRes
 			errorType = ErrorTypeUnknown
 		}
 
-		return &Error{
+		return withRetryHint(&Error{
 			Type:    errorType,
 			Message: apiResp.Error.Message,
 			Code:    apiResp.Error.Code,
-		}
+		}, resp)
 	}
 
-	return &Error{
+	return withRetryHint(&Error{
 		Type:    ErrorTypeNetwork,
 		Message: fmt.Sprintf("HTTP %d: %s", resp.StatusCode(), resp.String()),
-	}
+	}, resp)
 }
@@ -0,0 +1,88 @@
+package llm
+
+import (
+	"fmt"
+
+	"forgor/internal/config"
+	"forgor/internal/utils"
+)
+
+// ProfileRequestOptions returns request options using the profile's
+// max_tokens and temperature. An unset max_tokens uses
+// config.DefaultMaxTokens, and a value above the model's output limit is
+// lowered with a warning. A temperature of 0 is left to the provider.
+func ProfileRequestOptions(profile config.Profile) RequestOptions {
+	maxTokens := profile.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = config.DefaultMaxTokens
+	}
+
+	if clamped, lowered := ClampMaxTokens(profile.Provider, profile.Model, maxTokens); lowered {
+		utils.Warnf("%s max_tokens %d is above the %s limit; using %d\n",
+			utils.Styled("[WARNING]", utils.StyleWarning), maxTokens, profile.Model, clamped)
+		maxTokens = clamped
+	}
+
+	options := RequestOptions{MaxTokens: maxTokens}
+	// Profiles can't tell a temperature of 0 from none; --temperature 0 is
+	// sent as is
+	if profile.Temperature != 0 {
+		options.Temperature = float64Ptr(profile.Temperature)
+	}
+	return options
+}
+
+// RequestOverrides are token and temperature settings given for a single
+// invocation (--temperature, --max-tokens), which take precedence over the
+// profile's. Nil fields keep the profile's value.
+type RequestOverrides struct {
+	Temperature *float64
+	MaxTokens   *int
+}
+
+// Validate checks the overrides are in the ranges profiles accept
+func (o RequestOverrides) Validate() error {
+	if o.Temperature != nil && (*o.Temperature < 0 || *o.Temperature > 2) {
+		return fmt.Errorf("--temperature must be between 0 and 2, got %g", *o.Temperature)
+	}
+	if o.MaxTokens != nil && *o.MaxTokens <= 0 {
+		return fmt.Errorf("--max-tokens must be positive, got %d", *o.MaxTokens)
+	}
+	return nil
+}
+
+// Apply sets the overrides on options. As with profiles, max tokens above the
+// profile model's output limit are lowered with a warning.
+func (o RequestOverrides) Apply(options *RequestOptions, profile config.Profile) {
+	if o.Temperature != nil {
+		options.Temperature = float64Ptr(*o.Temperature)
+	}
+	if o.MaxTokens != nil {
+		maxTokens := *o.MaxTokens
+		if clamped, lowered := ClampMaxTokens(profile.Provider, profile.Model, maxTokens); lowered {
+			utils.Warnf("%s --max-tokens %d is above the %s limit; using %d\n",
+				utils.Styled("[WARNING]", utils.StyleWarning), maxTokens, profile.Model, clamped)
+			maxTokens = clamped
+		}
+		options.MaxTokens = maxTokens
+	}
+}
+
+// DefaultAPIKeyEnv returns the environment variable conventionally holding
+// the API key for a provider type, or "" if the provider doesn't use one
+func DefaultAPIKeyEnv(providerType string) string {
+	switch normalizeProviderType(providerType) {
+	case "openai":
+		return "OPENAI_API_KEY"
+	case "anthropic":
+		return "ANTHROPIC_API_KEY"
+	case "gemini":
+		return "GOOGLE_AI_API_KEY"
+	case "groq":
+		return "GROQ_API_KEY"
+	case "mistral":
+		return "MISTRAL_API_KEY"
+	default:
+		return ""
+	}
+}
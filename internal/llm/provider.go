@@ -2,7 +2,9 @@ package llm
 
 import (
 	"context"
+	"errors"
 	"forgor/internal/history"
+	"strings"
 )
 
 // Provider defines the interface for LLM providers
@@ -17,6 +19,13 @@ type Provider interface {
 	GetProviderInfo() ProviderInfo
 }
 
+// ModelLister is implemented by providers that can fetch their available
+// models from the provider's API
+type ModelLister interface {
+	// ListModels returns the model IDs available to the configured API key
+	ListModels(ctx context.Context) ([]string, error)
+}
+
 // Request represents a query to the LLM
 type Request struct {
 	// The user's natural language query
@@ -46,6 +55,12 @@ type Context struct {
 	// Additional context from user
 	UserContext string `json:"user_context,omitempty"`
 
+	// Project type detected in the working directory, e.g. "Go project in a git repository"
+	ProjectType string `json:"project_type,omitempty"`
+
+	// Git branch and dirty/clean summary, e.g. "branch main, 2 uncommitted changes"
+	GitStatus string `json:"git_status,omitempty"`
+
 	// System architecture
 	Architecture string `json:"architecture,omitempty"`
 
@@ -91,8 +106,9 @@ type RequestOptions struct {
 	// Maximum tokens to generate
 	MaxTokens int `json:"max_tokens,omitempty"`
 
-	// Temperature for randomness (0.0 to 1.0)
-	Temperature float64 `json:"temperature,omitempty"`
+	// Temperature for randomness (0.0 to 2.0); nil leaves it to the
+	// provider's default, while 0 is sent as is
+	Temperature *float64 `json:"temperature,omitempty"`
 
 	// Whether to include explanations
 	IncludeExplanation bool `json:"include_explanation,omitempty"`
@@ -124,6 +140,14 @@ type Response struct {
 	// Safety warnings
 	Warnings []string `json:"warnings,omitempty"`
 
+	// Danger assessment of the command, so tools consuming JSON output can
+	// refuse to run anything above a chosen level
+	Safety *DangerAssessment `json:"safety,omitempty"`
+
+	// Blocked is set when safe mode refused the command for being above the
+	// allowed danger level
+	Blocked bool `json:"blocked,omitempty"`
+
 	// Provider-specific metadata
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
 
@@ -160,6 +184,22 @@ func GetDangerLevelValue(level DangerLevel) int {
 	}
 }
 
+// ParseDangerLevel converts a danger level string to a DangerLevel, defaulting to safe
+func ParseDangerLevel(level string) DangerLevel {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "low":
+		return DangerLevelLow
+	case "medium":
+		return DangerLevelMedium
+	case "high":
+		return DangerLevelHigh
+	case "critical":
+		return DangerLevelCritical
+	default:
+		return DangerLevelSafe
+	}
+}
+
 // IsAtLeastLevel checks if the current danger level is at least the specified level
 func (d DangerLevel) IsAtLeastLevel(level DangerLevel) bool {
 	return GetDangerLevelValue(d) >= GetDangerLevelValue(level)
@@ -210,6 +250,16 @@ func (e *Error) Unwrap() error {
 	return e.Cause
 }
 
+// IsUnreachable reports whether err means the provider couldn't be reached
+// at all, as opposed to the provider answering with an error
+func IsUnreachable(err error) bool {
+	var llmErr *Error
+	if !errors.As(err, &llmErr) {
+		return false
+	}
+	return llmErr.Type == ErrorTypeTimeout || (llmErr.Type == ErrorTypeNetwork && llmErr.Cause != nil)
+}
+
 // ErrorType represents different types of LLM errors
 type ErrorType string
 
@@ -223,4 +273,5 @@ const (
 	ErrorTypeModel        ErrorType = "model"         // Model-specific errors
 	ErrorTypeUnknown      ErrorType = "unknown"       // Unknown errors
 	ErrorTypeSafety       ErrorType = "safety"        // Safety/content filtering errors
+	ErrorTypeEmptyCommand ErrorType = "empty_command" // The response contained no command
 )
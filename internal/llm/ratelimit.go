@@ -0,0 +1,91 @@
+package llm
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// rateLimitResetHeaders are checked in order for a hint on when to retry
+var rateLimitResetHeaders = []string{
+	"Retry-After",
+	"X-Ratelimit-Reset",
+	"X-Ratelimit-Reset-Requests",
+	"X-Ratelimit-Reset-Tokens",
+	"Anthropic-Ratelimit-Requests-Reset",
+	"Anthropic-Ratelimit-Tokens-Reset",
+}
+
+// RetryAfter extracts the suggested wait time from rate limit response headers.
+// It understands delay seconds ("30"), Go durations ("6m0s", as sent by OpenAI),
+// Unix timestamps, HTTP dates and RFC 3339 timestamps. It returns 0 if no usable
+// header is present.
+func RetryAfter(header http.Header, now time.Time) time.Duration {
+	for _, name := range rateLimitResetHeaders {
+		value := strings.TrimSpace(header.Get(name))
+		if value == "" {
+			continue
+		}
+		if wait := parseResetValue(value, now); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// parseResetValue converts a single reset header value to a wait duration
+func parseResetValue(value string, now time.Time) time.Duration {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		// Large values are Unix timestamps rather than delays
+		if seconds > 1e9 {
+			return time.Unix(int64(seconds), 0).Sub(now)
+		}
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	if d, err := time.ParseDuration(value); err == nil {
+		return d
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.Sub(now)
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		return t.Sub(now)
+	}
+
+	return 0
+}
+
+// FormatRetryHint returns a human-friendly hint such as "retry in 12s"
+func FormatRetryHint(wait time.Duration) string {
+	if wait <= 0 {
+		return ""
+	}
+	if wait < time.Minute {
+		return fmt.Sprintf("retry in %ds", int(math.Ceil(wait.Seconds())))
+	}
+	return fmt.Sprintf("retry in %s", wait.Round(time.Second))
+}
+
+// withRetryHint marks 429 responses as rate limit errors and appends the
+// suggested wait time from the response headers to the error message
+func withRetryHint(apiErr *Error, resp *resty.Response) *Error {
+	if resp.StatusCode() == http.StatusTooManyRequests {
+		apiErr.Type = ErrorTypeRateLimit
+	}
+	if apiErr.Type != ErrorTypeRateLimit {
+		return apiErr
+	}
+
+	if hint := FormatRetryHint(RetryAfter(resp.Header(), time.Now())); hint != "" {
+		apiErr.Message = fmt.Sprintf("%s (%s)", apiErr.Message, hint)
+	}
+	return apiErr
+}
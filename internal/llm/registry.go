@@ -0,0 +1,119 @@
+package llm
+
+import (
+	"fmt"
+	"sync"
+
+	"forgor/internal/config"
+)
+
+// ProviderFactory creates a provider for a profile. The profile's API key
+// (including api_key_file) and environment references are resolved first.
+// opts carry the Factory's settings, such as its proxy, debug log, request
+// defaults and system prompt prefix/suffix; pass them on to the provider's
+// constructor, e.g. NewOpenAIProvider for an OpenAI-compatible API.
+type ProviderFactory func(profile config.Profile, opts ...ProviderOption) (Provider, error)
+
+var (
+	registryMutex sync.RWMutex
+	registry      = make(map[string]ProviderFactory)
+	// registryOrder keeps GetSupportedProviders in registration order
+	registryOrder []string
+	// thirdParty holds the names added with RegisterProvider, the only ones
+	// that may be replaced or removed
+	thirdParty = make(map[string]bool)
+)
+
+func init() {
+	registerProvider("openai", true, func(profile config.Profile, opts ...ProviderOption) (Provider, error) {
+		return NewOpenAIProvider(profile.APIKey, profile.Model, opts...), nil
+	})
+	registerProvider("anthropic", true, func(profile config.Profile, opts ...ProviderOption) (Provider, error) {
+		return NewAnthropicProvider(profile.APIKey, profile.Model, opts...), nil
+	})
+	gemini := func(profile config.Profile, opts ...ProviderOption) (Provider, error) {
+		return NewGeminiProvider(profile.APIKey, profile.Model, opts...), nil
+	}
+	registerProvider("gemini", true, gemini)
+	registerProvider("google", true, gemini)
+	registerProvider("groq", true, func(profile config.Profile, opts ...ProviderOption) (Provider, error) {
+		return NewGroqProvider(profile.APIKey, profile.Model, opts...), nil
+	})
+	registerProvider("mistral", true, func(profile config.Profile, opts ...ProviderOption) (Provider, error) {
+		return NewMistralProvider(profile.APIKey, profile.Model, opts...), nil
+	})
+	registerProvider("ollama", false, func(profile config.Profile, opts ...ProviderOption) (Provider, error) {
+		return NewOllamaProvider(profile.Endpoint, profile.Model, opts...), nil
+	})
+}
+
+// RegisterProvider makes name usable as a profile's provider, created with
+// factory. Registering a name again replaces its factory, but built-in
+// providers can't be replaced. Profiles for these providers don't require an
+// API key; factory should check for one if needed.
+func RegisterProvider(name string, factory ProviderFactory) {
+	if name == "" || factory == nil {
+		panic("llm: RegisterProvider requires a name and a factory")
+	}
+
+	registryMutex.Lock()
+	_, exists := registry[name]
+	builtIn := exists && !thirdParty[name]
+	if !builtIn {
+		thirdParty[name] = true
+	}
+	registryMutex.Unlock()
+	if builtIn {
+		panic(fmt.Sprintf("llm: RegisterProvider cannot replace the built-in provider %q", name))
+	}
+
+	registerProvider(name, false, factory)
+}
+
+// UnregisterProvider removes a provider added with RegisterProvider. Built-in
+// providers are left in place.
+func UnregisterProvider(name string) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if !thirdParty[name] {
+		return
+	}
+	delete(thirdParty, name)
+	delete(registry, name)
+	for i, registered := range registryOrder {
+		if registered == name {
+			registryOrder = append(registryOrder[:i:i], registryOrder[i+1:]...)
+			break
+		}
+	}
+	config.UnregisterProviderType(name)
+}
+
+// registerProvider adds a provider type to the registry and to the types
+// profiles may use
+func registerProvider(name string, requiresAPIKey bool, factory ProviderFactory) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if _, exists := registry[name]; !exists {
+		registryOrder = append(registryOrder, name)
+	}
+	registry[name] = factory
+	config.RegisterProviderType(name, requiresAPIKey)
+}
+
+// lookupProvider returns the factory registered for a provider type
+func lookupProvider(name string) (ProviderFactory, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	factory, ok := registry[name]
+	return factory, ok
+}
+
+// registeredProviders returns the registered provider types in registration order
+func registeredProviders() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	return append([]string(nil), registryOrder...)
+}
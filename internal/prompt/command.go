@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"forgor/internal/history"
 	"strings"
+	"sync"
 )
 
 // Request represents a command generation request
@@ -24,17 +25,134 @@ type RequestContext struct {
 type RequestOptions struct {
 	IncludeExplanation bool
 	MaxTokens          int
-	Temperature        float64
+	Temperature        *float64
+	SafetyLevel        string
 }
 
-func formatHistoryForPrompt(historyEntries []history.HistoryEntry) string {
+// Safety levels controlling how cautious generated commands are
+const (
+	SafetyLevelStrict     = "strict"     // Refuse destructive operations
+	SafetyLevelModerate   = "moderate"   // Default: destructive operations only when explicitly asked
+	SafetyLevelPermissive = "permissive" // Allow destructive operations, with warnings
+)
+
+// ParseSafetyLevel validates a safety level, defaulting to moderate when empty
+func ParseSafetyLevel(level string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "", SafetyLevelModerate:
+		return SafetyLevelModerate, nil
+	case SafetyLevelStrict:
+		return SafetyLevelStrict, nil
+	case SafetyLevelPermissive:
+		return SafetyLevelPermissive, nil
+	default:
+		return "", fmt.Errorf("invalid safety level: %s. Valid levels: %s, %s, %s",
+			level, SafetyLevelStrict, SafetyLevelModerate, SafetyLevelPermissive)
+	}
+}
+
+// safetyInstructions returns the generation guidance for a safety level
+func safetyInstructions(level string) string {
+	switch level {
+	case SafetyLevelStrict:
+		return "\nSafety: STRICT. Never generate destructive or irreversible commands (deleting files, overwriting data, formatting disks, force-pushing, killing processes, changing permissions recursively). If the request needs one, return a safe read-only or dry-run alternative instead and explain why in DANGER_REASON."
+	case SafetyLevelPermissive:
+		return "\nSafety: PERMISSIVE. Destructive commands are allowed when they directly answer the request, but always set DANGER_LEVEL and DANGER_REASON accurately so the user is warned."
+	default:
+		return "\nSafety: MODERATE. Prefer non-destructive commands and only use destructive operations when the request explicitly asks for them."
+	}
+}
+
+// MaxHistoryCommandChars is the default cap on a single history command in
+// the prompt
+const MaxHistoryCommandChars = 300
+
+var (
+	historyLimitMutex   sync.RWMutex
+	historyCommandLimit = MaxHistoryCommandChars
+)
+
+// SetHistoryCommandLimit sets how many characters of a single history
+// command are sent, from history.max_command_chars. A limit of 0 sends
+// commands in full.
+func SetHistoryCommandLimit(limit int) {
+	historyLimitMutex.Lock()
+	defer historyLimitMutex.Unlock()
+	historyCommandLimit = limit
+}
+
+// getHistoryCommandLimit returns the per-command history limit
+func getHistoryCommandLimit() int {
+	historyLimitMutex.RLock()
+	defer historyLimitMutex.RUnlock()
+	return historyCommandLimit
+}
+
+// LimitHistory trims history entries to fit within maxChars characters of
+// commands. Commands longer than the per-command limit (MaxHistoryCommandChars
+// unless changed with SetHistoryCommandLimit) are shortened on a character
+// boundary with an ellipsis, then the oldest entries are dropped until the
+// rest fit, so the most recent entries are kept. A maxChars of 0 or less only
+// applies the per-command limit.
+func LimitHistory(historyEntries []history.HistoryEntry, maxChars int) []history.HistoryEntry {
+	commandLimit := getHistoryCommandLimit()
+	limited := make([]history.HistoryEntry, len(historyEntries))
+	for i, entry := range historyEntries {
+		if commandLimit > 0 {
+			entry.Command = truncateWithEllipsis(entry.Command, commandLimit)
+		}
+		limited[i] = entry
+	}
+
+	if maxChars <= 0 {
+		return limited
+	}
+
+	// Walk back from the most recent entry until the budget runs out
+	used := 0
+	start := len(limited)
+	for start > 0 {
+		length := len([]rune(limited[start-1].Command))
+		if used+length > maxChars {
+			break
+		}
+		used += length
+		start--
+	}
+
+	// Keep a shortened most recent command rather than no history at all
+	if start == len(limited) && len(limited) > 0 {
+		last := limited[len(limited)-1]
+		last.Command = truncateWithEllipsis(last.Command, maxChars)
+		return []history.HistoryEntry{last}
+	}
+
+	return limited[start:]
+}
+
+// truncateWithEllipsis shortens s to at most limit runes, ending in "…"
+func truncateWithEllipsis(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	if limit <= 1 {
+		return "…"
+	}
+	return string(runes[:limit-1]) + "…"
+}
+
+// formatHistoryForPrompt lists recent commands and their exit status,
+// collapsing repeated commands and shortening very long ones. Commands run
+// outside workingDirectory note where they ran.
+func formatHistoryForPrompt(historyEntries []history.HistoryEntry, workingDirectory string) string {
 	if len(historyEntries) == 0 {
 		return ""
 	}
 
 	var parts []string
 	parts = append(parts, "\n\nHere is the recent command history (most recent last):")
-	for _, entry := range historyEntries {
+	for _, entry := range LimitHistory(history.CollapseDuplicates(historyEntries), 0) {
 		status := ""
 		if entry.ExitCode > 0 {
 			status = fmt.Sprintf(" (FAILED with exit code %d)", entry.ExitCode)
@@ -42,6 +160,12 @@ func formatHistoryForPrompt(historyEntries []history.HistoryEntry) string {
 			status = " (SUCCESS)"
 		}
 		// If ExitCode is -1 (unknown), no status is added.
+		if entry.Count > 1 {
+			status += fmt.Sprintf(" ×%d", entry.Count)
+		}
+		if entry.WorkingDirectory != "" && workingDirectory != "" && entry.WorkingDirectory != workingDirectory {
+			status += fmt.Sprintf(" (run in %s)", entry.WorkingDirectory)
+		}
 		parts = append(parts, fmt.Sprintf("- `%s`%s", entry.Command, status))
 	}
 	parts = append(parts, "\n\nPay special attention to any FAILED commands and try to fix them based on the user's request.")
@@ -60,7 +184,7 @@ func BuildCommandPrompt(request *Request) string {
 	}
 
 	// Add command history if available
-	parts = append(parts, formatHistoryForPrompt(request.Context.History))
+	parts = append(parts, formatHistoryForPrompt(request.Context.History, request.Context.WorkingDirectory))
 
 	// Add user context if provided
 	if request.Context.UserContext != "" {
@@ -70,11 +194,10 @@ func BuildCommandPrompt(request *Request) string {
 	return strings.Join(parts, "\n")
 }
 
-// BuildOpenAICommandPrompt builds the OpenAI-specific command prompt with structured output
-func BuildOpenAICommandPrompt(request *Request) string {
-	basePrompt := BuildCommandPrompt(request)
+// buildStructuredCommandPrompt appends the structured response format shared by all providers
+func buildStructuredCommandPrompt(request *Request) string {
+	basePrompt := BuildCommandPrompt(request) + "\n" + safetyInstructions(request.Options.SafetyLevel)
 
-	// Add OpenAI-specific response format instructions
 	var formatParts []string
 	formatParts = append(formatParts, "\nPlease respond in this exact format:")
 	formatParts = append(formatParts, "COMMAND: [the shell command]")
@@ -85,34 +208,143 @@ func BuildOpenAICommandPrompt(request *Request) string {
 
 	formatParts = append(formatParts, "DANGER_LEVEL: [safe/low/medium/high/critical]")
 	formatParts = append(formatParts, "DANGER_REASON: [reason for the danger level assessment]")
+	formatParts = append(formatParts, fmt.Sprintf("ALTERNATIVE: [a different command that also answers the request; repeat this line for up to %d alternatives, or omit it]", MaxAlternatives))
 
 	return basePrompt + strings.Join(formatParts, "\n")
 }
 
-// BuildAnthropicCommandPrompt builds the Anthropic-specific command prompt
+// BuildOpenAICommandPrompt builds the OpenAI-specific command prompt with structured output
+func BuildOpenAICommandPrompt(request *Request) string {
+	return buildStructuredCommandPrompt(request)
+}
+
+// BuildAnthropicCommandPrompt builds the Anthropic-specific command prompt with structured output
 func BuildAnthropicCommandPrompt(request *Request) string {
-	basePrompt := BuildCommandPrompt(request)
+	return buildStructuredCommandPrompt(request)
+}
 
-	// Add Anthropic-specific response format instructions
-	if request.Options.IncludeExplanation {
-		basePrompt += "\nRespond with the command followed by a brief explanation separated by '||'."
-	} else {
-		basePrompt += "\nRespond with only the shell command, no explanation."
+// BuildGeminiCommandPrompt builds the Gemini-specific command prompt with structured output
+func BuildGeminiCommandPrompt(request *Request) string {
+	return buildStructuredCommandPrompt(request)
+}
+
+// BuildOllamaCommandPrompt builds the Ollama-specific command prompt with structured output
+func BuildOllamaCommandPrompt(request *Request) string {
+	return buildStructuredCommandPrompt(request)
+}
+
+// MaxAlternatives caps how many alternative commands are requested and kept
+const MaxAlternatives = 3
+
+// StructuredResponse holds the fields parsed from a structured LLM response
+type StructuredResponse struct {
+	Command      string
+	Explanation  string
+	DangerLevel  string
+	DangerReason string
+	Alternatives []string
+}
+
+// responseFields are the markers that start a field of a structured response
+var responseFields = []string{"COMMAND:", "EXPLANATION:", "DANGER_LEVEL:", "DANGER_REASON:", "ALTERNATIVE:"}
+
+// ParseStructuredResponse extracts the command, explanation and danger assessment
+// from a response in the COMMAND/EXPLANATION/DANGER_LEVEL/DANGER_REASON format,
+// along with any ALTERNATIVE lines. A command continues onto the following
+// lines only inside a ``` fence or after a line ending in a continuation
+// (\, &&, |), and outside a fence never past a blank line, so prose after
+// the command is not mistaken for more steps.
+// Responses that ignore the format are treated as a bare command. Nothing is
+// split on "||", since commands use it for shell OR.
+func ParseStructuredResponse(content string, includeExplanation bool) StructuredResponse {
+	content = strings.TrimSpace(content)
+	parsed := StructuredResponse{
+		DangerReason: "No specific assessment provided",
+	}
+
+	inCommand, inFence := false, false
+	for _, rawLine := range strings.Split(content, "\n") {
+		line := strings.TrimSpace(rawLine)
+
+		if inCommand && !isResponseField(line) {
+			// A command may also start on the line after an empty COMMAND:
+			continues := inFence || (line != "" && (parsed.Command == "" || endsWithContinuation(parsed.Command)))
+			if continues {
+				parsed.Command = strings.TrimLeft(parsed.Command+"\n"+strings.TrimRight(rawLine, " \t\r"), "\n")
+				if strings.Count(line, "```")%2 == 1 {
+					inFence = !inFence
+					// The command ends with its closing fence
+					inCommand = inFence
+				}
+				continue
+			}
+		}
+		inCommand, inFence = false, false
+
+		if strings.HasPrefix(line, "COMMAND:") {
+			parsed.Command = strings.TrimSpace(strings.TrimPrefix(line, "COMMAND:"))
+			inCommand = true
+			inFence = strings.Count(parsed.Command, "```")%2 == 1
+		} else if strings.HasPrefix(line, "EXPLANATION:") && includeExplanation {
+			parsed.Explanation = strings.TrimSpace(strings.TrimPrefix(line, "EXPLANATION:"))
+		} else if strings.HasPrefix(line, "DANGER_LEVEL:") {
+			parsed.DangerLevel = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(line, "DANGER_LEVEL:")))
+		} else if strings.HasPrefix(line, "DANGER_REASON:") {
+			parsed.DangerReason = strings.TrimSpace(strings.TrimPrefix(line, "DANGER_REASON:"))
+		} else if strings.HasPrefix(line, "ALTERNATIVE:") {
+			parsed.Alternatives = append(parsed.Alternatives, strings.TrimSpace(strings.TrimPrefix(line, "ALTERNATIVE:")))
+		}
 	}
 
-	return basePrompt
+	// Fallback: if no structured response, use the whole content
+	parsed.Command = strings.TrimSpace(parsed.Command)
+	if parsed.Command == "" {
+		parsed.Command = content
+	}
+
+	// Clean up command using centralized function
+	parsed.Command = CleanCommand(parsed.Command)
+	parsed.Alternatives = cleanAlternatives(parsed.Alternatives, parsed.Command)
+
+	return parsed
 }
 
-// BuildGeminiCommandPrompt builds the Gemini-specific command prompt
-func BuildGeminiCommandPrompt(request *Request) string {
-	basePrompt := BuildCommandPrompt(request)
+// endsWithContinuation reports whether command's last line continues onto
+// the next one, with a backslash or a trailing && or pipe
+func endsWithContinuation(command string) bool {
+	command = strings.TrimRight(command, " \t\r")
+	return strings.HasSuffix(command, "\\") || strings.HasSuffix(command, "&&") || strings.HasSuffix(command, "|")
+}
 
-	// Add Gemini-specific response format instructions (same as Anthropic)
-	if request.Options.IncludeExplanation {
-		basePrompt += "\nRespond with the command followed by a brief explanation separated by '||'."
-	} else {
-		basePrompt += "\nRespond with only the shell command, no explanation."
+// isResponseField reports whether line starts a field of a structured response
+func isResponseField(line string) bool {
+	for _, field := range responseFields {
+		if strings.HasPrefix(line, field) {
+			return true
+		}
 	}
+	return false
+}
 
-	return basePrompt
+// cleanAlternatives cleans alternative commands, dropping empty ones,
+// placeholders like "none" and repeats of the primary command
+func cleanAlternatives(alternatives []string, command string) []string {
+	var cleaned []string
+	seen := map[string]bool{command: true}
+	for _, alternative := range alternatives {
+		alternative = CleanCommand(alternative)
+		switch strings.ToLower(alternative) {
+		case "", "none", "n/a":
+			continue
+		}
+		if seen[alternative] {
+			continue
+		}
+		seen[alternative] = true
+		cleaned = append(cleaned, alternative)
+		if len(cleaned) == MaxAlternatives {
+			break
+		}
+	}
+	return cleaned
 }
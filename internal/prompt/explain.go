@@ -0,0 +1,48 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// defaultExplainInstruction is the built-in system instruction for explaining commands
+const defaultExplainInstruction = "You are a helpful assistant that explains shell commands clearly and concisely."
+
+var (
+	explainMutex       sync.RWMutex
+	explainInstruction string
+)
+
+// SetExplainInstruction replaces the built-in instruction used when
+// explaining commands. Passing "" restores the default.
+func SetExplainInstruction(instruction string) {
+	explainMutex.Lock()
+	defer explainMutex.Unlock()
+	explainInstruction = strings.TrimSpace(instruction)
+}
+
+// GetExplainSystemPrompt returns the system prompt for explaining commands.
+// The user's OS and shell are always included so flags are explained as they
+// behave on that platform.
+func GetExplainSystemPrompt(context Context) string {
+	explainMutex.RLock()
+	instruction := explainInstruction
+	explainMutex.RUnlock()
+
+	if instruction == "" {
+		instruction = defaultExplainInstruction
+	}
+
+	platform := context.OS
+	if context.Architecture != "" {
+		platform += fmt.Sprintf(" (%s architecture)", context.Architecture)
+	}
+	return fmt.Sprintf("%s\n\nThe user runs commands on %s using %s. Explain flags and behavior as they apply on this platform, and point out when they differ elsewhere.",
+		instruction, platform, context.Shell)
+}
+
+// BuildExplainPrompt constructs the user prompt for explaining a command
+func BuildExplainPrompt(command string) string {
+	return fmt.Sprintf("Explain what this shell command does:\n\n%s\n\nProvide a clear, concise explanation of what this command accomplishes.", command)
+}
@@ -3,11 +3,39 @@ package prompt
 import (
 	"fmt"
 	"strings"
+	"sync"
 )
 
-// CheckCommandSafety performs basic safety checks on commands
+var (
+	safetyMutex   sync.RWMutex
+	safetyChecker func(command string) []string
+)
+
+// SetSafetyChecker makes CheckCommandSafety use check, e.g. the security
+// package's danger detector, so every provider warns the same way. Passing
+// nil restores the built-in pattern list.
+func SetSafetyChecker(check func(command string) []string) {
+	safetyMutex.Lock()
+	defer safetyMutex.Unlock()
+	safetyChecker = check
+}
+
+// CheckCommandSafety returns warnings for a generated command, using the
+// checker set with SetSafetyChecker or a basic built-in pattern list.
 // This replaces the duplicated checkSafety functions in each provider
 func CheckCommandSafety(command string) []string {
+	safetyMutex.RLock()
+	check := safetyChecker
+	safetyMutex.RUnlock()
+
+	if check != nil {
+		return check(command)
+	}
+	return checkBuiltinPatterns(command)
+}
+
+// checkBuiltinPatterns performs basic safety checks on commands
+func checkBuiltinPatterns(command string) []string {
 	var warnings []string
 	cmd := strings.ToLower(command)
 
@@ -32,16 +60,100 @@ func CheckCommandSafety(command string) []string {
 	return warnings
 }
 
-// CleanCommand removes common code block markers from command strings
+// CleanCommand extracts the bare command from raw LLM output.
+// If the output contains a fenced code block, the first block is used (ignoring
+// any language tag and surrounding prose). Otherwise surrounding prose such as
+// "Here is the command:" and wrapping backticks are stripped. Leading shell
+// prompt characters ("$ ", "# ") are removed as well.
 // This is used by response parsers to clean up LLM output
 func CleanCommand(command string) string {
-	// Remove code block markers if present
-	command = strings.TrimPrefix(command, "```bash")
-	command = strings.TrimPrefix(command, "```sh")
-	command = strings.TrimPrefix(command, "```shell")
-	command = strings.TrimPrefix(command, "```")
-	command = strings.TrimSuffix(command, "```")
 	command = strings.TrimSpace(command)
 
-	return command
+	if strings.Contains(command, "```") {
+		command = extractFencedBlock(command)
+	} else {
+		command = stripSurroundingProse(command)
+	}
+
+	return stripPromptPrefix(strings.TrimSpace(command))
+}
+
+// stripPromptPrefix removes shell prompt characters that LLMs sometimes prepend,
+// such as "$ ls" or "# apt update". A "$ " prefix is never valid shell so it is
+// removed from every line; "# " is only removed from single-line commands so
+// comments in scripts and shebangs ("#!") are preserved.
+func stripPromptPrefix(command string) string {
+	lines := strings.Split(command, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		if strings.HasPrefix(trimmed, "$ ") {
+			lines[i] = strings.TrimSpace(strings.TrimPrefix(trimmed, "$ "))
+		}
+	}
+
+	if len(lines) == 1 && strings.HasPrefix(lines[0], "# ") {
+		lines[0] = strings.TrimSpace(strings.TrimPrefix(lines[0], "# "))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// extractFencedBlock returns the contents of the first ``` fenced block in text
+func extractFencedBlock(text string) string {
+	start := strings.Index(text, "```")
+	rest := text[start+3:]
+
+	// Inline fence on a single line, e.g. ```ls -la```
+	firstLine, body, hasNewline := strings.Cut(rest, "\n")
+	if end := strings.Index(firstLine, "```"); end != -1 {
+		return firstLine[:end]
+	}
+
+	// A lone word after the opening fence is a language tag (```bash, ```sh, ...)
+	if trimmed := strings.TrimSpace(firstLine); trimmed != "" && strings.ContainsAny(trimmed, " \t") {
+		// Not a language tag, the command starts on the fence line itself
+		body = firstLine + "\n" + body
+	}
+	if !hasNewline {
+		return ""
+	}
+
+	var lines []string
+	for _, line := range strings.Split(body, "\n") {
+		if idx := strings.Index(line, "```"); idx != -1 && strings.TrimSpace(line[:idx]) == "" {
+			break // closing fence
+		} else if idx != -1 {
+			lines = append(lines, line[:idx]) // closing fence at the end of a line
+			break
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// stripSurroundingProse removes explanatory lead-ins and wrapping backticks from a command
+func stripSurroundingProse(text string) string {
+	lines := strings.Split(text, "\n")
+
+	// Drop a lead-in line such as "Here is the command:" when a command follows it
+	if len(lines) > 1 && strings.HasSuffix(strings.TrimSpace(lines[0]), ":") {
+		text = strings.TrimSpace(strings.Join(lines[1:], "\n"))
+	}
+
+	// Whole command wrapped in inline code: `ls -la`
+	if len(text) > 1 && strings.HasPrefix(text, "`") && strings.HasSuffix(text, "`") && strings.Count(text, "`") == 2 {
+		return text[1 : len(text)-1]
+	}
+
+	// Single-line lead-in with an inline code span: Run this: `git status`
+	if strings.Count(text, "`") == 2 {
+		open := strings.Index(text, "`")
+		if strings.HasSuffix(strings.TrimSpace(text[:open]), ":") {
+			closing := strings.LastIndex(text, "`")
+			return text[open+1 : closing]
+		}
+	}
+
+	return text
 }
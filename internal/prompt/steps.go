@@ -0,0 +1,81 @@
+package prompt
+
+import (
+	"regexp"
+	"strings"
+)
+
+// heredocPattern matches a here-document operator such as <<EOF, <<-EOF or <<'EOF'
+var heredocPattern = regexp.MustCompile(`<<-?\s*['"]?([A-Za-z_][A-Za-z0-9_]*)['"]?`)
+
+// compoundKeywords start shell constructs that span several lines and must be
+// run as a single script rather than split into steps
+var compoundKeywords = []string{"if", "for", "while", "until", "case", "select", "function"}
+
+// SplitCommandSteps splits multi-line command output into individual steps.
+// Blank lines and comments are dropped. Lines continued with a backslash, &&
+// or a pipe, and here-documents, are kept together with the line that starts
+// them. If the output contains multi-line constructs (if/for/while/functions),
+// it is returned as a single step so it can be run as one script.
+func SplitCommandSteps(command string) []string {
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return nil
+	}
+
+	lines := strings.Split(command, "\n")
+	var steps []string
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if isCompoundStart(line) {
+			return []string{command}
+		}
+
+		step := line
+
+		// Join line continuations: a trailing backslash, && or pipe
+		for endsWithContinuation(step) && i+1 < len(lines) {
+			i++
+			step += "\n" + lines[i]
+		}
+
+		// Keep here-document bodies with their command
+		if match := heredocPattern.FindStringSubmatch(step); match != nil && !strings.Contains(step, "<<<") {
+			delimiter := match[1]
+			for i+1 < len(lines) {
+				i++
+				step += "\n" + lines[i]
+				if strings.TrimSpace(lines[i]) == delimiter {
+					break
+				}
+			}
+		}
+
+		steps = append(steps, step)
+	}
+
+	return steps
+}
+
+// isCompoundStart checks if a line opens a multi-line shell construct
+func isCompoundStart(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false
+	}
+
+	for _, keyword := range compoundKeywords {
+		if fields[0] == keyword {
+			return true
+		}
+	}
+
+	// Function definitions like "name() {" or a dangling block opener
+	last := fields[len(fields)-1]
+	return strings.HasSuffix(fields[0], "()") || last == "{" || last == "then" || last == "do"
+}
@@ -2,7 +2,11 @@ package prompt
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
+
+	"forgor/internal/utils"
 )
 
 // Context represents the system context for prompt generation
@@ -12,15 +16,53 @@ type Context struct {
 	Architecture     string
 	User             string
 	WorkingDirectory string
+	ProjectType      string
+	GitStatus        string
 	ToolsSummary     string
 	PackageManagers  []string
 	Languages        []string
 	ContainerTools   []string
 	CloudTools       []string
+
+	// Environment holds selected environment variables, only set when the
+	// user opts in with --include-env
+	Environment map[string]string
+
+	// Extra per-profile text placed before and after the system prompt
+	SystemPromptPrefix string
+	SystemPromptSuffix string
 }
 
-// GetSystemPrompt returns the enhanced system prompt for command generation
+// GetSystemPrompt returns the enhanced system prompt for command generation.
+// If a custom template is set, it is rendered instead; a template that fails
+// to render falls back to the built-in prompt. The context's prefix and
+// suffix are added around either prompt.
 func GetSystemPrompt(context Context) string {
+	systemPrompt := ""
+	if tmpl := getSystemTemplate(); tmpl != nil {
+		rendered, err := RenderSystemTemplate(tmpl, context)
+		if err == nil {
+			systemPrompt = rendered
+		} else {
+			utils.Warnf("%s %v. Using the built-in prompt\n", utils.Styled("[WARNING]", utils.StyleWarning), err)
+		}
+	}
+	if systemPrompt == "" {
+		systemPrompt = builtinSystemPrompt(context)
+	}
+
+	if prefix := strings.TrimSpace(context.SystemPromptPrefix); prefix != "" {
+		systemPrompt = prefix + "\n\n" + systemPrompt
+	}
+	if suffix := strings.TrimSpace(context.SystemPromptSuffix); suffix != "" {
+		systemPrompt += "\n\n" + suffix
+	}
+
+	return systemPrompt
+}
+
+// builtinSystemPrompt returns the default system prompt for command generation
+func builtinSystemPrompt(context Context) string {
 	basePrompt := fmt.Sprintf(`You are a helpful shell command assistant. Convert natural language requests into safe, executable shell commands for %s using %s.
 
 System Information:
@@ -29,6 +71,18 @@ System Information:
 - User: %s
 - Working Directory: %s`, context.OS, context.Shell, context.OS, context.Architecture, context.Shell, context.User, context.WorkingDirectory)
 
+	// Add the detected project type if available
+	if context.ProjectType != "" {
+		basePrompt += fmt.Sprintf(`
+- Project: %s`, context.ProjectType)
+	}
+
+	// Add git branch and status if available
+	if context.GitStatus != "" {
+		basePrompt += fmt.Sprintf(`
+- Git: %s`, context.GitStatus)
+	}
+
 	// Add tool context if available
 	if context.ToolsSummary != "" {
 		basePrompt += fmt.Sprintf(`
@@ -59,6 +113,12 @@ System Information:
 - Cloud Tools: %s`, strings.Join(context.CloudTools, ", "))
 	}
 
+	// Add environment variables if the user opted in
+	if len(context.Environment) > 0 {
+		basePrompt += fmt.Sprintf(`
+- Environment: %s`, formatEnvironment(context.Environment))
+	}
+
 	basePrompt += `
 
 Rules:
@@ -167,5 +227,43 @@ COMPREHENSIVE EXAMPLES:
 
 Remember: Safety first - avoid destructive operations unless explicitly requested. Use tools that are actually available on this system. For alias creation, trust that commands mentioned are properly installed and available in PATH. When debugging or fixing issues, provide the most relevant diagnostic command first.`
 
+	// Shells with their own syntax get guidance so examples above aren't copied as-is
+	if guidance := shellGuidance(context.Shell); guidance != "" {
+		basePrompt += "\n\n" + guidance
+	}
+
 	return basePrompt
 }
+
+// nushellGuidance steers generation away from POSIX syntax, which nushell
+// doesn't accept
+const nushellGuidance = `IMPORTANT - Nushell Syntax:
+The user's shell is nushell (nu), which is not POSIX compatible. The examples above use bash syntax; translate them to nushell:
+- Pipelines pass structured data: prefer builtins like ls, ps, open, where, select, sort-by, get and first over parsing text with grep, awk, sed or cut (e.g. "ls | where size > 10mb | sort-by size")
+- Chain commands with ";" instead of "&&" and "||"; use "try { ... } catch { ... }" for fallbacks
+- Environment variables are $env.NAME, set with "$env.NAME = value"; use "let" for variables instead of VAR=value
+- Command substitution is "(command)", not "$(command)" or backticks
+- Use "def name [args] { ... }" instead of shell functions, and "alias name = command" (with spaces around "=")
+- Prefix an external program with "^" when a nushell builtin has the same name (e.g. "^ls -la", "^find . -name '*.txt'")
+- Redirect output with "| save file.txt" or "o> file.txt" instead of ">"
+- Write loops as "for x in [a b c] { ... }" or "ls *.txt | each { |f| ... }"`
+
+// shellGuidance returns extra syntax instructions for shells whose syntax
+// differs from bash, or "" when none are needed
+func shellGuidance(shell string) string {
+	switch utils.NormalizeShellName(shell) {
+	case "nu":
+		return nushellGuidance
+	default:
+		return ""
+	}
+}
+
+// formatEnvironment lists environment variables as NAME=value, sorted by name
+func formatEnvironment(env map[string]string) string {
+	vars := make([]string, 0, len(env))
+	for _, name := range slices.Sorted(maps.Keys(env)) {
+		vars = append(vars, name+"="+env[name])
+	}
+	return strings.Join(vars, ", ")
+}
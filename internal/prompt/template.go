@@ -0,0 +1,71 @@
+package prompt
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// TemplateData is the data a custom system prompt template is rendered with.
+// All Context fields are available directly (e.g. {{.OS}}, {{.Shell}}), and
+// {{.BuiltinPrompt}} holds the built-in prompt for templates that extend it.
+type TemplateData struct {
+	Context
+	BuiltinPrompt string
+}
+
+// templateFuncs are the helper functions available in custom templates
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
+var (
+	templateMutex  sync.RWMutex
+	systemTemplate *template.Template
+)
+
+// ParseSystemTemplate parses a custom system prompt template
+func ParseSystemTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template %s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// LoadSystemTemplate reads and parses a custom system prompt template file
+func LoadSystemTemplate(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt template: %w", err)
+	}
+	return ParseSystemTemplate(path, string(data))
+}
+
+// SetSystemTemplate replaces the built-in system prompt with tmpl. Passing
+// nil restores the built-in prompt.
+func SetSystemTemplate(tmpl *template.Template) {
+	templateMutex.Lock()
+	defer templateMutex.Unlock()
+	systemTemplate = tmpl
+}
+
+// getSystemTemplate returns the custom system prompt template, if any
+func getSystemTemplate() *template.Template {
+	templateMutex.RLock()
+	defer templateMutex.RUnlock()
+	return systemTemplate
+}
+
+// RenderSystemTemplate renders tmpl with the context and the built-in prompt
+func RenderSystemTemplate(tmpl *template.Template, context Context) (string, error) {
+	var buf bytes.Buffer
+	data := TemplateData{Context: context, BuiltinPrompt: builtinSystemPrompt(context)}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,48 @@
+package security
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ConfirmRetypeCommand is the security.critical_confirmation value that makes
+// critical commands require typing the command itself
+const ConfirmRetypeCommand = "command"
+
+// RequiredConfirmation returns what must be typed to run a critical command
+// under the security.critical_confirmation setting: the command for
+// "command", otherwise the configured phrase. An empty setting returns "",
+// leaving the default confirmation in place. Multi-line commands can't be
+// typed at a single prompt, so only their first line is required.
+func RequiredConfirmation(setting, command string) string {
+	setting = strings.TrimSpace(setting)
+	if setting != ConfirmRetypeCommand {
+		return setting
+	}
+
+	command = strings.TrimSpace(command)
+	if first, _, found := strings.Cut(command, "\n"); found {
+		return strings.TrimSpace(first)
+	}
+	return command
+}
+
+// ConfirmCritical asks for the required confirmation text on w and reads the
+// answer from r. Only an exact match, ignoring surrounding whitespace,
+// confirms.
+func ConfirmCritical(r io.Reader, w io.Writer, required string) (bool, error) {
+	if required == "" {
+		return false, fmt.Errorf("no confirmation text configured")
+	}
+
+	fmt.Fprintf(w, "This command is critical. Type '%s' to confirm: ", required)
+
+	answer, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && (err != io.EOF || answer == "") {
+		return false, fmt.Errorf("failed to read confirmation: %w", err)
+	}
+
+	return strings.TrimSpace(answer) == required, nil
+}
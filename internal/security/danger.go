@@ -53,6 +53,17 @@ func (d *DangerDetector) AssessCommand(command string, context *llm.Context) llm
 	return finalAssessment
 }
 
+// ShouldAutoRun reports whether a command assessed at level may run without a
+// confirmation prompt given the configured security.auto_run_max_level.
+// An empty maxLevel disables auto-run, and critical commands always prompt.
+func ShouldAutoRun(level llm.DangerLevel, maxLevel string) bool {
+	if strings.TrimSpace(maxLevel) == "" || level.IsAtLeastLevel(llm.DangerLevelCritical) {
+		return false
+	}
+
+	return llm.ParseDangerLevel(maxLevel).IsAtLeastLevel(level)
+}
+
 // assessPatterns checks command against known dangerous patterns
 func (d *DangerDetector) assessPatterns(command string, context *llm.Context) llm.DangerAssessment {
 	lowerCommand := strings.ToLower(command)
@@ -0,0 +1,137 @@
+package security
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"forgor/internal/llm"
+)
+
+// Elevation describes whether a command needs root privileges
+type Elevation struct {
+	// Explicit is set when the command itself runs sudo (or doas/pkexec),
+	// which may stop to ask for a password
+	Explicit bool
+	// NonInteractive is set when every elevation uses 'sudo -n', which fails
+	// instead of prompting
+	NonInteractive bool
+	// Reason says why elevation is needed; empty when it isn't
+	Reason string
+}
+
+// Required reports whether the command needs elevated privileges
+func (e Elevation) Required() bool {
+	return e.Reason != ""
+}
+
+// MayPrompt reports whether running the command may block on a password
+// prompt
+func (e Elevation) MayPrompt() bool {
+	return e.Explicit && !e.NonInteractive
+}
+
+// Warning describes the elevation need as a single warning line, or "" when
+// none is needed
+func (e Elevation) Warning() string {
+	switch {
+	case !e.Required():
+		return ""
+	case e.Explicit:
+		return "This command requires sudo and may ask for your password"
+	default:
+		return fmt.Sprintf("This command requires sudo: %s", e.Reason)
+	}
+}
+
+// elevationPrefix matches sudo, doas or pkexec at the start of a command or
+// of any step in a pipeline or command list
+var elevationPrefix = regexp.MustCompile(`(?:^|[;&|(]\s*|\n\s*)(sudo|doas|pkexec)\b([^;&|\n]*)`)
+
+// sudoNonInteractive matches the options of a 'sudo -n' invocation
+var sudoNonInteractive = regexp.MustCompile(`^\s*(?:-[A-Za-z]*n[A-Za-z]*|--non-interactive)\b`)
+
+// elevationPattern is a command that usually only works as root
+type elevationPattern struct {
+	pattern *regexp.Regexp
+	reason  string
+}
+
+// systemPath matches a directory only root can write to, or a path below it
+const systemPath = `/(?:etc|usr|boot|lib|lib64|sbin|bin|opt|var/lib|srv)(?:/[^\s;&|]*)?`
+
+// endOfStep matches the end of a command in a pipeline or command list
+const endOfStep = `\s*(?:$|[;&|\n])`
+
+var elevationPatterns = []elevationPattern{
+	{regexp.MustCompile(`>>?\s*` + systemPath + `(?:\s|$)`), "writes to a system directory"},
+	{regexp.MustCompile(`\btee\s+(?:-a\s+)?` + systemPath + `(?:\s|$)`), "writes to a system directory"},
+	// Copies and moves only write to their last argument
+	{regexp.MustCompile(`\b(?:cp|mv|ln|install)\s[^;&|\n]*\s` + systemPath + endOfStep), "copies files into a system directory"},
+	{regexp.MustCompile(`\b(?:rm|chmod|chown|chgrp|mkdir|touch|sed\s+-i)\s[^;&|\n]*` + systemPath + `(?:\s|$)`), "modifies files in a system directory"},
+	{regexp.MustCompile(`\bsystemctl\s+(?:start|stop|restart|reload|enable|disable|mask|unmask|daemon-reload)\b`), "manages system services"},
+	{regexp.MustCompile(`\bservice\s+\S+\s+(?:start|stop|restart|reload)\b`), "manages system services"},
+	{regexp.MustCompile(`\b(?:apt|apt-get|dnf|yum|zypper)\s+(?:install|remove|purge|upgrade|update|dist-upgrade|autoremove)\b`), "installs or removes system packages"},
+	{regexp.MustCompile(`\bpacman\s+-[SRU]`), "installs or removes system packages"},
+	{regexp.MustCompile(`\b(?:useradd|userdel|usermod|groupadd|groupdel|visudo)\b`), "changes system users or groups"},
+	{regexp.MustCompile(`\b(?:mount|umount|modprobe|rmmod|insmod|swapon|swapoff)\s+[^\s;&|]`), "changes system mounts or kernel modules"},
+}
+
+// DetectElevation checks whether command needs root privileges, either
+// because it runs sudo itself or, heuristically, because it writes to system
+// directories, manages services or installs system packages
+func DetectElevation(command string) Elevation {
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return Elevation{}
+	}
+
+	if matches := elevationPrefix.FindAllStringSubmatch(command, -1); len(matches) > 0 {
+		nonInteractive := true
+		for _, match := range matches {
+			if match[1] != "sudo" || !sudoNonInteractive.MatchString(match[2]) {
+				nonInteractive = false
+			}
+		}
+		return Elevation{
+			Explicit:       true,
+			NonInteractive: nonInteractive,
+			Reason:         fmt.Sprintf("runs %s", matches[0][1]),
+		}
+	}
+
+	for _, p := range elevationPatterns {
+		if p.pattern.MatchString(command) {
+			return Elevation{Reason: p.reason}
+		}
+	}
+	return Elevation{}
+}
+
+// AnnotateElevation adds a warning to response when its command needs sudo
+func AnnotateElevation(response *llm.Response) {
+	if response == nil || response.Command == "" {
+		return
+	}
+
+	if warning := DetectElevation(response.Command).Warning(); warning != "" && !slices.Contains(response.Warnings, warning) {
+		response.Warnings = append(response.Warnings, warning)
+	}
+}
+
+// ConfirmElevation asks on w whether to run a command that may stop to ask
+// for a sudo password, reading the answer from r. Only y or yes confirms.
+func ConfirmElevation(r io.Reader, w io.Writer, command string) (bool, error) {
+	fmt.Fprintf(w, "This command uses sudo and may wait for your password:\n  %s\nRun it? [y/N]: ", command)
+
+	answer, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && (err != io.EOF || answer == "") {
+		return false, fmt.Errorf("failed to read confirmation: %w", err)
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
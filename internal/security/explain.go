@@ -0,0 +1,79 @@
+package security
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"forgor/internal/llm"
+)
+
+// ExplainCommand asks provider to explain command and adds a danger
+// assessment of the command to the response, so pasting an unknown command
+// to explain also reveals how risky it is to run.
+func ExplainCommand(ctx context.Context, provider llm.Provider, command string, requestContext *llm.Context) (*llm.Response, error) {
+	response, err := provider.ExplainCommand(ctx, command)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Command == "" {
+		response.Command = command
+	}
+	AnnotateDanger(response, requestContext)
+
+	return response, nil
+}
+
+// AnnotateDanger assesses response.Command and records the result on the
+// response. The level only ever rises, and a warning with the reason is
+// added for anything above safe.
+func AnnotateDanger(response *llm.Response, requestContext *llm.Context) {
+	if response == nil || response.Command == "" {
+		return
+	}
+
+	assessment := NewDangerDetector().AssessCommand(response.Command, requestContext)
+	if response.DangerLevel == "" || assessment.Level.IsAtLeastLevel(response.DangerLevel) {
+		response.DangerLevel = assessment.Level
+	}
+
+	// Providers may already have added the same warning via CommandWarnings
+	if warning := dangerWarning(assessment); assessment.Level != llm.DangerLevelSafe && !slices.Contains(response.Warnings, warning) {
+		response.Warnings = append(response.Warnings, warning)
+	}
+}
+
+// CommandWarnings assesses a generated command with the danger detector and
+// returns a warning for anything rated medium or above. It is registered
+// with prompt.SetSafetyChecker so providers warn consistently with
+// 'forgor run' and safe mode.
+func CommandWarnings(command string) []string {
+	assessment := NewDangerDetector().AssessCommand(command, nil)
+	if !assessment.Level.IsAtLeastLevel(llm.DangerLevelMedium) {
+		return nil
+	}
+	return []string{dangerWarning(assessment)}
+}
+
+// dangerWarning describes an assessment as a single warning line. A level
+// raised only by heuristics has no pattern reason, so its factors are used.
+func dangerWarning(assessment llm.DangerAssessment) string {
+	reason := assessment.Reason
+	if reason == "Safe command" && len(assessment.Factors) > 0 {
+		reason = strings.Join(assessment.Factors, ", ")
+	}
+	return fmt.Sprintf("%s risk: %s", assessment.Level, reason)
+}
+
+// AssessSafety records the full danger assessment of response.Command on the
+// response, for JSON output that scripts use to gate execution
+func AssessSafety(response *llm.Response, requestContext *llm.Context) {
+	if response == nil || response.Command == "" {
+		return
+	}
+
+	assessment := NewDangerDetector().AssessCommand(response.Command, requestContext)
+	response.Safety = &assessment
+}
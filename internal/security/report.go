@@ -0,0 +1,40 @@
+package security
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"forgor/internal/llm"
+	"forgor/internal/utils"
+)
+
+// WriteDangerReport writes a styled report of assessment for command to w:
+// the danger level, the reasons it was flagged, its risk factors and how to
+// reduce the risk
+func WriteDangerReport(w io.Writer, command string, assessment llm.DangerAssessment) {
+	fmt.Fprintf(w, "\n%s\n", utils.Divider("DANGER ASSESSMENT", utils.StyleInfo))
+	fmt.Fprintf(w, "%s %s\n", utils.Styled("Command:", utils.StyleCommand), command)
+
+	style := utils.StyleWarning
+	if assessment.Level == llm.DangerLevelSafe {
+		style = utils.StyleSuccess
+	}
+	fmt.Fprintf(w, "%s %s\n", utils.DangerIcon(string(assessment.Level)),
+		utils.Styled(strings.ToUpper(string(assessment.Level))+" DANGER LEVEL", style))
+
+	if assessment.Reason != "" {
+		fmt.Fprintf(w, "\n%s\n", utils.Styled("Why:", utils.StyleSubtle))
+		fmt.Fprintf(w, "%s\n", utils.List(strings.Split(assessment.Reason, "; "), utils.StyleSubtle))
+	}
+
+	if len(assessment.Factors) > 0 {
+		fmt.Fprintf(w, "\n%s\n", utils.Styled("Risk Factors:", utils.StyleWarning))
+		fmt.Fprintf(w, "%s\n", utils.List(assessment.Factors, utils.StyleWarning))
+	}
+
+	if len(assessment.Mitigations) > 0 {
+		fmt.Fprintf(w, "\n%s\n", utils.Styled("Safety Recommendations:", utils.StyleInfo))
+		fmt.Fprintf(w, "%s\n", utils.List(assessment.Mitigations, utils.StyleInfo))
+	}
+}
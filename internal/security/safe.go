@@ -0,0 +1,45 @@
+package security
+
+import (
+	"fmt"
+	"strings"
+
+	"forgor/internal/llm"
+)
+
+// DefaultSafeMaxLevel is the highest danger level safe mode allows when
+// security.safe_max_level isn't set
+const DefaultSafeMaxLevel = llm.DangerLevelMedium
+
+// BlockedError reports a command that safe mode refuses to run or suggest
+type BlockedError struct {
+	Assessment llm.DangerAssessment
+	MaxLevel   llm.DangerLevel
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("blocked by safe mode: %s danger is above the %s limit (%s)",
+		e.Assessment.Level, e.MaxLevel, e.Assessment.Reason)
+}
+
+// SafeMaxLevel parses the security.safe_max_level setting, defaulting to
+// DefaultSafeMaxLevel when it is empty
+func SafeMaxLevel(setting string) llm.DangerLevel {
+	if strings.TrimSpace(setting) == "" {
+		return DefaultSafeMaxLevel
+	}
+	return llm.ParseDangerLevel(setting)
+}
+
+// CheckSafeMode assesses command and returns a *BlockedError when its danger
+// level is above maxLevel. The assessment is returned either way so its
+// factors and mitigations can be shown.
+func CheckSafeMode(command string, context *llm.Context, maxLevel string) (llm.DangerAssessment, error) {
+	assessment := NewDangerDetector().AssessCommand(command, context)
+
+	limit := SafeMaxLevel(maxLevel)
+	if assessment.Level != limit && assessment.Level.IsAtLeastLevel(limit) {
+		return assessment, &BlockedError{Assessment: assessment, MaxLevel: limit}
+	}
+	return assessment, nil
+}
@@ -0,0 +1,66 @@
+package security
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// What to do when an outgoing query contains something that looks like a secret
+const (
+	SecretActionRedact = "redact" // Replace the secret and send the rest
+	SecretActionAbort  = "abort"  // Don't send the query at all
+)
+
+// ParseSecretAction validates a secret action, defaulting to redact when empty
+func ParseSecretAction(action string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(action)) {
+	case "", SecretActionRedact:
+		return SecretActionRedact, nil
+	case SecretActionAbort:
+		return SecretActionAbort, nil
+	default:
+		return "", fmt.Errorf("invalid secret action: %s. Valid actions: %s, %s", action, SecretActionRedact, SecretActionAbort)
+	}
+}
+
+// secretPattern recognises one kind of secret
+type secretPattern struct {
+	kind    string
+	pattern *regexp.Regexp
+}
+
+// secretPatterns match secrets that are obvious from their shape alone
+var secretPatterns = []secretPattern{
+	{"private key", regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?(?:-----END [A-Z ]*PRIVATE KEY-----|$)`)},
+	{"AWS access key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"bearer token", regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]{16,}=*`)},
+	{"GitHub token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"API key", regexp.MustCompile(`\bsk-(?:ant-|proj-)?[A-Za-z0-9_\-]{20,}`)},
+}
+
+// FindSecrets returns the kinds of secret found in text, such as
+// "AWS access key", each listed once
+func FindSecrets(text string) []string {
+	var kinds []string
+	for _, secret := range secretPatterns {
+		if secret.pattern.MatchString(text) {
+			kinds = append(kinds, secret.kind)
+		}
+	}
+	return kinds
+}
+
+// RedactSecrets replaces anything in text that looks like a secret with a
+// placeholder naming its kind, and returns the kinds that were replaced
+func RedactSecrets(text string) (string, []string) {
+	var kinds []string
+	for _, secret := range secretPatterns {
+		if !secret.pattern.MatchString(text) {
+			continue
+		}
+		kinds = append(kinds, secret.kind)
+		text = secret.pattern.ReplaceAllLiteralString(text, fmt.Sprintf("[REDACTED %s]", secret.kind))
+	}
+	return text, kinds
+}
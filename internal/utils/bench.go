@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ToolCount is the number of tools detected in a category
+type ToolCount struct {
+	Category string `json:"category"`
+	Count    int    `json:"count"`
+}
+
+// ContextBenchmark holds the timings of a cold vs warm system context build
+type ContextBenchmark struct {
+	Summary    TimingSummary `json:"summary"`
+	ToolCounts []ToolCount   `json:"tool_counts"`
+}
+
+// RunContextBenchmark times a cold system context build, a load from the
+// persistent cache, a warm in-memory lookup and each tool detection category.
+// No LLM calls are made.
+func RunContextBenchmark() (*ContextBenchmark, error) {
+	timer := NewTimer("Context Benchmark", false)
+
+	step := timer.StartStep("Cold Build (RefreshSystemContext)")
+	ctx := RefreshSystemContext()
+	step.End()
+	if ctx == nil {
+		return nil, fmt.Errorf("failed to build system context")
+	}
+
+	// Drop the in-memory copy so the next lookup reads the persistent cache
+	contextCacheMutex.Lock()
+	systemContextCache = nil
+	contextCacheMutex.Unlock()
+
+	step = timer.StartStep("Disk Cache Load (GetSystemContext)")
+	GetSystemContext()
+	step.End()
+
+	step = timer.StartStep("Warm Lookup (GetSystemContext)")
+	ctx = GetSystemContext()
+	step.End()
+
+	// Break tool detection down by category
+	detectors := []struct {
+		name   string
+		detect func()
+	}{
+		{"Package Managers", func() { detectPackageManagers() }},
+		{"Languages", func() { detectLanguageRuntimes() }},
+		{"Development Tools", func() { detectDevelopmentTools() }},
+		{"System Commands", func() { detectSystemCommands() }},
+		{"Container Tools", func() { detectContainerTools() }},
+		{"Cloud Tools", func() { detectCloudTools() }},
+		{"Database Tools", func() { detectDatabaseTools() }},
+		{"Network Tools", func() { detectNetworkTools() }},
+	}
+	for _, d := range detectors {
+		step = timer.StartStep("Tool Detection: " + d.name)
+		d.detect()
+		step.End()
+	}
+
+	tools := ctx.Tools
+	return &ContextBenchmark{
+		Summary: timer.GetSummary(),
+		ToolCounts: []ToolCount{
+			{"Package Managers", len(tools.PackageManagers)},
+			{"Languages", len(tools.Languages)},
+			{"Development Tools", len(tools.DevelopmentTools)},
+			{"System Commands", len(tools.SystemCommands)},
+			{"Container Tools", len(tools.ContainerTools)},
+			{"Cloud Tools", len(tools.CloudTools)},
+			{"Database Tools", len(tools.DatabaseTools)},
+			{"Network Tools", len(tools.NetworkTools)},
+		},
+	}, nil
+}
+
+// Report formats the benchmark as styled tables for display
+func (b *ContextBenchmark) Report() string {
+	var report strings.Builder
+
+	report.WriteString(Divider("CONTEXT BUILD TIMINGS", StyleInfo) + "\n")
+	var rows [][]string
+	for _, step := range b.Summary.Steps {
+		rows = append(rows, []string{step.Name, formatDuration(step.Duration)})
+	}
+	rows = append(rows, []string{
+		Styled("Total", StyleHighlight),
+		Styled(formatDuration(b.Summary.TotalDuration), StyleHighlight),
+	})
+	report.WriteString(Table([]string{"Step", "Duration"}, rows, StyleInfo) + "\n")
+
+	report.WriteString("\n" + Divider("TOOLS DETECTED", StyleInfo) + "\n")
+	rows = nil
+	total := 0
+	for _, count := range b.ToolCounts {
+		rows = append(rows, []string{count.Category, fmt.Sprintf("%d", count.Count)})
+		total += count.Count
+	}
+	rows = append(rows, []string{Styled("Total", StyleHighlight), Styled(fmt.Sprintf("%d", total), StyleHighlight)})
+	report.WriteString(Table([]string{"Category", "Count"}, rows, StyleInfo))
+
+	return report.String()
+}
+
+// StepDuration returns the duration of the named step, or 0 if it wasn't recorded
+func (b *ContextBenchmark) StepDuration(name string) time.Duration {
+	for _, step := range b.Summary.Steps {
+		if step.Name == name {
+			return step.Duration
+		}
+	}
+	return 0
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+)
+
+// maxCapturedOutput limits how much command output is kept for diagnosis.
+// The tail is kept since errors are usually reported last.
+const maxCapturedOutput = 8 * 1024
+
+// CommandResult is the outcome of a command run with captured output
+type CommandResult struct {
+	Command  string
+	ExitCode int
+	Output   string
+}
+
+// CommandError is returned when an executed command exits unsuccessfully.
+// It carries the captured result so callers can offer to diagnose it.
+type CommandError struct {
+	Result *CommandResult
+	Err    error
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("command exited with code %d: %v", e.Result.ExitCode, e.Err)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// RunCapturedCommand runs command with shell -c, streaming its output to
+// stdout and stderr while keeping the combined tail for later use. A
+// non-zero exit is returned as a *CommandError.
+func RunCapturedCommand(shell, command string, stdin io.Reader, stdout, stderr io.Writer) (*CommandResult, error) {
+	captured := &tailBuffer{limit: maxCapturedOutput}
+
+	cmd := exec.Command(shell, "-c", command)
+	cmd.Stdin = stdin
+	cmd.Stdout = io.MultiWriter(stdout, captured)
+	cmd.Stderr = io.MultiWriter(stderr, captured)
+
+	err := cmd.Run()
+	result := &CommandResult{Command: command, Output: captured.String()}
+
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			result.ExitCode = exitErr.ExitCode()
+		} else {
+			result.ExitCode = -1
+		}
+		return result, &CommandError{Result: result, Err: err}
+	}
+
+	return result, nil
+}
+
+// ExitCode returns the exit code reported by err from running a command: 0
+// for nil, the process's code for an *exec.ExitError, and -1 otherwise
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// tailBuffer is a writer that keeps only the last limit bytes written to it
+type tailBuffer struct {
+	mu    sync.Mutex
+	limit int
+	data  []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.data = append(b.data, p...)
+	if len(b.data) > b.limit {
+		b.data = b.data[len(b.data)-b.limit:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.data)
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AppendBlockToFile appends a block of text to the end of a file unless the file
+// already contains the given marker. The block always starts on its own line,
+// even if the existing file lacks a trailing newline. The new contents are
+// written to a temporary file and atomically renamed over the original so a
+// crash mid-write never leaves a partially written file behind.
+// It returns true if the block was added.
+func AppendBlockToFile(path, block, marker string) (bool, error) {
+	// Write through symlinks (e.g. dotfile managers) instead of replacing them
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	perm := os.FileMode(0644)
+	var existing []byte
+	if stat, err := os.Stat(path); err == nil {
+		perm = stat.Mode().Perm()
+		existing, err = os.ReadFile(path) // #nosec G304 - path is provided by the caller
+		if err != nil {
+			return false, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return false, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	if marker != "" && strings.Contains(string(existing), marker) {
+		return false, nil
+	}
+
+	var content strings.Builder
+	content.Write(existing)
+	if len(existing) > 0 {
+		// Normalize to exactly one blank line between existing content and the block
+		if !strings.HasSuffix(string(existing), "\n") {
+			content.WriteString("\n")
+		}
+		content.WriteString("\n")
+	}
+	content.WriteString(strings.TrimRight(block, "\n"))
+	content.WriteString("\n")
+
+	if err := WriteFileAtomic(path, []byte(content.String()), perm); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// WriteFileAtomic writes data to a temporary file in the same directory as path
+// and renames it into place, so readers never observe a partially written file.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	tempFile, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tempPath := tempFile.Name()
+
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	// Flush to disk before the rename so a crash can't leave an empty file
+	if err := tempFile.Sync(); err != nil {
+		tempFile.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+
+	if err := tempFile.Close(); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	if err := os.Chmod(tempPath, perm); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to set permissions on temp file: %w", err)
+	}
+
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+
+	return nil
+}
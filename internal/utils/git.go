@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// gitStatusTimeout bounds how long gathering git status may take so a
+	// slow or huge repository never blocks a query
+	gitStatusTimeout = 2 * time.Second
+
+	// maxGitStatusOutput caps how much `git status` output is read
+	maxGitStatusOutput = 64 * 1024
+)
+
+// GitStatus is a short summary of a git working tree
+type GitStatus struct {
+	// Branch is the current branch, or "" for a detached HEAD
+	Branch string `json:"branch,omitempty"`
+
+	// Changes is the number of modified, staged and untracked paths
+	Changes int `json:"changes"`
+
+	// Truncated is set when the status output hit maxGitStatusOutput, in
+	// which case Changes is a lower bound
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// GetGitStatus returns the branch and dirty/clean state of the git
+// repository containing dir. It fails if git is unavailable, dir is not in a
+// repository, or git does not answer within gitStatusTimeout.
+func GetGitStatus(dir string) (*GitStatus, error) {
+	if !isCommandAvailable("git") {
+		return nil, fmt.Errorf("git is not installed")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), gitStatusTimeout)
+	defer cancel()
+
+	output := &limitedBuffer{limit: maxGitStatusOutput}
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, "status", "--porcelain=v1", "--branch", "--untracked-files=normal")
+	cmd.Stdout = output
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("git status timed out after %s", gitStatusTimeout)
+		}
+		return nil, fmt.Errorf("git status failed: %w", err)
+	}
+
+	return parseGitStatus(output.String(), output.truncated), nil
+}
+
+// parseGitStatus parses `git status --porcelain=v1 --branch` output
+func parseGitStatus(output string, truncated bool) *GitStatus {
+	status := &GitStatus{Truncated: truncated}
+
+	lines := strings.Split(output, "\n")
+	if truncated && len(lines) > 0 {
+		// The last line may have been cut off mid-path
+		lines = lines[:len(lines)-1]
+	}
+
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		if header, ok := strings.CutPrefix(line, "## "); ok {
+			status.Branch = parseGitBranch(header)
+			continue
+		}
+		status.Changes++
+	}
+
+	return status
+}
+
+// parseGitBranch extracts the branch name from a porcelain branch header
+// such as "main...origin/main [ahead 1]" or "No commits yet on main"
+func parseGitBranch(header string) string {
+	if strings.HasPrefix(header, "HEAD (no branch)") {
+		return ""
+	}
+	header = strings.TrimPrefix(header, "No commits yet on ")
+	header = strings.TrimPrefix(header, "Initial commit on ")
+	if i := strings.Index(header, "..."); i >= 0 {
+		header = header[:i]
+	}
+	if i := strings.IndexByte(header, ' '); i >= 0 {
+		header = header[:i]
+	}
+	return header
+}
+
+// Summary returns a concise description for prompts, e.g.
+// "branch main, 3 uncommitted changes"
+func (s *GitStatus) Summary() string {
+	branch := "detached HEAD"
+	if s.Branch != "" {
+		branch = "branch " + s.Branch
+	}
+
+	switch {
+	case s.Truncated:
+		return fmt.Sprintf("%s, %d+ uncommitted changes", branch, s.Changes)
+	case s.Changes == 0:
+		return branch + ", clean"
+	case s.Changes == 1:
+		return branch + ", 1 uncommitted change"
+	default:
+		return fmt.Sprintf("%s, %d uncommitted changes", branch, s.Changes)
+	}
+}
+
+// limitedBuffer keeps the first limit bytes written to it and discards the
+// rest, so huge command output can't exhaust memory
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.buf.Len(); remaining < len(p) {
+		b.truncated = true
+		if remaining > 0 {
+			b.buf.Write(p[:remaining])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
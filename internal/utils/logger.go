@@ -0,0 +1,219 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Logger is a minimal leveled logger for diagnostic output. Diagnostics are
+// kept separate from results so stdout stays clean for pipes and -f json.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+}
+
+// StepLogger is implemented by loggers that record timing steps as
+// structured fields rather than formatted text
+type StepLogger interface {
+	LogStep(step string, duration time.Duration)
+}
+
+// LogLevel is the minimum severity a logger emits
+type LogLevel int
+
+const (
+	LogLevelDebug LogLevel = iota // Verbose diagnostics, shown with --verbose
+	LogLevelInfo                  // Informational messages
+	LogLevelWarn                  // Warnings
+)
+
+// String returns the lowercase name of the level
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelDebug:
+		return "debug"
+	case LogLevelWarn:
+		return "warn"
+	default:
+		return "info"
+	}
+}
+
+// Supported values for --log-format
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// TextLogger writes plain text diagnostics at or above a minimum level
+type TextLogger struct {
+	mu    sync.Mutex
+	out   io.Writer
+	level LogLevel
+}
+
+// NewTextLogger creates a logger writing messages at or above level to out
+func NewTextLogger(out io.Writer, level LogLevel) *TextLogger {
+	return &TextLogger{out: out, level: level}
+}
+
+// Debugf logs a debug message
+func (l *TextLogger) Debugf(format string, args ...interface{}) {
+	l.logf(LogLevelDebug, format, args...)
+}
+
+// Infof logs an informational message
+func (l *TextLogger) Infof(format string, args ...interface{}) {
+	l.logf(LogLevelInfo, format, args...)
+}
+
+// Warnf logs a warning
+func (l *TextLogger) Warnf(format string, args ...interface{}) {
+	l.logf(LogLevelWarn, format, args...)
+}
+
+func (l *TextLogger) logf(level LogLevel, format string, args ...interface{}) {
+	if level < l.level {
+		return
+	}
+
+	msg := fmt.Sprintf(format, args...)
+	if !strings.HasSuffix(msg, "\n") {
+		msg += "\n"
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	io.WriteString(l.out, msg)
+}
+
+// JSONLogger writes each diagnostic as a single JSON line, suitable for
+// collecting logs and timing metrics in CI or scripts
+type JSONLogger struct {
+	mu    sync.Mutex
+	out   io.Writer
+	level LogLevel
+}
+
+// jsonLogEntry is a single line emitted by JSONLogger
+type jsonLogEntry struct {
+	Level      string   `json:"level"`
+	Msg        string   `json:"msg"`
+	Step       string   `json:"step,omitempty"`
+	DurationMs *float64 `json:"duration_ms,omitempty"`
+}
+
+// NewJSONLogger creates a logger writing JSON lines at or above level to out
+func NewJSONLogger(out io.Writer, level LogLevel) *JSONLogger {
+	return &JSONLogger{out: out, level: level}
+}
+
+// Debugf logs a debug message
+func (l *JSONLogger) Debugf(format string, args ...interface{}) {
+	l.logf(LogLevelDebug, format, args...)
+}
+
+// Infof logs an informational message
+func (l *JSONLogger) Infof(format string, args ...interface{}) {
+	l.logf(LogLevelInfo, format, args...)
+}
+
+// Warnf logs a warning
+func (l *JSONLogger) Warnf(format string, args ...interface{}) {
+	l.logf(LogLevelWarn, format, args...)
+}
+
+// LogStep records a completed timing step. Steps are always emitted so
+// metrics can be collected without enabling verbose output.
+func (l *JSONLogger) LogStep(step string, duration time.Duration) {
+	durationMs := float64(duration.Microseconds()) / 1000
+	l.write(jsonLogEntry{
+		Level:      LogLevelInfo.String(),
+		Msg:        "step completed",
+		Step:       step,
+		DurationMs: &durationMs,
+	})
+}
+
+func (l *JSONLogger) logf(level LogLevel, format string, args ...interface{}) {
+	if level < l.level {
+		return
+	}
+
+	msg := strings.TrimSpace(StripANSI(fmt.Sprintf(format, args...)))
+	if msg == "" {
+		return
+	}
+
+	l.write(jsonLogEntry{Level: level.String(), Msg: msg})
+}
+
+func (l *JSONLogger) write(entry jsonLogEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out.Write(append(data, '\n'))
+}
+
+var (
+	loggerMutex   sync.RWMutex
+	defaultLogger Logger = NewTextLogger(os.Stderr, LogLevelInfo)
+)
+
+// InitLogger configures the default logger from the --verbose and
+// --log-format flags. Debug messages are only shown in verbose mode.
+func InitLogger(verbose bool, format string) error {
+	level := LogLevelInfo
+	if verbose {
+		level = LogLevelDebug
+	}
+
+	switch strings.ToLower(format) {
+	case "", LogFormatText:
+		SetLogger(NewTextLogger(os.Stderr, level))
+	case LogFormatJSON:
+		SetLogger(NewJSONLogger(os.Stderr, level))
+	default:
+		return fmt.Errorf("invalid log format: %s. Valid formats: %s, %s", format, LogFormatText, LogFormatJSON)
+	}
+	return nil
+}
+
+// SetLogger replaces the default logger
+func SetLogger(logger Logger) {
+	loggerMutex.Lock()
+	defer loggerMutex.Unlock()
+	defaultLogger = logger
+}
+
+// GetLogger returns the default logger
+func GetLogger() Logger {
+	loggerMutex.RLock()
+	defer loggerMutex.RUnlock()
+	return defaultLogger
+}
+
+// Debugf logs a debug message with the default logger
+func Debugf(format string, args ...interface{}) {
+	GetLogger().Debugf(format, args...)
+}
+
+// Infof logs an informational message with the default logger
+func Infof(format string, args ...interface{}) {
+	GetLogger().Infof(format, args...)
+}
+
+// Warnf logs a warning with the default logger
+func Warnf(format string, args ...interface{}) {
+	GetLogger().Warnf(format, args...)
+}
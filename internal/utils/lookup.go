@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// shellBuiltins are builtins and keywords that never resolve on PATH
+var shellBuiltins = map[string]bool{
+	".": true, ":": true, "[": true, "[[": true, "alias": true, "bg": true,
+	"bind": true, "break": true, "builtin": true, "case": true, "cd": true,
+	"command": true, "continue": true, "declare": true, "dirs": true,
+	"disown": true, "do": true, "done": true, "echo": true, "eval": true,
+	"exec": true, "exit": true, "export": true, "false": true, "fg": true,
+	"for": true, "function": true, "getopts": true, "hash": true,
+	"history": true, "if": true, "jobs": true, "kill": true, "let": true,
+	"local": true, "popd": true, "printf": true, "pushd": true, "pwd": true,
+	"read": true, "readonly": true, "return": true, "select": true,
+	"set": true, "shift": true, "source": true, "test": true, "time": true,
+	"trap": true, "true": true, "type": true, "typeset": true, "ulimit": true,
+	"umask": true, "unalias": true, "unset": true, "until": true,
+	"wait": true, "while": true,
+}
+
+// commandWrappers run the command that follows them
+var commandWrappers = map[string]bool{
+	"sudo": true, "env": true, "nohup": true, "nice": true, "time": true,
+	"command": true, "exec": true, "doas": true,
+}
+
+// wrapperOptionsWithValue are wrapper options that consume the next word,
+// e.g. sudo -u root or nice -n 10
+var wrapperOptionsWithValue = map[string]bool{
+	"-u": true, "-g": true, "-n": true, "-C": true, "-p": true,
+}
+
+// installCommands maps system package managers to their install command, in
+// order of preference
+var installCommands = []struct {
+	manager string
+	install string
+}{
+	{"brew", "brew install %s"},
+	{"apt", "sudo apt install %s"},
+	{"apt-get", "sudo apt-get install %s"},
+	{"dnf", "sudo dnf install %s"},
+	{"yum", "sudo yum install %s"},
+	{"pacman", "sudo pacman -S %s"},
+	{"zypper", "sudo zypper install %s"},
+}
+
+// envAssignmentPattern matches a leading VAR=value assignment
+var envAssignmentPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// LeadingExecutable returns the executable of the first simple command in
+// command, skipping environment assignments and wrappers such as sudo. It
+// returns "" for shell builtins and keywords, and for explicit paths.
+func LeadingExecutable(command string) string {
+	command = strings.TrimSpace(command)
+	if i := strings.IndexByte(command, '\n'); i >= 0 {
+		command = command[:i]
+	}
+
+	// Only the first simple command of a pipeline or list is checked
+	if i := strings.IndexAny(command, "|;&"); i >= 0 {
+		command = command[:i]
+	}
+
+	skipNext := false
+	for _, field := range strings.Fields(command) {
+		field = strings.TrimLeft(field, "({")
+		field = strings.Trim(field, `"'`)
+
+		if skipNext {
+			skipNext = false
+			continue
+		}
+
+		switch {
+		case field == "":
+			continue
+		case envAssignmentPattern.MatchString(field):
+			continue
+		case strings.HasPrefix(field, "-"):
+			// Options of a wrapper, e.g. sudo -u root
+			skipNext = wrapperOptionsWithValue[field]
+			continue
+		case commandWrappers[field]:
+			continue
+		case shellBuiltins[field]:
+			return ""
+		case strings.ContainsAny(field, "/$`"):
+			// Explicit paths and expansions can't be checked against PATH
+			return ""
+		default:
+			return field
+		}
+	}
+
+	return ""
+}
+
+// MissingCommandWarning checks whether the leading executable of command is
+// on PATH. It returns a warning with an install suggestion based on the
+// detected package managers, or "" if the command looks available.
+func MissingCommandWarning(command string, packageManagers []string) string {
+	executable := LeadingExecutable(command)
+	if executable == "" || isCommandAvailable(executable) {
+		return ""
+	}
+
+	warning := fmt.Sprintf("command `%s` not found — you may need to install it", executable)
+	if suggestion := installSuggestion(executable, packageManagers); suggestion != "" {
+		warning += fmt.Sprintf(" (try: %s)", suggestion)
+	}
+	return warning
+}
+
+// installSuggestion returns an install command for executable using the
+// preferred system package manager among packageManagers
+func installSuggestion(executable string, packageManagers []string) string {
+	available := make(map[string]bool, len(packageManagers))
+	for _, manager := range packageManagers {
+		available[manager] = true
+	}
+
+	for _, candidate := range installCommands {
+		if available[candidate.manager] {
+			return fmt.Sprintf(candidate.install, executable)
+		}
+	}
+	return ""
+}
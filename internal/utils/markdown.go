@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	mdHeadingPattern    = regexp.MustCompile(`^#{1,6}\s+(.*?)\s*#*$`)
+	mdBulletPattern     = regexp.MustCompile(`^(\s*)[-*+]\s+`)
+	mdInlineCodePattern = regexp.MustCompile("`([^`]+)`")
+	mdBoldPattern       = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	mdItalicPattern     = regexp.MustCompile(`(^|[^*\w])\*([^*\s](?:[^*]*[^*\s])?)\*`)
+	mdLinkPattern       = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+)
+
+// RenderMarkdown converts the basic markdown LLMs use in explanations
+// (headings, bold, inline code, bullets, fences, links) to ANSI styling
+func RenderMarkdown(text string) string {
+	return formatMarkdown(text, true)
+}
+
+// StripMarkdown removes basic markdown syntax from text, leaving plain text
+// suitable for pipes and log files
+func StripMarkdown(text string) string {
+	return formatMarkdown(text, false)
+}
+
+// formatMarkdown rewrites markdown line by line, styling or stripping it
+func formatMarkdown(text string, styled bool) string {
+	style := func(s string, codes string) string {
+		if !styled {
+			return s
+		}
+		return codes + s + Reset
+	}
+
+	lines := strings.Split(text, "\n")
+	result := make([]string, 0, len(lines))
+	inFence := false
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		// Drop fence markers and show fenced lines as indented commands
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			result = append(result, "  "+style(line, getStyle(StyleCommand)))
+			continue
+		}
+
+		if match := mdHeadingPattern.FindStringSubmatch(trimmed); match != nil {
+			result = append(result, style(formatInlineMarkdown(match[1], false), Bold))
+			continue
+		}
+
+		line = mdBulletPattern.ReplaceAllString(line, "$1• ")
+		result = append(result, formatInlineMarkdown(line, styled))
+	}
+
+	return strings.Join(result, "\n")
+}
+
+// formatInlineMarkdown handles inline markup, leaving code spans untouched
+// apart from removing their backticks
+func formatInlineMarkdown(line string, styled bool) string {
+	var b strings.Builder
+	last := 0
+
+	for _, loc := range mdInlineCodePattern.FindAllStringSubmatchIndex(line, -1) {
+		b.WriteString(formatEmphasis(line[last:loc[0]], styled))
+		code := line[loc[2]:loc[3]]
+		if styled {
+			code = Styled(code, StyleCommand)
+		}
+		b.WriteString(code)
+		last = loc[1]
+	}
+	b.WriteString(formatEmphasis(line[last:], styled))
+
+	return b.String()
+}
+
+// formatEmphasis converts bold, italic and link markup outside code spans
+func formatEmphasis(text string, styled bool) string {
+	text = mdLinkPattern.ReplaceAllString(text, "$1 ($2)")
+
+	text = mdBoldPattern.ReplaceAllStringFunc(text, func(match string) string {
+		inner := match[2 : len(match)-2]
+		if styled {
+			return Bold + inner + Reset
+		}
+		return inner
+	})
+
+	return mdItalicPattern.ReplaceAllString(text, "$1$2")
+}
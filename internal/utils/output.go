@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteCommandOnly writes the bare command followed by a newline to stdout,
+// with no styling, so it can be captured with shell substitution such as
+// $(ff -q "list files"). Warnings go to stderr to keep stdout clean.
+func WriteCommandOnly(stdout, stderr io.Writer, command string, warnings []string) error {
+	for _, warning := range warnings {
+		fmt.Fprintf(stderr, "warning: %s\n", warning)
+	}
+
+	if _, err := fmt.Fprintln(stdout, strings.TrimSpace(command)); err != nil {
+		return fmt.Errorf("failed to write command: %w", err)
+	}
+	return nil
+}
+
+// ShellQuote wraps s in single quotes for POSIX shells. Embedded single
+// quotes end the quoting, add an escaped quote and start it again, so the
+// result always evaluates back to s.
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// WriteShellAssignment writes the command as FORGOR_COMMAND='...' so scripts
+// can eval "$(ff -f shell ...)" and inspect $FORGOR_COMMAND before running
+// it. Warnings go to stderr so stdout stays safe to eval.
+func WriteShellAssignment(stdout, stderr io.Writer, command string, warnings []string) error {
+	for _, warning := range warnings {
+		fmt.Fprintf(stderr, "warning: %s\n", warning)
+	}
+
+	if _, err := fmt.Fprintf(stdout, "FORGOR_COMMAND=%s\n", ShellQuote(strings.TrimSpace(command))); err != nil {
+		return fmt.Errorf("failed to write command: %w", err)
+	}
+	return nil
+}
+
+// WriteJSON writes v to stdout as indented JSON followed by a newline
+func WriteJSON(stdout io.Writer, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode JSON output: %w", err)
+	}
+
+	if _, err := fmt.Fprintln(stdout, string(data)); err != nil {
+		return fmt.Errorf("failed to write JSON output: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ProjectContext describes the kind of project a directory belongs to
+type ProjectContext struct {
+	// Directory that was inspected
+	Dir string `json:"dir"`
+
+	// GitRoot is the enclosing git repository root, if any
+	GitRoot string `json:"git_root,omitempty"`
+
+	// Types are the detected project types, e.g. "Go", "Node.js"
+	Types []string `json:"types,omitempty"`
+}
+
+// projectMarkers maps marker files to the project type they indicate, in
+// the order types are reported
+var projectMarkers = []struct {
+	file        string
+	projectType string
+}{
+	{"go.mod", "Go"},
+	{"package.json", "Node.js"},
+	{"Cargo.toml", "Rust"},
+	{"pyproject.toml", "Python"},
+	{"requirements.txt", "Python"},
+	{"Gemfile", "Ruby"},
+	{"pom.xml", "Java (Maven)"},
+	{"build.gradle", "Java (Gradle)"},
+	{"composer.json", "PHP"},
+	{"Dockerfile", "Docker"},
+	{"docker-compose.yml", "Docker Compose"},
+	{"compose.yaml", "Docker Compose"},
+	{"Makefile", "Make"},
+}
+
+// projectCache holds detected project contexts per directory for the life
+// of the process, since marker files rarely change during a run
+var projectCache sync.Map
+
+// DetectProjectContext inspects dir for project marker files (go.mod,
+// package.json, Dockerfile, ...) and an enclosing git repository. Results
+// are cached per directory.
+func DetectProjectContext(dir string) *ProjectContext {
+	if cached, ok := projectCache.Load(dir); ok {
+		return cached.(*ProjectContext)
+	}
+
+	project := &ProjectContext{Dir: dir, GitRoot: findGitRoot(dir)}
+
+	seen := make(map[string]bool)
+	for _, marker := range projectMarkers {
+		if seen[marker.projectType] {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, marker.file)); err == nil {
+			project.Types = append(project.Types, marker.projectType)
+			seen[marker.projectType] = true
+		}
+	}
+
+	projectCache.Store(dir, project)
+	return project
+}
+
+// Summary returns a concise one-line description for prompts, or "" if
+// nothing was detected
+func (p *ProjectContext) Summary() string {
+	var parts []string
+	if len(p.Types) > 0 {
+		parts = append(parts, strings.Join(p.Types, ", ")+" project")
+	}
+	if p.GitRoot != "" {
+		parts = append(parts, "git repository")
+	}
+	return strings.Join(parts, " in a ")
+}
+
+// findGitRoot walks up from dir to the directory containing .git
+func findGitRoot(dir string) string {
+	current, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+
+	for {
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return current
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			return ""
+		}
+		current = parent
+	}
+}
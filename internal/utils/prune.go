@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// staleTempFileAge is how old a temporary file must be before pruning
+// removes it, so writes in progress are left alone
+const staleTempFileAge = time.Hour
+
+// PruneResult lists what a prune removed and how much space it freed
+type PruneResult struct {
+	Removed        []string
+	BytesReclaimed int64
+}
+
+// Add merges another prune result into r
+func (r *PruneResult) Add(other PruneResult) {
+	r.Removed = append(r.Removed, other.Removed...)
+	r.BytesReclaimed += other.BytesReclaimed
+}
+
+// PruneCacheDir removes the system context cache in dir once it has expired,
+// along with leftover temporary files older than an hour
+func PruneCacheDir(dir string, now time.Time) (PruneResult, error) {
+	result, err := PruneTempFiles(dir, now)
+	if err != nil {
+		return result, err
+	}
+
+	contextFile := filepath.Join(dir, "system-context.json")
+	if !systemContextExpired(contextFile, now) {
+		return result, nil
+	}
+
+	lockFd, err := acquireFileLock(filepath.Join(dir, "system-context.lock"), true)
+	if err != nil {
+		return result, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	defer releaseFileLock(lockFd)
+
+	if err := removeCounted(contextFile, &result); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// systemContextExpired reports whether the cached system context at path is
+// too old to be used. Unreadable caches count as expired.
+func systemContextExpired(path string, now time.Time) bool {
+	data, err := os.ReadFile(path) // #nosec G304 - path is within the cache directory
+	if err != nil {
+		return !os.IsNotExist(err)
+	}
+
+	var cached CachedSystemContext
+	if err := json.Unmarshal(data, &cached); err != nil || cached.Version != "1.0" {
+		return true
+	}
+	return now.Sub(cached.Timestamp) > cacheExpiration+gracePeriod
+}
+
+// PruneTempFiles removes temporary files left in dir by interrupted writes,
+// such as "recent_commands.json.tmp", once they are older than an hour
+func PruneTempFiles(dir string, now time.Time) (PruneResult, error) {
+	var result PruneResult
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return result, nil
+		}
+		return result, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || !(strings.HasSuffix(name, ".tmp") || strings.Contains(name, ".tmp-")) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || now.Sub(info.ModTime()) < staleTempFileAge {
+			continue
+		}
+		if err := removeCounted(filepath.Join(dir, name), &result); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// removeCounted removes path and records it and its size in result
+func removeCounted(path string, result *PruneResult) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	result.Removed = append(result.Removed, path)
+	result.BytesReclaimed += info.Size()
+	return nil
+}
@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// backupBinaryName is the copy of the binary replaced by the last update
+	backupBinaryName = "forgor.prev"
+
+	// backupInfoName records which version the backup is and where it ran from
+	backupInfoName = "forgor.prev.json"
+)
+
+// BackupInfo describes the binary saved before the last update
+type BackupInfo struct {
+	Version    string    `json:"version"`
+	Executable string    `json:"executable"`
+	BackedUpAt time.Time `json:"backed_up_at"`
+}
+
+// GetUpdateBackupDir returns the directory holding the pre-update backup
+func GetUpdateBackupDir() (string, error) {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find cache directory: %w", err)
+	}
+	return filepath.Join(userCacheDir, "forgor"), nil
+}
+
+// BackupExecutable copies the installed binary at execPath into backupDir,
+// along with its version, so a later RestoreExecutable can undo an update.
+// Any previous backup is replaced.
+func BackupExecutable(execPath, version, backupDir string) error {
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	backupPath := filepath.Join(backupDir, backupBinaryName)
+	tempPath, err := copyBeside(execPath, backupPath)
+	if err != nil {
+		return fmt.Errorf("failed to back up %s: %w", execPath, err)
+	}
+	if err := os.Rename(tempPath, backupPath); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to back up %s: %w", execPath, err)
+	}
+
+	info := BackupInfo{Version: version, Executable: execPath, BackedUpAt: time.Now()}
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal backup info: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(backupDir, backupInfoName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write backup info: %w", err)
+	}
+
+	return nil
+}
+
+// LoadBackupInfo returns the details of the backup in backupDir
+func LoadBackupInfo(backupDir string) (*BackupInfo, error) {
+	data, err := os.ReadFile(filepath.Join(backupDir, backupInfoName)) // #nosec G304 - path is within the cache directory
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no previous version to roll back to")
+		}
+		return nil, fmt.Errorf("failed to read backup info: %w", err)
+	}
+
+	var info BackupInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse backup info: %w", err)
+	}
+	if info.Executable == "" {
+		return nil, fmt.Errorf("backup info does not record the executable path")
+	}
+
+	return &info, nil
+}
+
+// RestoreExecutable reinstalls the backup in backupDir over the executable it
+// was taken from. The backup is kept, so a rollback can be repeated.
+func RestoreExecutable(backupDir string) (*BackupInfo, error) {
+	info, err := LoadBackupInfo(backupDir)
+	if err != nil {
+		return nil, err
+	}
+
+	backupPath := filepath.Join(backupDir, backupBinaryName)
+	if _, err := os.Stat(backupPath); err != nil {
+		return nil, fmt.Errorf("backup binary is missing: %w", err)
+	}
+
+	tempPath, err := copyBeside(backupPath, info.Executable)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tempPath, info.Executable); err != nil {
+		os.Remove(tempPath)
+		return nil, fmt.Errorf("failed to restore %s: %w", info.Executable, err)
+	}
+
+	return info, nil
+}
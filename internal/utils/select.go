@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// SelectionAction is what the user chose to do with a picked command
+type SelectionAction int
+
+const (
+	SelectionNone SelectionAction = iota // Nothing picked
+	SelectionRun                         // Run the picked command
+	SelectionCopy                        // Keep the picked command without running it
+)
+
+// Selection is the result of picking among numbered commands
+type Selection struct {
+	Index  int
+	Action SelectionAction
+}
+
+// SelectCommand lists commands as a numbered list on w and reads the user's
+// choice from r. Entering a number runs that command, "c" followed by a
+// number copies it, and an empty line or "q" picks nothing. Invalid input is
+// asked again; end of input picks nothing.
+func SelectCommand(r io.Reader, w io.Writer, commands []string) (Selection, error) {
+	if len(commands) == 0 {
+		return Selection{}, nil
+	}
+
+	fmt.Fprintf(w, "%s\n", NumberedList(commands, StyleCommand))
+
+	reader := bufio.NewReader(r)
+	for {
+		fmt.Fprintf(w, "Run a command [1-%d], copy one with c<number>, or press Enter to skip: ", len(commands))
+
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return Selection{}, fmt.Errorf("failed to read selection: %w", err)
+		}
+
+		selection, ok := parseSelection(strings.TrimSpace(strings.ToLower(line)), len(commands))
+		if ok {
+			return selection, nil
+		}
+		if err == io.EOF {
+			return Selection{}, nil
+		}
+		fmt.Fprintf(w, "Please enter a number between 1 and %d\n", len(commands))
+	}
+}
+
+// parseSelection parses a selection such as "2" or "c2" against count commands
+func parseSelection(input string, count int) (Selection, bool) {
+	if input == "" || input == "q" {
+		return Selection{}, true
+	}
+
+	action := SelectionRun
+	if rest, found := strings.CutPrefix(input, "c"); found {
+		action = SelectionCopy
+		input = strings.TrimSpace(rest)
+	}
+
+	n, err := strconv.Atoi(input)
+	if err != nil || n < 1 || n > count {
+		return Selection{}, false
+	}
+	return Selection{Index: n - 1, Action: action}, true
+}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"cmp"
+	"strconv"
+	"strings"
+)
+
+// version is a parsed semantic version such as 1.4.0-beta.2
+type version struct {
+	core       [3]int
+	prerelease []string
+}
+
+// parseVersion parses a semantic version, allowing a leading "v" and a
+// missing minor or patch number. Build metadata (+...) is ignored.
+func parseVersion(s string) (version, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+
+	var v version
+	core := s
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		core = s[:i]
+		v.prerelease = strings.Split(s[i+1:], ".")
+	}
+
+	parts := strings.Split(core, ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return version{}, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return version{}, false
+		}
+		v.core[i] = n
+	}
+
+	return v, true
+}
+
+// CompareVersions compares two semantic versions, returning -1, 0 or 1 when a
+// is older than, the same as or newer than b. Prereleases sort before their
+// release (1.2.0-rc.1 < 1.2.0). Versions that don't parse, such as "dev",
+// sort before all valid versions.
+func CompareVersions(a, b string) int {
+	va, okA := parseVersion(a)
+	vb, okB := parseVersion(b)
+	switch {
+	case !okA && !okB:
+		return strings.Compare(a, b)
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+
+	for i := range va.core {
+		if va.core[i] != vb.core[i] {
+			return cmp.Compare(va.core[i], vb.core[i])
+		}
+	}
+
+	return comparePrerelease(va.prerelease, vb.prerelease)
+}
+
+// comparePrerelease orders prerelease identifiers per the semver spec
+func comparePrerelease(a, b []string) int {
+	// A release is newer than any of its prereleases
+	switch {
+	case len(a) == 0 && len(b) == 0:
+		return 0
+	case len(a) == 0:
+		return 1
+	case len(b) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(a) && i < len(b); i++ {
+		na, errA := strconv.Atoi(a[i])
+		nb, errB := strconv.Atoi(b[i])
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				return cmp.Compare(na, nb)
+			}
+		case errA == nil:
+			// Numeric identifiers sort before alphanumeric ones
+			return -1
+		case errB == nil:
+			return 1
+		default:
+			if c := strings.Compare(a[i], b[i]); c != 0 {
+				return c
+			}
+		}
+	}
+
+	return cmp.Compare(len(a), len(b))
+}
@@ -7,8 +7,8 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
-	"strconv"
 	"strings"
+	"time"
 )
 
 // GetCurrentShell attempts to detect the current shell
@@ -48,6 +48,12 @@ func GetOperatingSystem() string {
 	}
 }
 
+// GetPlatform returns the OS/architecture forgor was built for, e.g.
+// darwin/arm64, matching the naming of release binaries
+func GetPlatform() string {
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
+
 // GetWorkingDirectory returns the current working directory
 func GetWorkingDirectory() string {
 	wd, err := os.Getwd()
@@ -57,9 +63,28 @@ func GetWorkingDirectory() string {
 	return wd
 }
 
+// ResolveWorkingDirectory returns the absolute path of dir, which must be an
+// existing directory
+func ResolveWorkingDirectory(dir string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("invalid working directory %s: %w", dir, err)
+	}
+
+	info, err := os.Stat(absDir)
+	if err != nil {
+		return "", fmt.Errorf("working directory %s does not exist", dir)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("working directory %s is not a directory", dir)
+	}
+
+	return absDir, nil
+}
+
 // IsShellSupported checks if the shell is supported for history reading
 func IsShellSupported(shell string) bool {
-	supportedShells := []string{"bash", "zsh", "fish"}
+	supportedShells := []string{"bash", "zsh", "fish", "sh", "dash", "ksh"}
 	shell = strings.ToLower(shell)
 
 	for _, supported := range supportedShells {
@@ -86,6 +111,12 @@ func GetShellHistoryFile(shell string) string {
 	switch shell {
 	case "bash":
 		historyPath = filepath.Join(homeDir, ".bash_history")
+	case "sh", "dash":
+		// dash keeps no history file and sh is usually bash in POSIX mode,
+		// so interactive history is in bash's file
+		historyPath = filepath.Join(homeDir, ".bash_history")
+	case "ksh":
+		historyPath = filepath.Join(homeDir, ".sh_history")
 	case "zsh":
 		historyPath = filepath.Join(homeDir, ".zsh_history")
 	case "fish":
@@ -120,6 +151,7 @@ func DetectShellFromProcess() string {
 // NormalizeShellName normalizes shell names to standard format
 func NormalizeShellName(shell string) string {
 	shell = strings.ToLower(strings.TrimSpace(shell))
+	base := filepath.Base(shell)
 
 	// Handle common variations
 	switch {
@@ -129,10 +161,18 @@ func NormalizeShellName(shell string) string {
 		return "zsh"
 	case strings.Contains(shell, "fish"):
 		return "fish"
+	case strings.Contains(shell, "dash"):
+		return "dash"
+	case strings.Contains(shell, "ksh"): // ksh93, mksh, pdksh
+		return "ksh"
+	case base == "sh":
+		return "sh"
 	case strings.Contains(shell, "cmd"):
 		return "cmd"
 	case strings.Contains(shell, "powershell") || strings.Contains(shell, "pwsh"):
 		return "powershell"
+	case base == "nu" || strings.Contains(shell, "nushell"):
+		return "nu"
 	default:
 		return shell
 	}
@@ -169,20 +209,60 @@ func GetEnvironmentInfo() map[string]string {
 	return info
 }
 
+// HistoryOptions selects which history GetHistoryWithOptions returns
+type HistoryOptions struct {
+	// MaxCommands is how many of the most recent commands to return
+	MaxCommands int
+	// MaxAge drops commands logged longer ago than this. Only the enhanced
+	// command log records times; 0 keeps commands of any age.
+	MaxAge time.Duration
+	// CurrentSession keeps only logged commands from this terminal session,
+	// matched by the logger's SHELL_SESSION_ID, or SHELL_TTY if that is
+	// unset. Without either, all history is used.
+	CurrentSession bool
+	// NoNativeFallback reads only the enhanced command log. Without it, the
+	// shell's own history file is read when the log has no commands.
+	NoNativeFallback bool
+}
+
+// currentHistorySession returns the session and tty the history logger
+// exports to commands run in its shell
+func currentHistorySession() (session, tty string) {
+	return os.Getenv("SHELL_SESSION_ID"), os.Getenv("SHELL_TTY")
+}
+
+// inSession reports whether entry was logged by the given session, or the
+// given tty when the session is unknown
+func inSession(entry history.HistoryEntry, session, tty string) bool {
+	if session != "" {
+		return entry.Session == session
+	}
+	return tty == "" || entry.TTY == tty
+}
+
 // GetHistory reads history from the enhanced logger or native shell history files
 func GetHistory(maxCommands int) ([]history.HistoryEntry, error) {
+	return GetHistoryWithOptions(HistoryOptions{MaxCommands: maxCommands})
+}
+
+// GetHistoryWithOptions reads history like GetHistory, filtered by options
+func GetHistoryWithOptions(options HistoryOptions) ([]history.HistoryEntry, error) {
+	maxCommands := options.MaxCommands
 	if maxCommands <= 0 {
 		return []history.HistoryEntry{}, nil
 	}
 
 	// 1. Try the enhanced logger first
-	entries, err := readFromCommandLog(maxCommands)
+	entries, err := readFromCommandLog(options)
 	if err == nil && len(entries) > 0 {
-		return entries, nil // Logger script handles sanitization.
+		return history.CollapseDuplicates(entries), nil // Logger script handles sanitization.
 	}
 
-	// 2. Fallback to native history
-	shell := GetCurrentShell()
+	// 2. Fallback to native history, unless only the log may be read
+	if options.NoNativeFallback {
+		return []history.HistoryEntry{}, nil
+	}
+	shell := NormalizeShellName(GetCurrentShell())
 	commands, err := ReadShellHistory(shell, maxCommands)
 	if err != nil {
 		return nil, err
@@ -193,25 +273,26 @@ func GetHistory(maxCommands int) ([]history.HistoryEntry, error) {
 	for i, cmd := range commands {
 		fallbackEntries[i] = history.HistoryEntry{Command: cmd, ExitCode: -1}
 	}
-	return filterSensitiveHistory(fallbackEntries), nil
+	return history.CollapseDuplicates(filterSensitiveHistory(fallbackEntries)), nil
 }
 
-// readFromCommandLog reads from the enhanced logger's file.
-func readFromCommandLog(maxCommands int) ([]history.HistoryEntry, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, err
+// readFromCommandLog reads from the enhanced logger's file, skipping
+// commands older than options.MaxAge and, with options.CurrentSession,
+// commands from other sessions.
+func readFromCommandLog(options HistoryOptions) ([]history.HistoryEntry, error) {
+	maxCommands := options.MaxCommands
+	var cutoff time.Time
+	if options.MaxAge > 0 {
+		cutoff = time.Now().Add(-options.MaxAge)
+	}
+	var session, tty string
+	if options.CurrentSession {
+		session, tty = currentHistorySession()
 	}
 
-	// Clean and validate the home directory path
-	homeDir = filepath.Clean(homeDir)
-
-	// Construct and clean the log file path
-	logFilePath := filepath.Clean(filepath.Join(homeDir, ".command_log"))
-
-	// Validate that the path is within the home directory
-	if !strings.HasPrefix(logFilePath, homeDir) {
-		return nil, fmt.Errorf("invalid log file path: potential directory traversal")
+	logFilePath, err := commandLogPath()
+	if err != nil {
+		return nil, err
 	}
 
 	if _, err := os.Stat(logFilePath); os.IsNotExist(err) {
@@ -227,22 +308,18 @@ func readFromCommandLog(maxCommands int) ([]history.HistoryEntry, error) {
 	var allEntries []history.HistoryEntry
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		line := scanner.Text()
-		parts := strings.Split(line, "|")
-		// New format: timestamp|shell|pid|session_id|tty|pwd|exit_code|full_command_line
-		if len(parts) >= 8 {
-			exitCodeStr := parts[6]
-			fullCommand := strings.TrimSpace(parts[7])
-
-			exitCode, err := strconv.Atoi(exitCodeStr)
-			if err != nil {
-				exitCode = -1 // Mark as unknown if parsing fails
-			}
-
-			if fullCommand != "" {
-				allEntries = append(allEntries, history.HistoryEntry{Command: fullCommand, ExitCode: exitCode})
-			}
+		entry, ok := history.ParseCommandLogLine(scanner.Text())
+		if !ok {
+			continue
+		}
+		// Entries without a readable timestamp are kept
+		if !cutoff.IsZero() && !entry.Timestamp.IsZero() && entry.Timestamp.Before(cutoff) {
+			continue
+		}
+		if !inSession(entry, session, tty) {
+			continue
 		}
+		allEntries = append(allEntries, entry)
 	}
 	if err := scanner.Err(); err != nil {
 		return nil, err
@@ -254,6 +331,59 @@ func readFromCommandLog(maxCommands int) ([]history.HistoryEntry, error) {
 	return allEntries[len(allEntries)-maxCommands:], nil
 }
 
+// commandLogPath returns the path of the enhanced logger's file in the
+// home directory
+func commandLogPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	// Clean and validate the home directory path
+	homeDir = filepath.Clean(homeDir)
+
+	// Construct and clean the log file path
+	logFilePath := filepath.Clean(filepath.Join(homeDir, ".command_log"))
+
+	// Validate that the path is within the home directory
+	if !strings.HasPrefix(logFilePath, homeDir) {
+		return "", fmt.Errorf("invalid log file path: potential directory traversal")
+	}
+	return logFilePath, nil
+}
+
+// AppendToCommandLog records a command forgor ran, with its exit code, in
+// the enhanced command log so later --history calls include it. The line
+// carries this session's id and tty as the history logger would write them.
+func AppendToCommandLog(command string, exitCode int, workingDirectory string) error {
+	logFilePath, err := commandLogPath()
+	if err != nil {
+		return err
+	}
+
+	session, tty := currentHistorySession()
+	entry := history.HistoryEntry{
+		Command:          command,
+		ExitCode:         exitCode,
+		WorkingDirectory: workingDirectory,
+		Timestamp:        time.Now(),
+		Session:          session,
+		TTY:              tty,
+	}
+	line := history.FormatCommandLogLine(entry, NormalizeShellName(GetCurrentShell()), os.Getpid())
+
+	file, err := os.OpenFile(logFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600) // #nosec G304 - path is validated in commandLogPath
+	if err != nil {
+		return fmt.Errorf("failed to open command log: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := fmt.Fprintln(file, line); err != nil {
+		return fmt.Errorf("failed to write command log: %w", err)
+	}
+	return nil
+}
+
 // ReadShellHistory reads the last N commands from the shell history file
 func ReadShellHistory(shell string, maxCommands int) ([]string, error) {
 	if maxCommands <= 0 {
@@ -278,7 +408,7 @@ func ReadShellHistory(shell string, maxCommands int) ([]string, error) {
 		return readZshHistory(historyFile, maxCommands)
 	case "fish":
 		return readFishHistory(historyFile, maxCommands)
-	case "bash":
+	case "bash", "sh", "dash", "ksh":
 		fallthrough
 	default:
 		return readBashHistory(historyFile, maxCommands)
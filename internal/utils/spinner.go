@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// spinnerFrames are drawn in turn while a spinner runs
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// spinnerInterval is how often the spinner frame changes
+const spinnerInterval = 100 * time.Millisecond
+
+// Spinner shows an animated status line while waiting on a slow call
+type Spinner struct {
+	w       io.Writer
+	message string
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// IsTerminal reports whether w is a terminal rather than a pipe or file
+func IsTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+// StartSpinner draws a spinner with message on w until Stop is called.
+// Nothing is drawn unless w is a terminal, so piped and redirected output
+// stays clean.
+func StartSpinner(w io.Writer, message string) *Spinner {
+	s := &Spinner{w: w, message: message}
+	if !IsTerminal(w) {
+		return s
+	}
+
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.run()
+	return s
+}
+
+// Active reports whether the spinner is being drawn
+func (s *Spinner) Active() bool {
+	return s.stop != nil
+}
+
+// run redraws the spinner until stopped, then clears its line
+func (s *Spinner) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(spinnerInterval)
+	defer ticker.Stop()
+
+	for frame := 0; ; frame++ {
+		fmt.Fprintf(s.w, "\r%s %s", Styled(spinnerFrames[frame%len(spinnerFrames)], StyleInfo), s.message)
+		select {
+		case <-s.stop:
+			fmt.Fprint(s.w, "\r\033[K")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop clears the spinner. It is safe to call more than once, and on a
+// spinner that was never drawn.
+func (s *Spinner) Stop() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+}
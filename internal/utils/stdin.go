@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// maxStdinQuerySize limits how much piped input is read as a query
+const maxStdinQuerySize = 64 * 1024
+
+// StdinIsPiped reports whether stdin is a pipe or file rather than a terminal
+func StdinIsPiped() bool {
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice == 0
+}
+
+// ReadQuery reads a natural language query from r, joining multiple lines
+// with spaces
+func ReadQuery(r io.Reader) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxStdinQuerySize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read query from stdin: %w", err)
+	}
+
+	query := strings.Join(strings.Fields(string(data)), " ")
+	if query == "" {
+		return "", fmt.Errorf("no query provided on stdin")
+	}
+	return query, nil
+}
+
+// ResolveQuery builds the query from command-line arguments, falling back to
+// stdin when the only argument is "-" or when no arguments are given and
+// stdin is piped
+func ResolveQuery(args []string, stdin io.Reader, piped bool) (string, error) {
+	if len(args) == 1 && args[0] == "-" {
+		return ReadQuery(stdin)
+	}
+
+	if len(args) == 0 {
+		if piped {
+			return ReadQuery(stdin)
+		}
+		return "", fmt.Errorf("no query provided")
+	}
+
+	return strings.Join(args, " "), nil
+}
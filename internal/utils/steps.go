@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// StepResult records the outcome of a single step in a multi-step command
+type StepResult struct {
+	Index    int
+	Command  string
+	ExitCode int
+}
+
+// StepRun describes how RunSteps runs a multi-step command
+type StepRun struct {
+	// Shell runs the script. Shells that can't run POSIX scripts, such as
+	// fish, are replaced by sh.
+	Shell string
+	// ContinueOnError keeps running the remaining steps after a failure
+	ContinueOnError bool
+	// Header, if set, returns text printed to stdout before each step runs
+	Header func(index int, step string) string
+
+	Stdin          io.Reader
+	Stdout, Stderr io.Writer
+}
+
+// RunSteps runs steps in order as one script in a single shell, so state such
+// as the working directory, exported variables and sourced files carries over
+// from one step to the next. The script stops at the first failing step unless
+// run.ContinueOnError is set. The results of every step that ran are returned.
+// A failure is returned as a *CommandError for the last failed step, with the
+// tail of the script's output, and an error naming the failed steps.
+func RunSteps(steps []string, run StepRun) ([]StepResult, error) {
+	// The script appends each step's exit code to a report file, since the
+	// shell's own exit code can't tell which step failed
+	report, err := os.CreateTemp("", "forgor-steps-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create step report: %w", err)
+	}
+	report.Close()
+	defer os.Remove(report.Name())
+
+	cmd := exec.Command(stepScriptShell(run.Shell), "-c", stepScript(steps, run, report.Name()))
+	captured := &tailBuffer{limit: maxCapturedOutput}
+	cmd.Stdin = run.Stdin
+	cmd.Stdout = io.MultiWriter(run.Stdout, captured)
+	cmd.Stderr = io.MultiWriter(run.Stderr, captured)
+
+	runErr := cmd.Run()
+	var exitErr *exec.ExitError
+	if runErr != nil && !errors.As(runErr, &exitErr) {
+		return nil, fmt.Errorf("failed to run steps: %w", runErr)
+	}
+
+	results, err := readStepReport(report.Name(), steps)
+	if err != nil {
+		return nil, err
+	}
+
+	// A step that exits the shell itself never reaches its report line
+	stoppedAtFailure := !run.ContinueOnError && len(results) > 0 && results[len(results)-1].ExitCode != 0
+	if runErr != nil && !stoppedAtFailure && len(results) < len(steps) {
+		i := len(results)
+		results = append(results, StepResult{Index: i, Command: steps[i], ExitCode: ExitCode(runErr)})
+	}
+
+	var failed []string
+	var last StepResult
+	for _, result := range results {
+		if result.ExitCode != 0 {
+			failed = append(failed, fmt.Sprintf("%d", result.Index+1))
+			last = result
+		}
+	}
+	if len(failed) == 0 {
+		return results, nil
+	}
+
+	failure := &CommandError{
+		Result: &CommandResult{Command: last.Command, ExitCode: last.ExitCode, Output: captured.String()},
+		Err:    fmt.Errorf("%d of %d steps failed (steps %s)", len(failed), len(steps), strings.Join(failed, ", ")),
+	}
+	if !run.ContinueOnError {
+		failure.Err = fmt.Errorf("step %d of %d failed: %w", last.Index+1, len(steps), runErr)
+	}
+	return results, failure
+}
+
+// stepScript builds the POSIX script that runs steps, appending "<step>
+// <exit code>" to report after each one
+func stepScript(steps []string, run StepRun, report string) string {
+	var script strings.Builder
+	for i, step := range steps {
+		if run.Header != nil {
+			fmt.Fprintf(&script, "printf '%%s\\n' %s\n", ShellQuote(run.Header(i, step)))
+		}
+		script.WriteString(step)
+		fmt.Fprintf(&script, "\n__forgor_status=$?\nprintf '%d %%s\\n' \"$__forgor_status\" >> %s\n", i+1, ShellQuote(report))
+		if !run.ContinueOnError {
+			script.WriteString("[ \"$__forgor_status\" -eq 0 ] || exit \"$__forgor_status\"\n")
+		}
+	}
+	script.WriteString("exit 0\n")
+	return script.String()
+}
+
+// readStepReport reads the results of the steps that ran from a report
+// written by stepScript
+func readStepReport(path string, steps []string) ([]StepResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read step report: %w", err)
+	}
+	defer file.Close()
+
+	var results []StepResult
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var step, exitCode int
+		if _, err := fmt.Sscanf(scanner.Text(), "%d %d", &step, &exitCode); err != nil || step < 1 || step > len(steps) {
+			return nil, fmt.Errorf("invalid step report line %q", scanner.Text())
+		}
+		results = append(results, StepResult{Index: step - 1, Command: steps[step-1], ExitCode: exitCode})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read step report: %w", err)
+	}
+	return results, nil
+}
+
+// stepScriptShell returns shell if it runs POSIX scripts, or sh otherwise
+func stepScriptShell(shell string) string {
+	switch NormalizeShellName(shell) {
+	case "bash", "zsh", "sh", "dash", "ksh":
+		return shell
+	default:
+		return "sh"
+	}
+}
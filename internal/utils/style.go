@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 )
 
@@ -40,6 +41,9 @@ const (
 	BgWhite   = "\033[47m"
 )
 
+// ansiPattern matches ANSI escape sequences used for styling
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
 // Style types
 type StyleType int
 
@@ -176,6 +180,11 @@ func SimpleBox(content string, style StyleType) string {
 	return result.String()
 }
 
+// StripANSI removes ANSI styling codes from text
+func StripANSI(text string) string {
+	return ansiPattern.ReplaceAllString(text, "")
+}
+
 // Divider creates a horizontal divider
 func Divider(title string, style StyleType) string {
 	width := 60
@@ -254,6 +263,19 @@ func List(items []string, style StyleType) string {
 	return strings.TrimSuffix(result.String(), "\n")
 }
 
+// NumberedList creates a list with each item prefixed by its step number
+func NumberedList(items []string, style StyleType) string {
+	var result strings.Builder
+	width := len(fmt.Sprintf("%d", len(items)))
+	for i, item := range items {
+		prefix := fmt.Sprintf("%*d. ", width, i+1)
+		indent := strings.Repeat(" ", len(prefix))
+		item = strings.ReplaceAll(item, "\n", "\n"+indent)
+		result.WriteString(getStyle(style) + prefix + Reset + item + "\n")
+	}
+	return strings.TrimSuffix(result.String(), "\n")
+}
+
 // Table creates a simple table
 func Table(headers []string, rows [][]string, style StyleType) string {
 	if len(headers) == 0 || len(rows) == 0 {
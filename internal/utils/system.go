@@ -9,6 +9,7 @@ import (
 	"os/user"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -40,6 +41,9 @@ type ToolContext struct {
 	NetworkTools     []string          `json:"network_tools"`
 	Available        map[string]bool   `json:"available"`
 	LastChecked      time.Time         `json:"last_checked"`
+	// CategoryChecked records when each tool category was last detected, so
+	// stale categories can be refreshed without re-detecting the others
+	CategoryChecked map[string]time.Time `json:"category_checked,omitempty"`
 }
 
 // LanguageRuntime represents a programming language runtime
@@ -73,6 +77,10 @@ var (
 	cacheFile     string
 	lockFile      string
 	initCacheOnce sync.Once
+
+	// cacheInitErr is set when the cache directory can't be written, in which
+	// case the system context is only cached in memory for this process
+	cacheInitErr error
 )
 
 // CachedSystemContext represents the persistent cache structure
@@ -90,11 +98,13 @@ type CacheInfo struct {
 	FileExists  bool      `json:"file_exists"`
 	FileSize    int64     `json:"file_size"`
 	FileModTime time.Time `json:"file_mod_time"`
+	// InMemoryOnly is set when the cache directory isn't writable and the
+	// system context is only cached for the current process
+	InMemoryOnly bool `json:"in_memory_only,omitempty"`
 }
 
 // initPersistentCache initializes the persistent cache directory and file paths
 func initPersistentCache() error {
-	var err error
 	initCacheOnce.Do(func() {
 		// Get user cache directory
 		var userCacheDir string
@@ -114,16 +124,64 @@ func initPersistentCache() error {
 		cacheFile = filepath.Join(cacheDir, "system-context.json")
 		lockFile = filepath.Join(cacheDir, "system-context.lock")
 
-		// Create cache directory if it doesn't exist
-		err = os.MkdirAll(cacheDir, 0755)
+		// Fall back to the in-memory cache, warning once, when the directory
+		// can't be created or written to (read-only home, restricted container)
+		if dirErr := checkCacheDirWritable(cacheDir); dirErr != nil {
+			cacheInitErr = dirErr
+			Warnf("%s Cache directory %s is not writable (%v); caching system context in memory only\n",
+				Styled("[WARNING]", StyleWarning), cacheDir, dirErr)
+		}
 	})
-	return err
+	return cacheInitErr
+}
+
+// checkCacheDirWritable creates dir if needed and checks a file can be
+// written in it
+func checkCacheDirWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	probe, err := os.CreateTemp(dir, ".write-check.tmp-")
+	if err != nil {
+		return err
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// IsCacheInMemoryOnly reports whether the cache directory is unwritable, so
+// the system context is only cached for the current process
+func IsCacheInMemoryOnly() bool {
+	return initPersistentCache() != nil
 }
 
 // loadPersistentCache loads the system context from persistent cache
 func loadPersistentCache() (*SystemContext, error) {
+	cached, err := readPersistentCache()
+	if err != nil || cached == nil {
+		return nil, err
+	}
+
+	age := time.Since(cached.Timestamp)
+	if age > cacheExpiration+gracePeriod {
+		return nil, fmt.Errorf("cache too old: %v", age)
+	}
+
+	// Update in-memory cache
+	contextCacheMutex.Lock()
+	systemContextCache = cached.Context
+	cacheTimestamp = cached.Timestamp
+	contextCacheMutex.Unlock()
+
+	return cached.Context, nil
+}
+
+// readPersistentCache reads the persistent cache whatever its age. It returns
+// nil without an error when there is no cache file.
+func readPersistentCache() (*CachedSystemContext, error) {
 	if err := initPersistentCache(); err != nil {
-		return nil, fmt.Errorf("failed to initialize cache: %w", err)
+		return nil, nil // In-memory only, already warned about
 	}
 
 	// Check if cache file exists
@@ -147,26 +205,22 @@ func loadPersistentCache() (*SystemContext, error) {
 	// Parse cache
 	var cached CachedSystemContext
 	if err := json.Unmarshal(data, &cached); err != nil {
+		// A truncated or garbled file would fail to parse on every run, so
+		// remove it and let the context be rebuilt
+		Warnf("%s System context cache %s is corrupt (%v); rebuilding it\n",
+			Styled("[WARNING]", StyleWarning), cacheFile, err)
+		if removeErr := os.Remove(cacheFile); removeErr != nil && !os.IsNotExist(removeErr) {
+			return nil, fmt.Errorf("failed to remove corrupt cache: %w", removeErr)
+		}
 		return nil, fmt.Errorf("failed to parse cache: %w", err)
 	}
 
-	// Validate cache version and age
-	if cached.Version != "1.0" {
+	// Validate cache version
+	if cached.Version != "1.0" || cached.Context == nil {
 		return nil, fmt.Errorf("cache version mismatch")
 	}
 
-	age := time.Since(cached.Timestamp)
-	if age > cacheExpiration+gracePeriod {
-		return nil, fmt.Errorf("cache too old: %v", age)
-	}
-
-	// Update in-memory cache
-	contextCacheMutex.Lock()
-	systemContextCache = cached.Context
-	cacheTimestamp = cached.Timestamp
-	contextCacheMutex.Unlock()
-
-	return cached.Context, nil
+	return &cached, nil
 }
 
 // savePersistentCache saves the system context to persistent cache
@@ -262,7 +316,7 @@ func GetSystemContext() *SystemContext {
 	if cached, err := loadPersistentCache(); err == nil && cached != nil {
 		age := time.Since(cacheTimestamp)
 		if verbose {
-			fmt.Printf("📁 Loaded system context from cache (age: %v)\n", age)
+			Debugf("📁 Loaded system context from cache (age: %v)\n", age)
 		}
 
 		// Check if we should trigger background refresh
@@ -271,9 +325,9 @@ func GetSystemContext() *SystemContext {
 				go func() {
 					defer atomic.StoreInt32(&refreshInProgress, 0)
 					if verbose {
-						fmt.Printf("🔄 Refreshing system context in background...\n")
+						Debugf("🔄 Refreshing system context in background...\n")
 					}
-					refreshSystemContextInternal(false) // silent refresh
+					refreshSystemContextInternal(false, false) // silent refresh
 				}()
 			}
 		}
@@ -283,14 +337,15 @@ func GetSystemContext() *SystemContext {
 
 	// No valid cache - must refresh synchronously
 	if verbose {
-		fmt.Printf("🔍 Building system context (no valid cache found)...\n")
+		Debugf("🔍 Building system context (no valid cache found)...\n")
 	}
 
-	return refreshSystemContextInternal(verbose)
+	return refreshSystemContextInternal(verbose, false)
 }
 
-// refreshSystemContextInternal performs the actual cache refresh
-func refreshSystemContextInternal(verbose bool) *SystemContext {
+// refreshSystemContextInternal performs the actual cache refresh. Unless full
+// is set, only the tool categories whose TTL has passed are re-detected.
+func refreshSystemContextInternal(verbose, full bool) *SystemContext {
 	contextCacheMutex.Lock()
 	defer contextCacheMutex.Unlock()
 
@@ -343,7 +398,10 @@ func refreshSystemContextInternal(verbose bool) *SystemContext {
 		toolsStep = timer.StartStep("Tool Detection")
 	}
 
-	tools := gatherToolContext()
+	tools, refreshed := detectStaleTools(full)
+	if verbose {
+		Debugf("🔄 Detected tool categories: %s\n", strings.Join(refreshed, ", "))
+	}
 
 	if toolsStep != nil {
 		toolsStep.End()
@@ -362,7 +420,7 @@ func refreshSystemContextInternal(verbose bool) *SystemContext {
 		User:             username,
 		HomeDirectory:    homeDir,
 		WorkingDirectory: wd,
-		Environment:      getRelevantEnvironment(),
+		Environment:      GetRelevantEnvironment(),
 		Tools:            tools,
 	}
 
@@ -378,12 +436,15 @@ func refreshSystemContextInternal(verbose bool) *SystemContext {
 		saveStep = timer.StartStep("Cache Save")
 	}
 
-	if err := savePersistentCache(systemContextCache); err != nil {
-		if verbose {
-			fmt.Printf("⚠️  Failed to save cache: %v\n", err)
+	// An unwritable cache directory was already warned about once
+	if !IsCacheInMemoryOnly() {
+		if err := savePersistentCache(systemContextCache); err != nil {
+			if verbose {
+				Warnf("⚠️  Failed to save cache: %v\n", err)
+			}
+		} else if verbose {
+			Debugf("💾 Saved system context to persistent cache\n")
 		}
-	} else if verbose {
-		fmt.Printf("💾 Saved system context to persistent cache\n")
 	}
 
 	if saveStep != nil {
@@ -396,7 +457,7 @@ func refreshSystemContextInternal(verbose bool) *SystemContext {
 // RefreshSystemContext forces a refresh of the system context cache
 func RefreshSystemContext() *SystemContext {
 	if isVerboseMode() {
-		fmt.Printf("🔄 Forcing system context refresh...\n")
+		Debugf("🔄 Forcing system context refresh...\n")
 	}
 
 	contextCacheMutex.Lock()
@@ -405,7 +466,58 @@ func RefreshSystemContext() *SystemContext {
 	systemContextCache = nil
 	contextCacheMutex.Unlock()
 
-	return refreshSystemContextInternal(isVerboseMode())
+	return refreshSystemContextInternal(isVerboseMode(), true)
+}
+
+// detectStaleTools returns the tools of the previous system context, from
+// memory or the persistent cache whatever its age, with the stale categories
+// re-detected. Without a previous context, or when full is set, every
+// category is detected. The refreshed category names are returned too.
+func detectStaleTools(full bool) (ToolContext, []string) {
+	var previous *SystemContext
+	if !full {
+		previous = systemContextCache
+		if previous == nil {
+			if cached, err := readPersistentCache(); err == nil && cached != nil {
+				previous = cached.Context
+			}
+		}
+	}
+
+	if previous == nil {
+		return DetectTools(), ToolCategories()
+	}
+
+	tools := previous.Tools
+	refreshed := tools.RefreshStale(time.Now())
+	return tools, refreshed
+}
+
+// RefreshToolCategory re-detects one category of tools in the system context
+// and saves it, keeping the other categories as they were detected
+func RefreshToolCategory(name string) (*SystemContext, error) {
+	if _, err := lookupToolCategory(name); err != nil {
+		return nil, err
+	}
+
+	current := GetSystemContext()
+
+	contextCacheMutex.Lock()
+	defer contextCacheMutex.Unlock()
+
+	updated := *current
+	if err := updated.Tools.RefreshCategory(name); err != nil {
+		return nil, err
+	}
+	systemContextCache = &updated
+	cacheTimestamp = time.Now()
+
+	if !IsCacheInMemoryOnly() {
+		if err := savePersistentCache(systemContextCache); err != nil {
+			return systemContextCache, fmt.Errorf("failed to save cache: %w", err)
+		}
+	}
+	return systemContextCache, nil
 }
 
 // RefreshSystemContextBackground triggers a background refresh without blocking
@@ -414,15 +526,15 @@ func RefreshSystemContextBackground() {
 		go func() {
 			defer atomic.StoreInt32(&refreshInProgress, 0)
 			if isVerboseMode() {
-				fmt.Printf("🔄 Starting background system context refresh...\n")
+				Debugf("🔄 Starting background system context refresh...\n")
 			}
-			refreshSystemContextInternal(false)
+			refreshSystemContextInternal(false, false)
 			if isVerboseMode() {
-				fmt.Printf("✅ Background system context refresh completed\n")
+				Debugf("✅ Background system context refresh completed\n")
 			}
 		}()
 	} else if isVerboseMode() {
-		fmt.Printf("⏳ Background refresh already in progress\n")
+		Debugf("⏳ Background refresh already in progress\n")
 	}
 }
 
@@ -475,43 +587,31 @@ func GetCacheAge() time.Duration {
 	return 0
 }
 
-// gatherToolContext detects available tools and capabilities
-func gatherToolContext() ToolContext {
-	tools := ToolContext{
-		Available:   make(map[string]bool),
-		LastChecked: time.Now(),
-	}
-
-	// Detect package managers
-	tools.PackageManagers = detectPackageManagers()
-
-	// Detect programming languages
-	tools.Languages = detectLanguageRuntimes()
-
-	// Detect development tools
-	tools.DevelopmentTools = detectDevelopmentTools()
-
-	// Detect system commands
-	tools.SystemCommands = detectSystemCommands()
-
-	// Detect container tools
-	tools.ContainerTools = detectContainerTools()
-
-	// Detect cloud tools
-	tools.CloudTools = detectCloudTools()
-
-	// Detect database tools
-	tools.DatabaseTools = detectDatabaseTools()
-
-	// Detect network tools
-	tools.NetworkTools = detectNetworkTools()
-
-	// Build availability map
-	buildAvailabilityMap(&tools)
-
+// DetectTools detects available tools and capabilities, bypassing the cache.
+// Every list is sorted so the prompt and cache file are stable between runs.
+func DetectTools() ToolContext {
+	var tools ToolContext
+	tools.detectCategories(time.Now(), toolCategories...)
 	return tools
 }
 
+// sortToolContext sorts the detected tool lists, since several are built by
+// iterating over maps
+func sortToolContext(tools *ToolContext) {
+	for _, list := range [][]string{
+		tools.PackageManagers, tools.SystemCommands, tools.ContainerTools,
+		tools.CloudTools, tools.DatabaseTools, tools.NetworkTools,
+	} {
+		slices.Sort(list)
+	}
+	slices.SortFunc(tools.Languages, func(a, b LanguageRuntime) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	slices.SortFunc(tools.DevelopmentTools, func(a, b Tool) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+}
+
 // detectPackageManagers identifies available package managers
 func detectPackageManagers() []string {
 	managers := []string{}
@@ -835,8 +935,9 @@ func getHomeDirectory() string {
 	return "."
 }
 
-// getRelevantEnvironment returns environment variables relevant for command generation
-func getRelevantEnvironment() map[string]string {
+// GetRelevantEnvironment returns environment variables relevant for command
+// generation. They are only sent to the model with --include-env.
+func GetRelevantEnvironment() map[string]string {
 	env := make(map[string]string)
 
 	relevantVars := []string{
@@ -903,14 +1004,16 @@ func isVerboseMode() bool {
 
 // GetCacheInfo returns information about the persistent cache
 func GetCacheInfo() CacheInfo {
-	if err := initPersistentCache(); err != nil {
-		return CacheInfo{}
-	}
+	inMemoryOnly := initPersistentCache() != nil
 
 	info := CacheInfo{
-		CacheDir: cacheDir,
-		FilePath: cacheFile,
-		LockFile: lockFile,
+		CacheDir:     cacheDir,
+		FilePath:     cacheFile,
+		LockFile:     lockFile,
+		InMemoryOnly: inMemoryOnly,
+	}
+	if inMemoryOnly {
+		return info
 	}
 
 	if stat, err := os.Stat(cacheFile); err == nil {
@@ -922,10 +1025,63 @@ func GetCacheInfo() CacheInfo {
 	return info
 }
 
+// CacheExpiration returns how long the system context cache stays fresh
+func CacheExpiration() time.Duration {
+	return cacheExpiration
+}
+
+// CacheGracePeriod returns how long an expired cache is still used while it
+// is refreshed in the background
+func CacheGracePeriod() time.Duration {
+	return gracePeriod
+}
+
+// CacheStatus is the health of the system context cache in a form scripts
+// can check. AgeSeconds is nil without a cache and LastModified is nil
+// without a cache file.
+type CacheStatus struct {
+	AgeSeconds    *float64   `json:"age_seconds"`
+	Fresh         bool       `json:"fresh"`
+	Refreshing    bool       `json:"refreshing"`
+	FilePath      string     `json:"file_path"`
+	FileSize      int64      `json:"file_size"`
+	LastModified  *time.Time `json:"last_modified"`
+	ExpirySeconds float64    `json:"expiry_seconds"`
+	GraceSeconds  float64    `json:"grace_seconds"`
+}
+
+// GetCacheStatus returns the status of the system context cache
+func GetCacheStatus() CacheStatus {
+	info := GetCacheInfo()
+	status := CacheStatus{
+		Refreshing:    IsRefreshInProgress(),
+		FilePath:      info.FilePath,
+		FileSize:      info.FileSize,
+		ExpirySeconds: cacheExpiration.Seconds(),
+		GraceSeconds:  gracePeriod.Seconds(),
+	}
+	if info.FileExists {
+		modTime := info.FileModTime
+		status.LastModified = &modTime
+	}
+
+	if age := GetCacheAge(); age > 0 {
+		seconds := age.Seconds()
+		status.AgeSeconds = &seconds
+		status.Fresh = age < cacheExpiration
+	}
+	return status
+}
+
 // ClearPersistentCache removes the persistent cache file
 func ClearPersistentCache() error {
+	// With an unwritable cache directory only the in-memory cache exists
 	if err := initPersistentCache(); err != nil {
-		return fmt.Errorf("failed to initialize cache: %w", err)
+		contextCacheMutex.Lock()
+		systemContextCache = nil
+		cacheTimestamp = time.Time{}
+		contextCacheMutex.Unlock()
+		return nil
 	}
 
 	// Acquire write lock to ensure safe deletion
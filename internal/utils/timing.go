@@ -1,8 +1,13 @@
 package utils
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -60,9 +65,11 @@ func (t *Timer) AddStep(name string, duration time.Duration, started time.Time)
 
 	t.steps = append(t.steps, step)
 
-	if t.verbose {
+	if stepLogger, ok := GetLogger().(StepLogger); ok {
+		stepLogger.LogStep(name, duration)
+	} else if t.verbose {
 		icon := getStepIcon(name)
-		fmt.Printf("⏱️  %s %s: %v\n", icon, name, formatDuration(duration))
+		Debugf("⏱️  %s %s: %v\n", icon, name, formatDuration(duration))
 	}
 }
 
@@ -79,15 +86,89 @@ func (t *Timer) GetSummary() TimingSummary {
 	}
 }
 
+// AppendTimingSummary appends summary as a single JSON line to path, creating
+// the file if needed. The file is locked while writing so concurrent runs
+// never interleave their lines.
+func AppendTimingSummary(path string, summary TimingSummary) error {
+	if err := AppendJSONLine(path, summary); err != nil {
+		return fmt.Errorf("failed to write timing file: %w", err)
+	}
+	return nil
+}
+
+// AppendJSONLine appends v encoded as a single JSON line to path, creating
+// the file and its directory if needed. The file is locked while writing so
+// concurrent runs never interleave their lines.
+func AppendJSONLine(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644) // #nosec G304 - path comes from the user's own flag
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	defer syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// ReadTimingSummaries reads every summary previously appended to path
+func ReadTimingSummaries(path string) ([]TimingSummary, error) {
+	file, err := os.Open(path) // #nosec G304 - path comes from the user's own flag
+	if err != nil {
+		return nil, fmt.Errorf("failed to open timing file: %w", err)
+	}
+	defer file.Close()
+
+	var summaries []TimingSummary
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var summary TimingSummary
+		if err := json.Unmarshal([]byte(line), &summary); err != nil {
+			return nil, fmt.Errorf("invalid timing entry: %w", err)
+		}
+		summaries = append(summaries, summary)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read timing file: %w", err)
+	}
+	return summaries, nil
+}
+
 // PrintSummary prints the timing summary
 func (t *Timer) PrintSummary() {
-	if !t.verbose {
+	totalDuration := time.Since(t.startTime)
+
+	// Structured loggers get the total as a step instead of a table
+	if stepLogger, ok := GetLogger().(StepLogger); ok {
+		stepLogger.LogStep(t.name+" (total)", totalDuration)
 		return
 	}
 
-	totalDuration := time.Since(t.startTime)
+	if !t.verbose {
+		return
+	}
 
-	fmt.Printf("\n%s\n", Divider("TIMING SUMMARY", StyleInfo))
+	Debugf("\n%s\n", Divider("TIMING SUMMARY", StyleInfo))
 
 	headers := []string{"Step", "Duration", "Percentage"}
 	var rows [][]string
@@ -120,14 +201,14 @@ func (t *Timer) PrintSummary() {
 	})
 
 	// Print table
-	fmt.Printf("%s\n", Table(headers, rows, StyleInfo))
+	Debugf("%s\n", Table(headers, rows, StyleInfo))
 
 	// Add performance tips
 	if totalDuration > 10*time.Second {
-		fmt.Printf("\n%s Command took longer than usual. Check your network connection.\n",
+		Debugf("\n%s Command took longer than usual. Check your network connection.\n",
 			Styled("[TIP]", StyleWarning))
 	} else if totalDuration > 5*time.Second {
-		fmt.Printf("\n%s Consider using cache or optimizing your query.\n",
+		Debugf("\n%s Consider using cache or optimizing your query.\n",
 			Styled("[TIP]", StyleInfo))
 	}
 }
@@ -199,7 +280,7 @@ func MeasureOperation(name string, verbose bool, operation func()) time.Duration
 
 	if verbose {
 		icon := getStepIcon(name)
-		fmt.Printf("⏱️  %s %s: %v\n", icon, name, formatDuration(duration))
+		Debugf("⏱️  %s %s: %v\n", icon, name, formatDuration(duration))
 	}
 
 	return duration
@@ -213,7 +294,7 @@ func MeasureOperationWithResult[T any](name string, verbose bool, operation func
 
 	if verbose {
 		icon := getStepIcon(name)
-		fmt.Printf("⏱️  %s %s: %v\n", icon, name, formatDuration(duration))
+		Debugf("⏱️  %s %s: %v\n", icon, name, formatDuration(duration))
 	}
 
 	return result, duration
@@ -231,7 +312,7 @@ func MeasureOperationWithError(name string, verbose bool, operation func() error
 		if err != nil {
 			status = "❌"
 		}
-		fmt.Printf("⏱️  %s %s %s: %v\n", icon, status, name, formatDuration(duration))
+		Debugf("⏱️  %s %s %s: %v\n", icon, status, name, formatDuration(duration))
 	}
 
 	return duration, err
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+)
+
+// Tool categories of a ToolContext, which are detected and refreshed
+// independently
+const (
+	ToolCategoryPackageManagers  = "package_managers"
+	ToolCategoryLanguages        = "languages"
+	ToolCategoryDevelopmentTools = "development_tools"
+	ToolCategorySystemCommands   = "system_commands"
+	ToolCategoryContainerTools   = "container_tools"
+	ToolCategoryCloudTools       = "cloud_tools"
+	ToolCategoryDatabaseTools    = "database_tools"
+	ToolCategoryNetworkTools     = "network_tools"
+)
+
+// toolCategory detects one category of tools. ttl is how long a detection
+// stays fresh: container tools come and go with daemons, while language
+// runtimes, whose version checks are the slowest part, rarely change.
+type toolCategory struct {
+	name   string
+	ttl    time.Duration
+	detect func(tools *ToolContext)
+}
+
+var toolCategories = []toolCategory{
+	{ToolCategoryPackageManagers, 6 * time.Hour, func(t *ToolContext) { t.PackageManagers = detectPackageManagers() }},
+	{ToolCategoryLanguages, 24 * time.Hour, func(t *ToolContext) { t.Languages = detectLanguageRuntimes() }},
+	{ToolCategoryDevelopmentTools, 24 * time.Hour, func(t *ToolContext) { t.DevelopmentTools = detectDevelopmentTools() }},
+	{ToolCategorySystemCommands, 24 * time.Hour, func(t *ToolContext) { t.SystemCommands = detectSystemCommands() }},
+	{ToolCategoryContainerTools, 20 * time.Minute, func(t *ToolContext) { t.ContainerTools = detectContainerTools() }},
+	{ToolCategoryCloudTools, 6 * time.Hour, func(t *ToolContext) { t.CloudTools = detectCloudTools() }},
+	{ToolCategoryDatabaseTools, 6 * time.Hour, func(t *ToolContext) { t.DatabaseTools = detectDatabaseTools() }},
+	{ToolCategoryNetworkTools, 24 * time.Hour, func(t *ToolContext) { t.NetworkTools = detectNetworkTools() }},
+}
+
+// ToolCategories returns the names of the detected tool categories
+func ToolCategories() []string {
+	names := make([]string, len(toolCategories))
+	for i, category := range toolCategories {
+		names[i] = category.name
+	}
+	return names
+}
+
+// lookupToolCategory returns the category with the given name
+func lookupToolCategory(name string) (toolCategory, error) {
+	for _, category := range toolCategories {
+		if category.name == name {
+			return category, nil
+		}
+	}
+	return toolCategory{}, fmt.Errorf("unknown tool category '%s' (available: %s)", name, strings.Join(ToolCategories(), ", "))
+}
+
+// RefreshCategory re-detects the tools of one category, keeping the other
+// categories as they were detected
+func (t *ToolContext) RefreshCategory(name string) error {
+	category, err := lookupToolCategory(name)
+	if err != nil {
+		return err
+	}
+	t.detectCategories(time.Now(), category)
+	return nil
+}
+
+// RefreshStale re-detects the categories that were never detected or whose
+// detection is older than their TTL at now, and returns their names
+func (t *ToolContext) RefreshStale(now time.Time) []string {
+	var stale []toolCategory
+	var names []string
+	for _, category := range toolCategories {
+		checked, ok := t.CategoryChecked[category.name]
+		if !ok || now.Sub(checked) >= category.ttl {
+			stale = append(stale, category)
+			names = append(names, category.name)
+		}
+	}
+
+	if len(stale) > 0 {
+		t.detectCategories(now, stale...)
+	}
+	return names
+}
+
+// detectCategories runs the detection of categories and rebuilds the
+// availability map. The maps are replaced rather than modified, since t may
+// be a copy of a cached context.
+func (t *ToolContext) detectCategories(now time.Time, categories ...toolCategory) {
+	checked := maps.Clone(t.CategoryChecked)
+	if checked == nil {
+		checked = make(map[string]time.Time, len(toolCategories))
+	}
+
+	for _, category := range categories {
+		category.detect(t)
+		checked[category.name] = now
+	}
+
+	t.CategoryChecked = checked
+	t.Available = make(map[string]bool)
+	buildAvailabilityMap(t)
+	sortToolContext(t)
+	t.LastChecked = now
+}
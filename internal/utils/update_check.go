@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// updateCheckStateName records when the background update check last ran
+	updateCheckStateName = "update_check.json"
+
+	// DefaultUpdateCheckInterval is how often background update checks run
+	// when no interval is configured
+	DefaultUpdateCheckInterval = 24 * time.Hour
+)
+
+// UpdateCheckState records the result of the last background update check
+type UpdateCheckState struct {
+	CheckedAt     time.Time `json:"checked_at"`
+	LatestVersion string    `json:"latest_version,omitempty"`
+	// NotifiedVersion is the newest version the user has been told about,
+	// so each release is only announced once
+	NotifiedVersion string `json:"notified_version,omitempty"`
+}
+
+// GetUpdateCheckStatePath returns the file tracking background update checks
+func GetUpdateCheckStatePath() (string, error) {
+	cacheDir, err := GetUpdateBackupDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, updateCheckStateName), nil
+}
+
+// LoadUpdateCheckState reads the update check state at path. A missing or
+// unreadable file gives an empty state, which makes a check due.
+func LoadUpdateCheckState(path string) UpdateCheckState {
+	var state UpdateCheckState
+	data, err := os.ReadFile(path) // #nosec G304 - path is within the cache directory
+	if err != nil {
+		return state
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		Debugf("Ignoring invalid update check state %s: %v\n", path, err)
+		return UpdateCheckState{}
+	}
+	return state
+}
+
+// SaveUpdateCheckState writes the update check state to path
+func SaveUpdateCheckState(path string, state UpdateCheckState) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal update check state: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write update check state: %w", err)
+	}
+	return nil
+}
+
+// UpdateCheckDue reports whether more than interval has passed since the
+// last check. A last check in the future, e.g. after a clock change, is
+// treated as due. An interval of 0 or less uses DefaultUpdateCheckInterval.
+func UpdateCheckDue(state UpdateCheckState, now time.Time, interval time.Duration) bool {
+	if interval <= 0 {
+		interval = DefaultUpdateCheckInterval
+	}
+	if state.CheckedAt.IsZero() || state.CheckedAt.After(now) {
+		return true
+	}
+	return now.Sub(state.CheckedAt) >= interval
+}
+
+// UpdateNotice returns a one-line notice when the last check found a version
+// newer than currentVersion that hasn't been announced yet, or "" otherwise.
+// Development builds never get a notice.
+func UpdateNotice(state UpdateCheckState, currentVersion string) string {
+	if currentVersion == "dev" || currentVersion == "unknown" || state.LatestVersion == "" {
+		return ""
+	}
+	if state.LatestVersion == state.NotifiedVersion || CompareVersions(state.LatestVersion, currentVersion) <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s forgor %s is available (current: %s). Run '%s' to upgrade.",
+		Styled("🔄", StyleInfo),
+		Styled(state.LatestVersion, StyleSuccess),
+		currentVersion,
+		Styled("forgor update", StyleCommand))
+}
+
+// RefreshUpdateCheck asks latest for the newest release and records the
+// result at path, keeping which version was last announced. Failures leave
+// the previous state so the check is retried on the next run.
+func RefreshUpdateCheck(path string, now time.Time, latest func() (*ReleaseInfo, error)) error {
+	release, err := latest()
+	if err != nil {
+		return err
+	}
+
+	state := LoadUpdateCheckState(path)
+	state.CheckedAt = now
+	state.LatestVersion = release.TagName
+	return SaveUpdateCheckState(path, state)
+}
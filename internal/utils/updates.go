@@ -3,12 +3,15 @@ package utils
 import (
 	"archive/tar"
 	"compress/gzip"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
@@ -17,9 +20,16 @@ import (
 const (
 	githubRepo   = "Siutan/forgor"
 	githubApiURL = "https://api.github.com/repos/" + githubRepo + "/releases/latest"
+	// githubReleasesURL lists recent releases, including prereleases
+	githubReleasesURL = "https://api.github.com/repos/" + githubRepo + "/releases"
 	// Security limits for extraction
 	maxDecompressedSize = 1024 * 1024 * 100 // 100MB limit
 	maxFileCount        = 1000              // max files in archive
+	// maxDownloadSize caps release asset downloads, like extraction
+	maxDownloadSize = 1024 * 1024 * 100 // 100MB limit
+
+	// httpTimeout bounds release API requests
+	httpTimeout = 15 * time.Second
 )
 
 // isValidURL validates if the URL is from an allowed domain
@@ -73,28 +83,36 @@ func sanitizePath(basePath, userPath string) (string, error) {
 func secureRemoveAll(path string) {
 	if err := os.RemoveAll(path); err != nil {
 		// Log the error but don't fail the operation
-		fmt.Printf("Warning: failed to clean up temporary directory %s: %v\n", path, err)
+		Warnf("Warning: failed to clean up temporary directory %s: %v\n", path, err)
 	}
 }
 
 // ReleaseInfo holds information about a GitHub release
 type ReleaseInfo struct {
-	TagName string `json:"tag_name"`
-	Assets  []struct {
+	TagName    string `json:"tag_name"`
+	Prerelease bool   `json:"prerelease"`
+	Draft      bool   `json:"draft"`
+	Assets     []struct {
 		Name        string `json:"name"`
 		DownloadURL string `json:"browser_download_url"`
 	} `json:"assets"`
 }
 
 // httpGet performs a GET request and returns the response body
-func httpGet(url string) ([]byte, error) {
+func httpGet(ctx context.Context, url string) ([]byte, error) {
 	// Validate URL before making request
 	if err := isValidURL(url); err != nil {
 		return nil, fmt.Errorf("URL validation failed: %w", err)
 	}
 
-	client := &http.Client{Timeout: 15 * time.Second}
-	req, err := http.NewRequest("GET", url, nil)
+	return httpGetUnchecked(ctx, url)
+}
+
+// httpGetUnchecked performs a GET request without the GitHub host check.
+// The request is bounded by httpTimeout and aborted when ctx is cancelled.
+func httpGetUnchecked(ctx context.Context, url string) ([]byte, error) {
+	client := &http.Client{Timeout: httpTimeout}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -116,12 +134,17 @@ func httpGet(url string) ([]byte, error) {
 }
 
 // GetLatestVersion returns the latest version info of forgor from GitHub.
-func GetLatestVersion() (*ReleaseInfo, error) {
-	body, err := httpGet(githubApiURL)
+// Cancelling ctx aborts the request.
+func GetLatestVersion(ctx context.Context) (*ReleaseInfo, error) {
+	body, err := httpGet(ctx, githubApiURL)
 	if err != nil {
 		return nil, err
 	}
+	return parseRelease(body)
+}
 
+// parseRelease decodes a GitHub release response
+func parseRelease(body []byte) (*ReleaseInfo, error) {
 	var release ReleaseInfo
 	if err := json.Unmarshal(body, &release); err != nil {
 		return nil, fmt.Errorf("failed to parse json response: %w", err)
@@ -136,44 +159,119 @@ func GetLatestVersion() (*ReleaseInfo, error) {
 	return &release, nil
 }
 
+// Update channels for 'forgor update --channel'
+const (
+	UpdateChannelStable     = "stable"     // Latest full release
+	UpdateChannelPrerelease = "prerelease" // Newest release, including prereleases
+)
+
+// GetLatestRelease returns the newest release on channel
+func GetLatestRelease(ctx context.Context, channel string) (*ReleaseInfo, error) {
+	switch channel {
+	case "", UpdateChannelStable:
+		return GetLatestVersion(ctx)
+	case UpdateChannelPrerelease:
+		body, err := httpGet(ctx, githubReleasesURL)
+		if err != nil {
+			return nil, err
+		}
+		return newestRelease(body)
+	default:
+		return nil, fmt.Errorf("invalid update channel: %s. Valid channels: %s, %s",
+			channel, UpdateChannelStable, UpdateChannelPrerelease)
+	}
+}
+
+// newestRelease picks the highest version from a release list, skipping drafts
+func newestRelease(body []byte) (*ReleaseInfo, error) {
+	var releases []ReleaseInfo
+	if err := json.Unmarshal(body, &releases); err != nil {
+		return nil, fmt.Errorf("failed to parse json response: %w", err)
+	}
+
+	var newest *ReleaseInfo
+	for i := range releases {
+		release := &releases[i]
+		if release.Draft || release.TagName == "" {
+			continue
+		}
+		release.TagName = strings.TrimPrefix(release.TagName, "v")
+		if newest == nil || CompareVersions(release.TagName, newest.TagName) > 0 {
+			newest = release
+		}
+	}
+
+	if newest == nil {
+		return nil, fmt.Errorf("could not find any releases in GitHub response")
+	}
+	return newest, nil
+}
+
 // CheckForUpdates checks for updates to forgor and prints a message to the console.
 // This is intended for non-interactive checks, like in the 'version' command.
-func CheckForUpdates(currentVersion string) {
-	if currentVersion == "dev" || currentVersion == "unknown" {
-		fmt.Printf("\n%s %s\n",
+// Development builds are only checked when force is set. Cancelling ctx
+// aborts the check.
+func CheckForUpdates(ctx context.Context, currentVersion string, force bool) {
+	WriteUpdateStatus(os.Stdout, currentVersion, force, func() (*ReleaseInfo, error) {
+		return GetLatestVersion(ctx)
+	})
+}
+
+// WriteUpdateStatus checks latest for a newer release than currentVersion
+// and writes the result to w. Development builds skip the check unless force
+// is set.
+func WriteUpdateStatus(w io.Writer, currentVersion string, force bool, latest func() (*ReleaseInfo, error)) {
+	if !force && (currentVersion == "dev" || currentVersion == "unknown") {
+		fmt.Fprintf(w, "\n%s %s\n",
 			Styled("ℹ️", StyleInfo),
-			Styled("Development version - update check skipped.", StyleSubtle))
+			Styled("Development version - update check skipped. Use --check to check anyway.", StyleSubtle))
 		return
 	}
 
-	latestRelease, err := GetLatestVersion()
+	latestRelease, err := latest()
 	if err != nil {
 		// Non-blocking, just print a warning.
-		fmt.Printf("\n%s Could not check for updates: %s\n", Styled("[WARN]", StyleWarning), err)
+		fmt.Fprintf(w, "\n%s Could not check for updates: %s\n", Styled("[WARN]", StyleWarning), err)
 		return
 	}
 
-	// Using semantic version comparison would be better, but direct comparison is a good start.
-	if latestRelease.TagName == currentVersion {
-		fmt.Printf("\n%s Forgor is up to date (version %s)\n", Styled("✅", StyleSuccess), currentVersion)
+	// A prerelease or local build newer than the latest release is up to date
+	currentVersion = strings.TrimPrefix(currentVersion, "v")
+	if CompareVersions(latestRelease.TagName, currentVersion) <= 0 {
+		fmt.Fprintf(w, "\n%s Forgor is up to date (version %s)\n", Styled("✅", StyleSuccess), currentVersion)
 		return
 	}
 
-	fmt.Printf("\n%s A new version of forgor is available: %s (current: %s)\n",
+	fmt.Fprintf(w, "\n%s A new version of forgor is available: %s (current: %s)\n",
 		Styled("🔄", StyleInfo),
 		Styled(latestRelease.TagName, StyleSuccess),
 		Styled(currentVersion, StyleWarning))
-	fmt.Printf("   To update, run: %s\n", Styled("forgor update", StyleCommand))
+	fmt.Fprintf(w, "   To update, run: %s\n", Styled("forgor update", StyleCommand))
 }
 
 // DownloadUpdate downloads a file from a URL to a new temporary directory and returns the path to the downloaded file.
-func DownloadUpdate(url string) (string, error) {
-	// Validate URL before making request
-	if err := isValidURL(url); err != nil {
-		return "", fmt.Errorf("URL validation failed: %w", err)
+// Progress is drawn on progress, if not nil, and cancelling ctx aborts the download.
+func DownloadUpdate(ctx context.Context, url string, progress io.Writer) (string, error) {
+	return downloadUpdate(ctx, url, progress, true)
+}
+
+// downloadUpdate downloads a release asset, checking that url points at
+// GitHub when validate is set. Downloads larger than maxDownloadSize, or
+// shorter than their Content-Length, are rejected so a truncated archive is
+// never installed.
+func downloadUpdate(ctx context.Context, url string, progress io.Writer, validate bool) (string, error) {
+	if validate {
+		// Validate URL before making request
+		if err := isValidURL(url); err != nil {
+			return "", fmt.Errorf("URL validation failed: %w", err)
+		}
 	}
 
-	resp, err := http.Get(url) // #nosec G107 - URL is validated above
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil) // #nosec G107 - URL is validated unless validate is false (tests only)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to perform GET request to %s: %w", url, err)
 	}
@@ -183,6 +281,11 @@ func DownloadUpdate(url string) (string, error) {
 		return "", fmt.Errorf("bad status from %s: %s", url, resp.Status)
 	}
 
+	expected := resp.ContentLength
+	if expected > maxDownloadSize {
+		return "", fmt.Errorf("download too large: %d bytes (limit: %d bytes)", expected, maxDownloadSize)
+	}
+
 	// Create a temporary directory for the update
 	tmpDir, err := os.MkdirTemp("", "forgor-update-")
 	if err != nil {
@@ -190,7 +293,7 @@ func DownloadUpdate(url string) (string, error) {
 	}
 
 	// Create the file in the temp directory with sanitized path
-	fileName := filepath.Base(url)
+	fileName := filepath.Base(req.URL.Path)
 	if fileName == "." || fileName == "/" {
 		secureRemoveAll(tmpDir)
 		return "", fmt.Errorf("invalid filename from URL")
@@ -208,17 +311,55 @@ func DownloadUpdate(url string) (string, error) {
 		secureRemoveAll(tmpDir)
 		return "", fmt.Errorf("failed to create file in temp dir: %w", err)
 	}
-	defer file.Close()
 
-	// Write the downloaded content to the file
-	_, err = io.Copy(file, resp.Body)
+	// Write the downloaded content to the file, reading one byte past the
+	// limit to detect oversized downloads without a Content-Length
+	var dst io.Writer = file
+	showProgress := progress != nil && expected >= 1024
+	if showProgress {
+		dst = io.MultiWriter(file, &downloadProgress{w: progress, total: expected})
+	}
+	written, err := io.Copy(dst, io.LimitReader(resp.Body, maxDownloadSize+1))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if showProgress {
+		fmt.Fprintln(progress)
+	}
+
+	switch {
+	case err != nil:
+		err = fmt.Errorf("failed to write download to file: %w", err)
+	case written > maxDownloadSize:
+		err = fmt.Errorf("download too large (limit: %d bytes)", maxDownloadSize)
+	case expected >= 0 && written != expected:
+		err = fmt.Errorf("incomplete download: got %d of %d bytes", written, expected)
+	}
 	if err != nil {
-		// If copy fails, we should clean up the temp directory and file
+		// If the copy fails, we should clean up the temp directory and file
 		secureRemoveAll(tmpDir)
-		return "", fmt.Errorf("failed to write download to file: %w", err)
+		return "", err
 	}
 
-	return file.Name(), nil
+	return filePath, nil
+}
+
+// downloadProgress draws a progress bar for a download on w as bytes are
+// written, redrawing only when the percentage changes
+type downloadProgress struct {
+	w       io.Writer
+	total   int64
+	written int64
+	percent int64
+}
+
+func (p *downloadProgress) Write(b []byte) (int, error) {
+	p.written += int64(len(b))
+	if percent := p.written * 100 / p.total; percent != p.percent || p.written == int64(len(b)) {
+		p.percent = percent
+		fmt.Fprintf(p.w, "\r%s KB", ProgressBar(int(p.written/1024), int(p.total/1024), 30))
+	}
+	return len(b), nil
 }
 
 // ExtractTarGz extracts a gzipped tar file to a destination directory.
@@ -281,12 +422,19 @@ func ExtractTarGz(src, dest string) error {
 			return fmt.Errorf("invalid path in archive: %w", err)
 		}
 
-		// Check if the file is a directory
-		if header.Typeflag == tar.TypeDir {
+		// Only directories and regular files are extracted. Links could point
+		// outside dest and are never needed in a release archive.
+		switch header.Typeflag {
+		case tar.TypeDir:
 			if err := os.MkdirAll(target, 0750); err != nil {
 				return fmt.Errorf("failed to create directory: %w", err)
 			}
 			continue
+		case tar.TypeReg:
+		case tar.TypeSymlink, tar.TypeLink:
+			return fmt.Errorf("archive contains a link, which is not allowed: %s -> %s", header.Name, header.Linkname)
+		default:
+			return fmt.Errorf("archive contains an unsupported entry type %q: %s", header.Typeflag, header.Name)
 		}
 
 		// Create parent directories if needed
@@ -318,3 +466,130 @@ func ExtractTarGz(src, dest string) error {
 
 	return nil
 }
+
+// binaryNames are the executable names a release archive may contain
+var binaryNames = map[string]bool{"forgor": true, "forgor.exe": true}
+
+// verifyTimeout bounds how long a downloaded binary may take to answer --help
+const verifyTimeout = 10 * time.Second
+
+// FindExtractedBinary searches dir, an extracted release archive, for the
+// forgor executable. Archives may nest it in a subdirectory; the shallowest
+// match wins.
+func FindExtractedBinary(dir string) (string, error) {
+	found := ""
+	foundDepth := -1
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !binaryNames[d.Name()] || !d.Type().IsRegular() {
+			return nil
+		}
+
+		depth := strings.Count(strings.TrimPrefix(path, dir), string(filepath.Separator))
+		if foundDepth < 0 || depth < foundDepth {
+			found = path
+			foundDepth = depth
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to search the extracted update: %w", err)
+	}
+	if found == "" {
+		return "", fmt.Errorf("no forgor binary found in the release archive")
+	}
+
+	return found, nil
+}
+
+// VerifyBinary runs the binary at path with --help to confirm it starts on
+// this system and is forgor before it replaces the installed one
+func VerifyBinary(path string) error {
+	if err := os.Chmod(path, executableMode); err != nil {
+		return fmt.Errorf("failed to make update executable: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), verifyTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, path, "--help").CombinedOutput() // #nosec G204 - path is the extracted update
+	if err != nil {
+		return fmt.Errorf("downloaded binary failed to run: %w", err)
+	}
+	if !strings.Contains(strings.ToLower(string(output)), "forgor") {
+		return fmt.Errorf("downloaded binary does not look like forgor")
+	}
+
+	return nil
+}
+
+// executableMode is the permission set on an installed forgor binary
+const executableMode = 0755
+
+// ReplaceExecutable installs the binary at newPath over target, keeping it
+// executable. See ReplaceExecutableWith.
+func ReplaceExecutable(newPath, target string) error {
+	return ReplaceExecutableWith(newPath, target, os.Rename)
+}
+
+// ReplaceExecutableWith installs the binary at newPath over target using
+// rename. When the direct rename fails, typically with "invalid cross-device
+// link" because the download lives on another filesystem, the binary is
+// copied to a temporary file beside target and renamed into place, so target
+// is never left half-written.
+func ReplaceExecutableWith(newPath, target string, rename func(oldpath, newpath string) error) error {
+	if err := os.Chmod(newPath, executableMode); err != nil {
+		return fmt.Errorf("failed to make update executable: %w", err)
+	}
+
+	renameErr := rename(newPath, target)
+	if renameErr == nil {
+		return nil
+	}
+	Debugf("Direct rename failed, copying instead: %v\n", renameErr)
+
+	tempPath, err := copyBeside(newPath, target)
+	if err != nil {
+		return err
+	}
+
+	if err := rename(tempPath, target); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to replace %s: %w", target, err)
+	}
+	return nil
+}
+
+// copyBeside copies src to a new executable temporary file in the directory
+// of target and returns its path
+func copyBeside(src, target string) (string, error) {
+	in, err := os.Open(src) // #nosec G304 - path is the extracted update
+	if err != nil {
+		return "", fmt.Errorf("failed to open update: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".update-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file beside %s: %w", target, err)
+	}
+	tempPath := out.Name()
+
+	_, copyErr := io.Copy(out, in)
+	closeErr := out.Close()
+	if copyErr == nil {
+		copyErr = closeErr
+	}
+	if copyErr == nil {
+		copyErr = os.Chmod(tempPath, executableMode)
+	}
+	if copyErr != nil {
+		os.Remove(tempPath)
+		return "", fmt.Errorf("failed to copy update beside %s: %w", target, copyErr)
+	}
+
+	return tempPath, nil
+}
@@ -0,0 +1,171 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The release fetches only talk to GitHub, so these tests reach a local
+// server through the unexported helpers behind them
+
+// latestReleaseFrom fetches and parses a release like GetLatestVersion, but
+// from url
+func latestReleaseFrom(ctx context.Context, url string) (*ReleaseInfo, error) {
+	body, err := httpGetUnchecked(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return parseRelease(body)
+}
+
+func TestWriteUpdateStatusFromMockReleaseEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"tag_name": "v9.9.9", "assets": []}`))
+	}))
+	defer server.Close()
+
+	latest := func() (*ReleaseInfo, error) {
+		return latestReleaseFrom(context.Background(), server.URL)
+	}
+
+	var buf bytes.Buffer
+	WriteUpdateStatus(&buf, "1.0.0", false, latest)
+	output := buf.String()
+	if !strings.Contains(output, "A new version of forgor is available") || !strings.Contains(output, "9.9.9") {
+		t.Errorf("expected an update message for 9.9.9, got %q", output)
+	}
+
+	buf.Reset()
+	WriteUpdateStatus(&buf, "v9.9.9", false, latest)
+	if !strings.Contains(buf.String(), "up to date") {
+		t.Errorf("expected an up to date message, got %q", buf.String())
+	}
+}
+
+func TestWriteUpdateStatusReportsErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	WriteUpdateStatus(&buf, "1.0.0", false, func() (*ReleaseInfo, error) {
+		return latestReleaseFrom(context.Background(), server.URL)
+	})
+	if !strings.Contains(buf.String(), "Could not check for updates") {
+		t.Errorf("expected a warning, got %q", buf.String())
+	}
+}
+
+func TestGetLatestVersionCancelledMidRequest(t *testing.T) {
+	received := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(received)
+		// Respond only after the client gives up
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+		w.Write([]byte(`{"tag_name": "v9.9.9"}`))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-received
+		cancel()
+	}()
+
+	start := time.Now()
+	_, err := latestReleaseFrom(ctx, server.URL)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("cancelled check took %v; expected it to abort promptly", elapsed)
+	}
+}
+
+func TestGetLatestVersionRequiresGitHub(t *testing.T) {
+	if _, err := httpGet(context.Background(), "https://example.com/releases/latest"); err == nil {
+		t.Error("expected httpGet to reject hosts other than GitHub")
+	}
+}
+
+func TestNewestReleaseIncludesPrereleases(t *testing.T) {
+	release, err := newestRelease([]byte(`[
+		{"tag_name": "v1.4.0", "prerelease": false},
+		{"tag_name": "v1.6.0-beta.1", "prerelease": true, "draft": true},
+		{"tag_name": "v1.5.0-rc.2", "prerelease": true},
+		{"tag_name": "v1.5.0-rc.10", "prerelease": true},
+		{"tag_name": "v1.3.2", "prerelease": false}
+	]`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if release.TagName != "1.5.0-rc.10" || !release.Prerelease {
+		t.Errorf("expected prerelease 1.5.0-rc.10 (drafts skipped), got %+v", release)
+	}
+}
+
+func TestNewestReleaseWithOnlyDrafts(t *testing.T) {
+	if _, err := newestRelease([]byte(`[{"tag_name": "v2.0.0", "draft": true}]`)); err == nil {
+		t.Error("expected an error when every release is a draft")
+	}
+}
+
+func TestDownloadUpdateRejectsIncompleteDownload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Promise more than is sent, then drop the connection
+		w.Header().Set("Content-Length", "4096")
+		w.Write([]byte(strings.Repeat("x", 1024)))
+	}))
+	defer server.Close()
+
+	var progress bytes.Buffer
+	path, err := downloadUpdate(context.Background(), server.URL+"/forgor_linux_amd64.tar.gz", &progress, false)
+	if err == nil {
+		os.RemoveAll(filepath.Dir(path))
+		t.Fatal("expected an incomplete download to be rejected")
+	}
+	if path != "" {
+		t.Errorf("expected no file for a rejected download, got %s", path)
+	}
+}
+
+func TestDownloadUpdateComplete(t *testing.T) {
+	content := strings.Repeat("forgor", 1024)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	var progress bytes.Buffer
+	path, err := downloadUpdate(context.Background(), server.URL+"/forgor_linux_amd64.tar.gz", &progress, false)
+	if err != nil {
+		t.Fatalf("downloadUpdate returned error: %v", err)
+	}
+	defer os.RemoveAll(filepath.Dir(path))
+
+	if filepath.Base(path) != "forgor_linux_amd64.tar.gz" {
+		t.Errorf("expected the asset name as the file name, got %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != content {
+		t.Errorf("downloaded file does not match the served content (err: %v)", err)
+	}
+	if !strings.Contains(progress.String(), "100%") {
+		t.Errorf("expected progress to reach 100%%, got %q", progress.String())
+	}
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxContextFileSize caps how much of a --context-file is sent with a query
+const MaxContextFileSize = 16 * 1024
+
+// contextTruncatedNote marks context that was cut off at the size cap
+const contextTruncatedNote = "\n[... truncated]"
+
+// ReadContextFile reads extra background for a query, such as a README
+// excerpt or an error log. Content beyond maxBytes is cut off and marked as
+// truncated.
+func ReadContextFile(path string, maxBytes int) (string, error) {
+	file, err := os.Open(path) // #nosec G304 - path is chosen by the user
+	if err != nil {
+		return "", fmt.Errorf("failed to read context file: %w", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, int64(maxBytes)+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read context file: %w", err)
+	}
+
+	if len(data) <= maxBytes {
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	// Don't split a multi-byte character at the cut
+	data = data[:maxBytes]
+	for i := 0; i < utf8.UTFMax && len(data) > 0; i++ {
+		if r, _ := utf8.DecodeLastRune(data); r != utf8.RuneError {
+			break
+		}
+		data = data[:len(data)-1]
+	}
+	return strings.TrimSpace(string(data)) + contextTruncatedNote, nil
+}
+
+// BuildUserContext combines inline context from --context with the content
+// of a context file, skipping whichever is empty
+func BuildUserContext(inline, fileContent string) string {
+	var parts []string
+	for _, part := range []string{inline, fileContent} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,291 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"forgor/internal/config"
+	"forgor/internal/utils"
+)
+
+// writeCacheFile writes data to dir/name and sets its modification time
+func writeCacheFile(t *testing.T, dir, name, data string, modTime time.Time) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// systemContextCache returns a cached system context saved at timestamp
+func systemContextCache(t *testing.T, timestamp time.Time) string {
+	t.Helper()
+
+	data, err := json.Marshal(utils.CachedSystemContext{
+		Context:   &utils.SystemContext{},
+		Timestamp: timestamp,
+		Version:   "1.0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestPruneCacheDirRemovesStaleFiles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	contextFile := writeCacheFile(t, dir, "system-context.json", systemContextCache(t, now.Add(-2*time.Hour)), now.Add(-2*time.Hour))
+	staleTemp := writeCacheFile(t, dir, "system-context.json.tmp", "partial", now.Add(-2*time.Hour))
+	staleAtomic := writeCacheFile(t, dir, ".last_command.tmp-123", "partial", now.Add(-2*time.Hour))
+	freshTemp := writeCacheFile(t, dir, "recent_commands.json.tmp", "writing", now)
+	backup := writeCacheFile(t, dir, "forgor.prev", "binary", now.Add(-48*time.Hour))
+
+	result, err := utils.PruneCacheDir(dir, now)
+	if err != nil {
+		t.Fatalf("PruneCacheDir returned error: %v", err)
+	}
+
+	for _, path := range []string{contextFile, staleTemp, staleAtomic} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s should have been removed", filepath.Base(path))
+		}
+	}
+	for _, path := range []string{freshTemp, backup} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s should have been kept: %v", filepath.Base(path), err)
+		}
+	}
+
+	if len(result.Removed) != 3 {
+		t.Errorf("Removed = %v; want 3 files", result.Removed)
+	}
+	if result.BytesReclaimed <= int64(len("partial")*2) {
+		t.Errorf("BytesReclaimed = %d; want the size of all removed files", result.BytesReclaimed)
+	}
+}
+
+func TestPruneCacheDirKeepsFreshContext(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	contextFile := writeCacheFile(t, dir, "system-context.json", systemContextCache(t, now.Add(-time.Minute)), now)
+
+	result, err := utils.PruneCacheDir(dir, now)
+	if err != nil {
+		t.Fatalf("PruneCacheDir returned error: %v", err)
+	}
+	if _, err := os.Stat(contextFile); err != nil {
+		t.Errorf("fresh system context cache should be kept: %v", err)
+	}
+	if len(result.Removed) != 0 || result.BytesReclaimed != 0 {
+		t.Errorf("result = %+v; want nothing removed", result)
+	}
+
+	// A missing directory has nothing to prune
+	if _, err := utils.PruneCacheDir(filepath.Join(dir, "missing"), now); err != nil {
+		t.Errorf("PruneCacheDir on a missing directory returned error: %v", err)
+	}
+}
+
+func TestPruneCacheDirRemovesUnreadableContext(t *testing.T) {
+	dir := t.TempDir()
+
+	// A directory in place of the cache file can't be read, even as root
+	contextFile := filepath.Join(dir, "system-context.json")
+	if err := os.Mkdir(contextFile, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := utils.PruneCacheDir(dir, time.Now())
+	if err != nil {
+		t.Fatalf("PruneCacheDir returned error: %v", err)
+	}
+	if _, err := os.Stat(contextFile); !os.IsNotExist(err) {
+		t.Errorf("unreadable system context cache should have been removed: %v", err)
+	}
+	if len(result.Removed) != 1 {
+		t.Errorf("Removed = %v; want the unreadable cache", result.Removed)
+	}
+}
+
+func TestTruncateRecentCommands(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	for _, command := range []string{"ls", "pwd", "whoami", "date"} {
+		if err := config.RecordRecentCommand(command, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reclaimed, err := config.TruncateRecentCommands(2)
+	if err != nil {
+		t.Fatalf("TruncateRecentCommands returned error: %v", err)
+	}
+	if reclaimed <= 0 {
+		t.Errorf("reclaimed = %d; want the bytes of the dropped commands", reclaimed)
+	}
+
+	recent, err := config.LoadRecentCommands()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 2 || recent[0].Command != "whoami" || recent[1].Command != "date" {
+		t.Errorf("recent = %+v; want the two newest commands", recent)
+	}
+
+	// Already within the limit
+	if reclaimed, err := config.TruncateRecentCommands(2); err != nil || reclaimed != 0 {
+		t.Errorf("TruncateRecentCommands() = %d, %v; want nothing to do", reclaimed, err)
+	}
+}
+
+func TestUnwritableCacheDirUsesMemory(t *testing.T) {
+	// The cache directory is set up once per process, so check it in a
+	// fresh test process with XDG_CACHE_HOME below a regular file
+	if os.Getenv("FORGOR_TEST_UNWRITABLE_CACHE") == "1" {
+		var logs bytes.Buffer
+		utils.SetLogger(utils.NewTextLogger(&logs, utils.LogLevelWarn))
+
+		for i := 0; i < 2; i++ {
+			info := utils.GetCacheInfo()
+			if !info.InMemoryOnly || info.CacheDir == "" {
+				t.Errorf("GetCacheInfo() = %+v; want an in-memory-only cache with its directory", info)
+			}
+			if err := utils.ClearPersistentCache(); err != nil {
+				t.Errorf("ClearPersistentCache failed: %v", err)
+			}
+			utils.GetCacheAge()
+		}
+		if !utils.IsCacheInMemoryOnly() {
+			t.Error("IsCacheInMemoryOnly() = false; want true")
+		}
+		if count := strings.Count(logs.String(), "not writable"); count != 1 {
+			t.Errorf("warned %d times; want once:\n%s", count, logs.String())
+		}
+		return
+	}
+
+	blocker := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestUnwritableCacheDirUsesMemory$")
+	cmd.Env = append(os.Environ(), "FORGOR_TEST_UNWRITABLE_CACHE=1", "XDG_CACHE_HOME="+filepath.Join(blocker, "cache"))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("subprocess failed: %v\n%s", err, output)
+	}
+}
+
+func TestCacheStatusForExistingCache(t *testing.T) {
+	// The cache directory is set up once per process, so check it in a
+	// fresh test process with XDG_CACHE_HOME pointing at the test's cache
+	if os.Getenv("FORGOR_TEST_CACHE_STATUS") == "1" {
+		cacheFile := filepath.Join(os.Getenv("XDG_CACHE_HOME"), "forgor", "system-context.json")
+		stat, err := os.Stat(cacheFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := json.Marshal(utils.GetCacheStatus())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var status map[string]any
+		if err := json.Unmarshal(data, &status); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, field := range []string{"age_seconds", "fresh", "refreshing", "file_path", "file_size", "last_modified", "expiry_seconds", "grace_seconds"} {
+			if _, ok := status[field]; !ok {
+				t.Errorf("status JSON is missing %s: %s", field, data)
+			}
+		}
+		if age, _ := status["age_seconds"].(float64); age < 290 || age > 360 {
+			t.Errorf("age_seconds = %v; want about 300", status["age_seconds"])
+		}
+		if status["fresh"] != true || status["refreshing"] != false {
+			t.Errorf("fresh = %v, refreshing = %v; want a fresh, idle cache", status["fresh"], status["refreshing"])
+		}
+		if status["file_path"] != cacheFile || status["file_size"] != float64(stat.Size()) {
+			t.Errorf("file_path = %v, file_size = %v; want %s, %d", status["file_path"], status["file_size"], cacheFile, stat.Size())
+		}
+		if modified, _ := time.Parse(time.RFC3339Nano, status["last_modified"].(string)); !modified.Equal(stat.ModTime()) {
+			t.Errorf("last_modified = %v; want %v", status["last_modified"], stat.ModTime())
+		}
+		if status["expiry_seconds"] != utils.CacheExpiration().Seconds() || status["grace_seconds"] != utils.CacheGracePeriod().Seconds() {
+			t.Errorf("expiry_seconds = %v, grace_seconds = %v", status["expiry_seconds"], status["grace_seconds"])
+		}
+
+		// An hour-old cache is past its expiry
+		writeCacheFile(t, filepath.Dir(cacheFile), filepath.Base(cacheFile), systemContextCache(t, time.Now().Add(-time.Hour)), time.Now())
+		if status := utils.GetCacheStatus(); status.Fresh || status.AgeSeconds == nil {
+			t.Errorf("GetCacheStatus() = %+v; want an expired cache with its age", status)
+		}
+		return
+	}
+
+	cacheHome := t.TempDir()
+	dir := filepath.Join(cacheHome, "forgor")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeCacheFile(t, dir, "system-context.json", systemContextCache(t, time.Now().Add(-5*time.Minute)), time.Now().Add(-5*time.Minute))
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestCacheStatusForExistingCache$")
+	cmd.Env = append(os.Environ(), "FORGOR_TEST_CACHE_STATUS=1", "XDG_CACHE_HOME="+cacheHome)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("subprocess failed: %v\n%s", err, output)
+	}
+}
+
+func TestCorruptCacheIsRemovedAndRebuilt(t *testing.T) {
+	// The cache directory is set up once per process, so check it in a
+	// fresh test process with XDG_CACHE_HOME pointing at the corrupt cache
+	if os.Getenv("FORGOR_TEST_CORRUPT_CACHE") == "1" {
+		var logs bytes.Buffer
+		utils.SetLogger(utils.NewTextLogger(&logs, utils.LogLevelWarn))
+
+		if context := utils.GetSystemContext(); context == nil || context.OS == "" {
+			t.Fatalf("GetSystemContext() = %+v; want a rebuilt context", context)
+		}
+
+		data, err := os.ReadFile(utils.GetCacheInfo().FilePath)
+		if err != nil {
+			t.Fatalf("cache was not rewritten: %v", err)
+		}
+		var cached utils.CachedSystemContext
+		if err := json.Unmarshal(data, &cached); err != nil || cached.Context == nil {
+			t.Errorf("rebuilt cache is not valid: %v\n%s", err, data)
+		}
+		if !strings.Contains(logs.String(), "corrupt") {
+			t.Errorf("expected a warning about the corrupt cache, got:\n%s", logs.String())
+		}
+		return
+	}
+
+	cacheHome := t.TempDir()
+	dir := filepath.Join(cacheHome, "forgor")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeCacheFile(t, dir, "system-context.json", `{"context": {"os": "lin`, time.Now())
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestCorruptCacheIsRemovedAndRebuilt$")
+	cmd.Env = append(os.Environ(), "FORGOR_TEST_CORRUPT_CACHE=1", "XDG_CACHE_HOME="+cacheHome)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("subprocess failed: %v\n%s", err, output)
+	}
+}
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"forgor/internal/llm"
+)
+
+func TestRetryOnEmptyAsksAgain(t *testing.T) {
+	provider := &mockProvider{
+		generateResponses: []*llm.Response{
+			{Command: "  ", Usage: &llm.Usage{PromptTokens: 100, CompletionTokens: 5, TotalTokens: 105}},
+			{Command: "ls -la", Usage: &llm.Usage{PromptTokens: 120, CompletionTokens: 10, TotalTokens: 130}},
+		},
+	}
+
+	request := &llm.Request{Query: "list files"}
+	response, err := llm.RetryOnEmpty(provider).GenerateCommand(context.Background(), request)
+	if err != nil {
+		t.Fatalf("GenerateCommand returned error: %v", err)
+	}
+
+	if response.Command != "ls -la" {
+		t.Errorf("Command = %q; want the retried command", response.Command)
+	}
+	if len(provider.generateRequests) != 2 {
+		t.Fatalf("Expected 2 GenerateCommand calls, got %d", len(provider.generateRequests))
+	}
+
+	retryQuery := provider.generateRequests[1].Query
+	if !strings.HasPrefix(retryQuery, "list files") || !strings.Contains(retryQuery, "only the shell command") {
+		t.Errorf("Retry query %q should repeat the query and ask for only the command", retryQuery)
+	}
+	if request.Query != "list files" {
+		t.Errorf("The original request was modified: %q", request.Query)
+	}
+	if response.Usage == nil || response.Usage.TotalTokens != 235 {
+		t.Errorf("Expected usage to include both requests, got %+v", response.Usage)
+	}
+}
+
+func TestRetryOnEmptyFailsWhenStillEmpty(t *testing.T) {
+	provider := &mockProvider{
+		generateResponses: []*llm.Response{{Command: "```\n```"}},
+	}
+
+	_, err := llm.RetryOnEmpty(provider).GenerateCommand(context.Background(), &llm.Request{Query: "list files"})
+
+	var llmErr *llm.Error
+	if !errors.As(err, &llmErr) || llmErr.Type != llm.ErrorTypeEmptyCommand {
+		t.Fatalf("Expected an empty command error, got %v", err)
+	}
+	if len(provider.generateRequests) != 2 {
+		t.Errorf("Expected exactly one retry, got %d calls", len(provider.generateRequests))
+	}
+}
+
+func TestRetryOnEmptyRetriesEmptyCommandErrors(t *testing.T) {
+	provider := &mockProvider{
+		generateErr: &llm.Error{Type: llm.ErrorTypeEmptyCommand, Message: "response did not contain a command"},
+	}
+
+	_, err := llm.RetryOnEmpty(provider).GenerateCommand(context.Background(), &llm.Request{Query: "list files"})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if len(provider.generateRequests) != 2 {
+		t.Errorf("Expected the empty command error to be retried, got %d calls", len(provider.generateRequests))
+	}
+}
+
+func TestRetryOnEmptyKeepsOtherResults(t *testing.T) {
+	provider := &mockProvider{
+		generateResponses: []*llm.Response{{Command: "df -h"}},
+	}
+	response, err := llm.RetryOnEmpty(provider).GenerateCommand(context.Background(), &llm.Request{Query: "disk space"})
+	if err != nil || response.Command != "df -h" {
+		t.Fatalf("GenerateCommand = %+v, %v; want df -h", response, err)
+	}
+	if len(provider.generateRequests) != 1 {
+		t.Errorf("Expected no retry for a command, got %d calls", len(provider.generateRequests))
+	}
+
+	authErr := &llm.Error{Type: llm.ErrorTypeAuth, Message: "invalid API key"}
+	failing := &mockProvider{generateErr: authErr}
+	if _, err := llm.RetryOnEmpty(failing).GenerateCommand(context.Background(), &llm.Request{Query: "disk space"}); !errors.Is(err, authErr) {
+		t.Errorf("Expected the provider's error, got %v", err)
+	}
+	if len(failing.generateRequests) != 1 {
+		t.Errorf("Expected no retry for other errors, got %d calls", len(failing.generateRequests))
+	}
+}
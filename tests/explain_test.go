@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"forgor/internal/llm"
+	"forgor/internal/security"
+	"forgor/internal/utils"
+)
+
+func TestGenerateAndExplainMakesBothCalls(t *testing.T) {
+	provider := &mockProvider{
+		generateResponses: []*llm.Response{{
+			Command: "du -sh * | sort -h",
+			Usage:   &llm.Usage{PromptTokens: 100, CompletionTokens: 10, TotalTokens: 110},
+		}},
+		explanation: "Shows the size of each entry, sorted from smallest to largest",
+	}
+
+	request := &llm.Request{Query: "show folder sizes"}
+	response, err := llm.GenerateAndExplain(context.Background(), provider, request)
+	if err != nil {
+		t.Fatalf("GenerateAndExplain returned error: %v", err)
+	}
+
+	if len(provider.generateRequests) != 1 || provider.generateRequests[0] != request {
+		t.Errorf("Expected one GenerateCommand call with the request, got %d", len(provider.generateRequests))
+	}
+	if len(provider.explainCommands) != 1 || provider.explainCommands[0] != "du -sh * | sort -h" {
+		t.Errorf("Expected ExplainCommand to be called on the generated command, got %q", provider.explainCommands)
+	}
+
+	if response.Command != "du -sh * | sort -h" {
+		t.Errorf("Command = %q; want the generated command", response.Command)
+	}
+	if response.Explanation != provider.explanation {
+		t.Errorf("Explanation = %q; want %q", response.Explanation, provider.explanation)
+	}
+	if response.Usage == nil || response.Usage.TotalTokens != 160 {
+		t.Errorf("Expected usage to include both requests, got %+v", response.Usage)
+	}
+}
+
+func TestGenerateAndExplainKeepsCommandOnExplainFailure(t *testing.T) {
+	var buf bytes.Buffer
+	previous := utils.GetLogger()
+	utils.SetLogger(utils.NewTextLogger(&buf, utils.LogLevelInfo))
+	defer utils.SetLogger(previous)
+
+	provider := &mockProvider{
+		generateResponses: []*llm.Response{{Command: "ls -la"}},
+		explainErr:        errors.New("rate limited"),
+	}
+
+	response, err := llm.GenerateAndExplain(context.Background(), provider, &llm.Request{Query: "list files"})
+	if err != nil {
+		t.Fatalf("Explanation failure should not be fatal, got %v", err)
+	}
+	if response.Command != "ls -la" || response.Explanation != "" {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+	if !strings.Contains(buf.String(), "rate limited") {
+		t.Errorf("Expected a warning about the failed explanation, got %q", buf.String())
+	}
+}
+
+func TestGenerateAndExplainSkipsExplainOnGenerateError(t *testing.T) {
+	provider := &mockProvider{generateErr: errors.New("auth failed")}
+
+	if _, err := llm.GenerateAndExplain(context.Background(), provider, &llm.Request{Query: "list files"}); err == nil {
+		t.Fatal("Expected the generation error to be returned")
+	}
+	if len(provider.explainCommands) != 0 {
+		t.Error("ExplainCommand should not be called when generation fails")
+	}
+}
+
+func TestExplainCommandAssessesDanger(t *testing.T) {
+	provider := &mockProvider{explanation: "Recursively deletes every file on the system."}
+
+	response, err := security.ExplainCommand(context.Background(), provider, "rm -rf /", &llm.Context{OS: "linux", WorkingDirectory: "/home/user"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if response.DangerLevel != llm.DangerLevelCritical {
+		t.Errorf("expected critical danger level, got %q", response.DangerLevel)
+	}
+	if len(response.Warnings) == 0 || !strings.HasPrefix(response.Warnings[0], "critical risk:") {
+		t.Errorf("expected a critical risk warning, got %v", response.Warnings)
+	}
+	if response.Explanation != provider.explanation {
+		t.Errorf("expected the provider's explanation, got %q", response.Explanation)
+	}
+}
+
+func TestExplainCommandSafeCommandHasNoWarnings(t *testing.T) {
+	provider := &mockProvider{explanation: "Lists files."}
+
+	response, err := security.ExplainCommand(context.Background(), provider, "ls -la", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if response.DangerLevel != llm.DangerLevelSafe {
+		t.Errorf("expected safe danger level, got %q", response.DangerLevel)
+	}
+	if len(response.Warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", response.Warnings)
+	}
+}
+
+func TestExplainCommandReturnsProviderError(t *testing.T) {
+	provider := &mockProvider{explainErr: errors.New("rate limited")}
+
+	if _, err := security.ExplainCommand(context.Background(), provider, "rm -rf /", nil); err == nil {
+		t.Error("expected the provider error to be returned")
+	}
+}
+
+func TestAnnotateDangerNeverLowersLevel(t *testing.T) {
+	response := &llm.Response{Command: "ls", DangerLevel: llm.DangerLevelHigh}
+	security.AnnotateDanger(response, nil)
+
+	if response.DangerLevel != llm.DangerLevelHigh {
+		t.Errorf("expected the provider's higher level to be kept, got %q", response.DangerLevel)
+	}
+}
@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"forgor/internal/utils"
+)
+
+const testCompletionBlock = `# forgor shell completion
+if [ -f "/tmp/completion.bash" ]; then
+    source "/tmp/completion.bash"
+fi`
+
+func TestAppendBlockToFileWithoutTrailingNewline(t *testing.T) {
+	rcFile := filepath.Join(t.TempDir(), ".bashrc")
+	if err := os.WriteFile(rcFile, []byte("export PATH=$PATH:/opt/bin"), 0600); err != nil {
+		t.Fatalf("failed to write rc file: %v", err)
+	}
+
+	added, err := utils.AppendBlockToFile(rcFile, testCompletionBlock, "# forgor shell completion")
+	if err != nil {
+		t.Fatalf("AppendBlockToFile returned error: %v", err)
+	}
+	if !added {
+		t.Fatal("Expected block to be added")
+	}
+
+	content, err := os.ReadFile(rcFile)
+	if err != nil {
+		t.Fatalf("failed to read rc file: %v", err)
+	}
+
+	expected := "export PATH=$PATH:/opt/bin\n\n" + testCompletionBlock + "\n"
+	if string(content) != expected {
+		t.Errorf("Unexpected rc file content:\n%q\nwant:\n%q", string(content), expected)
+	}
+
+	// Existing permissions should be preserved by the atomic rename
+	stat, err := os.Stat(rcFile)
+	if err != nil {
+		t.Fatalf("failed to stat rc file: %v", err)
+	}
+	if stat.Mode().Perm() != 0600 {
+		t.Errorf("Expected permissions 0600, got %o", stat.Mode().Perm())
+	}
+
+	// No temp files should be left behind
+	entries, _ := os.ReadDir(filepath.Dir(rcFile))
+	if len(entries) != 1 {
+		t.Errorf("Expected only the rc file in directory, found %d entries", len(entries))
+	}
+}
+
+func TestAppendBlockToFileIdempotent(t *testing.T) {
+	rcFile := filepath.Join(t.TempDir(), ".zshrc")
+	if err := os.WriteFile(rcFile, []byte("alias ll='ls -la'\n"), 0644); err != nil {
+		t.Fatalf("failed to write rc file: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		added, err := utils.AppendBlockToFile(rcFile, testCompletionBlock, "# forgor shell completion")
+		if err != nil {
+			t.Fatalf("AppendBlockToFile returned error: %v", err)
+		}
+		if added != (i == 0) {
+			t.Errorf("Call %d: added = %v, want %v", i, added, i == 0)
+		}
+	}
+
+	content, err := os.ReadFile(rcFile)
+	if err != nil {
+		t.Fatalf("failed to read rc file: %v", err)
+	}
+
+	if count := strings.Count(string(content), "# forgor shell completion"); count != 1 {
+		t.Errorf("Expected completion block exactly once, found %d times", count)
+	}
+}
+
+func TestAppendBlockToFileCreatesMissingFile(t *testing.T) {
+	rcFile := filepath.Join(t.TempDir(), ".bashrc")
+
+	added, err := utils.AppendBlockToFile(rcFile, testCompletionBlock, "# forgor shell completion")
+	if err != nil {
+		t.Fatalf("AppendBlockToFile returned error: %v", err)
+	}
+	if !added {
+		t.Fatal("Expected block to be added")
+	}
+
+	content, err := os.ReadFile(rcFile)
+	if err != nil {
+		t.Fatalf("failed to read rc file: %v", err)
+	}
+	if string(content) != testCompletionBlock+"\n" {
+		t.Errorf("Unexpected rc file content: %q", string(content))
+	}
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"forgor/internal/config"
+	"forgor/internal/llm"
+	"forgor/internal/utils"
+)
+
+func TestRunCapturedCommandFailure(t *testing.T) {
+	result, err := utils.RunCapturedCommand("sh", "echo partial; echo 'boom: no such file' >&2; exit 1", nil, io.Discard, io.Discard)
+
+	var cmdErr *utils.CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("Expected a *CommandError, got %v", err)
+	}
+	if cmdErr.Result != result || result.ExitCode != 1 {
+		t.Errorf("Expected exit code 1 in the error result, got %+v", cmdErr.Result)
+	}
+	for _, want := range []string{"partial", "boom: no such file"} {
+		if !strings.Contains(result.Output, want) {
+			t.Errorf("Expected captured output to contain %q, got %q", want, result.Output)
+		}
+	}
+}
+
+func TestRunCapturedCommandSuccess(t *testing.T) {
+	result, err := utils.RunCapturedCommand("sh", "echo ok", nil, io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if result.ExitCode != 0 || strings.TrimSpace(result.Output) != "ok" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+}
+
+func TestSuggestFixSendsFailedCommandToProvider(t *testing.T) {
+	result, err := utils.RunCapturedCommand("sh", "echo 'tar: archive.tgz: Cannot open' >&2; exit 1", nil, io.Discard, io.Discard)
+	if err == nil {
+		t.Fatal("Expected the command to fail")
+	}
+
+	provider := &mockProvider{
+		generateResponses: []*llm.Response{{Command: "tar -xzf archive.tar.gz"}},
+	}
+	original := &llm.Request{
+		Query:   "extract the archive",
+		Context: llm.Context{OS: "linux", Shell: "bash"},
+		Options: llm.RequestOptions{SafetyLevel: "strict"},
+	}
+
+	fix, err := llm.SuggestFix(context.Background(), provider, original, result.Command, result.ExitCode, result.Output)
+	if err != nil {
+		t.Fatalf("SuggestFix returned error: %v", err)
+	}
+	if fix.Command != "tar -xzf archive.tar.gz" {
+		t.Errorf("Command = %q; want the provider's fix", fix.Command)
+	}
+
+	if len(provider.generateRequests) != 1 {
+		t.Fatalf("Expected one request to the provider, got %d", len(provider.generateRequests))
+	}
+	sent := provider.generateRequests[0]
+	for _, want := range []string{"extract the archive", result.Command, "exit code 1", "Cannot open"} {
+		if !strings.Contains(sent.Query, want) {
+			t.Errorf("Expected fix query to contain %q, got %q", want, sent.Query)
+		}
+	}
+	if sent.Context.OS != "linux" || sent.Options.SafetyLevel != "strict" {
+		t.Errorf("Expected the original context and options to be reused, got %+v", sent)
+	}
+}
+
+func TestRecordRecentCommand(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	for i := 0; i < config.MaxRecentCommands+5; i++ {
+		if err := config.RecordRecentCommand(fmt.Sprintf("echo %d", i), 0); err != nil {
+			t.Fatalf("RecordRecentCommand returned error: %v", err)
+		}
+	}
+	if err := config.RecordRecentCommand("false", 1); err != nil {
+		t.Fatalf("RecordRecentCommand returned error: %v", err)
+	}
+
+	recent, err := config.LoadRecentCommands()
+	if err != nil {
+		t.Fatalf("LoadRecentCommands returned error: %v", err)
+	}
+	if len(recent) != config.MaxRecentCommands {
+		t.Fatalf("Expected the ring to keep %d commands, got %d", config.MaxRecentCommands, len(recent))
+	}
+
+	last := recent[len(recent)-1]
+	if last.Command != "false" || last.ExitCode != 1 || !last.Failed() {
+		t.Errorf("Expected the failed command to be recorded last, got %+v", last)
+	}
+	if recent[0].Command != "echo 6" {
+		t.Errorf("Expected the oldest entries to be dropped, first is %q", recent[0].Command)
+	}
+}
@@ -0,0 +1,152 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"forgor/internal/config"
+	"forgor/internal/llm"
+	"forgor/internal/prompt"
+)
+
+var geminiTestProfile = config.Profile{Provider: "gemini", APIKey: "test-key", Model: "gemini-1.5-flash"}
+
+// geminiCommandReply is a Gemini answer with a plain command
+const geminiCommandReply = `{"candidates": [{"content": {"parts": [{"text": "COMMAND: ls"}]}, "finishReason": "STOP"}]}`
+
+func TestGeminiPromptBlocked(t *testing.T) {
+	server := newProviderTestServer(t, http.StatusOK, `{
+		"promptFeedback": {
+			"blockReason": "SAFETY",
+			"safetyRatings": [
+				{"category": "HARM_CATEGORY_DANGEROUS_CONTENT", "probability": "HIGH", "blocked": true},
+				{"category": "HARM_CATEGORY_HARASSMENT", "probability": "NEGLIGIBLE"}
+			]
+		}
+	}`)
+	provider := newProviderForServer(t, server, geminiTestProfile)
+
+	_, err := provider.GenerateCommand(context.Background(), &llm.Request{Query: "something blocked"})
+	assertGeminiSafetyError(t, err, "SAFETY", "DANGEROUS_CONTENT")
+}
+
+func TestGeminiCandidateBlocked(t *testing.T) {
+	server := newProviderTestServer(t, http.StatusOK, `{
+		"candidates": [{
+			"content": {"parts": []},
+			"finishReason": "SAFETY",
+			"index": 0,
+			"safetyRatings": [{"category": "HARM_CATEGORY_HARASSMENT", "probability": "MEDIUM"}]
+		}]
+	}`)
+	provider := newProviderForServer(t, server, geminiTestProfile)
+
+	_, err := provider.GenerateCommand(context.Background(), &llm.Request{Query: "something blocked"})
+	assertGeminiSafetyError(t, err, "SAFETY", "HARASSMENT")
+
+	_, err = provider.ExplainCommand(context.Background(), "ls -la")
+	assertGeminiSafetyError(t, err, "SAFETY", "HARASSMENT")
+}
+
+func TestGeminiNotBlocked(t *testing.T) {
+	server := newProviderTestServer(t, http.StatusOK, `{
+		"candidates": [{
+			"content": {"parts": [{"text": "COMMAND: ls -la\nDANGER_LEVEL: safe\nDANGER_REASON: Read-only"}]},
+			"finishReason": "STOP",
+			"index": 0
+		}]
+	}`)
+	provider := newProviderForServer(t, server, geminiTestProfile)
+
+	response, err := provider.GenerateCommand(context.Background(), &llm.Request{Query: "list files"})
+	if err != nil {
+		t.Fatalf("GenerateCommand returned error: %v", err)
+	}
+	if response.Command != "ls -la" {
+		t.Errorf("Command = %q; want %q", response.Command, "ls -la")
+	}
+}
+
+func assertGeminiSafetyError(t *testing.T, err error, reason, category string) {
+	t.Helper()
+
+	var llmErr *llm.Error
+	if !errors.As(err, &llmErr) {
+		t.Fatalf("Expected *llm.Error, got %v", err)
+	}
+	if llmErr.Type != llm.ErrorTypeSafety {
+		t.Errorf("Type = %s; want %s", llmErr.Type, llm.ErrorTypeSafety)
+	}
+	if llmErr.Code != reason {
+		t.Errorf("Code = %q; want %q", llmErr.Code, reason)
+	}
+	if !strings.Contains(llmErr.Message, category) {
+		t.Errorf("Expected message to name category %s, got %q", category, llmErr.Message)
+	}
+}
+
+func TestGeminiSafetyThresholds(t *testing.T) {
+	expected := map[string]string{
+		prompt.SafetyLevelStrict:     "BLOCK_LOW_AND_ABOVE",
+		prompt.SafetyLevelModerate:   "BLOCK_MEDIUM_AND_ABOVE",
+		prompt.SafetyLevelPermissive: "BLOCK_ONLY_HIGH",
+	}
+
+	for level, threshold := range expected {
+		server := newProviderTestServer(t, http.StatusOK, geminiCommandReply)
+		provider := newProviderForServer(t, server, geminiTestProfile)
+		_, err := provider.GenerateCommand(context.Background(), &llm.Request{
+			Query:   "list files",
+			Options: llm.RequestOptions{SafetyLevel: level},
+		})
+		if err != nil {
+			t.Fatalf("%s: GenerateCommand returned error: %v", level, err)
+		}
+
+		settings, _ := server.lastRequest(t).Body["safetySettings"].([]interface{})
+		if len(settings) == 0 {
+			t.Fatalf("%s: request had no safety settings", level)
+		}
+		for i := range settings {
+			if got := jsonPath(settings, i, "threshold"); got != threshold {
+				t.Errorf("%s: %v threshold = %v; want %s", level, jsonPath(settings, i, "category"), got, threshold)
+			}
+		}
+	}
+}
+
+func TestProfileSystemPromptAffixes(t *testing.T) {
+	nudgedProfile := geminiTestProfile
+	nudgedProfile.SystemPromptPrefix = "Prefer fd over find."
+	nudgedProfile.SystemPromptSuffix = "Use ripgrep for searching file contents."
+
+	systemPromptFor := func(profile config.Profile) string {
+		server := newProviderTestServer(t, http.StatusOK, geminiCommandReply)
+		provider := newProviderForServer(t, server, profile)
+		if _, err := provider.GenerateCommand(context.Background(), &llm.Request{Query: "find go files"}); err != nil {
+			t.Fatalf("GenerateCommand returned error: %v", err)
+		}
+
+		text, ok := jsonPath(server.lastRequest(t).Body, "systemInstruction", "parts", 0, "text").(string)
+		if !ok {
+			t.Fatal("request had no system instruction")
+		}
+		return text
+	}
+
+	nudged := systemPromptFor(nudgedProfile)
+	if !strings.HasPrefix(nudged, "Prefer fd over find.") {
+		t.Errorf("Expected the profile prefix at the start of the system prompt, got %q", nudged[:min(len(nudged), 80)])
+	}
+	if !strings.HasSuffix(nudged, "Use ripgrep for searching file contents.") {
+		t.Error("Expected the profile suffix at the end of the system prompt")
+	}
+
+	plain := systemPromptFor(geminiTestProfile)
+	if strings.Contains(plain, "Prefer fd over find.") || strings.Contains(plain, "Use ripgrep") {
+		t.Error("Profile prompt text should not leak into other profiles")
+	}
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"forgor/internal/config"
+	"forgor/internal/llm"
+)
+
+func TestGroqProviderFromFactory(t *testing.T) {
+	cfg := &config.Config{
+		DefaultProfile: "groq",
+		Profiles: map[string]config.Profile{
+			"groq": {Provider: "groq", APIKey: "gsk-test", Model: "llama-3.1-70b-versatile"},
+		},
+	}
+
+	provider, err := llm.NewFactory(cfg).GetProvider("default")
+	if err != nil {
+		t.Fatalf("GetProvider() returned error: %v", err)
+	}
+	groq, ok := provider.(*llm.GroqProvider)
+	if !ok {
+		t.Fatalf("Expected a *llm.GroqProvider, got %T", provider)
+	}
+
+	info := groq.GetProviderInfo()
+	if info.Name != "Groq" || info.Metadata["provider"] != "groq" {
+		t.Errorf("Expected Groq provider info, got %s (%s)", info.Name, info.Metadata["provider"])
+	}
+	if !contains(info.Models, "llama-3.1-70b-versatile") {
+		t.Errorf("Expected llama-3.1-70b-versatile in Groq models, got %v", info.Models)
+	}
+
+	var gotPath, gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"model": "llama-3.1-70b-versatile", "choices": [
+			{"index": 0, "message": {"role": "assistant", "content": "COMMAND: df -h\nDANGER_LEVEL: safe"}, "finish_reason": "stop"}
+		]}`))
+	}))
+	t.Cleanup(server.Close)
+	groq.SetBaseURL(server.URL)
+
+	response, err := groq.GenerateCommand(context.Background(), &llm.Request{Query: "disk space"})
+	if err != nil {
+		t.Fatalf("GenerateCommand returned error: %v", err)
+	}
+	if response.Command != "df -h" {
+		t.Errorf("Command = %q; want %q", response.Command, "df -h")
+	}
+	if gotPath != "/chat/completions" {
+		t.Errorf("Expected an OpenAI-compatible chat completions request, got %s", gotPath)
+	}
+	if gotAuth != "Bearer gsk-test" {
+		t.Errorf("Expected the API key as a bearer token, got %q", gotAuth)
+	}
+}
+
+func TestGroqProviderValidation(t *testing.T) {
+	if got := llm.DefaultAPIKeyEnv("groq"); got != "GROQ_API_KEY" {
+		t.Errorf("DefaultAPIKeyEnv(groq) = %q; want GROQ_API_KEY", got)
+	}
+
+	cfg := &config.Config{
+		DefaultProfile: "groq",
+		Profiles: map[string]config.Profile{
+			"groq": {Provider: "groq", APIKey: "gsk-test", Model: llm.SupportedModels("groq")[0]},
+		},
+	}
+	if err := llm.NewFactory(cfg).ValidateProvider("groq"); err != nil {
+		t.Errorf("ValidateProvider() rejected a known Groq model: %v", err)
+	}
+
+	missingKey := config.Profile{Provider: "groq", Model: "llama-3.1-8b-instant"}
+	err := missingKey.Validate()
+	if err == nil || !strings.Contains(err.Error(), "api_key") {
+		t.Errorf("Expected a Groq profile without an API key to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,328 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"forgor/internal/history"
+	"forgor/internal/prompt"
+	"forgor/internal/utils"
+)
+
+func TestCollapseDuplicates(t *testing.T) {
+	entries := []history.HistoryEntry{
+		{Command: "npm install", ExitCode: 1},
+		{Command: "npm install", ExitCode: 1},
+		{Command: "npm install ", ExitCode: 0},
+		{Command: "ls", ExitCode: 0},
+		{Command: "npm install", ExitCode: 0},
+		{Command: "git push", ExitCode: 1},
+		{Command: "git push", ExitCode: 128},
+	}
+
+	expected := []history.HistoryEntry{
+		{Command: "npm install", ExitCode: 0, Count: 3},
+		{Command: "ls", ExitCode: 0},
+		{Command: "npm install", ExitCode: 0},
+		{Command: "git push", ExitCode: 128, Count: 2},
+	}
+
+	collapsed := history.CollapseDuplicates(entries)
+	if !reflect.DeepEqual(collapsed, expected) {
+		t.Errorf("expected %+v, got %+v", expected, collapsed)
+	}
+
+	// Collapsing again changes nothing
+	if again := history.CollapseDuplicates(collapsed); !reflect.DeepEqual(again, expected) {
+		t.Errorf("expected collapsing to be idempotent, got %+v", again)
+	}
+
+	if len(history.CollapseDuplicates(nil)) != 0 {
+		t.Error("expected no entries from nil history")
+	}
+}
+
+func TestCommandPromptAnnotatesRepeatedCommands(t *testing.T) {
+	request := &prompt.Request{
+		Query: "fix it",
+		Context: prompt.RequestContext{
+			History: []history.HistoryEntry{
+				{Command: "make build", ExitCode: 2},
+				{Command: "make build", ExitCode: 2},
+				{Command: "make build", ExitCode: 2},
+			},
+		},
+	}
+
+	result := prompt.BuildCommandPrompt(request)
+	if strings.Count(result, "make build") != 1 {
+		t.Errorf("expected the repeated command once, got:\n%s", result)
+	}
+	if !strings.Contains(result, "- `make build` (FAILED with exit code 2) ×3") {
+		t.Errorf("expected a ×3 annotation, got:\n%s", result)
+	}
+}
+
+// writeCommandLog writes lines to .command_log in a fresh HOME directory
+func writeCommandLog(t *testing.T, lines []string) {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SHELL", "/bin/bash")
+
+	content := strings.Join(lines, "\n") + "\n"
+	if err := os.WriteFile(filepath.Join(home, ".command_log"), []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write command log: %v", err)
+	}
+}
+
+// commandLogLine formats an enhanced logger line for a command run at when
+func commandLogLine(when time.Time, exitCode int, command string) string {
+	return fmt.Sprintf("%d|bash|100|session-1|/dev/ttys001|/home/user|%d|%s", when.Unix(), exitCode, command)
+}
+
+func TestGetHistoryDropsOldCommands(t *testing.T) {
+	now := time.Now()
+	writeCommandLog(t, []string{
+		commandLogLine(now.Add(-72*time.Hour), 0, "ls ~/old"),
+		commandLogLine(now.Add(-25*time.Hour), 1, "make build"),
+		commandLogLine(now.Add(-2*time.Hour), 1, "go test ./..."),
+		commandLogLine(now.Add(-time.Minute), 0, "git status"),
+	})
+
+	entries, err := utils.GetHistoryWithOptions(utils.HistoryOptions{MaxCommands: 10, MaxAge: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("GetHistoryWithOptions returned error: %v", err)
+	}
+	if got, want := historyCommands(entries), []string{"go test ./...", "git status"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected only commands from the last day %v, got %v", want, got)
+	}
+
+	// Without a maximum age every logged command is returned
+	entries, err = utils.GetHistory(10)
+	if err != nil {
+		t.Fatalf("GetHistory returned error: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Errorf("expected all 4 commands without a maximum age, got %v", historyCommands(entries))
+	}
+}
+
+func TestGetHistoryCurrentSession(t *testing.T) {
+	now := time.Now()
+	line := func(offset time.Duration, session, tty, command string) string {
+		return fmt.Sprintf("%d|bash|100|%s|%s|/home/user|0|%s", now.Add(offset).Unix(), session, tty, command)
+	}
+	writeCommandLog(t, []string{
+		line(-5*time.Minute, "session-a", "/dev/ttys001", "cd ~/project"),
+		line(-4*time.Minute, "session-b", "/dev/ttys002", "htop"),
+		line(-3*time.Minute, "session-a", "/dev/ttys001", "make build"),
+		line(-2*time.Minute, "session-b", "/dev/ttys002", "ssh prod"),
+		line(-time.Minute, "session-a", "/dev/ttys001", "git status"),
+	})
+
+	current := utils.HistoryOptions{MaxCommands: 10, CurrentSession: true}
+
+	t.Setenv("SHELL_SESSION_ID", "session-a")
+	t.Setenv("SHELL_TTY", "/dev/ttys002")
+	entries, err := utils.GetHistoryWithOptions(current)
+	if err != nil {
+		t.Fatalf("GetHistoryWithOptions returned error: %v", err)
+	}
+	if got, want := historyCommands(entries), []string{"cd ~/project", "make build", "git status"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected only session-a commands %v, got %v", want, got)
+	}
+
+	// Without a session id the tty identifies the session
+	t.Setenv("SHELL_SESSION_ID", "")
+	entries, err = utils.GetHistoryWithOptions(current)
+	if err != nil {
+		t.Fatalf("GetHistoryWithOptions returned error: %v", err)
+	}
+	if got, want := historyCommands(entries), []string{"htop", "ssh prod"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected only /dev/ttys002 commands %v, got %v", want, got)
+	}
+
+	// With no session information all history is used
+	t.Setenv("SHELL_TTY", "")
+	entries, err = utils.GetHistoryWithOptions(current)
+	if err != nil {
+		t.Fatalf("GetHistoryWithOptions returned error: %v", err)
+	}
+	if len(entries) != 5 {
+		t.Errorf("expected all 5 commands without session info, got %v", historyCommands(entries))
+	}
+}
+
+func TestGetHistoryWithoutNativeFallback(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SHELL", "/bin/bash")
+	if err := os.WriteFile(filepath.Join(home, ".bash_history"), []byte("ls -la\ngit status\n"), 0600); err != nil {
+		t.Fatalf("failed to write shell history: %v", err)
+	}
+
+	// Without a command log the shell's history file is read by default
+	entries, err := utils.GetHistoryWithOptions(utils.HistoryOptions{MaxCommands: 10})
+	if err != nil {
+		t.Fatalf("GetHistoryWithOptions returned error: %v", err)
+	}
+	if got, want := historyCommands(entries), []string{"ls -la", "git status"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected the shell history %v, got %v", want, got)
+	}
+
+	entries, err = utils.GetHistoryWithOptions(utils.HistoryOptions{MaxCommands: 10, NoNativeFallback: true})
+	if err != nil {
+		t.Fatalf("GetHistoryWithOptions returned error: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no history without the fallback, got %v", historyCommands(entries))
+	}
+
+	// An unreadable history file would fail the fallback, so an error here
+	// means the file was opened
+	historyFile := filepath.Join(home, ".bash_history")
+	if err := os.Remove(historyFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(historyFile, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := utils.GetHistoryWithOptions(utils.HistoryOptions{MaxCommands: 10}); err == nil {
+		t.Fatal("expected the fallback to fail on a directory history file")
+	}
+	if _, err := utils.GetHistoryWithOptions(utils.HistoryOptions{MaxCommands: 10, NoNativeFallback: true}); err != nil {
+		t.Errorf("expected the shell history file not to be opened, got %v", err)
+	}
+
+	// The command log is still read
+	writeCommandLog(t, []string{commandLogLine(time.Now(), 0, "make build")})
+	entries, err = utils.GetHistoryWithOptions(utils.HistoryOptions{MaxCommands: 10, NoNativeFallback: true})
+	if err != nil {
+		t.Fatalf("GetHistoryWithOptions returned error: %v", err)
+	}
+	if got := historyCommands(entries); !reflect.DeepEqual(got, []string{"make build"}) {
+		t.Errorf("expected the logged command, got %v", got)
+	}
+}
+
+func TestAppendToCommandLog(t *testing.T) {
+	writeCommandLog(t, []string{
+		commandLogLine(time.Now().Add(-time.Minute), 0, "cd ~/project"),
+	})
+	t.Setenv("SHELL_SESSION_ID", "session-1")
+	t.Setenv("SHELL_TTY", "/dev/ttys001")
+
+	err := exec.Command("sh", "-c", "exit 3").Run()
+	if err := utils.AppendToCommandLog("make build\nmake test", utils.ExitCode(err), "/home/user/project"); err != nil {
+		t.Fatalf("AppendToCommandLog returned error: %v", err)
+	}
+
+	entries, err := utils.GetHistoryWithOptions(utils.HistoryOptions{MaxCommands: 10, CurrentSession: true})
+	if err != nil {
+		t.Fatalf("GetHistoryWithOptions returned error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected the logged and appended commands, got %v", historyCommands(entries))
+	}
+
+	appended := entries[1]
+	if appended.Command != "make build make test" {
+		t.Errorf("expected the command on one line, got %q", appended.Command)
+	}
+	if appended.ExitCode != 3 {
+		t.Errorf("expected exit code 3, got %d", appended.ExitCode)
+	}
+	if appended.WorkingDirectory != "/home/user/project" {
+		t.Errorf("expected working directory /home/user/project, got %q", appended.WorkingDirectory)
+	}
+	if time.Since(appended.Timestamp) > time.Minute {
+		t.Errorf("expected a current timestamp, got %v", appended.Timestamp)
+	}
+}
+
+func TestParseCommandLogLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want history.HistoryEntry
+		ok   bool
+	}{
+		{
+			name: "full format",
+			line: "1700000000|zsh|4242|session-1|/dev/ttys003|/home/user/project|2|go test ./...",
+			want: history.HistoryEntry{Command: "go test ./...", ExitCode: 2, WorkingDirectory: "/home/user/project", Timestamp: time.Unix(1700000000, 0), Session: "session-1", TTY: "/dev/ttys003"},
+			ok:   true,
+		},
+		{
+			name: "command containing pipes",
+			line: "1700000000|bash|1|s|tty|/tmp|0|ps aux | grep go | wc -l",
+			want: history.HistoryEntry{Command: "ps aux | grep go | wc -l", ExitCode: 0, WorkingDirectory: "/tmp", Timestamp: time.Unix(1700000000, 0), Session: "s", TTY: "tty"},
+			ok:   true,
+		},
+		{
+			name: "unreadable exit code and timestamp",
+			line: "soon|bash|1|s|tty|/tmp|?|make",
+			want: history.HistoryEntry{Command: "make", ExitCode: -1, WorkingDirectory: "/tmp", Session: "s", TTY: "tty"},
+			ok:   true,
+		},
+		{
+			name: "older format with fewer fields",
+			line: "1700000000|git status",
+			want: history.HistoryEntry{Command: "git status", ExitCode: -1, Timestamp: time.Unix(1700000000, 0)},
+			ok:   true,
+		},
+		{
+			name: "plain command",
+			line: "ls -la",
+			want: history.HistoryEntry{Command: "ls -la", ExitCode: -1},
+			ok:   true,
+		},
+		{
+			name: "empty command",
+			line: "1700000000|bash|1|s|tty|/tmp|0|  ",
+			ok:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, ok := history.ParseCommandLogLine(tt.line)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v; want %v", ok, tt.ok)
+			}
+			if ok && !reflect.DeepEqual(entry, tt.want) {
+				t.Errorf("ParseCommandLogLine(%q) = %+v; want %+v", tt.line, entry, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandPromptNotesOtherDirectories(t *testing.T) {
+	request := &prompt.Request{
+		Query: "fix the failing build",
+		Context: prompt.RequestContext{
+			WorkingDirectory: "/home/user/app",
+			History: []history.HistoryEntry{
+				{Command: "make build", ExitCode: 2, WorkingDirectory: "/home/user/lib"},
+				{Command: "git status", ExitCode: 0, WorkingDirectory: "/home/user/app"},
+				{Command: "ls", ExitCode: 0},
+			},
+		},
+	}
+
+	result := prompt.BuildCommandPrompt(request)
+	if !strings.Contains(result, "- `make build` (FAILED with exit code 2) (run in /home/user/lib)") {
+		t.Errorf("expected the other directory to be noted, got %q", result)
+	}
+	if strings.Contains(result, "run in /home/user/app") {
+		t.Error("commands run in the current directory should not note it")
+	}
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"forgor/internal/utils"
+)
+
+func TestTextLoggerLevels(t *testing.T) {
+	var buf bytes.Buffer
+	logger := utils.NewTextLogger(&buf, utils.LogLevelInfo)
+
+	logger.Debugf("debug %d", 1)
+	logger.Infof("info %d", 2)
+	logger.Warnf("warn %d\n", 3)
+
+	output := buf.String()
+	if strings.Contains(output, "debug 1") {
+		t.Error("Debug message should be filtered at info level")
+	}
+	if output != "info 2\nwarn 3\n" {
+		t.Errorf("Unexpected logger output: %q", output)
+	}
+}
+
+func TestTextLoggerDebugLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := utils.NewTextLogger(&buf, utils.LogLevelDebug)
+
+	logger.Debugf("⏱️  step: %s", "1.0ms")
+
+	if buf.String() != "⏱️  step: 1.0ms\n" {
+		t.Errorf("Unexpected logger output: %q", buf.String())
+	}
+}
+
+func TestDefaultLoggerRouting(t *testing.T) {
+	var buf bytes.Buffer
+	previous := utils.GetLogger()
+	utils.SetLogger(utils.NewTextLogger(&buf, utils.LogLevelDebug))
+	defer utils.SetLogger(previous)
+
+	utils.Debugf("debug message")
+	utils.Warnf("warning message")
+
+	// Timer output is diagnostic and goes through the logger, not stdout
+	timer := utils.NewTimer("Test", true)
+	timer.StartStep("Config Loading").End()
+
+	output := buf.String()
+	for _, want := range []string{"debug message", "warning message", "Config Loading"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected logger output to contain %q, got %q", want, output)
+		}
+	}
+}
+
+func TestJSONLoggerLines(t *testing.T) {
+	var buf bytes.Buffer
+	previous := utils.GetLogger()
+	utils.SetLogger(utils.NewJSONLogger(&buf, utils.LogLevelDebug))
+	defer utils.SetLogger(previous)
+
+	utils.Debugf("%s Loaded %d commands\n", utils.Styled("[INFO]", utils.StyleInfo), 3)
+	utils.Warnf("cache save failed")
+
+	timer := utils.NewTimer("Command Execution", false)
+	timer.StartStep("Config Loading").EndWithResult("success")
+	timer.PrintSummary()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected 4 log lines, got %d: %q", len(lines), buf.String())
+	}
+
+	var steps int
+	for _, line := range lines {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Line is not valid JSON: %q (%v)", line, err)
+		}
+
+		for _, field := range []string{"level", "msg"} {
+			if _, ok := entry[field]; !ok {
+				t.Errorf("Line %q is missing field %q", line, field)
+			}
+		}
+		if strings.Contains(entry["msg"].(string), "\033[") {
+			t.Errorf("Message should not contain ANSI codes: %q", entry["msg"])
+		}
+
+		if step, ok := entry["step"]; ok {
+			steps++
+			if step == "" {
+				t.Errorf("Step name should not be empty: %q", line)
+			}
+			if _, ok := entry["duration_ms"].(float64); !ok {
+				t.Errorf("Step line %q should have numeric duration_ms", line)
+			}
+		}
+	}
+
+	if steps != 2 {
+		t.Errorf("Expected 2 step lines (step and total), got %d", steps)
+	}
+}
+
+func TestInitLoggerFormats(t *testing.T) {
+	previous := utils.GetLogger()
+	defer utils.SetLogger(previous)
+
+	if err := utils.InitLogger(false, "json"); err != nil {
+		t.Fatalf("InitLogger(json) returned error: %v", err)
+	}
+	if _, ok := utils.GetLogger().(*utils.JSONLogger); !ok {
+		t.Error("Expected a JSON logger")
+	}
+
+	if err := utils.InitLogger(true, "text"); err != nil {
+		t.Fatalf("InitLogger(text) returned error: %v", err)
+	}
+	if _, ok := utils.GetLogger().(*utils.TextLogger); !ok {
+		t.Error("Expected a text logger")
+	}
+
+	if err := utils.InitLogger(false, "xml"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"forgor/internal/utils"
+)
+
+func TestLeadingExecutable(t *testing.T) {
+	tests := []struct {
+		command  string
+		expected string
+	}{
+		{"ls -la", "ls"},
+		{"cd /tmp && ls", ""},
+		{"echo hello | grep h", ""},
+		{"fd -e go | xargs wc -l", "fd"},
+		{"sudo apt update", "apt"},
+		{"sudo -u postgres psql", "psql"},
+		{"FOO=bar BAZ=1 make build", "make"},
+		{"env -i PATH=/bin sh -c 'ls'", "sh"},
+		{"./configure --prefix=/usr", ""},
+		{"$EDITOR notes.txt", ""},
+		{"(cd build; make)", ""},
+		{"for f in *.txt; do echo $f; done", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if result := utils.LeadingExecutable(tt.command); result != tt.expected {
+			t.Errorf("LeadingExecutable(%q) = %q; want %q", tt.command, result, tt.expected)
+		}
+	}
+}
+
+func TestMissingCommandWarning(t *testing.T) {
+	warning := utils.MissingCommandWarning("forgor-hallucinated-tool --fix | less", []string{"npm", "brew"})
+	if !strings.Contains(warning, "command `forgor-hallucinated-tool` not found") {
+		t.Errorf("Expected a not-found warning, got %q", warning)
+	}
+	if !strings.Contains(warning, "brew install forgor-hallucinated-tool") {
+		t.Errorf("Expected an install suggestion from the detected package manager, got %q", warning)
+	}
+
+	if warning := utils.MissingCommandWarning("forgor-hallucinated-tool", nil); strings.Contains(warning, "try:") {
+		t.Errorf("Expected no suggestion without a system package manager, got %q", warning)
+	}
+
+	// Builtins never resolve on PATH and must not be flagged
+	if warning := utils.MissingCommandWarning("cd /tmp", nil); warning != "" {
+		t.Errorf("Expected no warning for a builtin, got %q", warning)
+	}
+
+	// Binaries on PATH are not flagged
+	if warning := utils.MissingCommandWarning("sh -c 'echo hi'", nil); warning != "" {
+		t.Errorf("Expected no warning for an available command, got %q", warning)
+	}
+}
@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"forgor/internal/config"
+	"forgor/internal/llm"
+)
+
+var mistralTestProfile = config.Profile{Provider: "mistral", APIKey: "test-key", Model: "codestral-latest"}
+
+func TestMistralProviderFromFactory(t *testing.T) {
+	cfg := &config.Config{
+		DefaultProfile: "mistral",
+		Profiles: map[string]config.Profile{
+			"mistral": {Provider: "mistral", APIKey: "test-key", Model: "codestral-latest"},
+		},
+	}
+
+	provider, err := llm.NewFactory(cfg).GetProvider("default")
+	if err != nil {
+		t.Fatalf("GetProvider() returned error: %v", err)
+	}
+	if _, ok := provider.(*llm.MistralProvider); !ok {
+		t.Fatalf("Expected a *llm.MistralProvider, got %T", provider)
+	}
+
+	info := provider.GetProviderInfo()
+	if info.Name != "Mistral" || info.Metadata["provider"] != "mistral" {
+		t.Errorf("Expected Mistral provider info, got %s (%s)", info.Name, info.Metadata["provider"])
+	}
+	for _, model := range []string{"codestral-latest", "mistral-large-latest"} {
+		if !contains(info.Models, model) {
+			t.Errorf("Expected %s in Mistral models, got %v", model, info.Models)
+		}
+	}
+}
+
+func TestMistralProviderValidation(t *testing.T) {
+	if got := llm.DefaultAPIKeyEnv("mistral"); got != "MISTRAL_API_KEY" {
+		t.Errorf("DefaultAPIKeyEnv(mistral) = %q; want MISTRAL_API_KEY", got)
+	}
+
+	cfg := &config.Config{
+		DefaultProfile: "mistral",
+		Profiles: map[string]config.Profile{
+			"mistral": {Provider: "mistral", APIKey: "test-key", Model: "mistral-large-latest"},
+		},
+	}
+	if err := llm.NewFactory(cfg).ValidateProvider("mistral"); err != nil {
+		t.Errorf("ValidateProvider() rejected a known Mistral model: %v", err)
+	}
+
+	missingKey := config.Profile{Provider: "mistral", Model: "codestral-latest"}
+	if err := missingKey.Validate(); err == nil || !strings.Contains(err.Error(), "api_key") {
+		t.Errorf("Expected a Mistral profile without an API key to be rejected, got %v", err)
+	}
+}
+
+func TestMistralGenerateCommand(t *testing.T) {
+	server := newProviderTestServer(t, http.StatusOK, `{"id": "cmpl-1", "object": "chat.completion", "model": "codestral-latest",
+		"choices": [{"index": 0, "message": {"role": "assistant", "content": "COMMAND: du -sh *\nDANGER_LEVEL: safe"}, "finish_reason": "stop"}],
+		"usage": {"prompt_tokens": 120, "completion_tokens": 8, "total_tokens": 128}}`)
+	provider := newProviderForServer(t, server, mistralTestProfile)
+
+	response, err := provider.GenerateCommand(context.Background(), &llm.Request{Query: "folder sizes"})
+	if err != nil {
+		t.Fatalf("GenerateCommand returned error: %v", err)
+	}
+	request := server.lastRequest(t)
+	if request.Path != "/chat/completions" {
+		t.Errorf("Expected a chat completions request, got %s", request.Path)
+	}
+	if got := request.Header.Get("Authorization"); got != "Bearer test-key" {
+		t.Errorf("Expected the API key as a bearer token, got %q", got)
+	}
+	if response.Command != "du -sh *" {
+		t.Errorf("Command = %q; want %q", response.Command, "du -sh *")
+	}
+	if response.Usage == nil || response.Usage.TotalTokens != 128 {
+		t.Errorf("Expected usage to be reported, got %+v", response.Usage)
+	}
+}
+
+func TestMistralAPIErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantType    llm.ErrorType
+		wantMessage string
+	}{
+		{
+			name:        "unauthorized",
+			status:      http.StatusUnauthorized,
+			body:        `{"message": "Unauthorized", "request_id": "abc"}`,
+			wantType:    llm.ErrorTypeAuth,
+			wantMessage: "Unauthorized",
+		},
+		{
+			name:        "invalid request",
+			status:      http.StatusBadRequest,
+			body:        `{"object": "error", "message": "Invalid model: codestral-nope", "type": "invalid_request_error", "param": null, "code": "1500"}`,
+			wantType:    llm.ErrorTypeInvalidInput,
+			wantMessage: "Invalid model",
+		},
+		{
+			name:        "validation details",
+			status:      http.StatusUnprocessableEntity,
+			body:        `{"object": "error", "message": {"detail": [{"msg": "Input should be a valid integer"}]}, "type": "invalid_request_message_error", "code": null}`,
+			wantType:    llm.ErrorTypeInvalidInput,
+			wantMessage: "Input should be a valid integer",
+		},
+		{
+			name:        "rate limited",
+			status:      http.StatusTooManyRequests,
+			body:        `{"object": "error", "message": "Requests rate limit exceeded", "type": "rate_limited"}`,
+			wantType:    llm.ErrorTypeRateLimit,
+			wantMessage: "rate limit exceeded",
+		},
+		{
+			name:     "server error",
+			status:   http.StatusInternalServerError,
+			body:     `oops`,
+			wantType: llm.ErrorTypeModel,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newProviderTestServer(t, tt.status, tt.body)
+			provider := newProviderForServer(t, server, mistralTestProfile)
+
+			_, err := provider.GenerateCommand(context.Background(), &llm.Request{Query: "list files"})
+			var llmErr *llm.Error
+			if !errors.As(err, &llmErr) {
+				t.Fatalf("Expected *llm.Error, got %v", err)
+			}
+			if llmErr.Type != tt.wantType {
+				t.Errorf("Error type = %s; want %s", llmErr.Type, tt.wantType)
+			}
+			if !strings.Contains(llmErr.Message, tt.wantMessage) {
+				t.Errorf("Expected message to contain %q, got %q", tt.wantMessage, llmErr.Message)
+			}
+		})
+	}
+}
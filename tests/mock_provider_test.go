@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"context"
+	"sync"
+
+	"forgor/internal/llm"
+)
+
+// mockProvider is an llm.Provider that returns canned responses and records
+// the calls it receives
+type mockProvider struct {
+	mu sync.Mutex
+
+	generateResponses []*llm.Response
+	generateErr       error
+	explanation       string
+	explainErr        error
+
+	generateRequests []*llm.Request
+	explainCommands  []string
+}
+
+// GenerateCommand returns the next canned response, repeating the last one
+func (m *mockProvider) GenerateCommand(ctx context.Context, request *llm.Request) (*llm.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.generateRequests = append(m.generateRequests, request)
+	if m.generateErr != nil {
+		return nil, m.generateErr
+	}
+	if len(m.generateResponses) == 0 {
+		return &llm.Response{}, nil
+	}
+
+	index := len(m.generateRequests) - 1
+	if index >= len(m.generateResponses) {
+		index = len(m.generateResponses) - 1
+	}
+	response := *m.generateResponses[index]
+	return &response, nil
+}
+
+// ExplainCommand returns the canned explanation
+func (m *mockProvider) ExplainCommand(ctx context.Context, command string) (*llm.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.explainCommands = append(m.explainCommands, command)
+	if m.explainErr != nil {
+		return nil, m.explainErr
+	}
+	return &llm.Response{
+		Command:     command,
+		Explanation: m.explanation,
+		Usage:       &llm.Usage{PromptTokens: 20, CompletionTokens: 30, TotalTokens: 50},
+	}, nil
+}
+
+// GetProviderInfo describes the mock provider
+func (m *mockProvider) GetProviderInfo() llm.ProviderInfo {
+	return llm.ProviderInfo{
+		Name:     "Mock",
+		Version:  "1.0.0",
+		Metadata: map[string]string{"provider": "mock", "model": "mock-model"},
+	}
+}
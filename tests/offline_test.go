@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"forgor/internal/llm"
+)
+
+func TestOfflineProviderMappings(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"find all txt files", `find . -name "*.txt"`},
+		{"Find the .go files", `find . -name "*.go"`},
+		{"show disk usage", "df -h"},
+		{"how much free space is left", "df -h"},
+		{"list running processes", "ps aux"},
+		{"compress this folder", "tar -czf archive.tar.gz ."},
+		{"what are the options for rsync", "rsync --help"},
+		{"what is using port 8080", "lsof -i :8080"},
+		{"show me the largest files here", "du -sh * | sort -hr | head -10"},
+		{"print my PATH", "echo $PATH"},
+		{"list files", "ls -la"},
+	}
+
+	provider := llm.NewOfflineProvider()
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			response, err := provider.GenerateCommand(context.Background(), &llm.Request{Query: tt.query})
+			if err != nil {
+				t.Fatalf("GenerateCommand(%q) returned error: %v", tt.query, err)
+			}
+			if response.Command != tt.want {
+				t.Errorf("GenerateCommand(%q) = %q; want %q", tt.query, response.Command, tt.want)
+			}
+			if response.Confidence >= 0.5 {
+				t.Errorf("Expected low confidence for an offline command, got %v", response.Confidence)
+			}
+			if len(response.Warnings) == 0 {
+				t.Error("Expected a warning that the command was generated offline")
+			}
+		})
+	}
+}
+
+func TestOfflineProviderExplanationAndNoMatch(t *testing.T) {
+	provider := llm.NewOfflineProvider()
+
+	response, err := provider.GenerateCommand(context.Background(), &llm.Request{
+		Query:   "show disk usage",
+		Options: llm.RequestOptions{IncludeExplanation: true},
+	})
+	if err != nil {
+		t.Fatalf("GenerateCommand returned error: %v", err)
+	}
+	if response.Explanation == "" {
+		t.Error("Expected an explanation when one is requested")
+	}
+
+	if _, err := provider.GenerateCommand(context.Background(), &llm.Request{Query: "deploy the app to kubernetes"}); err == nil {
+		t.Error("Expected an error for a query no offline rule matches")
+	}
+}
+
+func TestIsUnreachable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection failure", &llm.Error{Type: llm.ErrorTypeNetwork, Message: "Failed to call OpenAI API", Cause: errors.New("connection refused")}, true},
+		{"wrapped timeout", fmt.Errorf("generate: %w", &llm.Error{Type: llm.ErrorTypeTimeout, Message: "timed out"}), true},
+		{"HTTP error response", &llm.Error{Type: llm.ErrorTypeNetwork, Message: "HTTP 502: bad gateway"}, false},
+		{"auth error", &llm.Error{Type: llm.ErrorTypeAuth, Message: "invalid key"}, false},
+		{"other error", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := llm.IsUnreachable(tt.err); got != tt.want {
+				t.Errorf("IsUnreachable() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"forgor/internal/config"
+	"forgor/internal/llm"
+)
+
+var ollamaTestProfile = config.Profile{Provider: "ollama", Model: "qwen2.5-coder"}
+
+func TestOllamaGenerateCommand(t *testing.T) {
+	server := newProviderTestServer(t, http.StatusOK, `{"model": "qwen2.5-coder",
+		"message": {"role": "assistant", "content": "COMMAND: free -h\nDANGER_LEVEL: safe"},
+		"done": true, "done_reason": "stop", "prompt_eval_count": 90, "eval_count": 10}`)
+	provider := newProviderForServer(t, server, ollamaTestProfile)
+
+	response, err := provider.GenerateCommand(context.Background(), &llm.Request{Query: "memory usage"})
+	if err != nil {
+		t.Fatalf("GenerateCommand returned error: %v", err)
+	}
+	if response.Command != "free -h" {
+		t.Errorf("Command = %q; want %q", response.Command, "free -h")
+	}
+	if response.Usage == nil || response.Usage.TotalTokens != 100 {
+		t.Errorf("Expected usage from the eval counts, got %+v", response.Usage)
+	}
+
+	request := server.lastRequest(t)
+	if request.Path != "/api/chat" {
+		t.Errorf("Expected a chat request, got %s", request.Path)
+	}
+	assertJSONField(t, request.Body, false, "stream")
+}
+
+func TestOllamaListModels(t *testing.T) {
+	server := newProviderTestServer(t, http.StatusOK, `{"models": [{"name": "llama3.2:latest"}, {"name": "codellama:7b"}]}`)
+	provider := newProviderForServer(t, server, ollamaTestProfile)
+
+	models, err := provider.(llm.ModelLister).ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels returned error: %v", err)
+	}
+	if want := []string{"codellama:7b", "llama3.2:latest"}; !reflect.DeepEqual(models, want) {
+		t.Errorf("ListModels() = %v; want %v", models, want)
+	}
+	if path := server.lastRequest(t).Path; path != "/api/tags" {
+		t.Errorf("Expected a tags request, got %s", path)
+	}
+}
+
+func TestOllamaModelNotPulled(t *testing.T) {
+	server := newProviderTestServer(t, http.StatusNotFound, `{"error": "model \"qwen2.5-coder\" not found, try pulling it first"}`)
+	provider := newProviderForServer(t, server, ollamaTestProfile)
+
+	_, err := provider.GenerateCommand(context.Background(), &llm.Request{Query: "list files"})
+	var llmErr *llm.Error
+	if !errors.As(err, &llmErr) {
+		t.Fatalf("Expected *llm.Error, got %v", err)
+	}
+	if !strings.Contains(llmErr.Message, "ollama pull qwen2.5-coder") {
+		t.Errorf("Expected the error to suggest pulling the model, got %q", llmErr.Message)
+	}
+}
+
+func TestOllamaNotRunning(t *testing.T) {
+	// A closed server leaves nothing listening on its address
+	server := newProviderTestServer(t, http.StatusNotFound, "")
+	server.Close()
+
+	provider := newProviderForServer(t, server, ollamaTestProfile)
+	_, err := provider.GenerateCommand(context.Background(), &llm.Request{Query: "list files"})
+	var llmErr *llm.Error
+	if !errors.As(err, &llmErr) {
+		t.Fatalf("Expected *llm.Error, got %v", err)
+	}
+	if llmErr.Type != llm.ErrorTypeNetwork || !strings.Contains(llmErr.Message, "Is ollama running?") {
+		t.Errorf("Expected a friendly connection error, got %s: %q", llmErr.Type, llmErr.Message)
+	}
+}
+
+func TestOllamaProviderFromFactory(t *testing.T) {
+	// No API key or endpoint is needed for a local Ollama
+	cfg := &config.Config{
+		DefaultProfile: "ollama",
+		Profiles: map[string]config.Profile{
+			"ollama": {Provider: "ollama", Model: "llama3.2"},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() rejected an Ollama profile without an endpoint: %v", err)
+	}
+
+	provider, err := llm.NewFactory(cfg).GetProvider("default")
+	if err != nil {
+		t.Fatalf("GetProvider() returned error: %v", err)
+	}
+	if _, ok := provider.(*llm.OllamaProvider); !ok {
+		t.Fatalf("Expected a *llm.OllamaProvider, got %T", provider)
+	}
+	if endpoint := provider.GetProviderInfo().Metadata["endpoint"]; endpoint != llm.DefaultOllamaEndpoint {
+		t.Errorf("Expected the default endpoint, got %q", endpoint)
+	}
+}
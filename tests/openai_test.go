@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"testing"
+
+	"forgor/internal/config"
+	"forgor/internal/llm"
+)
+
+var openAITestProfile = config.Profile{Provider: "openai", APIKey: "test-key", Model: "gpt-4o-mini"}
+
+func TestOpenAIEmptyOrRefusedChoice(t *testing.T) {
+	tests := []struct {
+		name     string
+		choice   string
+		wantType llm.ErrorType
+	}{
+		{
+			name:     "empty content",
+			choice:   `{"index": 0, "message": {"role": "assistant", "content": ""}, "finish_reason": "stop"}`,
+			wantType: llm.ErrorTypeModel,
+		},
+		{
+			name:     "whitespace content",
+			choice:   `{"index": 0, "message": {"role": "assistant", "content": "  \n"}, "finish_reason": "length"}`,
+			wantType: llm.ErrorTypeModel,
+		},
+		{
+			name:     "refusal",
+			choice:   `{"index": 0, "message": {"role": "assistant", "content": null, "refusal": "I can't help with that."}, "finish_reason": "stop"}`,
+			wantType: llm.ErrorTypeSafety,
+		},
+		{
+			name:     "content filter",
+			choice:   `{"index": 0, "message": {"role": "assistant", "content": ""}, "finish_reason": "content_filter"}`,
+			wantType: llm.ErrorTypeSafety,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newProviderTestServer(t, http.StatusOK, `{"model": "gpt-4o-mini", "choices": [`+tt.choice+`]}`)
+			provider := newProviderForServer(t, server, openAITestProfile)
+
+			response, err := provider.GenerateCommand(context.Background(), &llm.Request{Query: "list files"})
+			if err == nil {
+				t.Fatalf("expected an error, got command %q", response.Command)
+			}
+
+			var llmErr *llm.Error
+			if !errors.As(err, &llmErr) {
+				t.Fatalf("expected *llm.Error, got %v", err)
+			}
+			if llmErr.Type != tt.wantType {
+				t.Errorf("Type = %s; want %s", llmErr.Type, tt.wantType)
+			}
+
+			if _, err := provider.ExplainCommand(context.Background(), "ls -la"); err == nil {
+				t.Error("ExplainCommand should also return an error")
+			}
+		})
+	}
+}
+
+func TestOpenAICommandChoice(t *testing.T) {
+	server := newProviderTestServer(t, http.StatusOK, `{"model": "gpt-4o-mini", "choices": [
+		{"index": 0, "message": {"role": "assistant", "content": "COMMAND: ls -la\nDANGER_LEVEL: safe"}, "finish_reason": "stop"}
+	]}`)
+	provider := newProviderForServer(t, server, openAITestProfile)
+
+	response, err := provider.GenerateCommand(context.Background(), &llm.Request{Query: "list files"})
+	if err != nil {
+		t.Fatalf("GenerateCommand returned error: %v", err)
+	}
+	if response.Command != "ls -la" {
+		t.Errorf("Command = %q; want %q", response.Command, "ls -la")
+	}
+}
+
+func TestOpenAIConfidenceFromLogprobs(t *testing.T) {
+	// Only the tokens of the command itself count: ln(0.9), ln(0.8), ln(0.7)
+	server := newProviderTestServer(t, http.StatusOK, `{"model": "gpt-4o-mini", "choices": [{
+		"index": 0,
+		"message": {"role": "assistant", "content": "COMMAND: ls -la\nDANGER_LEVEL: safe"},
+		"finish_reason": "stop",
+		"logprobs": {"content": [
+			{"token": "COMMAND:", "logprob": -2.302585},
+			{"token": " ls", "logprob": -0.105361},
+			{"token": " -", "logprob": -0.223144},
+			{"token": "la", "logprob": -0.356675},
+			{"token": "\n", "logprob": -4.605170},
+			{"token": "DANGER_LEVEL:", "logprob": -0.01},
+			{"token": " safe", "logprob": -0.01}
+		]}
+	}]}`)
+	provider := newProviderForServer(t, server, openAITestProfile)
+
+	response, err := provider.GenerateCommand(context.Background(), &llm.Request{Query: "list files"})
+	if err != nil {
+		t.Fatalf("GenerateCommand returned error: %v", err)
+	}
+
+	assertJSONField(t, server.lastRequest(t).Body, true, "logprobs")
+	if math.Abs(response.Confidence-0.8) > 0.001 {
+		t.Errorf("Confidence = %v; want the average command token probability 0.8", response.Confidence)
+	}
+}
+
+func TestOpenAIConfidenceWithoutLogprobs(t *testing.T) {
+	server := newProviderTestServer(t, http.StatusOK, `{"model": "gpt-4o-mini", "choices": [
+		{"index": 0, "message": {"role": "assistant", "content": "COMMAND: ls -la"}, "finish_reason": "length"}
+	]}`)
+	provider := newProviderForServer(t, server, openAITestProfile)
+
+	response, err := provider.GenerateCommand(context.Background(), &llm.Request{Query: "list files"})
+	if err != nil {
+		t.Fatalf("GenerateCommand returned error: %v", err)
+	}
+	if response.Confidence != 0.7 {
+		t.Errorf("Confidence = %v; want the finish reason estimate 0.7", response.Confidence)
+	}
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"forgor/internal/utils"
+)
+
+func TestWriteCommandOnly(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	err := utils.WriteCommandOnly(&stdout, &stderr, "ls -la\n", []string{"Potentially dangerous command detected: rm -rf /"})
+	if err != nil {
+		t.Fatalf("WriteCommandOnly returned error: %v", err)
+	}
+
+	if stdout.String() != "ls -la\n" {
+		t.Errorf("stdout = %q; want %q", stdout.String(), "ls -la\n")
+	}
+	if !strings.Contains(stderr.String(), "rm -rf /") {
+		t.Errorf("Expected warning on stderr, got %q", stderr.String())
+	}
+}
+
+func TestWriteCommandOnlyNoStyling(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	if err := utils.WriteCommandOnly(&stdout, &stderr, "git status", nil); err != nil {
+		t.Fatalf("WriteCommandOnly returned error: %v", err)
+	}
+
+	if strings.Contains(stdout.String(), "\033[") {
+		t.Errorf("stdout should not contain ANSI escape codes: %q", stdout.String())
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("Expected nothing on stderr, got %q", stderr.String())
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"ls -la", `'ls -la'`},
+		{"echo 'hello world'", `'echo '\''hello world'\'''`},
+		{`grep -r "$HOME" . | wc -l`, `'grep -r "$HOME" . | wc -l'`},
+		{"echo `date` && rm *.tmp; echo \\n", "'echo `date` && rm *.tmp; echo \\n'"},
+		{"", "''"},
+	}
+
+	for _, tt := range tests {
+		if got := utils.ShellQuote(tt.input); got != tt.want {
+			t.Errorf("ShellQuote(%q) = %s; want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestWriteShellAssignmentEvaluates(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+
+	for _, command := range []string{
+		"find . -name '*.log' -mtime +7",
+		`echo "it's $USER's turn" | sed 's/a/b/g'`,
+		"printf '%s\\n' `ls` && echo done; echo $((1+2)) > /dev/null",
+	} {
+		var stdout, stderr bytes.Buffer
+		if err := utils.WriteShellAssignment(&stdout, &stderr, command, []string{"be careful"}); err != nil {
+			t.Fatalf("WriteShellAssignment returned error: %v", err)
+		}
+		if !strings.Contains(stderr.String(), "be careful") {
+			t.Errorf("Expected warning on stderr, got %q", stderr.String())
+		}
+
+		// Evaluating the assignment must reproduce the command exactly
+		script := stdout.String() + `printf '%s' "$FORGOR_COMMAND"`
+		out, err := exec.Command(sh, "-c", script).Output()
+		if err != nil {
+			t.Fatalf("failed to eval %q: %v", stdout.String(), err)
+		}
+		if string(out) != command {
+			t.Errorf("$FORGOR_COMMAND = %q; want %q", out, command)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"forgor/internal/config"
+	"forgor/internal/llm"
+
+	"gopkg.in/yaml.v3"
+)
+
+// profileTestConfig returns a minimal valid config with a single profile
+func profileTestConfig() *config.Config {
+	return &config.Config{
+		DefaultProfile: "openai",
+		Profiles: map[string]config.Profile{
+			"openai": {Provider: "openai", APIKey: "${FORGOR_TEST_OPENAI_KEY}", Model: "gpt-4o", MaxTokens: 150},
+		},
+	}
+}
+
+// readSavedConfig reads the config file written by config.SaveConfig
+func readSavedConfig(t *testing.T, home string) config.Config {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(home, ".config", "forgor", "config.yaml"))
+	if err != nil {
+		t.Fatalf("failed to read saved config: %v", err)
+	}
+
+	var saved config.Config
+	if err := yaml.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("failed to parse saved config: %v", err)
+	}
+	return saved
+}
+
+func TestAddProfile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("FORGOR_TEST_OPENAI_KEY", "sk-openai")
+	t.Setenv("FORGOR_TEST_ANTHROPIC_KEY", "sk-ant")
+	if err := os.MkdirAll(filepath.Join(home, ".config", "forgor"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := profileTestConfig()
+	profile := config.Profile{
+		Provider:    "anthropic",
+		APIKey:      "${FORGOR_TEST_ANTHROPIC_KEY}",
+		Model:       llm.GetDefaultModels()["anthropic"],
+		MaxTokens:   300,
+		Temperature: 0.2,
+	}
+	if err := config.AddProfile(cfg, "work", profile); err != nil {
+		t.Fatalf("AddProfile returned error: %v", err)
+	}
+
+	saved := readSavedConfig(t, home)
+	if got := saved.Profiles["work"]; got != profile {
+		t.Errorf("saved profile = %+v; want %+v", got, profile)
+	}
+	if saved.DefaultProfile != "openai" {
+		t.Errorf("DefaultProfile = %q; want it unchanged", saved.DefaultProfile)
+	}
+
+	// Adding the same name again is refused
+	if err := config.AddProfile(cfg, "work", profile); err == nil {
+		t.Error("AddProfile should refuse an existing profile name")
+	}
+}
+
+func TestAddProfileRejectsInvalidProfile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("FORGOR_TEST_OPENAI_KEY", "sk-openai")
+
+	tests := []struct {
+		name    string
+		profile config.Profile
+	}{
+		{"unsupported provider", config.Profile{Provider: "nope", APIKey: "sk-x", Model: "m"}},
+		{"missing model", config.Profile{Provider: "openai", APIKey: "sk-x"}},
+		{"missing api key", config.Profile{Provider: "openai", Model: "gpt-4o"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := profileTestConfig()
+			if err := config.AddProfile(cfg, "broken", tt.profile); err == nil {
+				t.Fatal("AddProfile should reject the profile")
+			}
+			if _, exists := cfg.Profiles["broken"]; exists {
+				t.Error("rejected profile should not be left in the config")
+			}
+			if _, err := os.Stat(filepath.Join(home, ".config", "forgor", "config.yaml")); !os.IsNotExist(err) {
+				t.Error("rejected profile should not be saved")
+			}
+		})
+	}
+}
+
+func TestAddProfileWithUnsetAPIKeyEnv(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	if err := os.MkdirAll(filepath.Join(home, ".config", "forgor"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// The key's environment variable may be exported after the profile is added
+	cfg := profileTestConfig()
+	profile := config.Profile{Provider: "anthropic", APIKey: "${FORGOR_TEST_UNSET_KEY}", Model: "claude-3-haiku-20240307"}
+	if err := config.AddProfile(cfg, "later", profile); err != nil {
+		t.Fatalf("AddProfile returned error: %v", err)
+	}
+	if got := readSavedConfig(t, home).Profiles["later"]; got != profile {
+		t.Errorf("saved profile = %+v; want %+v", got, profile)
+	}
+
+	// Using the profile is what needs the key
+	if _, err := llm.NewFactory(cfg).GetProvider("later"); err == nil || !strings.Contains(err.Error(), "FORGOR_TEST_UNSET_KEY") {
+		t.Errorf("Expected GetProvider to report the unset variable, got %v", err)
+	}
+}
+
+func TestDefaultAPIKeyEnv(t *testing.T) {
+	for provider, want := range map[string]string{
+		"openai":    "OPENAI_API_KEY",
+		"anthropic": "ANTHROPIC_API_KEY",
+		"google":    "GOOGLE_AI_API_KEY",
+		"local":     "",
+	} {
+		if got := llm.DefaultAPIKeyEnv(provider); got != want {
+			t.Errorf("DefaultAPIKeyEnv(%q) = %q; want %q", provider, got, want)
+		}
+	}
+}
+
+// twoProfileConfig returns a valid config with openai as default and a
+// second anthropic profile, with its config directory created under home
+func twoProfileConfig(t *testing.T, home string) *config.Config {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Join(home, ".config", "forgor"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := profileTestConfig()
+	cfg.Profiles["anthropic"] = config.Profile{Provider: "anthropic", APIKey: "${ANTHROPIC_API_KEY}", Model: "claude-3-haiku-20240307"}
+	return cfg
+}
+
+func TestRemoveProfile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	cfg := twoProfileConfig(t, home)
+
+	if err := config.RemoveProfile(cfg, "anthropic", ""); err != nil {
+		t.Fatalf("RemoveProfile returned error: %v", err)
+	}
+	if _, exists := cfg.Profiles["anthropic"]; exists {
+		t.Error("removed profile should be gone from the config")
+	}
+
+	saved := readSavedConfig(t, home)
+	if _, exists := saved.Profiles["anthropic"]; exists {
+		t.Error("removed profile should be gone from the saved config")
+	}
+	if saved.DefaultProfile != "openai" || len(saved.Profiles) != 1 {
+		t.Errorf("saved config = %+v; want only the openai default profile", saved)
+	}
+
+	if err := config.RemoveProfile(cfg, "missing", ""); err == nil {
+		t.Error("RemoveProfile should fail for an unknown profile")
+	}
+}
+
+func TestRemoveDefaultProfile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	cfg := twoProfileConfig(t, home)
+
+	// Without a new default the default profile is kept
+	if err := config.RemoveProfile(cfg, "openai", ""); err == nil {
+		t.Fatal("RemoveProfile should refuse to remove the default profile")
+	}
+	for _, newDefault := range []string{"openai", "missing"} {
+		if err := config.RemoveProfile(cfg, "openai", newDefault); err == nil {
+			t.Errorf("RemoveProfile should reject %q as the new default", newDefault)
+		}
+	}
+	if _, exists := cfg.Profiles["openai"]; !exists || cfg.DefaultProfile != "openai" {
+		t.Fatalf("refused removals should leave the config unchanged, got %+v", cfg)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".config", "forgor", "config.yaml")); !os.IsNotExist(err) {
+		t.Error("refused removals should not save the config")
+	}
+
+	// Naming a new default allows it
+	if err := config.RemoveProfile(cfg, "openai", "anthropic"); err != nil {
+		t.Fatalf("RemoveProfile returned error: %v", err)
+	}
+	saved := readSavedConfig(t, home)
+	if saved.DefaultProfile != "anthropic" {
+		t.Errorf("DefaultProfile = %q; want anthropic", saved.DefaultProfile)
+	}
+	if _, exists := saved.Profiles["openai"]; exists {
+		t.Error("removed default profile should be gone from the saved config")
+	}
+}
+
+func TestRemoveProfileUsedByDirectoryMapping(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	cfg := twoProfileConfig(t, home)
+	cfg.DirectoryProfiles = []config.DirectoryProfile{{Path: "~/work", Profile: "anthropic"}}
+
+	if err := config.RemoveProfile(cfg, "anthropic", ""); err == nil {
+		t.Fatal("RemoveProfile should refuse to leave a dangling directory mapping")
+	}
+	if _, exists := cfg.Profiles["anthropic"]; !exists {
+		t.Error("refused removal should leave the profile in place")
+	}
+}
+
+func TestProfileRequestOptions(t *testing.T) {
+	tests := []struct {
+		name            string
+		profile         config.Profile
+		wantMaxTokens   int
+		wantTemperature float64
+	}{
+		{
+			name:            "profile values",
+			profile:         config.Profile{Provider: "openai", Model: "gpt-4o", MaxTokens: 500, Temperature: 0.3},
+			wantMaxTokens:   500,
+			wantTemperature: 0.3,
+		},
+		{
+			name:          "unset max tokens uses the default",
+			profile:       config.Profile{Provider: "anthropic", Model: "claude-3-haiku-20240307"},
+			wantMaxTokens: config.DefaultMaxTokens,
+		},
+		{
+			name:            "clamped to the model limit",
+			profile:         config.Profile{Provider: "openai", Model: "gpt-4-turbo", MaxTokens: 20000, Temperature: 1},
+			wantMaxTokens:   4096,
+			wantTemperature: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := llm.ProfileRequestOptions(tt.profile)
+			if options.MaxTokens != tt.wantMaxTokens {
+				t.Errorf("MaxTokens = %d; want %d", options.MaxTokens, tt.wantMaxTokens)
+			}
+			if tt.wantTemperature == 0 {
+				if options.Temperature != nil {
+					t.Errorf("Temperature = %g; want it left to the provider", *options.Temperature)
+				}
+			} else if options.Temperature == nil || *options.Temperature != tt.wantTemperature {
+				t.Errorf("Temperature = %v; want %g", options.Temperature, tt.wantTemperature)
+			}
+		})
+	}
+}
+
+func TestProviderRequestUsesProfileSettings(t *testing.T) {
+	var body struct {
+		GenerationConfig struct {
+			Temperature     float64 `json:"temperature"`
+			MaxOutputTokens int     `json:"maxOutputTokens"`
+		} `json:"generationConfig"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "COMMAND: ls"}]}, "finishReason": "STOP"}]}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		DefaultProfile: "gemini",
+		Profiles: map[string]config.Profile{
+			"gemini": {Provider: "gemini", APIKey: "test-key", Model: "gemini-1.5-flash", MaxTokens: 500, Temperature: 0.3},
+		},
+	}
+	provider, err := llm.NewFactory(cfg).GetProvider("default")
+	if err != nil {
+		t.Fatalf("GetProvider failed: %v", err)
+	}
+	gemini, ok := provider.(*llm.GeminiProvider)
+	if !ok {
+		t.Fatalf("provider is %T; want *llm.GeminiProvider", provider)
+	}
+	gemini.SetBaseURL(server.URL)
+
+	if _, err := gemini.GenerateCommand(context.Background(), &llm.Request{Query: "list files"}); err != nil {
+		t.Fatalf("GenerateCommand failed: %v", err)
+	}
+	if body.GenerationConfig.MaxOutputTokens != 500 {
+		t.Errorf("maxOutputTokens = %d; want 500", body.GenerationConfig.MaxOutputTokens)
+	}
+	if body.GenerationConfig.Temperature != 0.3 {
+		t.Errorf("temperature = %g; want 0.3", body.GenerationConfig.Temperature)
+	}
+}
@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"forgor/internal/llm"
+	"forgor/internal/prompt"
+	"forgor/internal/utils"
+)
+
+func TestDetectProjectContext(t *testing.T) {
+	testCases := []struct {
+		name     string
+		markers  []string
+		types    []string
+		inGit    bool
+		expected string
+	}{
+		{"empty directory", nil, nil, false, ""},
+		{"go module", []string{"go.mod"}, []string{"Go"}, false, "Go project"},
+		{"node with docker", []string{"package.json", "Dockerfile"}, []string{"Node.js", "Docker"}, false, "Node.js, Docker project"},
+		{"rust in git", []string{"Cargo.toml", ".git"}, []string{"Rust"}, true, "Rust project in a git repository"},
+		{"bare git repository", []string{".git"}, nil, true, "git repository"},
+		{"python deduplicated", []string{"pyproject.toml", "requirements.txt"}, []string{"Python"}, false, "Python project"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, marker := range tc.markers {
+				if err := os.WriteFile(filepath.Join(dir, marker), nil, 0644); err != nil {
+					t.Fatalf("failed to create %s: %v", marker, err)
+				}
+			}
+
+			project := utils.DetectProjectContext(dir)
+			if !reflect.DeepEqual(project.Types, tc.types) {
+				t.Errorf("expected types %v, got %v", tc.types, project.Types)
+			}
+			if (project.GitRoot != "") != tc.inGit {
+				t.Errorf("expected in git repository = %v, got git root %q", tc.inGit, project.GitRoot)
+			}
+			if summary := project.Summary(); summary != tc.expected {
+				t.Errorf("expected summary %q, got %q", tc.expected, summary)
+			}
+		})
+	}
+}
+
+func TestDetectProjectContextFindsEnclosingGitRoot(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatalf("failed to create .git: %v", err)
+	}
+	subdir := filepath.Join(root, "cmd", "app")
+	if err := os.MkdirAll(subdir, 0755); err != nil {
+		t.Fatalf("failed to create subdirectory: %v", err)
+	}
+
+	project := utils.DetectProjectContext(subdir)
+	if project.GitRoot != root {
+		t.Errorf("expected git root %s, got %q", root, project.GitRoot)
+	}
+}
+
+func TestDetectProjectContextIsCached(t *testing.T) {
+	dir := t.TempDir()
+	first := utils.DetectProjectContext(dir)
+
+	// Markers added later aren't seen until the next run
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), nil, 0644); err != nil {
+		t.Fatalf("failed to create go.mod: %v", err)
+	}
+
+	second := utils.DetectProjectContext(dir)
+	if first != second {
+		t.Error("expected the cached project context to be reused")
+	}
+	if len(second.Types) != 0 {
+		t.Errorf("expected cached result without types, got %v", second.Types)
+	}
+}
+
+func TestSystemPromptIncludesProjectType(t *testing.T) {
+	result := prompt.GetSystemPrompt(prompt.Context{OS: "linux", ProjectType: "Go project in a git repository"})
+	if !strings.Contains(result, "- Project: Go project in a git repository") {
+		t.Errorf("expected project line in system prompt, got:\n%s", result)
+	}
+
+	if strings.Contains(prompt.GetSystemPrompt(prompt.Context{OS: "linux"}), "- Project:") {
+		t.Error("expected no project line without a detected project type")
+	}
+}
+
+// initGitRepo creates a git repository on branch main in a temp directory
+func initGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"checkout", "--quiet", "-b", "main"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	return dir
+}
+
+func TestGetGitStatus(t *testing.T) {
+	dir := initGitRepo(t)
+
+	status, err := utils.GetGitStatus(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.Branch != "main" || status.Changes != 0 {
+		t.Errorf("expected clean main branch, got %+v", status)
+	}
+	if summary := status.Summary(); summary != "branch main, clean" {
+		t.Errorf("unexpected summary %q", summary)
+	}
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	status, err = utils.GetGitStatus(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary := status.Summary(); summary != "branch main, 2 uncommitted changes" {
+		t.Errorf("unexpected summary %q", summary)
+	}
+}
+
+func TestGetGitStatusOutsideRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	// Keep git from finding a repository above the temp directory
+	dir := t.TempDir()
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(dir))
+
+	if _, err := utils.GetGitStatus(dir); err == nil {
+		t.Error("expected an error outside a git repository")
+	}
+
+	context := llm.EnhanceContextWithGitStatus(llm.Context{WorkingDirectory: dir})
+	if context.GitStatus != "" {
+		t.Errorf("expected no git status, got %q", context.GitStatus)
+	}
+}
+
+func TestGitStatusSummary(t *testing.T) {
+	testCases := []struct {
+		status   utils.GitStatus
+		expected string
+	}{
+		{utils.GitStatus{Branch: "main"}, "branch main, clean"},
+		{utils.GitStatus{Branch: "feature/x", Changes: 1}, "branch feature/x, 1 uncommitted change"},
+		{utils.GitStatus{Changes: 3}, "detached HEAD, 3 uncommitted changes"},
+		{utils.GitStatus{Branch: "main", Changes: 1500, Truncated: true}, "branch main, 1500+ uncommitted changes"},
+	}
+
+	for _, tc := range testCases {
+		if summary := tc.status.Summary(); summary != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, summary)
+		}
+	}
+}
+
+func TestSystemPromptIncludesGitStatus(t *testing.T) {
+	result := prompt.GetSystemPrompt(prompt.Context{OS: "linux", GitStatus: "branch main, clean"})
+	if !strings.Contains(result, "- Git: branch main, clean") {
+		t.Errorf("expected git line in system prompt, got:\n%s", result)
+	}
+}
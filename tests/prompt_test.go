@@ -0,0 +1,571 @@
+package tests
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"forgor/internal/config"
+	"forgor/internal/history"
+	"forgor/internal/llm"
+	"forgor/internal/prompt"
+	"forgor/internal/utils"
+)
+
+func TestProviderPromptsRequestDangerFields(t *testing.T) {
+	request := &prompt.Request{
+		Query:   "delete all log files",
+		Options: prompt.RequestOptions{IncludeExplanation: true},
+	}
+
+	builders := map[string]func(*prompt.Request) string{
+		"openai":    prompt.BuildOpenAICommandPrompt,
+		"anthropic": prompt.BuildAnthropicCommandPrompt,
+		"gemini":    prompt.BuildGeminiCommandPrompt,
+	}
+
+	for name, build := range builders {
+		result := build(request)
+		for _, field := range []string{"COMMAND:", "EXPLANATION:", "DANGER_LEVEL:", "DANGER_REASON:", "ALTERNATIVE:"} {
+			if !strings.Contains(result, field) {
+				t.Errorf("%s prompt should request %s", name, field)
+			}
+		}
+	}
+}
+
+func TestParseStructuredResponse(t *testing.T) {
+	tests := []struct {
+		name               string
+		content            string
+		includeExplanation bool
+		wantCommand        string
+		wantExplanation    string
+		wantLevel          llm.DangerLevel
+		wantReason         string
+	}{
+		{
+			name: "anthropic structured response",
+			content: `COMMAND: find . -name "*.log" -delete
+EXPLANATION: Deletes all .log files below the current directory
+DANGER_LEVEL: high
+DANGER_REASON: Permanently deletes files`,
+			includeExplanation: true,
+			wantCommand:        `find . -name "*.log" -delete`,
+			wantExplanation:    "Deletes all .log files below the current directory",
+			wantLevel:          llm.DangerLevelHigh,
+			wantReason:         "Permanently deletes files",
+		},
+		{
+			name: "gemini structured response with lowercase-insensitive level",
+			content: `COMMAND: df -h
+DANGER_LEVEL: Safe
+DANGER_REASON: Read-only disk usage query
+`,
+			wantCommand: "df -h",
+			wantLevel:   llm.DangerLevelSafe,
+			wantReason:  "Read-only disk usage query",
+		},
+		{
+			name: "structured command containing shell OR",
+			content: `COMMAND: make test || make clean || echo "build failed"
+EXPLANATION: Runs the tests, cleaning up if they fail
+DANGER_LEVEL: low
+DANGER_REASON: Removes build output`,
+			includeExplanation: true,
+			wantCommand:        `make test || make clean || echo "build failed"`,
+			wantExplanation:    "Runs the tests, cleaning up if they fail",
+			wantLevel:          llm.DangerLevelLow,
+			wantReason:         "Removes build output",
+		},
+		{
+			name: "multi-step command in a fence",
+			content: "COMMAND: ```bash\npython3 -m venv .venv\nsource .venv/bin/activate\npip install -r requirements.txt\n```\n" +
+				`EXPLANATION: Creates a virtualenv and installs the dependencies into it
+DANGER_LEVEL: low
+DANGER_REASON: Installs packages`,
+			includeExplanation: false,
+			wantCommand:        "python3 -m venv .venv\nsource .venv/bin/activate\npip install -r requirements.txt",
+			wantLevel:          llm.DangerLevelLow,
+			wantReason:         "Installs packages",
+		},
+		{
+			name:        "multi-step command in a fence on the following lines",
+			content:     "COMMAND:\n```bash\nmkdir demo\ncd demo\n```\nDANGER_LEVEL: safe\nDANGER_REASON: Creates a directory",
+			wantCommand: "mkdir demo\ncd demo",
+			wantLevel:   llm.DangerLevelSafe,
+			wantReason:  "Creates a directory",
+		},
+		{
+			name:        "command continued with && and a pipe",
+			content:     "COMMAND: mkdir demo &&\n  cd demo\nDANGER_LEVEL: safe\nDANGER_REASON: Creates a directory",
+			wantCommand: "mkdir demo &&\n  cd demo",
+			wantLevel:   llm.DangerLevelSafe,
+			wantReason:  "Creates a directory",
+		},
+		{
+			name:        "prose after the command",
+			content:     "COMMAND: ls\n\nNote: this lists files\n**EXPLANATION:** Lists the directory\nDANGER_LEVEL: safe\nDANGER_REASON: Read-only",
+			wantCommand: "ls",
+			wantLevel:   llm.DangerLevelSafe,
+			wantReason:  "Read-only",
+		},
+		{
+			name:        "prose right after the command",
+			content:     "COMMAND: ls -la\nNote: this lists files",
+			wantCommand: "ls -la",
+			wantLevel:   llm.DangerLevelSafe,
+			wantReason:  "No specific assessment provided",
+		},
+		{
+			name:               "unstructured command containing shell OR",
+			content:            "test -f .env || cp .env.example .env",
+			includeExplanation: true,
+			wantCommand:        "test -f .env || cp .env.example .env",
+			wantLevel:          llm.DangerLevelSafe,
+			wantReason:         "No specific assessment provided",
+		},
+		{
+			name:        "bare command",
+			content:     "ps aux",
+			wantCommand: "ps aux",
+			wantLevel:   llm.DangerLevelSafe,
+			wantReason:  "No specific assessment provided",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed := prompt.ParseStructuredResponse(tt.content, tt.includeExplanation)
+
+			if parsed.Command != tt.wantCommand {
+				t.Errorf("Command = %q; want %q", parsed.Command, tt.wantCommand)
+			}
+			if parsed.Explanation != tt.wantExplanation {
+				t.Errorf("Explanation = %q; want %q", parsed.Explanation, tt.wantExplanation)
+			}
+			if level := llm.ParseDangerLevel(parsed.DangerLevel); level != tt.wantLevel {
+				t.Errorf("DangerLevel = %v; want %v", level, tt.wantLevel)
+			}
+			if parsed.DangerReason != tt.wantReason {
+				t.Errorf("DangerReason = %q; want %q", parsed.DangerReason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestParseStructuredResponseAlternatives(t *testing.T) {
+	content := `COMMAND: du -sh *
+DANGER_LEVEL: safe
+DANGER_REASON: Read-only
+ALTERNATIVE: du -h --max-depth=1
+ALTERNATIVE: none
+ALTERNATIVE: du -sh *
+ALTERNATIVE: ` + "`ncdu`" + `
+ALTERNATIVE: ls -lS
+ALTERNATIVE: find . -maxdepth 1 -size +10M`
+
+	parsed := prompt.ParseStructuredResponse(content, false)
+
+	want := []string{"du -h --max-depth=1", "ncdu", "ls -lS"}
+	if !reflect.DeepEqual(parsed.Alternatives, want) {
+		t.Errorf("Alternatives = %q; want %q", parsed.Alternatives, want)
+	}
+	if parsed.Command != "du -sh *" {
+		t.Errorf("Command = %q; want %q", parsed.Command, "du -sh *")
+	}
+}
+
+func TestCleanCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"plain command", "ls -la", "ls -la"},
+		{"surrounding whitespace", "\n  ls -la  \n", "ls -la"},
+		{"bash fence", "```bash\nls -la\n```", "ls -la"},
+		{"fence without language", "```\ndf -h\n```", "df -h"},
+		{"fence with trailing prose", "```bash\nls -la\n``` more text", "ls -la"},
+		{"fence with other language tag", "```powershell\nGet-ChildItem\n```", "Get-ChildItem"},
+		{"indented fence", "  ```sh\n  ls -la\n  ```", "ls -la"},
+		{"inline fence", "```ls -la```", "ls -la"},
+		{"unterminated fence", "```zsh\ngit status", "git status"},
+		{
+			"prose around fence",
+			"Here is the command:\n```zsh\nfind . -name '*.go'\n```\nThis finds all Go files.",
+			"find . -name '*.go'",
+		},
+		{
+			"first of multiple fences",
+			"```bash\nls\n```\nor alternatively\n```bash\nls -la\n```",
+			"ls",
+		},
+		{"multi-line fenced script", "```bash\ncd /tmp\nls\n```", "cd /tmp\nls"},
+		{"wrapped in inline code", "`ls -la`", "ls -la"},
+		{"lead-in with inline code", "Run this: `git status`", "git status"},
+		{"lead-in line", "Here is the command:\nls -la", "ls -la"},
+		{"command substitution preserved", "echo `date`", "echo `date`"},
+		{"dollar prompt", "$ ls -la", "ls -la"},
+		{"root prompt", "# apt update", "apt update"},
+		{"dollar prompt in fence", "```bash\n$ git status\n```", "git status"},
+		{"dollar prompt on every line", "$ cd /tmp\n$ ls", "cd /tmp\nls"},
+		{"shebang preserved", "#!/bin/bash\necho hello", "#!/bin/bash\necho hello"},
+		{"single-line shebang preserved", "#!/usr/bin/env python3", "#!/usr/bin/env python3"},
+		{"script comment preserved", "# install deps\nnpm install", "# install deps\nnpm install"},
+		{"variable not stripped", "$HOME/bin/tool", "$HOME/bin/tool"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := prompt.CleanCommand(tt.input)
+			if result != tt.expected {
+				t.Errorf("CleanCommand(%q) = %q; want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSplitCommandSteps(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"single command", "ls -la", []string{"ls -la"}},
+		{"empty input", "  ", nil},
+		{"and and pipe continuations", "mkdir demo &&\n  cd demo\nps aux |\n  grep go", []string{"mkdir demo &&\n  cd demo", "ps aux |\n  grep go"}},
+		{
+			"venv setup",
+			"python3 -m venv .venv\nsource .venv/bin/activate\npip install -r requirements.txt",
+			[]string{"python3 -m venv .venv", "source .venv/bin/activate", "pip install -r requirements.txt"},
+		},
+		{
+			"blank lines and comments dropped",
+			"#!/bin/bash\n# create the directory\nmkdir -p build\n\ncd build",
+			[]string{"mkdir -p build", "cd build"},
+		},
+		{
+			"line continuation kept together",
+			"docker run \\\n  -p 8080:80 \\\n  nginx\ndocker ps",
+			[]string{"docker run \\\n  -p 8080:80 \\\n  nginx", "docker ps"},
+		},
+		{
+			"heredoc kept together",
+			"cat <<EOF > notes.txt\nhello\nworld\nEOF\nwc -l notes.txt",
+			[]string{"cat <<EOF > notes.txt\nhello\nworld\nEOF", "wc -l notes.txt"},
+		},
+		{
+			"here-string is a single line",
+			"grep foo <<< \"$text\"\necho done",
+			[]string{"grep foo <<< \"$text\"", "echo done"},
+		},
+		{
+			"loop returned as one script",
+			"for f in *.txt; do\n  echo \"$f\"\ndone",
+			[]string{"for f in *.txt; do\n  echo \"$f\"\ndone"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := prompt.SplitCommandSteps(tt.input)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("SplitCommandSteps(%q) = %q; want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSafetyLevelChangesPrompt(t *testing.T) {
+	expected := map[string]string{
+		prompt.SafetyLevelStrict:     "Never generate destructive",
+		prompt.SafetyLevelModerate:   "Prefer non-destructive commands",
+		prompt.SafetyLevelPermissive: "Destructive commands are allowed",
+	}
+
+	for level, want := range expected {
+		request := &prompt.Request{
+			Query:   "delete all log files",
+			Options: prompt.RequestOptions{SafetyLevel: level},
+		}
+
+		result := prompt.BuildOpenAICommandPrompt(request)
+		if !strings.Contains(result, want) {
+			t.Errorf("%s prompt should contain %q", level, want)
+		}
+		for other, otherText := range expected {
+			if other != level && strings.Contains(result, otherText) {
+				t.Errorf("%s prompt should not contain %s instructions", level, other)
+			}
+		}
+	}
+}
+
+func TestParseSafetyLevel(t *testing.T) {
+	tests := map[string]string{
+		"":           prompt.SafetyLevelModerate,
+		"STRICT":     prompt.SafetyLevelStrict,
+		"permissive": prompt.SafetyLevelPermissive,
+	}
+	for input, want := range tests {
+		got, err := prompt.ParseSafetyLevel(input)
+		if err != nil || got != want {
+			t.Errorf("ParseSafetyLevel(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+
+	if _, err := prompt.ParseSafetyLevel("reckless"); err == nil {
+		t.Error("Expected an error for an unknown safety level")
+	}
+}
+
+func TestCustomSystemTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "system.tmpl")
+	text := "You write {{.Shell}} commands for {{.OS}}/{{.Architecture}}. Package managers: {{join .PackageManagers \", \"}}.\n" +
+		"Always prefer our internal tools.\n{{.BuiltinPrompt}}"
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+
+	tmpl, err := prompt.LoadSystemTemplate(path)
+	if err != nil {
+		t.Fatalf("LoadSystemTemplate returned error: %v", err)
+	}
+	prompt.SetSystemTemplate(tmpl)
+	defer prompt.SetSystemTemplate(nil)
+
+	context := prompt.Context{OS: "linux", Shell: "zsh", Architecture: "arm64", PackageManagers: []string{"apt", "npm"}}
+	result := prompt.GetSystemPrompt(context)
+
+	if !strings.HasPrefix(result, "You write zsh commands for linux/arm64. Package managers: apt, npm.\nAlways prefer our internal tools.") {
+		t.Errorf("Expected the custom template to be rendered, got %q", result[:min(len(result), 120)])
+	}
+	if !strings.Contains(result, "You are a helpful shell command assistant") {
+		t.Error("Expected {{.BuiltinPrompt}} to include the built-in prompt")
+	}
+
+	prompt.SetSystemTemplate(nil)
+	if !strings.HasPrefix(prompt.GetSystemPrompt(context), "You are a helpful shell command assistant") {
+		t.Error("Expected the built-in prompt after clearing the template")
+	}
+}
+
+func TestCustomSystemTemplateErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.tmpl")
+	if err := os.WriteFile(path, []byte("Shell: {{.Shell"), 0644); err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+
+	if _, err := prompt.LoadSystemTemplate(path); err == nil {
+		t.Error("Expected a parse error for a malformed template")
+	}
+
+	// The template is checked when it is loaded for a query, so a broken
+	// template doesn't make the rest of the config unusable
+	cfg := &config.Config{
+		DefaultProfile: "openai",
+		Profiles: map[string]config.Profile{
+			"openai": {Provider: "openai", APIKey: "sk-test", Model: "gpt-4"},
+		},
+		Prompt: config.PromptConfig{TemplateFile: path},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected config validation to leave the template to the query, got %v", err)
+	}
+
+	// A template that fails to render falls back to the built-in prompt
+	var logs bytes.Buffer
+	previous := utils.GetLogger()
+	utils.SetLogger(utils.NewTextLogger(&logs, utils.LogLevelInfo))
+	defer utils.SetLogger(previous)
+
+	tmpl, err := prompt.ParseSystemTemplate("unknown-field", "Hello {{.NoSuchField}}")
+	if err != nil {
+		t.Fatalf("ParseSystemTemplate returned error: %v", err)
+	}
+	prompt.SetSystemTemplate(tmpl)
+	defer prompt.SetSystemTemplate(nil)
+
+	if !strings.HasPrefix(prompt.GetSystemPrompt(prompt.Context{OS: "linux"}), "You are a helpful shell command assistant") {
+		t.Error("Expected a render failure to fall back to the built-in prompt")
+	}
+	if !strings.Contains(logs.String(), "NoSuchField") {
+		t.Errorf("Expected a warning naming the template error, got %q", logs.String())
+	}
+}
+
+func TestSystemPromptPrefixAndSuffix(t *testing.T) {
+	result := prompt.GetSystemPrompt(prompt.Context{
+		OS:                 "linux",
+		SystemPromptPrefix: "Prefer fd over find.",
+		SystemPromptSuffix: "Never use sudo.",
+	})
+
+	if !strings.HasPrefix(result, "Prefer fd over find.\n\nYou are a helpful shell command assistant") {
+		t.Errorf("Expected the prefix before the built-in prompt, got %q", result[:min(len(result), 80)])
+	}
+	if !strings.HasSuffix(result, "\n\nNever use sudo.") {
+		t.Error("Expected the suffix after the built-in prompt")
+	}
+}
+
+func TestNushellGuidanceInSystemPrompt(t *testing.T) {
+	nu := prompt.GetSystemPrompt(prompt.Context{OS: "linux", Shell: "nu"})
+	if !strings.Contains(nu, "Nushell Syntax") {
+		t.Error("Expected nushell guidance for a nu context")
+	}
+	if !strings.Contains(nu, "$env.NAME") {
+		t.Error("Expected nushell guidance to describe $env variables")
+	}
+
+	bash := prompt.GetSystemPrompt(prompt.Context{OS: "linux", Shell: "bash"})
+	if strings.Contains(bash, "Nushell Syntax") {
+		t.Error("Expected no nushell guidance for a bash context")
+	}
+}
+
+func historyCommands(entries []history.HistoryEntry) []string {
+	commands := make([]string, len(entries))
+	for i, entry := range entries {
+		commands[i] = entry.Command
+	}
+	return commands
+}
+
+func TestLimitHistoryKeepsMostRecentEntries(t *testing.T) {
+	entries := []history.HistoryEntry{
+		{Command: "cd ~/projects", ExitCode: 0},
+		{Command: "git status", ExitCode: 0},
+		{Command: "make test", ExitCode: 2},
+		{Command: "go test ./...", ExitCode: 1},
+	}
+
+	// Room for the last two commands (9 + 13 characters) but not the third
+	limited := prompt.LimitHistory(entries, 25)
+	expected := []string{"make test", "go test ./..."}
+	if got := historyCommands(limited); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if limited[1].ExitCode != 1 {
+		t.Errorf("expected exit codes to be kept, got %d", limited[1].ExitCode)
+	}
+
+	if got := prompt.LimitHistory(entries, 0); len(got) != len(entries) {
+		t.Errorf("expected no total limit with max 0, got %d entries", len(got))
+	}
+}
+
+func TestLimitHistoryTruncatesLongCommands(t *testing.T) {
+	long := "echo " + strings.Repeat("x", 1000)
+	entries := []history.HistoryEntry{
+		{Command: "ls", ExitCode: 0},
+		{Command: long, ExitCode: 0},
+	}
+
+	limited := prompt.LimitHistory(entries, 0)
+	command := limited[1].Command
+	if len([]rune(command)) != prompt.MaxHistoryCommandChars || !strings.HasSuffix(command, "…") {
+		t.Errorf("expected a %d character command ending in an ellipsis, got %d characters", prompt.MaxHistoryCommandChars, len([]rune(command)))
+	}
+	if entries[1].Command != long {
+		t.Error("expected the original entries to be left unchanged")
+	}
+
+	// A budget smaller than the most recent command still keeps a shortened copy
+	limited = prompt.LimitHistory(entries, 50)
+	if len(limited) != 1 || len([]rune(limited[0].Command)) != 50 || !strings.HasPrefix(limited[0].Command, "echo x") {
+		t.Errorf("expected only a shortened most recent command, got %v", historyCommands(limited))
+	}
+}
+
+func TestCommandPromptTruncatesLongHistory(t *testing.T) {
+	request := &prompt.Request{
+		Query: "rerun the last command",
+		Context: prompt.RequestContext{
+			History: []history.HistoryEntry{{Command: strings.Repeat("a", 5000), ExitCode: 0}},
+		},
+	}
+
+	result := prompt.BuildCommandPrompt(request)
+	if strings.Contains(result, strings.Repeat("a", prompt.MaxHistoryCommandChars)) {
+		t.Error("expected the long history command to be truncated in the prompt")
+	}
+	if !strings.Contains(result, "…") {
+		t.Error("expected an ellipsis marking the truncated command")
+	}
+}
+
+func TestExplainSystemPromptIncludesPlatform(t *testing.T) {
+	context := prompt.Context{OS: "macOS", Shell: "zsh", Architecture: "arm64"}
+
+	result := prompt.GetExplainSystemPrompt(context)
+	for _, expected := range []string{"explains shell commands", "macOS (arm64 architecture)", "using zsh"} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("expected %q in explain prompt, got:\n%s", expected, result)
+		}
+	}
+
+	prompt.SetExplainInstruction("Explain commands for a beginner.")
+	defer prompt.SetExplainInstruction("")
+
+	result = prompt.GetExplainSystemPrompt(context)
+	if !strings.HasPrefix(result, "Explain commands for a beginner.") || strings.Contains(result, "explains shell commands") {
+		t.Errorf("expected the configured instruction to replace the default, got:\n%s", result)
+	}
+	if !strings.Contains(result, "macOS") || !strings.Contains(result, "zsh") {
+		t.Errorf("expected OS and shell with a custom instruction, got:\n%s", result)
+	}
+}
+
+func TestBuildExplainPrompt(t *testing.T) {
+	result := prompt.BuildExplainPrompt("tar -xzf archive.tar.gz")
+	if !strings.Contains(result, "tar -xzf archive.tar.gz") {
+		t.Errorf("expected the command in the explain prompt, got:\n%s", result)
+	}
+}
+
+func TestHistoryCommandLimitTruncatesMultibyte(t *testing.T) {
+	prompt.SetHistoryCommandLimit(40)
+	defer prompt.SetHistoryCommandLimit(prompt.MaxHistoryCommandChars)
+
+	long := `curl -d '{"text": "` + strings.Repeat("日本語のテキスト", 50) + `"}' https://example.com`
+	request := &prompt.Request{
+		Query: "retry that request",
+		Context: prompt.RequestContext{
+			History: []history.HistoryEntry{{Command: long, ExitCode: 7}},
+		},
+	}
+
+	result := prompt.BuildCommandPrompt(request)
+	if !utf8.ValidString(result) {
+		t.Fatal("expected truncation on character boundaries")
+	}
+
+	var line string
+	for _, l := range strings.Split(result, "\n") {
+		if strings.HasPrefix(l, "- `curl") {
+			line = l
+		}
+	}
+	command, status, found := strings.Cut(strings.TrimPrefix(line, "- `"), "`")
+	if !found {
+		t.Fatalf("expected the history command in the prompt, got %q", result)
+	}
+	if len([]rune(command)) != 40 || !strings.HasSuffix(command, "…") {
+		t.Errorf("expected a 40 character command ending in an ellipsis, got %q", command)
+	}
+	if status != " (FAILED with exit code 7)" {
+		t.Errorf("expected the exit code annotation to be kept, got %q", status)
+	}
+
+	// A limit of 0 sends the command in full
+	prompt.SetHistoryCommandLimit(0)
+	if !strings.Contains(prompt.BuildCommandPrompt(request), long) {
+		t.Error("expected the full command with no per-command limit")
+	}
+}
@@ -0,0 +1,544 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"forgor/internal/config"
+	"forgor/internal/llm"
+)
+
+// capturedRequest is a request received by a providerTestServer
+type capturedRequest struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   map[string]interface{}
+}
+
+// providerTestServer stands in for a provider's API. It records every
+// request and answers with a canned status, headers and body.
+type providerTestServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []capturedRequest
+}
+
+// newProviderTestServer starts a server answering every request with status
+// and body, plus any headers given as name/value pairs
+func newProviderTestServer(t *testing.T, status int, body string, headers ...string) *providerTestServer {
+	t.Helper()
+
+	server := &providerTestServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read request body: %v", err)
+		}
+		request := capturedRequest{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.Query(),
+			Header: r.Header.Clone(),
+		}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &request.Body); err != nil {
+				t.Errorf("request body is not JSON: %v\n%s", err, data)
+			}
+		}
+
+		server.mu.Lock()
+		server.requests = append(server.requests, request)
+		server.mu.Unlock()
+
+		for i := 0; i+1 < len(headers); i += 2 {
+			w.Header().Set(headers[i], headers[i+1])
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// lastRequest returns the most recent request the server received
+func (s *providerTestServer) lastRequest(t *testing.T) capturedRequest {
+	t.Helper()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requests) == 0 {
+		t.Fatal("the provider made no request")
+	}
+	return s.requests[len(s.requests)-1]
+}
+
+// newProviderForServer creates a provider through the factory, as forgor
+// does, with the profile's endpoint pointing at server
+func newProviderForServer(t *testing.T, server *providerTestServer, profile config.Profile) llm.Provider {
+	t.Helper()
+
+	profile.Endpoint = server.URL
+	cfg := &config.Config{
+		DefaultProfile: "test",
+		Profiles:       map[string]config.Profile{"test": profile},
+	}
+	provider, err := llm.NewFactory(cfg).GetProvider("test")
+	if err != nil {
+		t.Fatalf("GetProvider failed: %v", err)
+	}
+	return provider
+}
+
+// jsonPath walks a decoded JSON value by object keys and array indexes
+func jsonPath(value interface{}, path ...interface{}) interface{} {
+	for _, step := range path {
+		switch key := step.(type) {
+		case string:
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			value = object[key]
+		case int:
+			array, ok := value.([]interface{})
+			if !ok || key >= len(array) {
+				return nil
+			}
+			value = array[key]
+		}
+	}
+	return value
+}
+
+// providerHTTPCase describes one provider's API for the shared tests
+type providerHTTPCase struct {
+	name    string
+	profile config.Profile
+	// reply wraps structured response text in the provider's response
+	// format, with 20 prompt and 10 completion tokens
+	reply func(content string) string
+	// checkRequest asserts the provider-specific shape of a command request
+	checkRequest func(t *testing.T, request capturedRequest)
+	// errorBody is an error response in the provider's format
+	errorBody func(status int, errorType, message string) string
+	// errorTypes maps HTTP statuses to the provider's error type field
+	errorTypes map[int]string
+}
+
+var providerHTTPCases = []providerHTTPCase{
+	{
+		name: "openai",
+		profile: config.Profile{
+			Provider: "openai", APIKey: "test-key", Model: "gpt-4o-mini", MaxTokens: 222, Temperature: 0.3,
+		},
+		reply: func(content string) string {
+			return `{"model": "gpt-4o-mini", "choices": [{"index": 0, "message": {"role": "assistant", "content": ` +
+				jsonString(content) + `}, "finish_reason": "stop"}],
+				"usage": {"prompt_tokens": 20, "completion_tokens": 10, "total_tokens": 30}}`
+		},
+		checkRequest: func(t *testing.T, request capturedRequest) {
+			if request.Path != "/chat/completions" {
+				t.Errorf("path = %q; want /chat/completions", request.Path)
+			}
+			if got := request.Header.Get("Authorization"); got != "Bearer test-key" {
+				t.Errorf("Authorization = %q; want the API key as a bearer token", got)
+			}
+			assertJSONField(t, request.Body, "gpt-4o-mini", "model")
+			assertJSONField(t, request.Body, float64(222), "max_tokens")
+			assertJSONField(t, request.Body, 0.3, "temperature")
+			assertJSONField(t, request.Body, false, "stream")
+			assertJSONField(t, request.Body, "system", "messages", 0, "role")
+			assertJSONField(t, request.Body, "user", "messages", 1, "role")
+			assertPromptText(t, jsonPath(request.Body, "messages", 0, "content"), jsonPath(request.Body, "messages", 1, "content"))
+		},
+		errorBody: func(status int, errorType, message string) string {
+			return `{"error": {"type": "` + errorType + `", "message": "` + message + `"}}`
+		},
+		errorTypes: map[int]string{
+			http.StatusBadRequest:          "invalid_request_error",
+			http.StatusUnauthorized:        "authentication_error",
+			http.StatusTooManyRequests:     "rate_limit_error",
+			http.StatusInternalServerError: "server_error",
+		},
+	},
+	{
+		name: "anthropic",
+		profile: config.Profile{
+			Provider: "anthropic", APIKey: "test-key", Model: "claude-3-5-haiku-20241022", MaxTokens: 222, Temperature: 0.3,
+		},
+		reply: func(content string) string {
+			return `{"model": "claude-3-5-haiku-20241022", "content": [{"type": "text", "text": ` +
+				jsonString(content) + `}], "stop_reason": "end_turn",
+				"usage": {"input_tokens": 20, "output_tokens": 10}}`
+		},
+		checkRequest: func(t *testing.T, request capturedRequest) {
+			if request.Path != "/messages" {
+				t.Errorf("path = %q; want /messages", request.Path)
+			}
+			if got := request.Header.Get("x-api-key"); got != "test-key" {
+				t.Errorf("x-api-key = %q; want the API key", got)
+			}
+			if request.Header.Get("anthropic-version") == "" {
+				t.Error("expected an anthropic-version header")
+			}
+			assertJSONField(t, request.Body, "claude-3-5-haiku-20241022", "model")
+			assertJSONField(t, request.Body, float64(222), "max_tokens")
+			assertJSONField(t, request.Body, 0.3, "temperature")
+			assertJSONField(t, request.Body, "user", "messages", 0, "role")
+			if messages, _ := request.Body["messages"].([]interface{}); len(messages) != 1 {
+				t.Errorf("expected only the user message, got %d messages", len(messages))
+			}
+			assertPromptText(t, request.Body["system"], jsonPath(request.Body, "messages", 0, "content"))
+		},
+		errorBody: func(status int, errorType, message string) string {
+			return `{"type": "error", "error": {"type": "` + errorType + `", "message": "` + message + `"}}`
+		},
+		errorTypes: map[int]string{
+			http.StatusBadRequest:          "invalid_request_error",
+			http.StatusUnauthorized:        "authentication_error",
+			http.StatusTooManyRequests:     "rate_limit_error",
+			http.StatusInternalServerError: "overloaded_error",
+		},
+	},
+	{
+		name: "gemini",
+		profile: config.Profile{
+			Provider: "gemini", APIKey: "test-key", Model: "gemini-1.5-flash", MaxTokens: 222, Temperature: 0.3,
+		},
+		reply: func(content string) string {
+			return `{"candidates": [{"content": {"role": "model", "parts": [{"text": ` + jsonString(content) + `}]},
+				"finishReason": "STOP", "index": 0}],
+				"usageMetadata": {"promptTokenCount": 20, "candidatesTokenCount": 10, "totalTokenCount": 30}}`
+		},
+		checkRequest: func(t *testing.T, request capturedRequest) {
+			if request.Path != "/models/gemini-1.5-flash:generateContent" {
+				t.Errorf("path = %q; want the model's generateContent method", request.Path)
+			}
+			if got := request.Query.Get("key"); got != "test-key" {
+				t.Errorf("key = %q; want the API key", got)
+			}
+			assertJSONField(t, request.Body, float64(222), "generationConfig", "maxOutputTokens")
+			assertJSONField(t, request.Body, 0.3, "generationConfig", "temperature")
+			assertJSONField(t, request.Body, "user", "contents", 0, "role")
+			if request.Body["safetySettings"] == nil {
+				t.Error("expected safety settings")
+			}
+			assertPromptText(t, jsonPath(request.Body, "systemInstruction", "parts", 0, "text"), jsonPath(request.Body, "contents", 0, "parts", 0, "text"))
+		},
+		errorBody: func(status int, errorType, message string) string {
+			return `{"error": {"code": ` + jsonNumber(status) + `, "status": "` + errorType + `", "message": "` + message + `"}}`
+		},
+		errorTypes: map[int]string{
+			http.StatusBadRequest:          "INVALID_ARGUMENT",
+			http.StatusUnauthorized:        "UNAUTHENTICATED",
+			http.StatusTooManyRequests:     "RESOURCE_EXHAUSTED",
+			http.StatusInternalServerError: "INTERNAL",
+		},
+	},
+}
+
+func jsonString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+func jsonNumber(n int) string {
+	data, _ := json.Marshal(n)
+	return string(data)
+}
+
+// assertJSONField checks the value at path in a decoded request body
+func assertJSONField(t *testing.T, body map[string]interface{}, want interface{}, path ...interface{}) {
+	t.Helper()
+	if got := jsonPath(body, path...); got != want {
+		t.Errorf("%v = %#v; want %#v", path, got, want)
+	}
+}
+
+// assertPromptText checks that the system prompt and user message were sent
+func assertPromptText(t *testing.T, system, user interface{}) {
+	t.Helper()
+	if text, _ := system.(string); !strings.Contains(text, "shell") {
+		t.Errorf("expected the system prompt, got %q", text)
+	}
+	text, _ := user.(string)
+	if !strings.Contains(text, "show folder sizes") || !strings.Contains(text, "COMMAND:") {
+		t.Errorf("expected the query with the response format, got %q", text)
+	}
+}
+
+func TestProviderRequestConstruction(t *testing.T) {
+	for _, tc := range providerHTTPCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newProviderTestServer(t, http.StatusOK, tc.reply("COMMAND: du -sh *"))
+			provider := newProviderForServer(t, server, tc.profile)
+
+			_, err := provider.GenerateCommand(context.Background(), &llm.Request{Query: "show folder sizes"})
+			if err != nil {
+				t.Fatalf("GenerateCommand returned error: %v", err)
+			}
+
+			request := server.lastRequest(t)
+			if request.Method != http.MethodPost {
+				t.Errorf("method = %s; want POST", request.Method)
+			}
+			tc.checkRequest(t, request)
+		})
+	}
+}
+
+func TestProviderRequestOptionsOverrideProfile(t *testing.T) {
+	for _, tc := range providerHTTPCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newProviderTestServer(t, http.StatusOK, tc.reply("COMMAND: du -sh *"))
+			provider := newProviderForServer(t, server, tc.profile)
+
+			requestTemperature := 0.7
+			_, err := provider.GenerateCommand(context.Background(), &llm.Request{
+				Query:   "show folder sizes",
+				Options: llm.RequestOptions{MaxTokens: 64, Temperature: &requestTemperature},
+			})
+			if err != nil {
+				t.Fatalf("GenerateCommand returned error: %v", err)
+			}
+
+			body := server.lastRequest(t).Body
+			maxTokens, temperature := []interface{}{"max_tokens"}, []interface{}{"temperature"}
+			if tc.name == "gemini" {
+				maxTokens = []interface{}{"generationConfig", "maxOutputTokens"}
+				temperature = []interface{}{"generationConfig", "temperature"}
+			}
+			assertJSONField(t, body, float64(64), maxTokens...)
+			assertJSONField(t, body, 0.7, temperature...)
+		})
+	}
+}
+
+func TestRequestOverridesReplaceProfileSettings(t *testing.T) {
+	for _, tc := range providerHTTPCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newProviderTestServer(t, http.StatusOK, tc.reply("COMMAND: du -sh *"))
+			provider := newProviderForServer(t, server, tc.profile)
+
+			temperature, maxTokens := 1.4, 96
+			overrides := llm.RequestOverrides{Temperature: &temperature, MaxTokens: &maxTokens}
+			if err := overrides.Validate(); err != nil {
+				t.Fatalf("Validate returned error: %v", err)
+			}
+
+			request := &llm.Request{Query: "show folder sizes"}
+			overrides.Apply(&request.Options, tc.profile)
+			if _, err := provider.GenerateCommand(context.Background(), request); err != nil {
+				t.Fatalf("GenerateCommand returned error: %v", err)
+			}
+
+			body := server.lastRequest(t).Body
+			maxTokensPath, temperaturePath := []interface{}{"max_tokens"}, []interface{}{"temperature"}
+			if tc.name == "gemini" {
+				maxTokensPath = []interface{}{"generationConfig", "maxOutputTokens"}
+				temperaturePath = []interface{}{"generationConfig", "temperature"}
+			}
+			assertJSONField(t, body, float64(96), maxTokensPath...)
+			assertJSONField(t, body, 1.4, temperaturePath...)
+		})
+	}
+}
+
+func TestRequestOverridesSendZeroTemperature(t *testing.T) {
+	for _, tc := range providerHTTPCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newProviderTestServer(t, http.StatusOK, tc.reply("COMMAND: du -sh *"))
+			provider := newProviderForServer(t, server, tc.profile)
+
+			// The profile's 0.3 must not replace an explicit --temperature 0
+			temperature := 0.0
+			request := &llm.Request{Query: "show folder sizes"}
+			llm.RequestOverrides{Temperature: &temperature}.Apply(&request.Options, tc.profile)
+			if _, err := provider.GenerateCommand(context.Background(), request); err != nil {
+				t.Fatalf("GenerateCommand returned error: %v", err)
+			}
+
+			temperaturePath := []interface{}{"temperature"}
+			if tc.name == "gemini" {
+				temperaturePath = []interface{}{"generationConfig", "temperature"}
+			}
+			assertJSONField(t, server.lastRequest(t).Body, float64(0), temperaturePath...)
+		})
+	}
+}
+
+func TestRequestOverridesKeepUnsetProfileSettings(t *testing.T) {
+	temperature, profileTemperature := 0.9, 0.1
+	options := llm.RequestOptions{MaxTokens: 200, Temperature: &profileTemperature}
+	llm.RequestOverrides{Temperature: &temperature}.Apply(&options, config.Profile{Provider: "openai", Model: "gpt-4o"})
+
+	if options.Temperature == nil || *options.Temperature != 0.9 || options.MaxTokens != 200 {
+		t.Errorf("options = %+v; want temperature 0.9 and the original max tokens", options)
+	}
+}
+
+func TestRequestOverridesValidate(t *testing.T) {
+	tooHot, negative, zeroTokens := 2.5, -0.1, 0
+	for name, overrides := range map[string]llm.RequestOverrides{
+		"temperature above 2":  {Temperature: &tooHot},
+		"negative temperature": {Temperature: &negative},
+		"zero max tokens":      {MaxTokens: &zeroTokens},
+	} {
+		if err := overrides.Validate(); err == nil {
+			t.Errorf("%s: expected a validation error", name)
+		}
+	}
+
+	if err := (llm.RequestOverrides{}).Validate(); err != nil {
+		t.Errorf("empty overrides should be valid: %v", err)
+	}
+}
+
+func TestProviderResponseParsing(t *testing.T) {
+	content := strings.Join([]string{
+		"COMMAND: `du -sh * | sort -h`",
+		"EXPLANATION: Shows the size of each entry, smallest first",
+		"DANGER_LEVEL: Safe",
+		"DANGER_REASON: Only reads file sizes",
+		"ALTERNATIVE: du -h --max-depth=1",
+		"ALTERNATIVE: none",
+	}, "\n")
+
+	for _, tc := range providerHTTPCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newProviderTestServer(t, http.StatusOK, tc.reply(content))
+			provider := newProviderForServer(t, server, tc.profile)
+
+			response, err := provider.GenerateCommand(context.Background(), &llm.Request{
+				Query:   "show folder sizes",
+				Options: llm.RequestOptions{IncludeExplanation: true},
+			})
+			if err != nil {
+				t.Fatalf("GenerateCommand returned error: %v", err)
+			}
+
+			if response.Command != "du -sh * | sort -h" {
+				t.Errorf("Command = %q; want the cleaned command", response.Command)
+			}
+			if response.Explanation != "Shows the size of each entry, smallest first" {
+				t.Errorf("Explanation = %q", response.Explanation)
+			}
+			if response.DangerLevel != llm.DangerLevelSafe || response.DangerReason != "Only reads file sizes" {
+				t.Errorf("danger = %q (%q); want safe (Only reads file sizes)", response.DangerLevel, response.DangerReason)
+			}
+			if len(response.Alternatives) != 1 || response.Alternatives[0] != "du -h --max-depth=1" {
+				t.Errorf("Alternatives = %q; want the one real alternative", response.Alternatives)
+			}
+			if response.Confidence != 0.9 {
+				t.Errorf("Confidence = %v; want 0.9 for a completed response", response.Confidence)
+			}
+			if response.Usage == nil || *response.Usage != (llm.Usage{PromptTokens: 20, CompletionTokens: 10, TotalTokens: 30}) {
+				t.Errorf("Usage = %+v; want 20 prompt, 10 completion and 30 total tokens", response.Usage)
+			}
+		})
+	}
+}
+
+func TestProviderErrorMapping(t *testing.T) {
+	want := map[int]llm.ErrorType{
+		http.StatusBadRequest:          llm.ErrorTypeInvalidInput,
+		http.StatusUnauthorized:        llm.ErrorTypeAuth,
+		http.StatusTooManyRequests:     llm.ErrorTypeRateLimit,
+		http.StatusInternalServerError: llm.ErrorTypeModel,
+	}
+
+	for _, tc := range providerHTTPCases {
+		for status, wantType := range want {
+			t.Run(tc.name+"/"+http.StatusText(status), func(t *testing.T) {
+				server := newProviderTestServer(t, status, tc.errorBody(status, tc.errorTypes[status], "something went wrong"), "Retry-After", "12")
+				provider := newProviderForServer(t, server, tc.profile)
+
+				_, err := provider.GenerateCommand(context.Background(), &llm.Request{Query: "show folder sizes"})
+
+				var llmErr *llm.Error
+				if !errors.As(err, &llmErr) {
+					t.Fatalf("expected an *llm.Error, got %v", err)
+				}
+				if llmErr.Type != wantType {
+					t.Errorf("Type = %q; want %q", llmErr.Type, wantType)
+				}
+				if !strings.Contains(llmErr.Message, "something went wrong") {
+					t.Errorf("Message = %q; want the API's message", llmErr.Message)
+				}
+				if status == http.StatusTooManyRequests && !strings.Contains(llmErr.Message, "retry in 12s") {
+					t.Errorf("Message = %q; want the Retry-After hint", llmErr.Message)
+				}
+			})
+		}
+	}
+}
+
+func TestErrorReportForAuthError(t *testing.T) {
+	server := newProviderTestServer(t, http.StatusUnauthorized,
+		`{"error": {"type": "authentication_error", "message": "Incorrect API key provided", "code": "invalid_api_key"}}`)
+	provider := newProviderForServer(t, server, config.Profile{Provider: "openai", APIKey: "sk-test", Model: "gpt-4o-mini"})
+
+	_, err := provider.GenerateCommand(context.Background(), &llm.Request{Query: "show folder sizes"})
+	if err == nil {
+		t.Fatal("expected an auth error")
+	}
+
+	data, marshalErr := json.Marshal(llm.NewErrorReport(fmt.Errorf("failed to generate command: %w", err)))
+	if marshalErr != nil {
+		t.Fatal(marshalErr)
+	}
+	var report map[string]map[string]string
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("report is not {\"error\": {...}}: %v\n%s", err, data)
+	}
+
+	want := map[string]map[string]string{"error": {
+		"type":    "auth",
+		"message": "failed to generate command: Incorrect API key provided",
+		"code":    "invalid_api_key",
+	}}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("report = %s; want %v", data, want)
+	}
+}
+
+func TestErrorReportForOtherErrors(t *testing.T) {
+	report := llm.NewErrorReport(errors.New("no saved query named 'deploy'"))
+	if report.Error.Type != llm.ErrorTypeUnknown || report.Error.Message != "no saved query named 'deploy'" || report.Error.Code != "" {
+		t.Errorf("report = %+v; want an unknown error with the message", report)
+	}
+}
+
+func TestProviderErrorWithoutBody(t *testing.T) {
+	for _, tc := range providerHTTPCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newProviderTestServer(t, http.StatusBadGateway, "upstream unavailable")
+			provider := newProviderForServer(t, server, tc.profile)
+
+			_, err := provider.GenerateCommand(context.Background(), &llm.Request{Query: "show folder sizes"})
+
+			var llmErr *llm.Error
+			if !errors.As(err, &llmErr) {
+				t.Fatalf("expected an *llm.Error, got %v", err)
+			}
+			if !strings.Contains(llmErr.Message, "HTTP 502") {
+				t.Errorf("Message = %q; want the HTTP status", llmErr.Message)
+			}
+		})
+	}
+}
@@ -1,7 +1,18 @@
 package tests
 
 import (
+	"bytes"
+	"context"
+	"forgor/internal/config"
 	"forgor/internal/llm"
+	"forgor/internal/utils"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -117,3 +128,403 @@ type providerTestError struct {
 func (e *providerTestError) Error() string {
 	return e.msg
 }
+
+func TestGetProviderUnsetAPIKeyEnvVar(t *testing.T) {
+	os.Unsetenv("FORGOR_TEST_MISSING_KEY")
+
+	cfg := &config.Config{
+		DefaultProfile: "openai",
+		Profiles: map[string]config.Profile{
+			"openai": {
+				Provider: "openai",
+				APIKey:   "${FORGOR_TEST_MISSING_KEY}",
+				Model:    "gpt-4",
+			},
+		},
+	}
+
+	factory := llm.NewFactory(cfg)
+	_, err := factory.GetProvider("openai")
+	if err == nil {
+		t.Fatal("GetProvider() should fail when the API key env var is unset")
+	}
+	if !strings.Contains(err.Error(), "FORGOR_TEST_MISSING_KEY") {
+		t.Errorf("Expected error to name the missing env var, got: %v", err)
+	}
+}
+
+func TestAPIKeyFile(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "openai_api_key")
+	secret := "sk-file-secret-1234567890"
+	if err := os.WriteFile(keyFile, []byte("  "+secret+"\n"), 0600); err != nil {
+		t.Fatalf("failed to write key file: %v", err)
+	}
+
+	profile := config.Profile{
+		Provider:   "openai",
+		APIKey:     "sk-should-be-ignored",
+		APIKeyFile: keyFile,
+		Model:      "gpt-4",
+	}
+
+	key, err := profile.ResolveAPIKey()
+	if err != nil {
+		t.Fatalf("ResolveAPIKey() returned error: %v", err)
+	}
+	if key != secret {
+		t.Errorf("Expected key to be loaded from file and trimmed, got %q", key)
+	}
+
+	cfg := &config.Config{
+		DefaultProfile: "openai",
+		Profiles:       map[string]config.Profile{"openai": profile},
+	}
+	factory := llm.NewFactory(cfg)
+
+	// Capture stdout to make sure the key never gets printed
+	oldStdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	os.Stdout = w
+
+	validateErr := factory.ValidateProvider("openai")
+	_, providerErr := factory.GetProvider("openai")
+
+	w.Close()
+	os.Stdout = oldStdout
+	output, _ := io.ReadAll(r)
+
+	if validateErr != nil {
+		t.Errorf("ValidateProvider() returned error: %v", validateErr)
+	}
+	if providerErr != nil {
+		t.Errorf("GetProvider() returned error: %v", providerErr)
+	}
+	if strings.Contains(string(output), secret) {
+		t.Error("API key from file must not be printed")
+	}
+}
+
+func TestAPIKeyFileMissing(t *testing.T) {
+	profile := config.Profile{
+		Provider:   "openai",
+		APIKeyFile: filepath.Join(t.TempDir(), "does-not-exist"),
+		Model:      "gpt-4",
+	}
+
+	cfg := &config.Config{
+		DefaultProfile: "openai",
+		Profiles:       map[string]config.Profile{"openai": profile},
+	}
+
+	if err := llm.NewFactory(cfg).ValidateProvider("openai"); err == nil {
+		t.Error("ValidateProvider() should fail when api_key_file does not exist")
+	}
+}
+
+func TestSupportedModels(t *testing.T) {
+	for _, providerType := range []string{"openai", "anthropic", "gemini"} {
+		if len(llm.SupportedModels(providerType)) == 0 {
+			t.Errorf("Expected known models for %s", providerType)
+		}
+	}
+
+	if !reflect.DeepEqual(llm.SupportedModels("google"), llm.SupportedModels("gemini")) {
+		t.Error("google should list the same models as gemini")
+	}
+	if llm.SupportedModels("unknown") != nil {
+		t.Error("Expected no models for an unsupported provider")
+	}
+
+	// Validation uses the same allowlist that is listed to users
+	cfg := &config.Config{
+		DefaultProfile: "gemini",
+		Profiles: map[string]config.Profile{
+			"gemini": {Provider: "gemini", APIKey: "test-key", Model: llm.SupportedModels("gemini")[0]},
+		},
+	}
+	if err := llm.NewFactory(cfg).ValidateProvider("gemini"); err != nil {
+		t.Errorf("Listed model should validate, got %v", err)
+	}
+}
+
+func TestFormatModelList(t *testing.T) {
+	output := llm.FormatModelList([]string{"gpt-4", "gpt-4-turbo"}, "gpt-4-turbo")
+	expected := "  • gpt-4\n  • gpt-4-turbo (current)\n"
+	if output != expected {
+		t.Errorf("FormatModelList() = %q; want %q", output, expected)
+	}
+
+	output = llm.FormatModelList([]string{"gpt-4"}, "gpt-5")
+	if !strings.Contains(output, "gpt-5 is not in this list") {
+		t.Errorf("Expected a note for an unlisted configured model, got %q", output)
+	}
+}
+
+func TestDefaultModelsAreSupported(t *testing.T) {
+	defaults := llm.GetDefaultModels()
+
+	for _, providerType := range llm.GetSupportedProviders() {
+		model, ok := defaults[providerType]
+		if !ok {
+			t.Errorf("Expected a default model for %s", providerType)
+			continue
+		}
+
+		supported := llm.SupportedModels(providerType)
+		if !contains(supported, model) {
+			t.Errorf("Default model %s for %s is not in its allowlist %v", model, providerType, supported)
+		}
+	}
+}
+
+func TestProviderInfoModelsMatchAllowlist(t *testing.T) {
+	providers := map[string]llm.Provider{
+		"openai":    llm.NewOpenAIProvider("test-key", "gpt-4"),
+		"anthropic": llm.NewAnthropicProvider("test-key", "claude-3-haiku-20240307"),
+		"gemini":    llm.NewGeminiProvider("test-key", "gemini-1.5-flash"),
+	}
+
+	for providerType, provider := range providers {
+		if !reflect.DeepEqual(provider.GetProviderInfo().Models, llm.SupportedModels(providerType)) {
+			t.Errorf("%s GetProviderInfo().Models should match the allowlist", providerType)
+		}
+	}
+}
+
+func TestProviderInfoLimitsMatchConfiguredModel(t *testing.T) {
+	tests := []struct {
+		provider      llm.Provider
+		maxTokens     int
+		contextWindow int
+	}{
+		{llm.NewOpenAIProvider("test-key", "gpt-4-turbo"), 4096, 128000},
+		{llm.NewOpenAIProvider("test-key", "gpt-4o"), 16384, 128000},
+		{llm.NewAnthropicProvider("test-key", "claude-3-5-sonnet-20241022"), 8192, 200000},
+		{llm.NewGeminiProvider("test-key", "gemini-2.5-pro"), 65536, 1048576},
+	}
+
+	for _, tt := range tests {
+		info := tt.provider.GetProviderInfo()
+		model := info.Metadata["model"]
+		if got := info.Limits["max_tokens"]; got != tt.maxTokens {
+			t.Errorf("%s max_tokens = %d; want %d", model, got, tt.maxTokens)
+		}
+		if got := info.Limits["context_window"]; got != tt.contextWindow {
+			t.Errorf("%s context_window = %d; want %d", model, got, tt.contextWindow)
+		}
+	}
+}
+
+func TestEveryKnownModelHasLimits(t *testing.T) {
+	for providerType, models := range llm.GetSupportedModels() {
+		for _, model := range models {
+			if _, known := llm.GetModelLimits(providerType, model); !known {
+				t.Errorf("%s model %s has no limits", providerType, model)
+			}
+		}
+	}
+}
+
+func TestClampMaxTokens(t *testing.T) {
+	if got, clamped := llm.ClampMaxTokens("openai", "gpt-4-turbo", 10000); got != 4096 || !clamped {
+		t.Errorf("ClampMaxTokens(gpt-4-turbo, 10000) = %d, %v; want 4096, true", got, clamped)
+	}
+	if got, clamped := llm.ClampMaxTokens("openai", "gpt-4o", 10000); got != 10000 || clamped {
+		t.Errorf("ClampMaxTokens(gpt-4o, 10000) = %d, %v; want it unchanged", got, clamped)
+	}
+
+	// Unknown models fall back to the provider's conservative default
+	limits, known := llm.GetModelLimits("google", "gemini-future")
+	if known || limits.MaxOutputTokens != 8192 {
+		t.Errorf("GetModelLimits(gemini-future) = %+v, %v; want the Gemini default", limits, known)
+	}
+}
+
+func TestNewOpenAIModelsAccepted(t *testing.T) {
+	for _, model := range []string{"gpt-4o", "gpt-4o-mini", "o1", "o1-mini", "o3-mini", "gpt-4.1"} {
+		if !contains(llm.SupportedModels("openai"), model) {
+			t.Errorf("Expected %s to be a known OpenAI model", model)
+		}
+	}
+}
+
+func TestUnknownModelWarnsInsteadOfFailing(t *testing.T) {
+	var buf bytes.Buffer
+	previous := utils.GetLogger()
+	utils.SetLogger(utils.NewTextLogger(&buf, utils.LogLevelInfo))
+	defer utils.SetLogger(previous)
+
+	cfg := &config.Config{
+		DefaultProfile: "openai",
+		Profiles: map[string]config.Profile{
+			"openai": {Provider: "openai", APIKey: "sk-test", Model: "gpt-7-preview"},
+			"known":  {Provider: "openai", APIKey: "sk-test", Model: "gpt-4o"},
+		},
+	}
+	factory := llm.NewFactory(cfg)
+
+	if err := factory.ValidateProvider("openai"); err != nil {
+		t.Errorf("Unknown model should not fail validation, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "Unknown OpenAI model: gpt-7-preview") {
+		t.Errorf("Expected a warning for the unknown model, got %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := factory.ValidateProvider("known"); err != nil {
+		t.Errorf("Known model should validate, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no warning for a known model, got %q", buf.String())
+	}
+}
+
+// contains checks if a slice contains a string
+func contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRegisterProvider(t *testing.T) {
+	t.Setenv("FORGOR_TEST_FAKE_KEY", "fake-secret")
+
+	fake := &mockProvider{}
+	var received config.Profile
+	llm.RegisterProvider("fake", func(profile config.Profile, opts ...llm.ProviderOption) (llm.Provider, error) {
+		received = profile
+		return fake, nil
+	})
+	t.Cleanup(func() { llm.UnregisterProvider("fake") })
+
+	if !contains(llm.GetSupportedProviders(), "fake") {
+		t.Errorf("Expected fake in supported providers, got %v", llm.GetSupportedProviders())
+	}
+
+	cfg := &config.Config{
+		DefaultProfile: "fake",
+		Profiles: map[string]config.Profile{
+			"fake": {Provider: "fake", APIKey: "${FORGOR_TEST_FAKE_KEY}", Model: "fake-model"},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() rejected a registered provider: %v", err)
+	}
+
+	provider, err := llm.NewFactory(cfg).GetProvider("default")
+	if err != nil {
+		t.Fatalf("GetProvider() returned error: %v", err)
+	}
+	if provider != llm.Provider(fake) {
+		t.Errorf("Expected the registered factory's provider, got %T", provider)
+	}
+	if received.APIKey != "fake-secret" || received.Model != "fake-model" {
+		t.Errorf("Expected the factory to get the resolved profile, got %+v", received)
+	}
+
+	llm.UnregisterProvider("fake")
+	if contains(llm.GetSupportedProviders(), "fake") {
+		t.Errorf("Expected fake to be removed, got %v", llm.GetSupportedProviders())
+	}
+	if _, err := llm.NewFactory(cfg).GetProvider("default"); err == nil {
+		t.Error("Expected an unregistered provider to be unsupported")
+	}
+}
+
+func TestRegisteredProviderGetsFactoryOptions(t *testing.T) {
+	// A third-party OpenAI-compatible provider, built with the options the
+	// factory passes on
+	llm.RegisterProvider("compatible", func(profile config.Profile, opts ...llm.ProviderOption) (llm.Provider, error) {
+		return llm.NewOpenAIProvider(profile.APIKey, profile.Model, opts...), nil
+	})
+	t.Cleanup(func() { llm.UnregisterProvider("compatible") })
+
+	server := newProviderTestServer(t, http.StatusOK, `{"choices": [{"index": 0, "message": {"role": "assistant", "content": "COMMAND: ls"}, "finish_reason": "stop"}]}`)
+	provider := newProviderForServer(t, server, config.Profile{
+		Provider:           "compatible",
+		APIKey:             "test-key",
+		Model:              "local-model",
+		SystemPromptPrefix: "Prefer fd over find.",
+	})
+	if _, err := provider.GenerateCommand(context.Background(), &llm.Request{Query: "find go files"}); err != nil {
+		t.Fatalf("GenerateCommand returned error: %v", err)
+	}
+
+	system, _ := jsonPath(server.lastRequest(t).Body, "messages", 0, "content").(string)
+	if !strings.HasPrefix(system, "Prefer fd over find.") {
+		t.Errorf("Expected the profile's system prompt prefix, got %q", system[:min(len(system), 80)])
+	}
+}
+
+func TestRegisterProviderKeepsBuiltIns(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected RegisterProvider to refuse to replace a built-in provider")
+		}
+		missingKey := config.Profile{Provider: "openai", Model: "gpt-4o-mini"}
+		if err := missingKey.Validate(); err == nil {
+			t.Error("Expected openai profiles to still require an API key")
+		}
+	}()
+
+	llm.UnregisterProvider("openai")
+	if !contains(llm.GetSupportedProviders(), "openai") {
+		t.Fatal("UnregisterProvider removed a built-in provider")
+	}
+	llm.RegisterProvider("openai", func(profile config.Profile, opts ...llm.ProviderOption) (llm.Provider, error) {
+		return &mockProvider{}, nil
+	})
+}
+
+func TestFactoryGetProviderConcurrently(t *testing.T) {
+	cfg := &config.Config{
+		DefaultProfile: "openai",
+		Profiles: map[string]config.Profile{
+			"openai":    {Provider: "openai", APIKey: "sk-test", Model: "gpt-4o-mini"},
+			"anthropic": {Provider: "anthropic", APIKey: "sk-ant-test", Model: "claude-3-5-haiku-latest"},
+			"ollama":    {Provider: "ollama", Model: "llama3"},
+		},
+	}
+	factory := llm.NewFactory(cfg)
+	profiles := []string{"openai", "anthropic", "ollama", "default"}
+
+	// Run with -race to check the provider cache is guarded
+	const callsPerProfile = 20
+	results := make([][]llm.Provider, len(profiles))
+	var wg sync.WaitGroup
+	for i, name := range profiles {
+		results[i] = make([]llm.Provider, callsPerProfile)
+		for j := 0; j < callsPerProfile; j++ {
+			wg.Add(1)
+			go func(i, j int, name string) {
+				defer wg.Done()
+				provider, err := factory.GetProvider(name)
+				if err != nil {
+					t.Errorf("GetProvider(%q) returned error: %v", name, err)
+				}
+				results[i][j] = provider
+			}(i, j, name)
+		}
+	}
+	wg.Wait()
+
+	for i, name := range profiles {
+		for _, provider := range results[i] {
+			if provider == nil || provider != results[i][0] {
+				t.Fatalf("GetProvider(%q) returned different providers to concurrent callers", name)
+			}
+		}
+	}
+	if results[0][0] != results[3][0] {
+		t.Error("the default profile should share the openai provider")
+	}
+	if results[0][0] == results[1][0] {
+		t.Error("different profiles should get different providers")
+	}
+}
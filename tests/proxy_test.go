@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"forgor/internal/config"
+	"forgor/internal/llm"
+)
+
+// newTestProxy returns a fake HTTP proxy that answers every request itself
+// with a canned Gemini response, counting the requests it receives
+func newTestProxy(t *testing.T) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var hits int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "COMMAND: ls"}]}, "finishReason": "STOP"}]}`))
+	}))
+	t.Cleanup(proxy.Close)
+	return proxy, &hits
+}
+
+func TestProviderUsesConfiguredProxy(t *testing.T) {
+	proxy, hits := newTestProxy(t)
+
+	provider := llm.NewGeminiProvider("test-key", "gemini-1.5-flash", llm.WithProxy(proxy.URL))
+	provider.SetBaseURL("http://generativelanguage.invalid/v1beta")
+
+	response, err := provider.GenerateCommand(context.Background(), &llm.Request{Query: "list files"})
+	if err != nil {
+		t.Fatalf("GenerateCommand returned error: %v", err)
+	}
+	if atomic.LoadInt32(hits) != 1 {
+		t.Errorf("Expected the request to go through the proxy, proxy saw %d requests", atomic.LoadInt32(hits))
+	}
+	if response.Command != "ls" {
+		t.Errorf("Command = %q; want %q", response.Command, "ls")
+	}
+}
+
+func TestProfileProxyValidation(t *testing.T) {
+	profile := config.Profile{Provider: "openai", APIKey: "sk-test", Model: "gpt-4", Proxy: "http://proxy.corp:8080"}
+	if err := profile.Validate(); err != nil {
+		t.Errorf("Expected valid proxy, got %v", err)
+	}
+
+	profile.Proxy = "not a url"
+	if err := profile.Validate(); err == nil {
+		t.Error("Expected an error for an invalid proxy URL")
+	}
+}
@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"forgor/internal/llm"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		header   string
+		value    string
+		expected time.Duration
+	}{
+		{"retry-after seconds", "Retry-After", "30", 30 * time.Second},
+		{"retry-after http date", "Retry-After", "Sat, 01 Jun 2024 12:01:00 GMT", time.Minute},
+		{"openai duration", "x-ratelimit-reset-requests", "6m0s", 6 * time.Minute},
+		{"openai milliseconds", "x-ratelimit-reset-tokens", "250ms", 250 * time.Millisecond},
+		{"unix timestamp", "x-ratelimit-reset", "1717243215", 15 * time.Second},
+		{"anthropic rfc3339", "anthropic-ratelimit-requests-reset", "2024-06-01T12:00:45Z", 45 * time.Second},
+		{"unparseable", "Retry-After", "soon", 0},
+		{"missing", "X-Other", "10", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			header.Set(tt.header, tt.value)
+
+			if got := llm.RetryAfter(header, now); got != tt.expected {
+				t.Errorf("RetryAfter(%s: %s) = %v; want %v", tt.header, tt.value, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatRetryHint(t *testing.T) {
+	tests := []struct {
+		wait     time.Duration
+		expected string
+	}{
+		{0, ""},
+		{250 * time.Millisecond, "retry in 1s"},
+		{30 * time.Second, "retry in 30s"},
+		{6 * time.Minute, "retry in 6m0s"},
+	}
+
+	for _, tt := range tests {
+		if got := llm.FormatRetryHint(tt.wait); got != tt.expected {
+			t.Errorf("FormatRetryHint(%v) = %q; want %q", tt.wait, got, tt.expected)
+		}
+	}
+}
+
+func TestRateLimitErrorIncludesRetryHint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "12")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error": {"code": 429, "message": "Resource has been exhausted", "status": "RESOURCE_EXHAUSTED"}}`))
+	}))
+	defer server.Close()
+
+	provider := llm.NewGeminiProvider("test-key", "gemini-1.5-flash")
+	provider.SetBaseURL(server.URL)
+
+	_, err := provider.GenerateCommand(context.Background(), &llm.Request{Query: "list files"})
+
+	var llmErr *llm.Error
+	if !errors.As(err, &llmErr) {
+		t.Fatalf("Expected *llm.Error, got %v", err)
+	}
+	if llmErr.Type != llm.ErrorTypeRateLimit {
+		t.Errorf("Type = %s; want %s", llmErr.Type, llm.ErrorTypeRateLimit)
+	}
+	if !strings.Contains(llmErr.Message, "retry in 12s") {
+		t.Errorf("Expected retry hint in message, got %q", llmErr.Message)
+	}
+}
@@ -0,0 +1,47 @@
+package tests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"forgor/internal/utils"
+)
+
+func TestSelectCommand(t *testing.T) {
+	commands := []string{"du -sh *", "du -h --max-depth=1", "ncdu"}
+
+	tests := []struct {
+		name  string
+		input string
+		want  utils.Selection
+	}{
+		{"run primary", "1\n", utils.Selection{Index: 0, Action: utils.SelectionRun}},
+		{"run alternative", "3\n", utils.Selection{Index: 2, Action: utils.SelectionRun}},
+		{"copy alternative", "c2\n", utils.Selection{Index: 1, Action: utils.SelectionCopy}},
+		{"skip with enter", "\n", utils.Selection{Action: utils.SelectionNone}},
+		{"skip with q", "q\n", utils.Selection{Action: utils.SelectionNone}},
+		{"retry after invalid input", "7\nabc\n2\n", utils.Selection{Index: 1, Action: utils.SelectionRun}},
+		{"last line without newline", "2", utils.Selection{Index: 1, Action: utils.SelectionRun}},
+		{"end of input", "", utils.Selection{Action: utils.SelectionNone}},
+		{"end of input after invalid", "9\n", utils.Selection{Action: utils.SelectionNone}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			got, err := utils.SelectCommand(strings.NewReader(tt.input), &out, commands)
+			if err != nil {
+				t.Fatalf("SelectCommand returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("SelectCommand(%q) = %+v; want %+v", tt.input, got, tt.want)
+			}
+			for _, command := range commands {
+				if !strings.Contains(out.String(), command) {
+					t.Errorf("output should list %q, got:\n%s", command, out.String())
+				}
+			}
+		})
+	}
+}
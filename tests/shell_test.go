@@ -1,10 +1,14 @@
 package tests
 
 import (
+	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
 
+	"forgor/internal/llm"
+	"forgor/internal/security"
 	"forgor/internal/utils"
 )
 
@@ -46,6 +50,13 @@ func TestGetOperatingSystem(t *testing.T) {
 	}
 }
 
+func TestGetPlatform(t *testing.T) {
+	expected := runtime.GOOS + "/" + runtime.GOARCH
+	if platform := utils.GetPlatform(); platform != expected {
+		t.Errorf("expected platform %s, got %s", expected, platform)
+	}
+}
+
 func TestNormalizeShellName(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -60,7 +71,15 @@ func TestNormalizeShellName(t *testing.T) {
 		{"fish", "fish"},
 		{"/usr/local/bin/fish", "fish"},
 		{"sh", "sh"},
-		{"/bin/sh", "/bin/sh"}, // Doesn't contain "bash", "zsh", etc. so returns as-is
+		{"/bin/sh", "sh"},
+		{"dash", "dash"},
+		{"/usr/bin/dash", "dash"},
+		{"ksh", "ksh"},
+		{"/bin/ksh93", "ksh"},
+		{"mksh", "ksh"},
+		{"nu", "nu"},
+		{"/opt/homebrew/bin/nu", "nu"},
+		{"nushell", "nu"},
 		{"powershell", "powershell"},
 		{"pwsh", "powershell"}, // pwsh maps to powershell
 		{"cmd", "cmd"},
@@ -84,9 +103,11 @@ func TestIsShellSupported(t *testing.T) {
 		{"bash", true},
 		{"zsh", true},
 		{"fish", true},
-		{"sh", false},         // Only bash, zsh, fish are supported
-		{"powershell", false}, // Only bash, zsh, fish are supported
-		{"pwsh", false},       // Only bash, zsh, fish are supported
+		{"sh", true},
+		{"dash", true},
+		{"ksh", true},
+		{"powershell", false},
+		{"pwsh", false},
 		{"cmd", false},
 		{"unknown", false},
 		{"", false},
@@ -100,6 +121,49 @@ func TestIsShellSupported(t *testing.T) {
 	}
 }
 
+func TestGetShellHistoryFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	tests := []struct {
+		shell    string
+		expected string
+	}{
+		{"bash", filepath.Join(home, ".bash_history")},
+		{"sh", filepath.Join(home, ".bash_history")},
+		{"dash", filepath.Join(home, ".bash_history")},
+		{"ksh", filepath.Join(home, ".sh_history")},
+		{"zsh", filepath.Join(home, ".zsh_history")},
+		{"fish", filepath.Join(home, ".local", "share", "fish", "fish_history")},
+		{"powershell", ""},
+	}
+
+	for _, test := range tests {
+		if result := utils.GetShellHistoryFile(test.shell); result != test.expected {
+			t.Errorf("GetShellHistoryFile(%s) = %q; want %q", test.shell, result, test.expected)
+		}
+	}
+}
+
+func TestReadKshHistory(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	content := "ls -la\ncd /tmp\necho done\n"
+	if err := os.WriteFile(filepath.Join(home, ".sh_history"), []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write history: %v", err)
+	}
+
+	commands, err := utils.ReadShellHistory("ksh", 2)
+	if err != nil {
+		t.Fatalf("ReadShellHistory returned error: %v", err)
+	}
+	expected := []string{"cd /tmp", "echo done"}
+	if strings.Join(commands, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("ReadShellHistory(ksh) = %v; want %v", commands, expected)
+	}
+}
+
 func TestGetWorkingDirectory(t *testing.T) {
 	wd := utils.GetWorkingDirectory()
 
@@ -173,3 +237,75 @@ func TestFilterSensitiveCommands(t *testing.T) {
 		}
 	}
 }
+
+func TestRunContextBenchmark(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping context benchmark in short mode")
+	}
+
+	result, err := utils.RunContextBenchmark()
+	if err != nil {
+		t.Fatalf("RunContextBenchmark returned error: %v", err)
+	}
+
+	if len(result.Summary.Steps) == 0 {
+		t.Error("Expected timing steps to be recorded")
+	}
+	if result.StepDuration("Cold Build (RefreshSystemContext)") <= 0 {
+		t.Error("Expected a cold build timing")
+	}
+	if len(result.ToolCounts) != 8 {
+		t.Errorf("Expected counts for 8 tool categories, got %d", len(result.ToolCounts))
+	}
+	if !strings.Contains(result.Report(), "TOOLS DETECTED") {
+		t.Error("Expected the report to include tool counts")
+	}
+}
+
+func TestEnhanceContextWithWorkingDirectory(t *testing.T) {
+	dir := t.TempDir()
+	base := llm.Context{OS: "linux", Shell: "bash", WorkingDirectory: "/home/user/project"}
+
+	ctx, err := llm.EnhanceContextWithWorkingDirectory(base, dir)
+	if err != nil {
+		t.Fatalf("EnhanceContextWithWorkingDirectory returned error: %v", err)
+	}
+	if ctx.WorkingDirectory != dir {
+		t.Errorf("WorkingDirectory = %q; want %q", ctx.WorkingDirectory, dir)
+	}
+	if ctx.OS != "linux" || ctx.Shell != "bash" {
+		t.Error("Other context fields should be preserved")
+	}
+
+	if _, err := llm.EnhanceContextWithWorkingDirectory(base, filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected an error for a directory that does not exist")
+	}
+
+	file := filepath.Join(dir, "file.txt")
+	os.WriteFile(file, []byte("x"), 0644)
+	if _, err := llm.EnhanceContextWithWorkingDirectory(base, file); err == nil {
+		t.Error("Expected an error for a path that is not a directory")
+	}
+}
+
+func TestWorkingDirectoryOverrideReachesDangerAssessment(t *testing.T) {
+	ctx, err := llm.EnhanceContextWithWorkingDirectory(llm.Context{WorkingDirectory: t.TempDir()}, "/")
+	if err != nil {
+		t.Fatalf("EnhanceContextWithWorkingDirectory returned error: %v", err)
+	}
+
+	// Medium-risk commands are escalated when run from the root directory
+	assessment := security.NewDangerDetector().AssessCommand("killall -9 node", &ctx)
+	if assessment.Level != llm.DangerLevelHigh {
+		t.Errorf("Expected the overridden root directory to raise the level to high, got %s", assessment.Level)
+	}
+	found := false
+	for _, factor := range assessment.Factors {
+		if factor == "Operating in root directory" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected a root directory factor, got %v", assessment.Factors)
+	}
+}
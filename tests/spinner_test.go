@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"forgor/internal/utils"
+)
+
+func TestSpinnerSuppressedWithoutTerminal(t *testing.T) {
+	var buf bytes.Buffer
+	spinner := utils.StartSpinner(&buf, "Generating command...")
+	if spinner.Active() {
+		t.Error("spinner should not run on a non-terminal writer")
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	spinner.Stop()
+	spinner.Stop()
+
+	if buf.Len() != 0 {
+		t.Errorf("expected no spinner output, got %q", buf.String())
+	}
+}
+
+func TestSpinnerSuppressedForRedirectedFile(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "stderr"))
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	defer file.Close()
+
+	if utils.IsTerminal(file) {
+		t.Fatal("a regular file should not be a terminal")
+	}
+
+	spinner := utils.StartSpinner(file, "Generating command...")
+	spinner.Stop()
+
+	info, err := file.Stat()
+	if err != nil {
+		t.Fatalf("failed to stat file: %v", err)
+	}
+	if spinner.Active() || info.Size() != 0 {
+		t.Errorf("expected no spinner output in a redirected file, got %d bytes", info.Size())
+	}
+}
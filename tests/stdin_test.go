@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"forgor/internal/prompt"
+	"forgor/internal/utils"
+)
+
+func TestResolveQueryFromPipedStdin(t *testing.T) {
+	stdin := strings.NewReader("find large files\nin my home directory\n")
+
+	query, err := utils.ResolveQuery(nil, stdin, true)
+	if err != nil {
+		t.Fatalf("ResolveQuery returned error: %v", err)
+	}
+	if query != "find large files in my home directory" {
+		t.Errorf("Unexpected query: %q", query)
+	}
+
+	request := &prompt.Request{Query: query}
+	if !strings.Contains(prompt.BuildCommandPrompt(request), "find large files in my home directory") {
+		t.Error("Expected the piped query to be included in the request prompt")
+	}
+}
+
+func TestResolveQueryDashReadsStdin(t *testing.T) {
+	query, err := utils.ResolveQuery([]string{"-"}, strings.NewReader("list open ports"), false)
+	if err != nil {
+		t.Fatalf("ResolveQuery returned error: %v", err)
+	}
+	if query != "list open ports" {
+		t.Errorf("Unexpected query: %q", query)
+	}
+}
+
+func TestResolveQueryPrefersArgs(t *testing.T) {
+	query, err := utils.ResolveQuery([]string{"show", "disk", "usage"}, strings.NewReader("ignored"), true)
+	if err != nil {
+		t.Fatalf("ResolveQuery returned error: %v", err)
+	}
+	if query != "show disk usage" {
+		t.Errorf("Unexpected query: %q", query)
+	}
+}
+
+func TestResolveQueryErrors(t *testing.T) {
+	if _, err := utils.ResolveQuery(nil, strings.NewReader(""), false); err == nil {
+		t.Error("Expected an error with no args and a terminal stdin")
+	}
+	if _, err := utils.ResolveQuery(nil, strings.NewReader("  \n "), true); err == nil {
+		t.Error("Expected an error for empty piped input")
+	}
+}
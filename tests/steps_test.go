@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"forgor/internal/utils"
+)
+
+// runStepsInShell runs steps with sh, returning their results and output
+func runStepsInShell(t *testing.T, steps []string, continueOnError bool) ([]utils.StepResult, string, error) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	var output bytes.Buffer
+	results, err := utils.RunSteps(steps, utils.StepRun{
+		Shell:           "sh",
+		ContinueOnError: continueOnError,
+		Stdout:          &output,
+		Stderr:          io.Discard,
+	})
+	return results, output.String(), err
+}
+
+func TestRunStepsStopsOnFirstFailure(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "marker")
+
+	results, _, err := runStepsInShell(t, []string{"true", "(exit 3)", "touch " + marker}, false)
+	if err == nil || !strings.Contains(err.Error(), "step 2 of 3") {
+		t.Fatalf("Expected an error naming step 2, got %v", err)
+	}
+	if utils.ExitCode(err) != 3 {
+		t.Errorf("ExitCode(err) = %d; want the failed step's 3", utils.ExitCode(err))
+	}
+	if len(results) != 2 || results[0].ExitCode != 0 || results[1].ExitCode != 3 {
+		t.Errorf("Expected the second result to record the failure, got %+v", results)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("Step after the failure should not have run")
+	}
+}
+
+func TestRunStepsContinueOnError(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "marker")
+
+	results, _, err := runStepsInShell(t, []string{"false", "true", "touch " + marker}, true)
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 steps failed (steps 1)") {
+		t.Fatalf("Expected an error summarising the failed step, got %v", err)
+	}
+	if len(results) != 3 || results[0].ExitCode != 1 || results[1].ExitCode != 0 || results[2].ExitCode != 0 {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("Step after the failure should have run with continueOnError: %v", err)
+	}
+}
+
+func TestRunStepsReportsFailedStep(t *testing.T) {
+	_, _, err := runStepsInShell(t, []string{"echo preparing", "echo 'no such file' >&2; (exit 2)", "true"}, false)
+
+	var cmdErr *utils.CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("Expected a *utils.CommandError, got %v", err)
+	}
+	if cmdErr.Result.Command != "echo 'no such file' >&2; (exit 2)" || cmdErr.Result.ExitCode != 2 {
+		t.Errorf("Result = %+v; want the failed step and its exit code", cmdErr.Result)
+	}
+	if !strings.Contains(cmdErr.Result.Output, "no such file") {
+		t.Errorf("Output = %q; want the step's error output", cmdErr.Result.Output)
+	}
+}
+
+func TestRunStepsStepExitsShell(t *testing.T) {
+	results, _, err := runStepsInShell(t, []string{"true", "exit 4", "true"}, true)
+	if err == nil || !strings.Contains(err.Error(), "steps 2") {
+		t.Fatalf("Expected an error naming step 2, got %v", err)
+	}
+	if len(results) != 2 || results[1].ExitCode != 4 {
+		t.Errorf("Expected the step that exited the shell to be reported, got %+v", results)
+	}
+}
+
+func TestRunStepsAllSucceed(t *testing.T) {
+	results, _, err := runStepsInShell(t, []string{"true", "true"}, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Expected 2 results, got %d", len(results))
+	}
+}
+
+func TestRunStepsShareShellState(t *testing.T) {
+	dir := t.TempDir()
+	steps := []string{
+		"cd " + utils.ShellQuote(dir),
+		"export FORGOR_STEP_VALUE=kept",
+		`echo "$FORGOR_STEP_VALUE" > value`,
+	}
+
+	if _, _, err := runStepsInShell(t, steps, false); err != nil {
+		t.Fatalf("RunSteps returned error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "value"))
+	if err != nil {
+		t.Fatalf("Later steps should run in the directory of an earlier cd: %v", err)
+	}
+	if strings.TrimSpace(string(data)) != "kept" {
+		t.Errorf("value = %q; want the variable exported by an earlier step", data)
+	}
+}
+
+func TestRunStepsPrintsHeaders(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	var output bytes.Buffer
+	_, err := utils.RunSteps([]string{"echo one", "echo 'two'"}, utils.StepRun{
+		Shell:  "fish", // replaced by sh, as the script is POSIX
+		Stdout: &output,
+		Stderr: io.Discard,
+		Header: func(index int, step string) string { return "== " + step },
+	})
+	if err != nil {
+		t.Fatalf("RunSteps returned error: %v", err)
+	}
+	if want := "== echo one\none\n== echo 'two'\ntwo\n"; output.String() != want {
+		t.Errorf("output = %q; want %q", output.String(), want)
+	}
+}
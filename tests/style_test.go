@@ -120,3 +120,45 @@ func TestDivider(t *testing.T) {
 		t.Errorf("Divider should contain title '%s'", title)
 	}
 }
+
+const sampleMarkdownExplanation = "## Overview\n" +
+	"This uses **find** to search for `*.log` files.\n" +
+	"- `-name` matches the *file name*\n" +
+	"* `-delete` removes matches, see [the docs](https://example.com/find)\n" +
+	"```bash\n" +
+	"find . -name \"*.log\" -delete\n" +
+	"```\n" +
+	"Keeps my_var_name and 2 * 3 intact."
+
+func TestStripMarkdown(t *testing.T) {
+	expected := "Overview\n" +
+		"This uses find to search for *.log files.\n" +
+		"• -name matches the file name\n" +
+		"• -delete removes matches, see the docs (https://example.com/find)\n" +
+		"  find . -name \"*.log\" -delete\n" +
+		"Keeps my_var_name and 2 * 3 intact."
+
+	result := utils.StripMarkdown(sampleMarkdownExplanation)
+	if result != expected {
+		t.Errorf("StripMarkdown() =\n%s\nwant\n%s", result, expected)
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	result := utils.RenderMarkdown(sampleMarkdownExplanation)
+
+	if strings.Contains(result, "**") || strings.Contains(result, "`") || strings.Contains(result, "##") {
+		t.Errorf("Rendered markdown should not contain markup, got %q", result)
+	}
+	if !strings.Contains(result, utils.Bold+"find"+utils.Reset) {
+		t.Errorf("Expected bold text to be styled, got %q", result)
+	}
+	if !strings.Contains(result, utils.Styled("*.log", utils.StyleCommand)) {
+		t.Errorf("Expected inline code to be styled as a command, got %q", result)
+	}
+
+	// Styling must not change the visible text
+	if utils.StripANSI(result) != utils.StripMarkdown(sampleMarkdownExplanation) {
+		t.Errorf("Rendered text differs from stripped text:\n%q\n%q", utils.StripANSI(result), utils.StripMarkdown(sampleMarkdownExplanation))
+	}
+}
@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"forgor/internal/utils"
+)
+
+func TestDetectToolsOrderIsStable(t *testing.T) {
+	first := utils.DetectTools()
+	second := utils.DetectTools()
+
+	second.LastChecked = first.LastChecked
+	second.CategoryChecked = first.CategoryChecked
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("detections differ:\n%+v\n%+v", first, second)
+	}
+
+	for name, list := range map[string][]string{
+		"PackageManagers": first.PackageManagers,
+		"SystemCommands":  first.SystemCommands,
+		"ContainerTools":  first.ContainerTools,
+		"CloudTools":      first.CloudTools,
+		"DatabaseTools":   first.DatabaseTools,
+		"NetworkTools":    first.NetworkTools,
+	} {
+		if !slices.IsSorted(list) {
+			t.Errorf("%s not sorted: %v", name, list)
+		}
+	}
+	if !slices.IsSortedFunc(first.Languages, func(a, b utils.LanguageRuntime) int { return strings.Compare(a.Name, b.Name) }) {
+		t.Errorf("Languages not sorted: %v", first.Languages)
+	}
+	if !slices.IsSortedFunc(first.DevelopmentTools, func(a, b utils.Tool) int { return strings.Compare(a.Name, b.Name) }) {
+		t.Errorf("DevelopmentTools not sorted: %v", first.DevelopmentTools)
+	}
+}
+
+// seededToolContext returns a ToolContext with a made-up tool in every
+// category, so a re-detected category is the one that loses its tool
+func seededToolContext(checked time.Time) utils.ToolContext {
+	tools := utils.ToolContext{
+		PackageManagers:  []string{"fake-pm"},
+		Languages:        []utils.LanguageRuntime{{Name: "fake-lang"}},
+		DevelopmentTools: []utils.Tool{{Name: "fake-dev"}},
+		SystemCommands:   []string{"fake-cmd"},
+		ContainerTools:   []string{"fake-container"},
+		CloudTools:       []string{"fake-cloud"},
+		DatabaseTools:    []string{"fake-db"},
+		NetworkTools:     []string{"fake-net"},
+		CategoryChecked:  make(map[string]time.Time),
+	}
+	for _, category := range utils.ToolCategories() {
+		tools.CategoryChecked[category] = checked
+	}
+	return tools
+}
+
+func TestRefreshCategoryOnlyRedetectsThatCategory(t *testing.T) {
+	checked := time.Now().Add(-time.Minute).Truncate(time.Second)
+	tools := seededToolContext(checked)
+	original := tools.CategoryChecked
+
+	if err := tools.RefreshCategory(utils.ToolCategoryContainerTools); err != nil {
+		t.Fatalf("RefreshCategory returned error: %v", err)
+	}
+
+	if slices.Contains(tools.ContainerTools, "fake-container") {
+		t.Errorf("container_tools was not re-detected: %v", tools.ContainerTools)
+	}
+	if !tools.CategoryChecked[utils.ToolCategoryContainerTools].After(checked) {
+		t.Errorf("container_tools timestamp was not updated: %v", tools.CategoryChecked[utils.ToolCategoryContainerTools])
+	}
+	if len(tools.Languages) != 1 || tools.Languages[0].Name != "fake-lang" || !reflect.DeepEqual(tools.NetworkTools, []string{"fake-net"}) {
+		t.Errorf("Other categories were re-detected: %+v", tools)
+	}
+	for _, category := range utils.ToolCategories() {
+		if category != utils.ToolCategoryContainerTools && !tools.CategoryChecked[category].Equal(checked) {
+			t.Errorf("%s timestamp changed to %v", category, tools.CategoryChecked[category])
+		}
+	}
+	if !tools.Available["fake-lang"] || tools.Available["fake-container"] {
+		t.Errorf("Availability map not rebuilt: %v", tools.Available)
+	}
+	if !original[utils.ToolCategoryContainerTools].Equal(checked) {
+		t.Error("RefreshCategory modified the original timestamps map")
+	}
+
+	if err := tools.RefreshCategory("gpu_tools"); err == nil || !strings.Contains(err.Error(), "unknown tool category") {
+		t.Errorf("Expected an unknown category error, got %v", err)
+	}
+}
+
+func TestRefreshStaleOnlyRedetectsStaleCategories(t *testing.T) {
+	now := time.Now()
+	tools := seededToolContext(now.Add(-time.Hour))
+	// Languages are fresh for a day, container tools only for minutes
+	refreshed := tools.RefreshStale(now)
+
+	if !reflect.DeepEqual(refreshed, []string{utils.ToolCategoryContainerTools}) {
+		t.Fatalf("RefreshStale refreshed %v; want only container_tools", refreshed)
+	}
+	if slices.Contains(tools.ContainerTools, "fake-container") {
+		t.Errorf("container_tools was not re-detected: %v", tools.ContainerTools)
+	}
+	if !tools.CategoryChecked[utils.ToolCategoryContainerTools].Equal(now) {
+		t.Errorf("container_tools timestamp = %v; want %v", tools.CategoryChecked[utils.ToolCategoryContainerTools], now)
+	}
+	if !reflect.DeepEqual(tools.PackageManagers, []string{"fake-pm"}) || !reflect.DeepEqual(tools.CloudTools, []string{"fake-cloud"}) {
+		t.Errorf("Fresh categories were re-detected: %+v", tools)
+	}
+
+	// A category without a timestamp is stale
+	delete(tools.CategoryChecked, utils.ToolCategoryDatabaseTools)
+	if refreshed := tools.RefreshStale(now); !reflect.DeepEqual(refreshed, []string{utils.ToolCategoryDatabaseTools}) {
+		t.Errorf("RefreshStale refreshed %v; want only database_tools", refreshed)
+	}
+}
@@ -0,0 +1,447 @@
+package tests
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"forgor/internal/utils"
+)
+
+// crossDeviceRename fails like os.Rename across filesystems whenever the
+// source and target are in different directories
+func crossDeviceRename(calls *[]string) func(oldpath, newpath string) error {
+	return func(oldpath, newpath string) error {
+		*calls = append(*calls, oldpath)
+		if filepath.Dir(oldpath) != filepath.Dir(newpath) {
+			return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+		}
+		return os.Rename(oldpath, newpath)
+	}
+}
+
+// writeUpdateFixture creates a downloaded binary and an installed one in
+// separate directories
+func writeUpdateFixture(t *testing.T) (newPath, target string) {
+	t.Helper()
+	newPath = filepath.Join(t.TempDir(), "forgor")
+	target = filepath.Join(t.TempDir(), "forgor")
+	if err := os.WriteFile(newPath, []byte("new version"), 0600); err != nil {
+		t.Fatalf("failed to write update: %v", err)
+	}
+	if err := os.WriteFile(target, []byte("old version"), 0755); err != nil {
+		t.Fatalf("failed to write current binary: %v", err)
+	}
+	return newPath, target
+}
+
+func TestReplaceExecutableDirectRename(t *testing.T) {
+	newPath, target := writeUpdateFixture(t)
+
+	if err := utils.ReplaceExecutableWith(newPath, target, os.Rename); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertInstalledUpdate(t, target)
+}
+
+func TestReplaceExecutableCrossDeviceFallback(t *testing.T) {
+	newPath, target := writeUpdateFixture(t)
+
+	var calls []string
+	if err := utils.ReplaceExecutableWith(newPath, target, crossDeviceRename(&calls)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(calls) != 2 {
+		t.Fatalf("expected a failed direct rename and a rename from beside the target, got %v", calls)
+	}
+	if filepath.Dir(calls[1]) != filepath.Dir(target) {
+		t.Errorf("expected the fallback to rename from %s, got %s", filepath.Dir(target), calls[1])
+	}
+
+	assertInstalledUpdate(t, target)
+
+	// No temporary files are left beside the target
+	entries, err := os.ReadDir(filepath.Dir(target))
+	if err != nil {
+		t.Fatalf("failed to read install directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the installed binary, found %d entries", len(entries))
+	}
+}
+
+func TestReplaceExecutableFallbackFailureKeepsCurrentBinary(t *testing.T) {
+	newPath, target := writeUpdateFixture(t)
+
+	rename := func(oldpath, newpath string) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EACCES}
+	}
+
+	err := utils.ReplaceExecutableWith(newPath, target, rename)
+	if err == nil || !errors.Is(err, syscall.EACCES) {
+		t.Fatalf("expected the rename error, got %v", err)
+	}
+
+	data, _ := os.ReadFile(target)
+	if string(data) != "old version" {
+		t.Errorf("expected the current binary to be untouched, got %q", data)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(target))
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".update-") {
+			t.Errorf("expected the temporary copy to be removed, found %s", entry.Name())
+		}
+	}
+}
+
+// assertInstalledUpdate checks target holds the new, executable binary
+func assertInstalledUpdate(t *testing.T, target string) {
+	t.Helper()
+
+	data, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("failed to read installed binary: %v", err)
+	}
+	if string(data) != "new version" {
+		t.Errorf("expected the new version to be installed, got %q", data)
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		t.Fatalf("failed to stat installed binary: %v", err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("expected mode 0755, got %o", info.Mode().Perm())
+	}
+}
+
+// writeTarGz creates a gzipped tar archive with the given files and modes
+func writeTarGz(t *testing.T, path string, files map[string]string, mode int64) {
+	t.Helper()
+
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+	defer file.Close()
+
+	gzw := gzip.NewWriter(file)
+	tw := tar.NewWriter(gzw)
+	for name, content := range files {
+		header := &tar.Header{Name: name, Mode: mode, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("failed to write header: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to close tar: %v", err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatalf("failed to close gzip: %v", err)
+	}
+}
+
+func TestFindExtractedBinaryNested(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "forgor_linux_amd64.tar.gz")
+	writeTarGz(t, archive, map[string]string{
+		"forgor_linux_amd64/README.md":   "readme",
+		"forgor_linux_amd64/LICENSE":     "license",
+		"forgor_linux_amd64/bin/forgor":  "nested deeper",
+		"forgor_linux_amd64/forgor":      "binary",
+		"forgor_linux_amd64/docs/forgor": "",
+	}, 0755)
+
+	if err := utils.ExtractTarGz(archive, dir); err != nil {
+		t.Fatalf("failed to extract: %v", err)
+	}
+
+	binary, err := utils.FindExtractedBinary(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := filepath.Join(dir, "forgor_linux_amd64", "forgor"); binary != expected {
+		t.Errorf("expected the shallowest binary %s, got %s", expected, binary)
+	}
+}
+
+func TestFindExtractedBinaryMissing(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "forgor"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("readme"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	// A directory named forgor is not the binary
+	if _, err := utils.FindExtractedBinary(dir); err == nil || !strings.Contains(err.Error(), "no forgor binary") {
+		t.Errorf("expected a clear not found error, got %v", err)
+	}
+}
+
+func TestVerifyBinary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not executable on Windows")
+	}
+
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "forgor")
+	if err := os.WriteFile(valid, []byte("#!/bin/sh\necho 'forgor - remember shell commands'\n"), 0600); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+	if err := utils.VerifyBinary(valid); err != nil {
+		t.Errorf("expected the binary to verify, got %v", err)
+	}
+
+	other := filepath.Join(dir, "other")
+	if err := os.WriteFile(other, []byte("#!/bin/sh\necho 'something else'\n"), 0600); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+	if err := utils.VerifyBinary(other); err == nil {
+		t.Error("expected a binary that isn't forgor to be rejected")
+	}
+
+	broken := filepath.Join(dir, "broken")
+	if err := os.WriteFile(broken, []byte("not an executable"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := utils.VerifyBinary(broken); err == nil {
+		t.Error("expected a binary that fails to run to be rejected")
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2.4", "1.2.3", 1},
+		{"1.10.0", "1.9.0", 1},
+		{"2.0", "1.99.99", 1},
+		{"1.2.0-rc.1", "1.2.0", -1},
+		{"1.2.0-rc.2", "1.2.0-rc.1", 1},
+		{"1.2.0-rc.10", "1.2.0-rc.2", 1},
+		{"1.2.0-alpha", "1.2.0-beta", -1},
+		{"1.2.0-alpha.1", "1.2.0-alpha", 1},
+		{"1.2.0-1", "1.2.0-alpha", -1},
+		{"1.2.0+build.5", "1.2.0", 0},
+		{"dev", "0.0.1", -1},
+		{"1.0.0", "unknown", 1},
+	}
+
+	for _, tt := range tests {
+		if got := utils.CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d; want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestGetLatestReleaseRejectsUnknownChannel(t *testing.T) {
+	if _, err := utils.GetLatestRelease(context.Background(), "nightly"); err == nil || !strings.Contains(err.Error(), "invalid update channel") {
+		t.Errorf("expected an invalid channel error, got %v", err)
+	}
+}
+
+func TestUpdateThenRollback(t *testing.T) {
+	newPath, target := writeUpdateFixture(t)
+	backupDir := t.TempDir()
+
+	if err := utils.BackupExecutable(target, "1.0.0", backupDir); err != nil {
+		t.Fatalf("failed to back up: %v", err)
+	}
+	if err := utils.ReplaceExecutable(newPath, target); err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "new version" {
+		t.Fatalf("expected the update to be installed, got %q", data)
+	}
+
+	info, err := utils.RestoreExecutable(backupDir)
+	if err != nil {
+		t.Fatalf("failed to roll back: %v", err)
+	}
+	if info.Version != "1.0.0" || info.Executable != target {
+		t.Errorf("unexpected backup info %+v", info)
+	}
+
+	data, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("failed to read restored binary: %v", err)
+	}
+	if string(data) != "old version" {
+		t.Errorf("expected the original bytes to be restored, got %q", data)
+	}
+	if runtime.GOOS != "windows" {
+		if stat, _ := os.Stat(target); stat.Mode().Perm() != 0755 {
+			t.Errorf("expected the restored binary to be executable, got %o", stat.Mode().Perm())
+		}
+	}
+}
+
+func TestRollbackWithoutBackup(t *testing.T) {
+	if _, err := utils.RestoreExecutable(t.TempDir()); err == nil || !strings.Contains(err.Error(), "no previous version") {
+		t.Errorf("expected a clear error without a backup, got %v", err)
+	}
+}
+
+func TestUpdateCheckDue(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		checkedAt time.Time
+		interval  time.Duration
+		want      bool
+	}{
+		{"never checked", time.Time{}, 24 * time.Hour, true},
+		{"checked recently", now.Add(-time.Hour), 24 * time.Hour, false},
+		{"interval just passed", now.Add(-24 * time.Hour), 24 * time.Hour, true},
+		{"custom interval", now.Add(-2 * time.Hour), time.Hour, true},
+		{"default interval when unset", now.Add(-12 * time.Hour), 0, false},
+		{"clock moved backwards", now.Add(time.Hour), 24 * time.Hour, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := utils.UpdateCheckState{CheckedAt: tt.checkedAt}
+			if got := utils.UpdateCheckDue(state, now, tt.interval); got != tt.want {
+				t.Errorf("UpdateCheckDue() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateNotice(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   utils.UpdateCheckState
+		current string
+		want    bool
+	}{
+		{"newer release", utils.UpdateCheckState{LatestVersion: "1.3.0"}, "1.2.0", true},
+		{"already announced", utils.UpdateCheckState{LatestVersion: "1.3.0", NotifiedVersion: "1.3.0"}, "1.2.0", false},
+		{"up to date", utils.UpdateCheckState{LatestVersion: "1.2.0"}, "1.2.0", false},
+		{"never checked", utils.UpdateCheckState{}, "1.2.0", false},
+		{"development build", utils.UpdateCheckState{LatestVersion: "1.3.0"}, "dev", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notice := utils.UpdateNotice(tt.state, tt.current)
+			if (notice != "") != tt.want {
+				t.Errorf("UpdateNotice() = %q; want notice: %v", notice, tt.want)
+			}
+			if tt.want && !strings.Contains(notice, tt.state.LatestVersion) {
+				t.Errorf("notice should name the new version, got %q", notice)
+			}
+		})
+	}
+}
+
+func TestRefreshUpdateCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "forgor", "update_check.json")
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// A failed check leaves the state alone so it is retried
+	failing := func() (*utils.ReleaseInfo, error) { return nil, errors.New("offline") }
+	if err := utils.RefreshUpdateCheck(path, now, failing); err == nil {
+		t.Fatal("RefreshUpdateCheck should return the check's error")
+	}
+	if !utils.UpdateCheckDue(utils.LoadUpdateCheckState(path), now, time.Hour) {
+		t.Error("a failed check should still be due")
+	}
+
+	if err := utils.SaveUpdateCheckState(path, utils.UpdateCheckState{NotifiedVersion: "1.2.0"}); err != nil {
+		t.Fatal(err)
+	}
+	latest := func() (*utils.ReleaseInfo, error) { return &utils.ReleaseInfo{TagName: "1.3.0"}, nil }
+	if err := utils.RefreshUpdateCheck(path, now, latest); err != nil {
+		t.Fatalf("RefreshUpdateCheck returned error: %v", err)
+	}
+
+	state := utils.LoadUpdateCheckState(path)
+	if !state.CheckedAt.Equal(now) || state.LatestVersion != "1.3.0" || state.NotifiedVersion != "1.2.0" {
+		t.Errorf("state = %+v; want check time, latest version and the earlier notified version", state)
+	}
+	if utils.UpdateCheckDue(state, now.Add(time.Hour), 24*time.Hour) {
+		t.Error("a fresh check should not be due again within the interval")
+	}
+}
+func TestDownloadUpdateRequiresGitHub(t *testing.T) {
+	if _, err := utils.DownloadUpdate(context.Background(), "https://example.com/forgor.tar.gz", nil); err == nil {
+		t.Error("expected DownloadUpdate to reject hosts other than GitHub")
+	}
+}
+
+// writeTarGzHeaders creates a gzipped tar archive of header-only entries
+func writeTarGzHeaders(t *testing.T, path string, headers []*tar.Header) {
+	t.Helper()
+
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+	defer file.Close()
+
+	gzw := gzip.NewWriter(file)
+	tw := tar.NewWriter(gzw)
+	for _, header := range headers {
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("failed to write header %s: %v", header.Name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to close tar: %v", err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatalf("failed to close gzip: %v", err)
+	}
+}
+
+func TestExtractTarGzRejectsLinks(t *testing.T) {
+	tests := []struct {
+		name   string
+		header *tar.Header
+	}{
+		{"escaping symlink", &tar.Header{Name: "forgor", Linkname: "../../etc/passwd", Typeflag: tar.TypeSymlink}},
+		{"absolute symlink", &tar.Header{Name: "forgor", Linkname: "/usr/bin/sudo", Typeflag: tar.TypeSymlink}},
+		{"hardlink", &tar.Header{Name: "forgor", Linkname: "../outside", Typeflag: tar.TypeLink}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			archive := filepath.Join(dir, "forgor.tar.gz")
+			writeTarGzHeaders(t, archive, []*tar.Header{tt.header})
+
+			dest := filepath.Join(dir, "out")
+			err := utils.ExtractTarGz(archive, dest)
+			if err == nil || !strings.Contains(err.Error(), "link") {
+				t.Fatalf("expected the link to be rejected, got %v", err)
+			}
+			if _, err := os.Lstat(filepath.Join(dest, "forgor")); !os.IsNotExist(err) {
+				t.Errorf("expected no link to be created, got %v", err)
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package tests
 
 import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -119,3 +123,100 @@ type testError struct {
 func (e *testError) Error() string {
 	return e.msg
 }
+
+func TestAppendTimingSummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics", "timing.jsonl")
+
+	for i, name := range []string{"Config Loading", "LLM API Request"} {
+		timer := utils.NewTimer("Command Execution", false)
+		timer.AddStep(name, time.Duration(i+1)*time.Millisecond, time.Now())
+
+		if err := utils.AppendTimingSummary(path, timer.GetSummary()); err != nil {
+			t.Fatalf("AppendTimingSummary returned error: %v", err)
+		}
+	}
+
+	summaries, err := utils.ReadTimingSummaries(path)
+	if err != nil {
+		t.Fatalf("ReadTimingSummaries returned error: %v", err)
+	}
+
+	if len(summaries) != 2 {
+		t.Fatalf("Expected 2 summaries, got %d", len(summaries))
+	}
+	if summaries[0].Steps[0].Name != "Config Loading" || summaries[1].Steps[0].Name != "LLM API Request" {
+		t.Errorf("Unexpected step names: %+v", summaries)
+	}
+	if summaries[1].Steps[0].Duration != 2*time.Millisecond {
+		t.Errorf("Expected duration 2ms, got %v", summaries[1].Steps[0].Duration)
+	}
+}
+
+func TestAppendTimingSummaryConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timing.jsonl")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			timer := utils.NewTimer("Concurrent", false)
+			timer.AddStep("Step", time.Millisecond, time.Now())
+			if err := utils.AppendTimingSummary(path, timer.GetSummary()); err != nil {
+				t.Errorf("AppendTimingSummary returned error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	summaries, err := utils.ReadTimingSummaries(path)
+	if err != nil {
+		t.Fatalf("ReadTimingSummaries returned error: %v", err)
+	}
+	if len(summaries) != 20 {
+		t.Errorf("Expected 20 summaries, got %d", len(summaries))
+	}
+}
+
+func TestWriteUpdateStatusDevBuilds(t *testing.T) {
+	called := false
+	latest := func() (*utils.ReleaseInfo, error) {
+		called = true
+		return &utils.ReleaseInfo{TagName: "9.9.9"}, nil
+	}
+
+	var buf bytes.Buffer
+	utils.WriteUpdateStatus(&buf, "dev", false, latest)
+	if called || !strings.Contains(buf.String(), "update check skipped") {
+		t.Errorf("expected the check to be skipped for dev builds, got %q", buf.String())
+	}
+
+	buf.Reset()
+	utils.WriteUpdateStatus(&buf, "dev", true, latest)
+	if !called || !strings.Contains(buf.String(), "A new version of forgor is available") {
+		t.Errorf("expected --check to force the check, got %q", buf.String())
+	}
+}
+
+func TestWriteUpdateStatusComparesVersions(t *testing.T) {
+	latest := func() (*utils.ReleaseInfo, error) {
+		return &utils.ReleaseInfo{TagName: "1.2.0"}, nil
+	}
+
+	tests := []struct {
+		current    string
+		wantUpdate bool
+	}{
+		{"1.3.0-rc.1", false},
+		{"v1.2.0", false},
+		{"1.2.0-rc.2", true},
+		{"1.1.9", true},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		utils.WriteUpdateStatus(&buf, tt.current, false, latest)
+		if got := strings.Contains(buf.String(), "A new version of forgor is available"); got != tt.wantUpdate {
+			t.Errorf("WriteUpdateStatus(%s) offered an update = %v; want %v: %q", tt.current, got, tt.wantUpdate, buf.String())
+		}
+	}
+}